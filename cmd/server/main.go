@@ -2,7 +2,7 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,9 +13,20 @@ import (
 	"smart_alert_system/internal/config"
 	"smart_alert_system/internal/handler"
 	"smart_alert_system/internal/infrastructure/ai"
+	"smart_alert_system/internal/infrastructure/cache"
+	"smart_alert_system/internal/infrastructure/channel"
 	"smart_alert_system/internal/infrastructure/database"
+	"smart_alert_system/internal/infrastructure/health"
+	"smart_alert_system/internal/infrastructure/ical"
+	"smart_alert_system/internal/infrastructure/logging"
+	"smart_alert_system/internal/infrastructure/mailbox"
+	"smart_alert_system/internal/infrastructure/metrics"
+	"smart_alert_system/internal/infrastructure/outbox"
 	infraRepo "smart_alert_system/internal/infrastructure/repository"
 	"smart_alert_system/internal/infrastructure/scheduler"
+	"smart_alert_system/internal/infrastructure/speech"
+	"smart_alert_system/internal/infrastructure/telegram"
+	"smart_alert_system/internal/infrastructure/tracing"
 	"smart_alert_system/internal/infrastructure/whatsapp"
 	"smart_alert_system/internal/usecase"
 
@@ -26,16 +37,46 @@ func main() {
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		fatal("Failed to load config", "error", err)
+	}
+
+	logLevel, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fatal("Failed to configure logging", "error", err)
+	}
+	logging.Setup(os.Stdout, logging.Config{Level: logLevel, RedactPII: cfg.LogRedactPII})
+	if !cfg.LogRedactPII {
+		slog.Warn("PII redaction disabled, message contents and phone numbers are logged")
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint:    cfg.OTLPEndpoint,
+		ServiceName: cfg.TracingServiceName,
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		fatal("Failed to configure tracing", "error", err)
+	}
+	// Deferred first so it runs last and flushes the spans of everything
+	// that shuts down before it
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+	}()
+	if cfg.OTLPEndpoint != "" {
+		slog.Info("Tracing enabled", "endpoint", cfg.OTLPEndpoint, "sample_ratio", cfg.TracingSampleRatio)
 	}
 
 	// Connect to database
 	db, err := database.NewPostgresDB(cfg.DatabaseURL)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal("Failed to connect to database", "error", err)
 	}
 	defer db.Close()
-	log.Println("✓ Connected to database")
+	slog.Info("Connected to database")
 
 	// Initialize repositories
 	userRepo := infraRepo.NewUserRepository(db)
@@ -44,12 +85,50 @@ func main() {
 	alertRepo := infraRepo.NewAlertRepository(db)
 	healthRepo := infraRepo.NewHealthRepository(db)
 	categoryRepo := infraRepo.NewCategoryRepository(db)
+	outboxRepo := infraRepo.NewOutboxRepository(db)
+	webSessionRepo := infraRepo.NewWebSessionRepository(db)
+	calendarSubscriptionRepo := infraRepo.NewCalendarSubscriptionRepository(db)
+	confirmationRepo := infraRepo.NewPendingConfirmationRepository(db)
+	identityRepo := infraRepo.NewUserIdentityRepository(db)
+	contactRepo := infraRepo.NewWhatsAppContactRepository(db)
+	groupMemberRepo := infraRepo.NewGroupMemberRepository(db)
+	caregiverRepo := infraRepo.NewCaregiverRepository(db)
 
 	// Initialize infrastructure services
-	wahaClient := whatsapp.NewWahaClient(cfg.WahaServerURL, cfg.WahaAPIKey)
+	// One Waha client per session; each number has its own send limits
+	var wahaSessions []*whatsapp.Session
+	for _, sc := range cfg.WahaSessions {
+		client := whatsapp.NewWahaClient(cfg.WahaServerURL, sc.Name, sc.APIKey)
+		client.SetRateLimiter(whatsapp.NewRateLimiter(whatsapp.RateLimitConfig{
+			GlobalPerMinute: cfg.WahaRateGlobalPerMinute,
+			GlobalBurst:     cfg.WahaRateGlobalBurst,
+			ChatPerMinute:   cfg.WahaRateChatPerMinute,
+			ChatBurst:       cfg.WahaRateChatBurst,
+			JitterMin:       cfg.WahaSendJitterMin,
+			JitterMax:       cfg.WahaSendJitterMax,
+		}))
+		wahaSessions = append(wahaSessions, &whatsapp.Session{
+			Client:  client,
+			Welcome: sc.Welcome,
+			Persona: sc.Persona,
+		})
+		slog.Info("WAHA session configured", "session", sc.Name)
+	}
+	sessions := whatsapp.NewSessions(wahaSessions...)
+
+	// Messaging channels users talk to the assistant through
+	whatsappChannel := whatsapp.NewChannel(sessions)
+	connected := []channel.Channel{whatsappChannel}
+	var telegramChannel *telegram.Channel
+	if cfg.TelegramBotToken != "" {
+		telegramChannel = telegram.NewChannel(telegram.NewClient(cfg.TelegramAPIURL, cfg.TelegramBotToken), cfg.TelegramWebhookSecret)
+		connected = append(connected, telegramChannel)
+		slog.Info("Telegram bot enabled")
+	}
+	channels := channel.NewChannels(connected...)
 
 	// Initialize AI Service
-	var aiService ai.AIService
+	var openAIService *ai.OpenAIService
 
 	if cfg.AIProvider == "ollama" || cfg.AIBaseURL != "" {
 		// Using Ollama (free, local)
@@ -59,73 +138,253 @@ func main() {
 		if cfg.AIModel == "" {
 			cfg.AIModel = "llama3.2" // Default Ollama model
 		}
-		log.Printf("✓ AI Service: Ollama (Free)")
-		log.Printf("  Base URL: %s", cfg.AIBaseURL)
-		log.Printf("  Model: %s", cfg.AIModel)
-		aiService = ai.NewOpenAIService("", cfg.AIModel, cfg.AIBaseURL)
+		slog.Info("AI service configured", "provider", "ollama", "base_url", cfg.AIBaseURL, "model", cfg.AIModel)
+		openAIService = ai.NewOpenAIService("", cfg.AIModel, cfg.AIBaseURL)
+		openAIService.SetProvider("ollama")
 	} else {
 		// Using OpenAI or other provider
 		if cfg.AIApiKey == "" {
-			log.Fatalf("❌ AI_API_KEY is not set in .env file. Please add your OpenAI API key, or use Ollama by setting AI_PROVIDER=ollama")
+			fatal("AI_API_KEY is not set in .env file. Please add your OpenAI API key, or use Ollama by setting AI_PROVIDER=ollama")
 		}
 		if cfg.AIModel == "" {
-			log.Printf("⚠️  AI_MODEL not set, using default: gpt-3.5-turbo")
+			slog.Warn("AI_MODEL not set, using default", "model", "gpt-3.5-turbo")
 			cfg.AIModel = "gpt-3.5-turbo"
 		}
 
 		// Normalize model name - ensure it has 'gpt-' prefix if it's a version number
 		normalizedModel := normalizeModelName(cfg.AIModel)
 		if normalizedModel != cfg.AIModel {
-			log.Printf("⚠️  Model name normalized: %s -> %s", cfg.AIModel, normalizedModel)
+			slog.Warn("Model name normalized", "from", cfg.AIModel, "to", normalizedModel)
 			cfg.AIModel = normalizedModel
 		}
 
-		log.Printf("✓ AI Service: OpenAI")
-		log.Printf("  Model: %s", cfg.AIModel)
-		aiService = ai.NewOpenAIService(cfg.AIApiKey, cfg.AIModel, cfg.AIBaseURL)
+		slog.Info("AI service configured", "provider", "openai", "model", cfg.AIModel)
+		openAIService = ai.NewOpenAIService(cfg.AIApiKey, cfg.AIModel, cfg.AIBaseURL)
+		openAIService.SetProvider(cfg.AIProvider)
+	}
+	if cfg.AIVisionModel != "" {
+		slog.Info("AI vision model configured", "model", cfg.AIVisionModel)
+		openAIService.SetVisionModel(cfg.AIVisionModel)
+	}
+	var aiService ai.AIService = openAIService
+	switch cfg.LLMCache {
+	case "":
+		slog.Info("LLM cache disabled")
+	case "memory":
+		slog.Info("LLM cache configured", "backend", "memory", "size", cfg.LLMCacheSize, "ttl", cfg.LLMCacheTTL)
+		aiService = ai.NewCachedService(openAIService, cache.NewLRU(cfg.LLMCacheSize), cfg.LLMCacheTTL)
+	case "postgres":
+		slog.Info("LLM cache configured", "backend", "postgres", "ttl", cfg.LLMCacheTTL)
+		aiService = ai.NewCachedService(openAIService, cache.NewPostgres(db), cfg.LLMCacheTTL)
+	default:
+		fatal("Unknown LLM_CACHE (use memory or postgres)", "llm_cache", cfg.LLMCache)
 	}
 
 	// Initialize use cases
-	userUseCase := usecase.NewUserUseCase(userRepo)
-	activityUseCase := usecase.NewActivityUseCase(activityRepo, userRepo, categoryRepo)
+	userUseCase := usecase.NewUserUseCase(userRepo, identityRepo, contactRepo)
+	// LIDs and phone numbers of the same person lead to one user
+	userUseCase.SetContactLookup(whatsappChannel)
+	groupUseCase := usecase.NewGroupUseCase(userRepo, identityRepo, groupMemberRepo)
+	activityUseCase := usecase.NewActivityUseCase(activityRepo, userRepo, categoryRepo, alertRepo)
+	outboxUseCase := usecase.NewOutboxUseCase(
+		outboxRepo,
+		messageRepo,
+		alertRepo,
+		healthRepo,
+		userRepo,
+		identityRepo,
+		channels,
+		usecase.RetryPolicy{
+			MaxAttempts: cfg.OutboxMaxAttempts,
+			BaseBackoff: cfg.OutboxBaseBackoff,
+			MaxBackoff:  cfg.OutboxMaxBackoff,
+		},
+	)
 	schedulerUseCase := usecase.NewSchedulerUseCase(
 		userRepo,
 		activityRepo,
 		healthRepo,
 		alertRepo,
 		aiService,
-		wahaClient,
+		outboxUseCase,
+		usecase.SchedulerConfig{
+			SpreadWindow:      cfg.AlertSpreadWindow,
+			Concurrency:       cfg.AlertConcurrency,
+			PageSize:          cfg.AlertPageSize,
+			GenerationTimeout: cfg.AlertAITimeout,
+			RunTimeout:        cfg.AlertRunTimeout,
+		},
+	)
+
+	schedulerUseCase.SetSessions(sessions)
+	schedulerUseCase.SetGroups(groupUseCase)
+
+	caregiverUseCase := usecase.NewCaregiverUseCase(
+		caregiverRepo,
+		userRepo,
+		activityRepo,
+		categoryRepo,
+		alertRepo,
+		outboxUseCase,
+		usecase.CaregiverConfig{
+			EscalationDelay: cfg.CaregiverEscalationDelay,
+			Lookback:        cfg.CaregiverEscalationLookback,
+			Categories:      cfg.CaregiverCategories,
+		},
+	)
+
+	healthUseCase := usecase.NewHealthUseCase(healthRepo)
+	webAuthUseCase := usecase.NewWebAuthUseCase(
+		userRepo,
+		webSessionRepo,
+		outboxUseCase,
+		usecase.WebAuthConfig{
+			CodeTTL:         cfg.WebLoginCodeTTL,
+			SessionTTL:      cfg.WebSessionTTL,
+			ResendInterval:  cfg.WebLoginResendInterval,
+			MaxFailedLogins: cfg.WebLoginMaxFailures,
+			LockoutWindow:   cfg.WebLoginLockoutWindow,
+		},
+	)
+
+	location, err := cfg.GetLocation()
+	if err != nil {
+		fatal("Failed to load location", "error", err)
+	}
+	calendarUseCase := usecase.NewCalendarUseCase(
+		userRepo,
+		activityRepo,
+		categoryRepo,
+		calendarSubscriptionRepo,
+		ical.NewFetcher(cfg.CalendarFetchTimeout, int64(cfg.CalendarMaxBytes)),
+		location,
+		usecase.CalendarConfig{
+			MaxBytes:      int64(cfg.CalendarMaxBytes),
+			SyncInterval:  cfg.CalendarSyncInterval,
+			SyncBatchSize: 20,
+		},
+	)
+
+	imageUseCase := usecase.NewImageUseCase(
+		aiService,
+		activityUseCase,
+		userRepo,
+		categoryRepo,
+		confirmationRepo,
+		location,
+		usecase.ImageConfig{
+			MaxBytes:        int64(cfg.ImageMaxBytes),
+			ConfirmationTTL: cfg.ConfirmationTTL,
+		},
 	)
 
+	// `server trigger ...` sends one alert from the command line and exits
+	if len(os.Args) > 1 && os.Args[1] == "trigger" {
+		if err := runTrigger(context.Background(), schedulerUseCase, userRepo, os.Args[2:]); err != nil {
+			fatal("Trigger failed", "error", err)
+		}
+		return
+	}
+
+	// Messages from the same user are processed in order, one at a time
+	messageMailbox := mailbox.NewMailbox()
+
 	// Initialize handlers
-	whatsappHandler := handler.NewWhatsAppHandler(
+	messageHandler := handler.NewMessageHandler(
 		userUseCase,
 		activityUseCase,
 		aiService,
-		wahaClient,
+		outboxUseCase,
 		messageRepo,
 		alertRepo,
+		messageMailbox,
+		calendarUseCase,
+		imageUseCase,
+		groupUseCase,
+		caregiverUseCase,
 	)
-
-	// Setup scheduler
-	location, err := cfg.GetLocation()
-	if err != nil {
-		log.Fatalf("Failed to load location: %v", err)
+	switch cfg.STTProvider {
+	case "":
+		slog.Warn("Speech-to-text disabled, voice notes are not transcribed (set STT_PROVIDER to enable)")
+	case "openai":
+		slog.Info("Speech-to-text configured", "provider", "openai", "model", cfg.STTModel)
+		messageHandler.SetTranscriber(
+			speech.NewOpenAITranscriber(cfg.STTApiKey, cfg.STTModel, cfg.STTBaseURL, cfg.STTLanguage, cfg.STTTimeout),
+			int64(cfg.STTMaxBytes),
+		)
+	case "whisper":
+		slog.Info("Speech-to-text configured", "provider", "whisper")
+		messageHandler.SetTranscriber(
+			speech.NewWhisperTranscriber(cfg.STTBaseURL, cfg.STTLanguage, cfg.STTTimeout),
+			int64(cfg.STTMaxBytes),
+		)
+	default:
+		fatal("Unknown STT_PROVIDER (use openai or whisper)", "stt_provider", cfg.STTProvider)
 	}
 
-	sched := scheduler.NewScheduler(schedulerUseCase, cfg.MorningAlertTime, cfg.EveningSummaryTime, location)
+	// Setup scheduler
+	// Only one instance (the advisory lock holder) runs scheduled jobs
+	leaderLock := scheduler.NewLeaderLock(db)
+	sched := scheduler.NewScheduler(schedulerUseCase, cfg.MorningAlertTime, cfg.EveningSummaryTime, location,
+		leaderLock, cfg.SchedulerCatchUpWindow)
+	sched.SetCalendarSync(calendarUseCase)
+	sched.SetCaregivers(caregiverUseCase)
 	if err := sched.Start(); err != nil {
-		log.Fatalf("Failed to start scheduler: %v", err)
+		fatal("Failed to start scheduler", "error", err)
 	}
 	defer sched.Stop()
 
+	// All outbound messages are delivered from the outbox
+	dispatcher := outbox.NewDispatcher(outboxUseCase, cfg.OutboxPollInterval, cfg.OutboxBatchSize)
+	dispatcher.Start()
+	defer dispatcher.Stop()
+
 	// Setup HTTP router
 	router := mux.NewRouter()
-	router.HandleFunc("/webhook", whatsappHandler.HandleWebhook).Methods("POST")
-	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}).Methods("GET")
+	// Every request's log records share its correlation ID
+	router.Use(logging.Middleware)
+	router.HandleFunc("/webhook", messageHandler.Webhook(whatsappChannel)).Methods("POST")
+	if telegramChannel != nil {
+		router.HandleFunc("/webhook/telegram", messageHandler.Webhook(telegramChannel)).Methods("POST")
+	}
+
+	// Admin REST API, only enabled when an API key is configured
+	if cfg.AdminAPIKey != "" {
+		adminHandler := handler.NewAdminHandler(userUseCase, activityUseCase, schedulerUseCase, alertRepo, messageRepo, location)
+		adminHandler.RegisterRoutes(router, cfg.AdminAPIKey)
+		slog.Info("Admin API enabled", "path", handler.APIBasePath, "docs", handler.APIBasePath+"/openapi.json")
+	} else {
+		slog.Warn("ADMIN_API_KEY not set, admin API disabled")
+	}
+
+	// User dashboard
+	webHandler := handler.NewWebHandler(webAuthUseCase, activityUseCase, healthUseCase, calendarUseCase, location, handler.WebConfig{
+		SecureCookies:     cfg.WebCookieSecure,
+		PublicURL:         cfg.PublicBaseURL,
+		LoginRatePerIP:    cfg.WebLoginRatePerIP,
+		TrustProxyHeaders: cfg.WebTrustProxyHeaders,
+	})
+	webHandler.RegisterRoutes(router)
+
+	// iCalendar feeds, authenticated by the secret token in the URL
+	calendarHandler := handler.NewCalendarHandler(calendarUseCase)
+	calendarHandler.RegisterRoutes(router)
+
+	// Prometheus metrics
+	router.Handle("/metrics", metrics.Handler(cfg.MetricsToken)).Methods("GET")
+
+	// Liveness and readiness probes. Only the database and the scheduler are
+	// critical: without Waha messages wait in the outbox, and without the LLM
+	// the fallback parser takes over.
+	checker := health.NewChecker(cfg.HealthCheckTimeout)
+	checker.Add("database", true, health.Database(db))
+	for _, session := range sessions.All() {
+		checker.Add("waha:"+session.Name(), false, health.WahaSession(session.Client))
+	}
+	checker.Add("llm", false, health.Cached(cfg.HealthLLMProbeInterval, health.LLM(openAIService)))
+	checker.Add("scheduler", true, health.Scheduler(sched, 3*time.Minute))
+	healthHandler := handler.NewHealthHandler(checker)
+	healthHandler.RegisterRoutes(router)
 
 	// Start HTTP server
 	server := &http.Server{
@@ -138,9 +397,9 @@ func main() {
 
 	// Start server in goroutine
 	go func() {
-		log.Printf("Server starting on port %s", cfg.AppPort)
+		slog.Info("Server starting", "port", cfg.AppPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			fatal("Server failed to start", "error", err)
 		}
 	}()
 
@@ -149,17 +408,28 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	slog.Info("Shutting down server")
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		fatal("Server forced to shutdown", "error", err)
 	}
 
-	log.Println("Server exited")
+	// Let in-flight user messages finish before closing the database
+	if err := messageMailbox.Shutdown(ctx); err != nil {
+		slog.Warn("Timed out waiting for queued messages", "error", err)
+	}
+
+	slog.Info("Server exited")
+}
+
+// fatal logs msg as an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
 
 // normalizeModelName ensures the model name has the correct format
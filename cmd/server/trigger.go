@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/usecase"
+)
+
+var triggerTypes = map[string]entity.AlertType{
+	"morning":  entity.AlertTypeMorning,
+	"evening":  entity.AlertTypeEvening,
+	"reminder": entity.AlertTypeActivityReminder,
+}
+
+// runTrigger implements `server trigger`: it runs one alert now and prints the
+// report as JSON. Messages are only queued in the outbox; the running server's
+// dispatcher delivers them.
+func runTrigger(ctx context.Context, schedulerUseCase *usecase.SchedulerUseCase, userRepo repository.UserRepository, args []string) error {
+	flags := flag.NewFlagSet("trigger", flag.ContinueOnError)
+	alertType := flags.String("type", "morning", "alert to send: morning, evening or reminder")
+	userArg := flags.String("user", "", "user ID or WhatsApp number (default: all active users)")
+	activityArg := flags.String("activity", "", "activity ID to remind about (reminder only)")
+	dryRun := flags.Bool("dry-run", false, "print the generated text without sending it or writing alert_logs")
+	force := flags.Bool("force", false, "resend a morning alert or evening summary already sent today")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	req := usecase.TriggerRequest{DryRun: *dryRun, Force: *force}
+
+	var ok bool
+	if req.AlertType, ok = triggerTypes[*alertType]; !ok {
+		return fmt.Errorf("unknown alert type %q (use morning, evening or reminder)", *alertType)
+	}
+
+	if *userArg != "" {
+		userID, err := resolveUser(ctx, userRepo, *userArg)
+		if err != nil {
+			return err
+		}
+		req.UserID = &userID
+	}
+
+	if *activityArg != "" {
+		activityID, err := uuid.Parse(*activityArg)
+		if err != nil {
+			return fmt.Errorf("invalid activity ID: %w", err)
+		}
+		req.ActivityID = &activityID
+	}
+
+	report, err := schedulerUseCase.Trigger(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// resolveUser accepts either a user ID or a WhatsApp number.
+func resolveUser(ctx context.Context, userRepo repository.UserRepository, value string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(value); err == nil {
+		return id, nil
+	}
+
+	user, err := userRepo.GetByWhatsAppNumber(ctx, value)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return uuid.Nil, fmt.Errorf("no user with WhatsApp number %s", value)
+	}
+	return user.ID, nil
+}
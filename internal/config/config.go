@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -20,12 +22,46 @@ type Config struct {
 	// Waha Server
 	WahaServerURL string
 	WahaAPIKey    string
+	// WhatsApp numbers connected to Waha; the first one is the default
+	WahaSessions []WahaSession
+
+	// Waha outbound throttling (anti-ban)
+	WahaRateGlobalPerMinute int
+	WahaRateGlobalBurst     int
+	WahaRateChatPerMinute   int
+	WahaRateChatBurst       int
+	WahaSendJitterMin       time.Duration
+	WahaSendJitterMax       time.Duration
+
+	// Telegram bot, disabled when the token is empty
+	TelegramBotToken      string
+	TelegramWebhookSecret string
+	TelegramAPIURL        string
 
 	// AI Configuration
 	AIProvider string
 	AIApiKey   string
 	AIModel    string
 	AIBaseURL  string // For Ollama or other OpenAI-compatible APIs
+	// Vision-capable model for photos; AIModel is used if empty
+	AIVisionModel string
+	// LLM response cache: "" (disabled), "memory" or "postgres"
+	LLMCache     string
+	LLMCacheTTL  time.Duration
+	LLMCacheSize int // entries, for the memory cache
+
+	// Speech-to-text for voice notes
+	STTProvider string // "" (disabled), "openai" or "whisper"
+	STTApiKey   string
+	STTModel    string
+	STTBaseURL  string
+	STTLanguage string
+	STTMaxBytes int
+	STTTimeout  time.Duration
+
+	// Photos (prescriptions, schedules) read into activities
+	ImageMaxBytes   int
+	ConfirmationTTL time.Duration
 
 	// Application
 	AppEnv   string
@@ -33,9 +69,71 @@ type Config struct {
 	Timezone string
 	AppName  string
 
+	// Logging: level (debug, info, warn, error), and whether message
+	// contents and phone numbers are redacted from the logs
+	LogLevel     string
+	LogRedactPII bool
+
+	// Admin REST API (/api/v1); disabled when empty
+	AdminAPIKey string
+
+	// Bearer token required to scrape /metrics; open when empty
+	MetricsToken string
+
+	// Readiness checks (/readyz)
+	HealthCheckTimeout     time.Duration
+	HealthLLMProbeInterval time.Duration
+
+	// OpenTelemetry tracing, exported over OTLP/HTTP; off when the endpoint
+	// is empty
+	OTLPEndpoint       string
+	TracingServiceName string
+	TracingSampleRatio float64
+
+	// Web dashboard
+	WebSessionTTL          time.Duration
+	WebLoginCodeTTL        time.Duration
+	WebLoginResendInterval time.Duration
+	WebLoginMaxFailures    int
+	WebLoginLockoutWindow  time.Duration
+	// Login requests allowed per client IP per minute
+	WebLoginRatePerIP int
+	// Take the client IP from X-Forwarded-For (behind a reverse proxy)
+	WebTrustProxyHeaders bool
+	WebCookieSecure      bool
+	// Externally visible base URL used in links (e.g. calendar feeds)
+	PublicBaseURL string
+
 	// Scheduler
 	MorningAlertTime   string
 	EveningSummaryTime string
+	AlertSpreadWindow  time.Duration
+	AlertConcurrency   int
+	AlertPageSize      int
+	AlertAITimeout     time.Duration
+	AlertRunTimeout    time.Duration
+
+	// Runs missed less than this long ago are run on startup
+	SchedulerCatchUpWindow time.Duration
+
+	// Caregiver alerts: unconfirmed activities in these categories (or about
+	// medication) are escalated to caregivers after the delay, and until
+	// the lookback has passed
+	CaregiverEscalationDelay    time.Duration
+	CaregiverEscalationLookback time.Duration
+	CaregiverCategories         []string
+
+	// Calendar import
+	CalendarMaxBytes     int
+	CalendarFetchTimeout time.Duration
+	CalendarSyncInterval time.Duration
+
+	// Outbox
+	OutboxMaxAttempts  int
+	OutboxBaseBackoff  time.Duration
+	OutboxMaxBackoff   time.Duration
+	OutboxPollInterval time.Duration
+	OutboxBatchSize    int
 }
 
 func Load() (*Config, error) {
@@ -56,11 +154,41 @@ func Load() (*Config, error) {
 		WahaServerURL: getEnv("WAHA_SERVER_URL", "http://localhost:3000"),
 		WahaAPIKey:    getEnv("WAHA_API_KEY", ""),
 
+		// Waha outbound throttling (anti-ban)
+		WahaRateGlobalPerMinute: getEnvInt("WAHA_RATE_GLOBAL_PER_MINUTE", 20),
+		WahaRateGlobalBurst:     getEnvInt("WAHA_RATE_GLOBAL_BURST", 5),
+		WahaRateChatPerMinute:   getEnvInt("WAHA_RATE_CHAT_PER_MINUTE", 6),
+		WahaRateChatBurst:       getEnvInt("WAHA_RATE_CHAT_BURST", 3),
+		WahaSendJitterMin:       getEnvDuration("WAHA_SEND_JITTER_MIN", 500*time.Millisecond),
+		WahaSendJitterMax:       getEnvDuration("WAHA_SEND_JITTER_MAX", 3*time.Second),
+
+		// Telegram bot
+		TelegramBotToken:      getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramWebhookSecret: getEnv("TELEGRAM_WEBHOOK_SECRET", ""),
+		TelegramAPIURL:        getEnv("TELEGRAM_API_URL", ""),
+
 		// AI Configuration
-		AIProvider: getEnv("AI_PROVIDER", "openai"),
-		AIApiKey:   getEnv("AI_API_KEY", ""),
-		AIModel:    getEnv("AI_MODEL", "gpt-3.5-turbo"),
-		AIBaseURL:  getEnv("AI_BASE_URL", ""), // For Ollama: http://localhost:11434/v1
+		AIProvider:    getEnv("AI_PROVIDER", "openai"),
+		AIApiKey:      getEnv("AI_API_KEY", ""),
+		AIModel:       getEnv("AI_MODEL", "gpt-3.5-turbo"),
+		AIBaseURL:     getEnv("AI_BASE_URL", ""), // For Ollama: http://localhost:11434/v1
+		AIVisionModel: getEnv("AI_VISION_MODEL", ""),
+		LLMCache:      getEnv("LLM_CACHE", ""),
+		LLMCacheTTL:   getEnvDuration("LLM_CACHE_TTL", 24*time.Hour),
+		LLMCacheSize:  getEnvInt("LLM_CACHE_SIZE", 1000),
+
+		// Speech-to-text for voice notes
+		STTProvider: getEnv("STT_PROVIDER", ""),
+		STTApiKey:   getEnv("STT_API_KEY", ""),
+		STTModel:    getEnv("STT_MODEL", "whisper-1"),
+		STTBaseURL:  getEnv("STT_BASE_URL", ""),
+		STTLanguage: getEnv("STT_LANGUAGE", "id"),
+		STTMaxBytes: getEnvInt("STT_MAX_BYTES", 16<<20),
+		STTTimeout:  getEnvDuration("STT_TIMEOUT", 2*time.Minute),
+
+		// Photos (prescriptions, schedules) read into activities
+		ImageMaxBytes:   getEnvInt("IMAGE_MAX_BYTES", 10<<20),
+		ConfirmationTTL: getEnvDuration("CONFIRMATION_TTL", 24*time.Hour),
 
 		// Application
 		AppEnv:   getEnv("APP_ENV", "development"),
@@ -68,11 +196,65 @@ func Load() (*Config, error) {
 		Timezone: getEnv("TIMEZONE", "Asia/Jakarta"),
 		AppName:  "Smart Alert System",
 
+		// Logging
+		LogLevel:     getEnv("LOG_LEVEL", "info"),
+		LogRedactPII: getEnvBool("LOG_REDACT_PII", true),
+
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		MetricsToken: getEnv("METRICS_TOKEN", ""),
+
+		// Readiness checks
+		HealthCheckTimeout:     getEnvDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		HealthLLMProbeInterval: getEnvDuration("HEALTH_LLM_PROBE_INTERVAL", time.Minute),
+
+		// Tracing
+		OTLPEndpoint:       getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		TracingServiceName: getEnv("OTEL_SERVICE_NAME", "smart-alert-system"),
+		TracingSampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1.0),
+
+		// Web dashboard
+		WebSessionTTL:          getEnvDuration("WEB_SESSION_TTL", 30*24*time.Hour),
+		WebLoginCodeTTL:        getEnvDuration("WEB_LOGIN_CODE_TTL", 10*time.Minute),
+		WebLoginResendInterval: getEnvDuration("WEB_LOGIN_RESEND_INTERVAL", time.Minute),
+		WebLoginMaxFailures:    getEnvInt("WEB_LOGIN_MAX_FAILURES", 10),
+		WebLoginLockoutWindow:  getEnvDuration("WEB_LOGIN_LOCKOUT_WINDOW", time.Hour),
+		WebLoginRatePerIP:      getEnvInt("WEB_LOGIN_RATE_PER_IP", 10),
+		WebTrustProxyHeaders:   getEnvBool("WEB_TRUST_PROXY_HEADERS", false),
+		WebCookieSecure:        getEnvBool("WEB_COOKIE_SECURE", false),
+		PublicBaseURL:          getEnv("PUBLIC_BASE_URL", ""),
+
 		// Scheduler
 		MorningAlertTime:   getEnv("MORNING_ALERT_TIME", "05:00"),
 		EveningSummaryTime: getEnv("EVENING_SUMMARY_TIME", "22:00"),
+		AlertSpreadWindow:  getEnvDuration("ALERT_SPREAD_WINDOW", 30*time.Minute),
+		AlertConcurrency:   getEnvInt("ALERT_CONCURRENCY", 8),
+		AlertPageSize:      getEnvInt("ALERT_PAGE_SIZE", 200),
+		AlertAITimeout:     getEnvDuration("ALERT_AI_TIMEOUT", 60*time.Second),
+		AlertRunTimeout:    getEnvDuration("ALERT_RUN_TIMEOUT", 2*time.Hour),
+
+		SchedulerCatchUpWindow: getEnvDuration("SCHEDULER_CATCHUP_WINDOW", 2*time.Hour),
+
+		// Caregiver alerts
+		CaregiverEscalationDelay:    getEnvDuration("CAREGIVER_ESCALATION_DELAY", 30*time.Minute),
+		CaregiverEscalationLookback: getEnvDuration("CAREGIVER_ESCALATION_LOOKBACK", 10*time.Minute),
+		CaregiverCategories:         getEnvList("CAREGIVER_CATEGORIES", "Kesehatan"),
+
+		// Calendar import
+		CalendarMaxBytes:     getEnvInt("CALENDAR_MAX_BYTES", 5<<20),
+		CalendarFetchTimeout: getEnvDuration("CALENDAR_FETCH_TIMEOUT", 30*time.Second),
+		CalendarSyncInterval: getEnvDuration("CALENDAR_SYNC_INTERVAL", 30*time.Minute),
+
+		// Outbox
+		OutboxMaxAttempts:  getEnvInt("OUTBOX_MAX_ATTEMPTS", 5),
+		OutboxBaseBackoff:  getEnvDuration("OUTBOX_BASE_BACKOFF", 30*time.Second),
+		OutboxMaxBackoff:   getEnvDuration("OUTBOX_MAX_BACKOFF", 30*time.Minute),
+		OutboxPollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", 5*time.Second),
+		OutboxBatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 20),
 	}
 
+	cfg.WahaSessions = loadWahaSessions(cfg.WahaAPIKey)
+
 	// Build DatabaseURL if not provided
 	if cfg.DatabaseURL == "" {
 		cfg.DatabaseURL = buildDatabaseURL(cfg)
@@ -81,6 +263,51 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// WahaSession configures one Waha session, i.e. one WhatsApp number.
+type WahaSession struct {
+	Name   string
+	APIKey string
+	// Welcome is sent to users who message this number first; the built-in
+	// text is used if empty
+	Welcome string
+	// Persona tells the AI who it writes as on this number
+	Persona string
+}
+
+// loadWahaSessions reads WAHA_SESSIONS, a comma-separated list of session
+// names, and each session's WAHA_SESSION_<NAME>_API_KEY, _WELCOME and
+// _PERSONA. Sessions without their own API key use WAHA_API_KEY.
+func loadWahaSessions(defaultAPIKey string) []WahaSession {
+	var sessions []WahaSession
+	for _, name := range strings.Split(getEnv("WAHA_SESSIONS", "default"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		prefix := "WAHA_SESSION_" + sessionEnvName(name) + "_"
+		sessions = append(sessions, WahaSession{
+			Name:    name,
+			APIKey:  getEnv(prefix+"API_KEY", defaultAPIKey),
+			Welcome: strings.ReplaceAll(getEnv(prefix+"WELCOME", ""), `\n`, "\n"),
+			Persona: getEnv(prefix+"PERSONA", ""),
+		})
+	}
+	return sessions
+}
+
+// sessionEnvName turns a session name like "clinic-1" into CLINIC_1.
+func sessionEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			return r - 'a' + 'A'
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -88,6 +315,54 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDuration reads durations in Go format, e.g. "30s", "5m", "1h30m"
+// getEnvList splits a comma-separated variable, dropping empty items.
+func getEnvList(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 func buildDatabaseURL(cfg *Config) string {
 	return "postgres://" + cfg.DBUser + ":" + cfg.DBPassword + "@" + cfg.DBHost + ":" + cfg.DBPort + "/" + cfg.DBName + "?sslmode=" + cfg.DBSSLMode
 }
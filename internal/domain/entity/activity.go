@@ -28,6 +28,17 @@ type Activity struct {
 	CreatedAt     time.Time      `json:"created_at" db:"created_at"`
 	UpdatedAt     time.Time      `json:"updated_at" db:"updated_at"`
 	CompletedAt   *time.Time     `json:"completed_at" db:"completed_at"`
+	// RecurrenceRule is an RFC 5545 RRULE such as "FREQ=WEEKLY;BYDAY=MO",
+	// empty for one-off activities.
+	RecurrenceRule string `json:"recurrence_rule,omitempty" db:"recurrence_rule"`
+	// ExternalUID is the iCalendar UID of the event the activity was
+	// imported from, empty for activities created here.
+	ExternalUID string `json:"external_uid,omitempty" db:"external_uid"`
+	// PostponeCount is how often the user pushed the activity back.
+	PostponeCount int `json:"postpone_count" db:"postpone_count"`
+	// AssigneeID is the group member an activity of a group calendar is
+	// for, nil for the whole group and for personal activities.
+	AssigneeID *uuid.UUID `json:"assignee_id,omitempty" db:"assignee_id"`
 }
 
 func NewActivity(userID uuid.UUID, title, description string, scheduledTime time.Time, priority int) *Activity {
@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ActivityPostponement records one time the user pushed an activity back,
+// e.g. by answering a reminder with "tunda 30 menit".
+type ActivityPostponement struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	ActivityID uuid.UUID `json:"activity_id" db:"activity_id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	// AlertLogID is the reminder the user answered, nil if there was none.
+	AlertLogID *uuid.UUID `json:"alert_log_id" db:"alert_log_id"`
+	FromTime   time.Time  `json:"from_time" db:"from_time"`
+	ToTime     time.Time  `json:"to_time" db:"to_time"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+func NewActivityPostponement(activity *Activity, from, to time.Time) *ActivityPostponement {
+	return &ActivityPostponement{
+		ID:         uuid.New(),
+		ActivityID: activity.ID,
+		UserID:     activity.UserID,
+		FromTime:   from,
+		ToTime:     to,
+		CreatedAt:  time.Now(),
+	}
+}
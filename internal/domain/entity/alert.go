@@ -12,6 +12,10 @@ const (
 	AlertTypeMorning      AlertType = "morning_alert"
 	AlertTypeEvening      AlertType = "evening_summary"
 	AlertTypeActivityReminder AlertType = "activity_reminder"
+	// Alerts to a user's caregivers about a missed activity, and their
+	// daily adherence digest
+	AlertTypeCaregiverEscalation AlertType = "caregiver_escalation"
+	AlertTypeCaregiverDigest     AlertType = "caregiver_digest"
 )
 
 type AlertStatus string
@@ -28,11 +32,18 @@ type AlertLog struct {
 	AlertType    AlertType  `json:"alert_type" db:"alert_type"`
 	AlertContent string     `json:"alert_content" db:"alert_content"`
 	ScheduledTime time.Time `json:"scheduled_time" db:"scheduled_time"`
+	// AlertDate is the local date of the scheduled run this alert belongs to.
+	// Only one alert per user, type and date can exist.
+	AlertDate    *time.Time `json:"alert_date" db:"alert_date"`
+	// ActivityID is the activity an activity_reminder alert is about.
+	ActivityID   *uuid.UUID `json:"activity_id" db:"activity_id"`
 	SentAt       *time.Time `json:"sent_at" db:"sent_at"`
 	IsSent       bool       `json:"is_sent" db:"is_sent"`
 	Status       AlertStatus `json:"status" db:"status"`
 	ErrorMessage string     `json:"error_message" db:"error_message"`
+	WahaMessageID string    `json:"waha_message_id" db:"waha_message_id"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	DeliveryState
 }
 
 func NewAlertLog(userID uuid.UUID, alertType AlertType, content string, scheduledTime time.Time) *AlertLog {
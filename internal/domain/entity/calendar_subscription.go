@@ -0,0 +1,33 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CalendarSubscription is an iCalendar URL whose events are periodically
+// imported as the user's activities.
+type CalendarSubscription struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	URL    string    `json:"url" db:"url"`
+	// ETag of the last fetched version, used to skip unchanged calendars
+	ETag         string     `json:"-" db:"etag"`
+	LastSyncedAt *time.Time `json:"last_synced_at" db:"last_synced_at"`
+	// LastError is the error of the last sync, empty if it succeeded
+	LastError string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func NewCalendarSubscription(userID uuid.UUID, url string) *CalendarSubscription {
+	now := time.Now()
+	return &CalendarSubscription{
+		ID:        uuid.New(),
+		UserID:    userID,
+		URL:       url,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
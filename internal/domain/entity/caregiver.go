@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Caregiver is someone a user authorized to be alerted when they miss a
+// medication or health activity, e.g. a family member of an elderly user.
+type Caregiver struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	Name   string    `json:"name" db:"name"`
+	// PhoneNumber is the caregiver's WhatsApp number, e.g. "6281234567890"
+	PhoneNumber string `json:"phone_number" db:"phone_number"`
+	// DailyDigest sends the caregiver a summary of the user's adherence
+	// every evening
+	DailyDigest bool      `json:"daily_digest" db:"daily_digest"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+func NewCaregiver(userID uuid.UUID, name, phoneNumber string) *Caregiver {
+	return &Caregiver{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Name:        name,
+		PhoneNumber: phoneNumber,
+		CreatedAt:   time.Now(),
+	}
+}
+
+// DisplayName is the caregiver's name, or their number if they have none.
+func (c *Caregiver) DisplayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return "+" + c.PhoneNumber
+}
@@ -0,0 +1,86 @@
+package entity
+
+import "time"
+
+// DeliveryStatus mirrors WhatsApp's message ack levels.
+type DeliveryStatus string
+
+const (
+	DeliveryStatusError     DeliveryStatus = "error"
+	DeliveryStatusSent      DeliveryStatus = "sent"
+	DeliveryStatusDelivered DeliveryStatus = "delivered"
+	DeliveryStatusRead      DeliveryStatus = "read"
+	DeliveryStatusPlayed    DeliveryStatus = "played"
+)
+
+// DeliveryStatusFromAck maps a Waha ack value (-1 ERROR, 0 PENDING, 1 SERVER,
+// 2 DEVICE, 3 READ, 4 PLAYED) to a delivery status. Pending returns "".
+func DeliveryStatusFromAck(ack int) DeliveryStatus {
+	switch {
+	case ack < 0:
+		return DeliveryStatusError
+	case ack == 1:
+		return DeliveryStatusSent
+	case ack == 2:
+		return DeliveryStatusDelivered
+	case ack == 3:
+		return DeliveryStatusRead
+	case ack >= 4:
+		return DeliveryStatusPlayed
+	}
+	return ""
+}
+
+func (s DeliveryStatus) rank() int {
+	switch s {
+	case DeliveryStatusSent:
+		return 1
+	case DeliveryStatusDelivered:
+		return 2
+	case DeliveryStatusRead:
+		return 3
+	case DeliveryStatusPlayed:
+		return 4
+	}
+	return 0
+}
+
+// IsRead reports whether the recipient has opened the message.
+func (s DeliveryStatus) IsRead() bool {
+	return s.rank() >= DeliveryStatusRead.rank()
+}
+
+// DeliveryState is the receipt information tracked for an outgoing message.
+// Acks can arrive out of order, so Advance never moves the state backwards.
+type DeliveryState struct {
+	DeliveryStatus DeliveryStatus `json:"delivery_status" db:"delivery_status"`
+	DeliveredAt    *time.Time     `json:"delivered_at" db:"delivered_at"`
+	ReadAt         *time.Time     `json:"read_at" db:"read_at"`
+}
+
+// Advance applies a new ack and reports whether anything changed.
+func (d *DeliveryState) Advance(status DeliveryStatus, at time.Time) bool {
+	if status == "" {
+		return false
+	}
+	if status == DeliveryStatusError {
+		// An error ack only matters if nothing better was ever reported
+		if d.DeliveryStatus != "" {
+			return false
+		}
+		d.DeliveryStatus = status
+		return true
+	}
+	if status.rank() <= d.DeliveryStatus.rank() {
+		return false
+	}
+
+	d.DeliveryStatus = status
+	if status.rank() >= DeliveryStatusDelivered.rank() && d.DeliveredAt == nil {
+		d.DeliveredAt = &at
+	}
+	if status.IsRead() && d.ReadAt == nil {
+		d.ReadAt = &at
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package entity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GroupMember is a user who writes in a group chat. GroupID is the users row
+// holding the group's shared calendar.
+type GroupMember struct {
+	GroupID     uuid.UUID `json:"group_id" db:"group_id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	// ChatID is the member's own chat ID, e.g. "6281234567890@c.us"
+	ChatID     string    `json:"chat_id" db:"chat_id"`
+	JoinedAt   time.Time `json:"joined_at" db:"joined_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+func NewGroupMember(groupID, userID uuid.UUID, displayName, chatID string) *GroupMember {
+	now := time.Now()
+	return &GroupMember{
+		GroupID:     groupID,
+		UserID:      userID,
+		DisplayName: displayName,
+		ChatID:      chatID,
+		JoinedAt:    now,
+		LastSeenAt:  now,
+	}
+}
+
+// MentionTag is the "@number" text that, together with ChatID in the
+// message's mentions, makes WhatsApp highlight and notify the member.
+func (m *GroupMember) MentionTag() string {
+	user, _, _ := strings.Cut(m.ChatID, "@")
+	return "@" + user
+}
@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+// Kinds of photos the vision model recognizes
+const (
+	ImageKindMedication  = "medication"
+	ImageKindSchedule    = "schedule"
+	ImageKindAppointment = "appointment"
+	ImageKindOther       = "other"
+)
+
+// ImageExtraction is what the vision model read from a photo sent by a user,
+// e.g. the doses on a prescription label or the slots of a class schedule.
+type ImageExtraction struct {
+	Kind string
+	// Summary is a short Indonesian description of the photo
+	Summary    string
+	Activities []ExtractedActivity
+}
+
+// ExtractedActivity is one activity read from a photo. Category is a name
+// (e.g. "Kesehatan"), not yet matched to a category.
+type ExtractedActivity struct {
+	Title          string
+	Description    string
+	ScheduledTime  time.Time
+	RecurrenceRule string
+	Category       string
+	Priority       int
+}
@@ -18,18 +18,51 @@ const (
 	IntentUnknown        IntentType = "unknown"
 )
 
+var intentTypes = map[IntentType]bool{
+	IntentAddActivity:    true,
+	IntentDeleteActivity: true,
+	IntentUpdateActivity: true,
+	IntentListActivities: true,
+	IntentQuestion:       true,
+	IntentGreeting:       true,
+	IntentUnknown:        true,
+}
+
+// ParseIntentType returns the intent type named s, or IntentUnknown if there
+// is none. Intents come from LLM output, which may name anything.
+func ParseIntentType(s string) IntentType {
+	if intentType := IntentType(s); intentTypes[intentType] {
+		return intentType
+	}
+	return IntentUnknown
+}
+
+// IntentSource tells how an intent was detected.
+type IntentSource string
+
+const (
+	IntentSourceAI             IntentSource = "ai"
+	IntentSourceTextExtraction IntentSource = "text_extraction"
+	IntentSourceFallback       IntentSource = "fallback"
+)
+
 type ParsedIntent struct {
 	Type       IntentType
 	Confidence float64
 	Entities   map[string]interface{}
+	Source     IntentSource
 }
 
 type ActivityIntentData struct {
-	Title         string
-	Description   string
-	ScheduledTime *time.Time
-	CategoryID    *uuid.UUID
-	Priority      int
+	Title         string     `json:"title"`
+	Description   string     `json:"description,omitempty"`
+	ScheduledTime *time.Time `json:"scheduled_time,omitempty"`
+	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
+	Priority      int        `json:"priority,omitempty"`
+	// RecurrenceRule is an RFC 5545 RRULE, empty for one-off activities
+	RecurrenceRule string `json:"recurrence_rule,omitempty"`
+	// AssigneeID is the group member the activity is for
+	AssigneeID *uuid.UUID `json:"assignee_id,omitempty"`
 }
 
 type UpdateActivityIntentData struct {
@@ -39,5 +72,7 @@ type UpdateActivityIntentData struct {
 	ScheduledTime *time.Time
 	Status        *string
 	Priority      *int
+	// RecurrenceRule replaces the rule; an empty string makes it one-off
+	RecurrenceRule *string
 }
 
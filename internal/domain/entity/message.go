@@ -13,17 +13,28 @@ const (
 	MessageTypeOutgoing MessageType = "outgoing"
 )
 
+// Media types of incoming messages
+const (
+	MediaTypeAudio    = "audio"
+	MediaTypeImage    = "image"
+	MediaTypeDocument = "document"
+)
+
 type MessageHistory struct {
 	ID            uuid.UUID  `json:"id" db:"id"`
 	UserID        uuid.UUID  `json:"user_id" db:"user_id"`
 	MessageContent string    `json:"message_content" db:"message_content"`
 	MessageType   MessageType `json:"message_type" db:"message_type"`
+	MediaType      string    `json:"media_type,omitempty" db:"media_type"`
+	Transcript     string    `json:"transcript,omitempty" db:"transcript"`
 	IntentDetected string    `json:"intent_detected" db:"intent_detected"`
 	AIResponse     string    `json:"ai_response" db:"ai_response"`
 	ReceivedAt     *time.Time `json:"received_at" db:"received_at"`
 	SentAt         *time.Time `json:"sent_at" db:"sent_at"`
 	IsProcessed    bool       `json:"is_processed" db:"is_processed"`
+	WahaMessageID  string     `json:"waha_message_id" db:"waha_message_id"`
 	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	DeliveryState
 }
 
 func NewMessageHistory(userID uuid.UUID, content string, msgType MessageType) *MessageHistory {
@@ -0,0 +1,161 @@
+package entity
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type OutboundStatus string
+
+const (
+	OutboundStatusPending OutboundStatus = "pending"
+	OutboundStatusSending OutboundStatus = "sending"
+	OutboundStatusSent    OutboundStatus = "sent"
+	OutboundStatusFailed  OutboundStatus = "failed"
+)
+
+// ContentType selects how an outbound message is sent.
+type ContentType string
+
+const (
+	ContentTypeText    ContentType = "text"
+	ContentTypeButtons ContentType = "buttons"
+	ContentTypeList    ContentType = "list"
+	ContentTypePoll    ContentType = "poll"
+	ContentTypeImage   ContentType = "image"
+	ContentTypeFile    ContentType = "file"
+)
+
+// IsInteractive reports whether the recipient answers the message by picking
+// one of its choices.
+func (t ContentType) IsInteractive() bool {
+	return t == ContentTypeButtons || t == ContentTypeList || t == ContentTypePoll
+}
+
+// Choice is a button, list row or poll option. ID is what comes back when the
+// user picks it.
+type Choice struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// OutboundPayload holds what a non-text message needs besides its Body, which
+// is the message text, list description, poll question or media caption.
+type OutboundPayload struct {
+	Choices []Choice `json:"choices,omitempty"`
+	Footer  string   `json:"footer,omitempty"`
+	// ListButton is the label of the button that opens a list
+	ListButton string `json:"list_button,omitempty"`
+	MediaURL   string `json:"media_url,omitempty"`
+	Mimetype   string `json:"mimetype,omitempty"`
+	Filename   string `json:"filename,omitempty"`
+	// Mentions are the chat IDs of group members the message @mentions;
+	// the body carries the matching "@number" tags
+	Mentions []string `json:"mentions,omitempty"`
+}
+
+// MatchChoice finds the choice a typed answer refers to, either by its number
+// in the list ("2") or by its title ("Tunda 15 menit").
+func (p *OutboundPayload) MatchChoice(answer string) (Choice, bool) {
+	answer = strings.Trim(answer, " \t\n.!")
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n >= 1 && n <= len(p.Choices) {
+			return p.Choices[n-1], true
+		}
+		return Choice{}, false
+	}
+	for _, choice := range p.Choices {
+		if strings.EqualFold(choice.Title, answer) {
+			return choice, true
+		}
+	}
+	return Choice{}, false
+}
+
+// OutboundMessage is a message waiting in the outbox to be delivered to a user.
+type OutboundMessage struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	IdempotencyKey string     `json:"idempotency_key" db:"idempotency_key"`
+	UserID         *uuid.UUID `json:"user_id" db:"user_id"`
+	// Channel is the messaging channel the message is sent on, "whatsapp"
+	// or "telegram".
+	Channel string `json:"channel" db:"channel"`
+	ChatID  string `json:"chat_id" db:"chat_id"`
+	// Session is the account the message is sent through (the Waha session
+	// on WhatsApp), empty for the channel's default one.
+	Session          string           `json:"session,omitempty" db:"session"`
+	Body             string           `json:"body" db:"body"`
+	ContentType      ContentType      `json:"content_type" db:"content_type"`
+	Payload          *OutboundPayload `json:"payload,omitempty" db:"payload"`
+	Status           OutboundStatus   `json:"status" db:"status"`
+	Attempts         int              `json:"attempts" db:"attempts"`
+	MaxAttempts      int              `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt    time.Time        `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError        string           `json:"last_error" db:"last_error"`
+	WahaMessageID    string           `json:"waha_message_id" db:"waha_message_id"`
+	MessageHistoryID *uuid.UUID       `json:"message_history_id" db:"message_history_id"`
+	AlertLogID       *uuid.UUID       `json:"alert_log_id" db:"alert_log_id"`
+	RecommendationID *uuid.UUID       `json:"recommendation_id" db:"recommendation_id"`
+	SentAt           *time.Time       `json:"sent_at" db:"sent_at"`
+	CreatedAt        time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at" db:"updated_at"`
+	// CorrelationID ties the delivery to the request that queued the
+	// message, e.g. the webhook of the message it answers.
+	CorrelationID string `json:"correlation_id,omitempty" db:"correlation_id"`
+	// TraceParent continues the trace of the request that queued the
+	// message when it is delivered.
+	TraceParent string `json:"-" db:"trace_parent"`
+	DeliveryState
+}
+
+func NewOutboundMessage(idempotencyKey, chatID, body string, maxAttempts int) *OutboundMessage {
+	now := time.Now()
+	return &OutboundMessage{
+		ID:             uuid.New(),
+		IdempotencyKey: idempotencyKey,
+		ChatID:         chatID,
+		Body:           body,
+		ContentType:    ContentTypeText,
+		Status:         OutboundStatusPending,
+		MaxAttempts:    maxAttempts,
+		NextAttemptAt:  now,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
+
+func (m *OutboundMessage) MarkSent(wahaMessageID string) {
+	now := time.Now()
+	m.Status = OutboundStatusSent
+	m.WahaMessageID = wahaMessageID
+	m.LastError = ""
+	m.SentAt = &now
+	m.UpdatedAt = now
+}
+
+// ScheduleRetry puts the message back in the queue after a failed attempt.
+func (m *OutboundMessage) ScheduleRetry(err error, nextAttemptAt time.Time) {
+	m.Status = OutboundStatusPending
+	m.NextAttemptAt = nextAttemptAt
+	if err != nil {
+		m.LastError = err.Error()
+	}
+	m.UpdatedAt = time.Now()
+}
+
+func (m *OutboundMessage) MarkFailed(err error) {
+	m.Status = OutboundStatusFailed
+	if err != nil {
+		m.LastError = err.Error()
+	}
+	m.UpdatedAt = time.Now()
+}
+
+// AttemptsExhausted reports whether the max-attempt policy forbids another try.
+func (m *OutboundMessage) AttemptsExhausted() bool {
+	return m.Attempts >= m.MaxAttempts
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ConfirmationAction string
+
+const (
+	// ConfirmationCreateActivities creates all Activities of the confirmation
+	ConfirmationCreateActivities ConfirmationAction = "create_activities"
+)
+
+// PendingConfirmation is an action the bot proposed and only carries out once
+// the user answers yes. A user has at most one; a new proposal replaces it.
+type PendingConfirmation struct {
+	ID         uuid.UUID            `json:"id" db:"id"`
+	UserID     uuid.UUID            `json:"user_id" db:"user_id"`
+	Action     ConfirmationAction   `json:"action" db:"action"`
+	Summary    string               `json:"summary" db:"summary"`
+	Activities []ActivityIntentData `json:"activities" db:"payload"`
+	ExpiresAt  time.Time            `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time            `json:"created_at" db:"created_at"`
+}
+
+func NewPendingConfirmation(userID uuid.UUID, action ConfirmationAction, ttl time.Duration) *PendingConfirmation {
+	now := time.Now()
+	return &PendingConfirmation{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Action:    action,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
+
+func (c *PendingConfirmation) IsExpired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
@@ -16,6 +16,10 @@ type User struct {
 	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt          time.Time  `json:"updated_at" db:"updated_at"`
 	LastInteractionAt  *time.Time `json:"last_interaction_at" db:"last_interaction_at"`
+	// WahaSession is the WhatsApp number (Waha session) the user first
+	// messaged; replies and alerts are sent through it. Empty means the
+	// default session.
+	WahaSession        string     `json:"waha_session" db:"waha_session"`
 }
 
 func NewUser(whatsappNumber, name, timezone string) *User {
@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity is the account of a user on one messaging channel, e.g. their
+// WhatsApp number or Telegram user ID. A user can have one per channel.
+type UserIdentity struct {
+	ID     uuid.UUID `json:"id" db:"id"`
+	UserID uuid.UUID `json:"user_id" db:"user_id"`
+	// Channel is "whatsapp" or "telegram"
+	Channel    string `json:"channel" db:"channel"`
+	ExternalID string `json:"external_id" db:"external_id"`
+	// ChatID is where messages to the user are sent
+	ChatID string `json:"chat_id" db:"chat_id"`
+	// Account is the number or bot the user talks to, e.g. the Waha session;
+	// empty for the channel's default account.
+	Account    string    `json:"account" db:"account"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+func NewUserIdentity(userID uuid.UUID, channel, externalID, chatID, account string) *UserIdentity {
+	now := time.Now()
+	return &UserIdentity{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Channel:    channel,
+		ExternalID: externalID,
+		ChatID:     chatID,
+		Account:    account,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+}
+
+// IdentityLinkCode is a one-time code that links the identity redeeming it to
+// the user who asked for it. Only the hash of the code is stored.
+type IdentityLinkCode struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+func NewIdentityLinkCode(userID uuid.UUID, codeHash string, ttl time.Duration) *IdentityLinkCode {
+	now := time.Now()
+	return &IdentityLinkCode{
+		ID:        uuid.New(),
+		UserID:    userID,
+		CodeHash:  codeHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
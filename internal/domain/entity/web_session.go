@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginCode is a one-time code sent over WhatsApp to log in to the web
+// dashboard. Only the hash of the code is stored.
+type LoginCode struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	CodeHash  string     `json:"-" db:"code_hash"`
+	Attempts  int        `json:"attempts" db:"attempts"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+func NewLoginCode(userID uuid.UUID, codeHash string, ttl time.Duration) *LoginCode {
+	now := time.Now()
+	return &LoginCode{
+		ID:        uuid.New(),
+		UserID:    userID,
+		CodeHash:  codeHash,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+}
+
+// WebSession is a logged-in web dashboard session. The browser holds the
+// session token; only its hash is stored.
+type WebSession struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	TokenHash  string    `json:"-" db:"token_hash"`
+	CSRFToken  string    `json:"-" db:"csrf_token"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+func NewWebSession(userID uuid.UUID, tokenHash, csrfToken string, ttl time.Duration) *WebSession {
+	now := time.Now()
+	return &WebSession{
+		ID:         uuid.New(),
+		UserID:     userID,
+		TokenHash:  tokenHash,
+		CSRFToken:  csrfToken,
+		ExpiresAt:  now.Add(ttl),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+}
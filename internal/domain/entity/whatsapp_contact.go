@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WhatsAppContact ties together the IDs WhatsApp uses for one person: their
+// LID (the privacy ID in "…@lid" chat IDs) and their phone number (in
+// "…@c.us" chat IDs). Either may be unknown. Both lead to the same identity.
+type WhatsAppContact struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	IdentityID uuid.UUID `json:"identity_id" db:"identity_id"`
+	LID        string    `json:"lid,omitempty" db:"lid"`
+	// PhoneNumber is in E.164 format without the plus, e.g. "6281234567890"
+	PhoneNumber string `json:"phone_number,omitempty" db:"phone_number"`
+	// ChatID is where messages to the contact are sent: the phone number
+	// chat ID when the number is known, the LID chat ID otherwise
+	ChatID    string    `json:"chat_id" db:"chat_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func NewWhatsAppContact(identityID uuid.UUID, lid, phoneNumber, chatID string) *WhatsAppContact {
+	now := time.Now()
+	return &WhatsAppContact{
+		ID:          uuid.New(),
+		IdentityID:  identityID,
+		LID:         lid,
+		PhoneNumber: phoneNumber,
+		ChatID:      chatID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
@@ -18,5 +18,20 @@ type ActivityRepository interface {
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetTodayActivities(ctx context.Context, userID uuid.UUID) ([]*entity.Activity, error)
 	GetCompletedToday(ctx context.Context, userID uuid.UUID) ([]*entity.Activity, error)
+	// GetByUserIDBetween returns the user's activities scheduled in [from, to).
+	GetByUserIDBetween(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*entity.Activity, error)
+	// GetUnconfirmedWithCaregivers returns pending or overdue activities
+	// scheduled in [from, to] of active users who have at least one caregiver.
+	GetUnconfirmedWithCaregivers(ctx context.Context, from, to time.Time) ([]*entity.Activity, error)
+	// GetByExternalUID returns the user's activity imported from the
+	// calendar event with this UID, nil if there is none.
+	GetByExternalUID(ctx context.Context, userID uuid.UUID, externalUID string) (*entity.Activity, error)
+	// Postpone saves the activity's new scheduled and reminder time and status
+	// and records the postponement, in one transaction. activity.PostponeCount
+	// is set to the incremented count.
+	Postpone(ctx context.Context, activity *entity.Activity, postponement *entity.ActivityPostponement) error
+	// GetPostponementsBetween returns the user's postponements made in
+	// [from, to), oldest first.
+	GetPostponementsBetween(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*entity.ActivityPostponement, error)
 }
 
@@ -11,8 +11,20 @@ import (
 type AlertRepository interface {
 	Create(ctx context.Context, alert *entity.AlertLog) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.AlertLog, error)
+	// Reserve inserts the alert unless it collides with an existing one (same
+	// user, alert type and alert date, or same reminder of the same activity).
+	// It reports whether the row was inserted.
+	Reserve(ctx context.Context, alert *entity.AlertLog) (bool, error)
+	GetByUserTypeDate(ctx context.Context, userID uuid.UUID, alertType entity.AlertType, alertDate time.Time) (*entity.AlertLog, error)
+	// GetLatestReminder returns the user's most recent activity reminder sent
+	// at or after sentAfter, nil if there is none.
+	GetLatestReminder(ctx context.Context, userID uuid.UUID, sentAfter time.Time) (*entity.AlertLog, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.AlertLog, error)
+	// ListByUserID returns one page of a user's alerts, newest first, and the
+	// total number of alerts of that user.
+	ListByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.AlertLog, int, error)
 	Update(ctx context.Context, alert *entity.AlertLog) error
+	UpdateDeliveryState(ctx context.Context, id uuid.UUID, state entity.DeliveryState) error
 	GetPendingAlerts(ctx context.Context, alertType entity.AlertType) ([]*entity.AlertLog, error)
 	GetByScheduledTime(ctx context.Context, startTime, endTime time.Time) ([]*entity.AlertLog, error)
 }
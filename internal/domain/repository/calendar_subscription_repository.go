@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type CalendarSubscriptionRepository interface {
+	// Create stores the subscription. It returns false if the user is
+	// already subscribed to the URL.
+	Create(ctx context.Context, subscription *entity.CalendarSubscription) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.CalendarSubscription, error)
+	GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.CalendarSubscription, error)
+	// GetDue returns up to limit subscriptions of active users that were
+	// never synced or last synced before the given time, oldest first.
+	GetDue(ctx context.Context, syncedBefore time.Time, limit int) ([]*entity.CalendarSubscription, error)
+	// UpdateSyncResult saves ETag, LastSyncedAt and LastError.
+	UpdateSyncResult(ctx context.Context, subscription *entity.CalendarSubscription) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type CaregiverRepository interface {
+	Create(ctx context.Context, caregiver *entity.Caregiver) error
+	// Update saves the caregiver's name and daily digest setting.
+	Update(ctx context.Context, caregiver *entity.Caregiver) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// GetByPhoneNumber returns the user's caregiver with this number, or nil.
+	GetByPhoneNumber(ctx context.Context, userID uuid.UUID, phoneNumber string) (*entity.Caregiver, error)
+	// ListByUserID returns the user's caregivers, oldest first.
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.Caregiver, error)
+	// ListWithDailyDigest returns the caregivers of active users that get the
+	// daily digest, ordered by user.
+	ListWithDailyDigest(ctx context.Context) ([]*entity.Caregiver, error)
+}
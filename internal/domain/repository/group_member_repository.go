@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type GroupMemberRepository interface {
+	// Upsert adds the member to the group or updates their display name,
+	// chat ID and last seen time.
+	Upsert(ctx context.Context, member *entity.GroupMember) error
+	// Get returns the member, or nil if the user isn't in the group.
+	Get(ctx context.Context, groupID, userID uuid.UUID) (*entity.GroupMember, error)
+	// ListByGroupID returns the group's members, most recently seen first.
+	ListByGroupID(ctx context.Context, groupID uuid.UUID) ([]*entity.GroupMember, error)
+}
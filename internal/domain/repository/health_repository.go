@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"smart_alert_system/internal/domain/entity"
@@ -13,5 +14,7 @@ type HealthRepository interface {
 	GetRecommendationTypes(ctx context.Context) ([]*entity.RecommendationType, error)
 	CreateRecommendation(ctx context.Context, recommendation *entity.HealthRecommendation) error
 	GetRecommendationsByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.HealthRecommendation, error)
+	MarkRecommendationSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error
+	MarkRecommendationRead(ctx context.Context, id uuid.UUID) error
 }
 
@@ -11,6 +11,10 @@ type MessageRepository interface {
 	Create(ctx context.Context, message *entity.MessageHistory) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.MessageHistory, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.MessageHistory, error)
+	// ListByUserID returns one page of a user's messages, newest first, and the
+	// total number of messages of that user.
+	ListByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.MessageHistory, int, error)
 	Update(ctx context.Context, message *entity.MessageHistory) error
+	UpdateDeliveryState(ctx context.Context, id uuid.UUID, state entity.DeliveryState) error
 }
 
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type OutboxRepository interface {
+	// Enqueue stores the message unless one with the same idempotency key
+	// already exists. It reports whether a new row was inserted.
+	Enqueue(ctx context.Context, message *entity.OutboundMessage) (bool, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.OutboundMessage, error)
+	GetByIdempotencyKey(ctx context.Context, key string) (*entity.OutboundMessage, error)
+	GetByWahaMessageID(ctx context.Context, wahaMessageID string) (*entity.OutboundMessage, error)
+	// GetByWahaMessageIDSuffix finds a message sent after sentAfter whose
+	// serialized Waha ID ("true_<chat>_<id>") ends in the bare ID id.
+	GetByWahaMessageIDSuffix(ctx context.Context, id string, sentAfter time.Time) (*entity.OutboundMessage, error)
+	// GetLatestInteractive returns the last buttons, list or poll message
+	// sent to the user after sentAfter, or nil if there is none.
+	GetLatestInteractive(ctx context.Context, userID uuid.UUID, sentAfter time.Time) (*entity.OutboundMessage, error)
+	// ClaimDue locks up to limit due messages, marks them as sending, bumps
+	// their attempt counter and pushes next_attempt_at out by lease so a
+	// crashed dispatcher's claims become due again. Messages that used up
+	// their attempts are never claimed.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.OutboundMessage, error)
+	// FailExhaustedClaims marks as failed the messages whose lease expired
+	// on their last attempt, i.e. whose dispatcher crashed mid-send, and
+	// returns them.
+	FailExhaustedClaims(ctx context.Context, now time.Time, lastError string) ([]*entity.OutboundMessage, error)
+	Update(ctx context.Context, message *entity.OutboundMessage) error
+}
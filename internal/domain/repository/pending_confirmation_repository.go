@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type PendingConfirmationRepository interface {
+	// Save stores the confirmation, replacing the user's previous one.
+	Save(ctx context.Context, confirmation *entity.PendingConfirmation) error
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*entity.PendingConfirmation, error)
+	// Delete removes the confirmation. It returns false if it was already
+	// gone, so that an answer is only acted on once.
+	Delete(ctx context.Context, id uuid.UUID) (bool, error)
+}
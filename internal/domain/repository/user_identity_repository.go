@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type UserIdentityRepository interface {
+	Create(ctx context.Context, identity *entity.UserIdentity) error
+	// GetByID returns the identity, or nil.
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.UserIdentity, error)
+	// GetByExternalID returns the identity of a channel account, or nil.
+	GetByExternalID(ctx context.Context, channel, externalID string) (*entity.UserIdentity, error)
+	// ListByUserID returns the user's identities, most recently used first.
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.UserIdentity, error)
+	// Touch saves the identity's chat ID, account and last seen time.
+	Touch(ctx context.Context, identity *entity.UserIdentity) error
+	// MoveToUser links the identity to another user.
+	MoveToUser(ctx context.Context, id, userID uuid.UUID) error
+
+	CreateLinkCode(ctx context.Context, code *entity.IdentityLinkCode) error
+	// UseLinkCode marks the unexpired, unused code with this hash as used and
+	// returns it, or nil if there is none. A code can only be redeemed once.
+	UseLinkCode(ctx context.Context, codeHash string, now time.Time) (*entity.IdentityLinkCode, error)
+}
@@ -8,6 +8,15 @@ import (
 	"smart_alert_system/internal/domain/entity"
 )
 
+// UserFilter selects a page of users. Query matches the WhatsApp number or
+// name; a nil IsActive matches both active and inactive users.
+type UserFilter struct {
+	Query    string
+	IsActive *bool
+	Limit    int
+	Offset   int
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *entity.User) error
 	GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
@@ -15,6 +24,21 @@ type UserRepository interface {
 	Update(ctx context.Context, user *entity.User) error
 	UpdateLastInteraction(ctx context.Context, userID uuid.UUID, timestamp time.Time) error
 	GetAllActive(ctx context.Context) ([]*entity.User, error)
+	// ListActive returns up to limit active users with an ID greater than
+	// afterID, ordered by ID. Pass uuid.Nil to start from the beginning.
+	ListActive(ctx context.Context, afterID uuid.UUID, limit int) ([]*entity.User, error)
 	MarkAsNotFirstTime(ctx context.Context, userID uuid.UUID) error
+	// Search returns the users matching filter and the total number of matches.
+	Search(ctx context.Context, filter UserFilter) ([]*entity.User, int, error)
+	// GetByCalendarToken returns the owner of an iCalendar feed token, or nil.
+	GetByCalendarToken(ctx context.Context, token string) (*entity.User, error)
+	// GetCalendarToken returns the user's feed token, "" if none was created yet.
+	GetCalendarToken(ctx context.Context, userID uuid.UUID) (string, error)
+	SetCalendarToken(ctx context.Context, userID uuid.UUID, token string) error
+	// SetWahaSession binds the user to the Waha session replies and alerts
+	// are sent through.
+	SetWahaSession(ctx context.Context, userID uuid.UUID, session string) error
+	// SetWhatsAppNumber changes the user's number; "" removes it.
+	SetWhatsAppNumber(ctx context.Context, userID uuid.UUID, whatsappNumber string) error
 }
 
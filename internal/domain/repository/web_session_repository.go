@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type WebSessionRepository interface {
+	CreateLoginCode(ctx context.Context, code *entity.LoginCode) error
+	// GetLatestLoginCode returns the user's most recent unused login code, or
+	// nil if there is none. The code may have expired.
+	GetLatestLoginCode(ctx context.Context, userID uuid.UUID) (*entity.LoginCode, error)
+	IncrementLoginCodeAttempts(ctx context.Context, id uuid.UUID) error
+	// UseLoginCode marks the code as used. It returns false if the code was
+	// already used, so a code can only be redeemed once.
+	UseLoginCode(ctx context.Context, id uuid.UUID, usedAt time.Time) (bool, error)
+
+	// CreateLoginFailure records a failed login guess by the user and returns
+	// its ID.
+	CreateLoginFailure(ctx context.Context, userID uuid.UUID, at time.Time) (uuid.UUID, error)
+	DeleteLoginFailure(ctx context.Context, id uuid.UUID) error
+	// CountLoginFailures returns how many failed guesses the user made since
+	// the given time, across all codes.
+	CountLoginFailures(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+
+	CreateSession(ctx context.Context, session *entity.WebSession) error
+	// GetSessionByTokenHash returns an unexpired session, or nil.
+	GetSessionByTokenHash(ctx context.Context, tokenHash string, now time.Time) (*entity.WebSession, error)
+	TouchSession(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error
+	DeleteSession(ctx context.Context, id uuid.UUID) error
+	// DeleteExpired removes expired sessions and login codes, and login
+	// failures made before failuresBefore.
+	DeleteExpired(ctx context.Context, now, failuresBefore time.Time) error
+}
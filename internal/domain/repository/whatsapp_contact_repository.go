@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+type WhatsAppContactRepository interface {
+	Create(ctx context.Context, contact *entity.WhatsAppContact) error
+	// Update saves the contact's LID, phone number and chat ID.
+	Update(ctx context.Context, contact *entity.WhatsAppContact) error
+	// GetByLID returns the contact with this LID, or nil.
+	GetByLID(ctx context.Context, lid string) (*entity.WhatsAppContact, error)
+	// GetByPhoneNumber returns the contact with this phone number, or nil.
+	GetByPhoneNumber(ctx context.Context, phoneNumber string) (*entity.WhatsAppContact, error)
+	// GetByIdentityID returns the contact of a WhatsApp identity, or nil.
+	GetByIdentityID(ctx context.Context, identityID uuid.UUID) (*entity.WhatsAppContact, error)
+}
@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/usecase"
+)
+
+// APIBasePath is where the admin REST API is mounted.
+const APIBasePath = "/api/v1"
+
+// AdminHandler serves the admin REST API under APIBasePath.
+type AdminHandler struct {
+	userUseCase      *usecase.UserUseCase
+	activityUseCase  *usecase.ActivityUseCase
+	schedulerUseCase *usecase.SchedulerUseCase
+	alertRepo        repository.AlertRepository
+	messageRepo      repository.MessageRepository
+	location         *time.Location
+}
+
+func NewAdminHandler(
+	userUseCase *usecase.UserUseCase,
+	activityUseCase *usecase.ActivityUseCase,
+	schedulerUseCase *usecase.SchedulerUseCase,
+	alertRepo repository.AlertRepository,
+	messageRepo repository.MessageRepository,
+	location *time.Location,
+) *AdminHandler {
+	return &AdminHandler{
+		userUseCase:      userUseCase,
+		activityUseCase:  activityUseCase,
+		schedulerUseCase: schedulerUseCase,
+		alertRepo:        alertRepo,
+		messageRepo:      messageRepo,
+		location:         location,
+	}
+}
+
+type updateUserRequest struct {
+	IsActive *bool `json:"is_active"`
+}
+
+type createActivityRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// ScheduledTime defaults to one hour from now
+	ScheduledTime *time.Time `json:"scheduled_time"`
+	CategoryID    *uuid.UUID `json:"category_id"`
+	// Priority from 1 (highest) to 5, default 3
+	Priority int `json:"priority,omitempty"`
+	// RecurrenceRule is an RFC 5545 RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO,WE"
+	RecurrenceRule string `json:"recurrence_rule,omitempty"`
+}
+
+type updateActivityRequest struct {
+	Title         *string    `json:"title"`
+	Description   *string    `json:"description"`
+	ScheduledTime *time.Time `json:"scheduled_time"`
+	Status        *string    `json:"status"`
+	Priority      *int       `json:"priority"`
+	// RecurrenceRule replaces the RRULE; "" makes the activity one-off
+	RecurrenceRule *string `json:"recurrence_rule"`
+}
+
+// triggerRequest targets one user when UserID (or ActivityID, for reminders)
+// is set, and all active users otherwise.
+type triggerRequest struct {
+	UserID     *uuid.UUID `json:"user_id"`
+	ActivityID *uuid.UUID `json:"activity_id"`
+	// DryRun returns the generated text without sending it or writing alert_logs
+	DryRun bool `json:"dry_run,omitempty"`
+	// Force resends a morning alert or evening summary already sent today
+	Force bool `json:"force,omitempty"`
+}
+
+// RegisterRoutes mounts the API on router. openapi.json is served without
+// authentication; every other route requires apiKey.
+func (h *AdminHandler) RegisterRoutes(router *mux.Router, apiKey string) {
+	api := router.PathPrefix(APIBasePath).Subrouter()
+	routes := h.routes()
+
+	spec := buildOpenAPI("Smart Alert System Admin API", "1.0.0", APIBasePath, routes)
+	api.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, spec)
+	}).Methods("GET")
+
+	registerAPIRoutes(api, apiKey, routes)
+}
+
+func (h *AdminHandler) routes() []apiRoute {
+	return []apiRoute{
+		{
+			Method: "GET", Path: "/users", Tag: "users",
+			Summary: "List and search users",
+			Query: append([]apiParam{
+				{Name: "q", Type: "string", Description: "Search in WhatsApp number and name"},
+				{Name: "is_active", Type: "boolean", Description: "Only active or only inactive users"},
+			}, pageQueryParams...),
+			Response: entity.User{}, List: true,
+			Handler: h.listUsers,
+		},
+		{
+			Method: "GET", Path: "/users/{id}", Tag: "users",
+			Summary:  "Get a user",
+			Response: entity.User{},
+			Handler:  h.getUser,
+		},
+		{
+			Method: "PATCH", Path: "/users/{id}", Tag: "users",
+			Summary:  "Activate or deactivate a user",
+			Request:  updateUserRequest{},
+			Response: entity.User{},
+			Handler:  h.updateUser,
+		},
+		{
+			Method: "GET", Path: "/users/{id}/activities", Tag: "activities",
+			Summary: "List a user's activities",
+			Query: []apiParam{
+				{Name: "status", Type: "string", Description: "pending, completed, cancelled or overdue"},
+				{Name: "date", Type: "string", Description: "Only activities on this date (YYYY-MM-DD)"},
+			},
+			Response: entity.Activity{}, List: true,
+			Handler: h.listActivities,
+		},
+		{
+			Method: "POST", Path: "/users/{id}/activities", Tag: "activities",
+			Summary:  "Create an activity for a user",
+			Request:  createActivityRequest{},
+			Response: entity.Activity{}, Status: http.StatusCreated,
+			Handler: h.createActivity,
+		},
+		{
+			Method: "GET", Path: "/activities/{id}", Tag: "activities",
+			Summary:  "Get an activity",
+			Response: entity.Activity{},
+			Handler:  h.getActivity,
+		},
+		{
+			Method: "PATCH", Path: "/activities/{id}", Tag: "activities",
+			Summary:  "Update an activity",
+			Request:  updateActivityRequest{},
+			Response: entity.Activity{},
+			Handler:  h.updateActivity,
+		},
+		{
+			Method: "DELETE", Path: "/activities/{id}", Tag: "activities",
+			Summary: "Delete an activity",
+			Status:  http.StatusNoContent,
+			Handler: h.deleteActivity,
+		},
+		{
+			Method: "GET", Path: "/users/{id}/alerts", Tag: "alerts",
+			Summary:  "List alerts sent to a user, newest first",
+			Query:    pageQueryParams,
+			Response: entity.AlertLog{}, List: true,
+			Handler: h.listAlerts,
+		},
+		{
+			Method: "GET", Path: "/users/{id}/messages", Tag: "messages",
+			Summary:  "List a user's message history, newest first",
+			Query:    pageQueryParams,
+			Response: entity.MessageHistory{}, List: true,
+			Handler: h.listMessages,
+		},
+		{
+			Method: "POST", Path: "/triggers/morning", Tag: "triggers",
+			Summary:  "Send the morning alert now, to one user or to all active users",
+			Request:  triggerRequest{},
+			Response: usecase.TriggerReport{},
+			Handler:  h.trigger(entity.AlertTypeMorning),
+		},
+		{
+			Method: "POST", Path: "/triggers/evening", Tag: "triggers",
+			Summary:  "Send the evening summary now, to one user or to all active users",
+			Request:  triggerRequest{},
+			Response: usecase.TriggerReport{},
+			Handler:  h.trigger(entity.AlertTypeEvening),
+		},
+		{
+			Method: "POST", Path: "/triggers/reminder", Tag: "triggers",
+			Summary:  "Send an activity reminder now (default: each user's next pending activity)",
+			Request:  triggerRequest{},
+			Response: usecase.TriggerReport{},
+			Handler:  h.trigger(entity.AlertTypeActivityReminder),
+		},
+	}
+}
+
+func (h *AdminHandler) listUsers(w http.ResponseWriter, r *http.Request) error {
+	page, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+
+	filter := repository.UserFilter{
+		Query:  strings.TrimSpace(r.URL.Query().Get("q")),
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	}
+	if v := r.URL.Query().Get("is_active"); v != "" {
+		isActive, err := strconv.ParseBool(v)
+		if err != nil {
+			return badRequest("is_active must be true or false")
+		}
+		filter.IsActive = &isActive
+	}
+
+	users, total, err := h.userUseCase.SearchUsers(r.Context(), filter)
+	if err != nil {
+		return err
+	}
+	page.Total = total
+	return writeList(w, nonNil(users), page)
+}
+
+func (h *AdminHandler) getUser(w http.ResponseWriter, r *http.Request) error {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+
+	user, err := h.userUseCase.GetUser(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+	return writeData(w, http.StatusOK, user)
+}
+
+func (h *AdminHandler) updateUser(w http.ResponseWriter, r *http.Request) error {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+
+	var req updateUserRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+	if req.IsActive == nil {
+		return badRequest("is_active is required")
+	}
+
+	user, err := h.userUseCase.SetUserActive(r.Context(), userID, *req.IsActive)
+	if err != nil {
+		return err
+	}
+	return writeData(w, http.StatusOK, user)
+}
+
+func (h *AdminHandler) listActivities(w http.ResponseWriter, r *http.Request) error {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+	if _, err := h.userUseCase.GetUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	var activities []*entity.Activity
+	switch {
+	case query.Get("date") != "":
+		date, err := time.ParseInLocation("2006-01-02", query.Get("date"), h.location)
+		if err != nil {
+			return badRequest("date must be formatted as YYYY-MM-DD")
+		}
+		activities, err = h.activityUseCase.GetUserActivitiesOnDate(r.Context(), userID, date)
+		if err != nil {
+			return err
+		}
+	case query.Get("status") != "":
+		status := entity.ActivityStatus(query.Get("status"))
+		if !validActivityStatus(status) {
+			return badRequest("invalid status %q", status)
+		}
+		activities, err = h.activityUseCase.GetUserActivitiesByStatus(r.Context(), userID, status)
+		if err != nil {
+			return err
+		}
+	default:
+		activities, err = h.activityUseCase.GetUserActivities(r.Context(), userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeList(w, nonNil(activities), PageInfo{Limit: len(activities), Total: len(activities)})
+}
+
+func (h *AdminHandler) createActivity(w http.ResponseWriter, r *http.Request) error {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+
+	var req createActivityRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		return badRequest("title is required")
+	}
+	if req.Priority != 0 && (req.Priority < 1 || req.Priority > 5) {
+		return badRequest("priority must be between 1 and 5")
+	}
+
+	activity, err := h.activityUseCase.CreateActivity(r.Context(), userID, entity.ActivityIntentData{
+		Title:          req.Title,
+		Description:    req.Description,
+		ScheduledTime:  req.ScheduledTime,
+		CategoryID:     req.CategoryID,
+		Priority:       req.Priority,
+		RecurrenceRule: req.RecurrenceRule,
+	})
+	if err != nil {
+		return err
+	}
+	return writeData(w, http.StatusCreated, activity)
+}
+
+func (h *AdminHandler) getActivity(w http.ResponseWriter, r *http.Request) error {
+	activityID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+
+	activity, err := h.activityUseCase.GetActivity(r.Context(), activityID)
+	if err != nil {
+		return err
+	}
+	return writeData(w, http.StatusOK, activity)
+}
+
+func (h *AdminHandler) updateActivity(w http.ResponseWriter, r *http.Request) error {
+	activityID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+
+	var req updateActivityRequest
+	if err := decodeJSON(r, &req); err != nil {
+		return err
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		return badRequest("title must not be empty")
+	}
+	if req.Status != nil && !validActivityStatus(entity.ActivityStatus(*req.Status)) {
+		return badRequest("invalid status %q", *req.Status)
+	}
+	if req.Priority != nil && (*req.Priority < 1 || *req.Priority > 5) {
+		return badRequest("priority must be between 1 and 5")
+	}
+
+	err = h.activityUseCase.UpdateActivity(r.Context(), activityID, entity.UpdateActivityIntentData{
+		ActivityID:     activityID,
+		Title:          req.Title,
+		Description:    req.Description,
+		ScheduledTime:  req.ScheduledTime,
+		Status:         req.Status,
+		Priority:       req.Priority,
+		RecurrenceRule: req.RecurrenceRule,
+	})
+	if err != nil {
+		return err
+	}
+
+	activity, err := h.activityUseCase.GetActivity(r.Context(), activityID)
+	if err != nil {
+		return err
+	}
+	return writeData(w, http.StatusOK, activity)
+}
+
+func (h *AdminHandler) deleteActivity(w http.ResponseWriter, r *http.Request) error {
+	activityID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.activityUseCase.DeleteActivity(r.Context(), activityID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (h *AdminHandler) listAlerts(w http.ResponseWriter, r *http.Request) error {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	if _, err := h.userUseCase.GetUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	alerts, total, err := h.alertRepo.ListByUserID(r.Context(), userID, page.Limit, page.Offset)
+	if err != nil {
+		return err
+	}
+	page.Total = total
+	return writeList(w, nonNil(alerts), page)
+}
+
+func (h *AdminHandler) listMessages(w http.ResponseWriter, r *http.Request) error {
+	userID, err := pathUUID(r, "id")
+	if err != nil {
+		return err
+	}
+	page, err := pageParams(r)
+	if err != nil {
+		return err
+	}
+	if _, err := h.userUseCase.GetUser(r.Context(), userID); err != nil {
+		return err
+	}
+
+	messages, total, err := h.messageRepo.ListByUserID(r.Context(), userID, page.Limit, page.Offset)
+	if err != nil {
+		return err
+	}
+	page.Total = total
+	return writeList(w, nonNil(messages), page)
+}
+
+func (h *AdminHandler) trigger(alertType entity.AlertType) apiHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		// An empty body triggers the alert for all active users
+		var req triggerRequest
+		if r.ContentLength != 0 {
+			if err := decodeJSON(r, &req); err != nil {
+				return err
+			}
+		}
+
+		// AI generation easily outlasts the server's write timeout; the run
+		// itself is bounded by the scheduler's timeouts
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			slog.WarnContext(r.Context(), "Could not lift write deadline for trigger", "error", err)
+		}
+
+		report, err := h.schedulerUseCase.Trigger(r.Context(), usecase.TriggerRequest{
+			AlertType:  alertType,
+			UserID:     req.UserID,
+			ActivityID: req.ActivityID,
+			DryRun:     req.DryRun,
+			Force:      req.Force,
+		})
+		if err != nil {
+			return err
+		}
+		return writeData(w, http.StatusOK, report)
+	}
+}
+
+func validActivityStatus(status entity.ActivityStatus) bool {
+	switch status {
+	case entity.ActivityStatusPending, entity.ActivityStatusCompleted,
+		entity.ActivityStatusCancelled, entity.ActivityStatusOverdue:
+		return true
+	}
+	return false
+}
+
+// nonNil makes empty lists encode as [] instead of null.
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"smart_alert_system/internal/usecase"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// apiRoute describes one REST endpoint. The same description is used to
+// register the handler and to generate the OpenAPI document, so the docs
+// can't drift from the routes that are actually served.
+type apiRoute struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	Query   []apiParam
+	// Request is a zero value of the JSON body type, nil if there is no body.
+	Request interface{}
+	// Response is a zero value of the type returned under "data", nil if the
+	// endpoint returns no content.
+	Response interface{}
+	// List wraps Response in an array with pagination info.
+	List    bool
+	Status  int
+	Handler apiHandlerFunc
+}
+
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// apiHandlerFunc writes a successful response itself and returns errors for
+// serveAPI to turn into an error envelope.
+type apiHandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// apiError is an error with the HTTP status and machine-readable code that
+// should be reported to the client.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{Status: http.StatusBadRequest, Code: "invalid_request", Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorResponse is the envelope of every error returned by the API.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type dataResponse struct {
+	Data interface{} `json:"data"`
+}
+
+type listResponse struct {
+	Data interface{} `json:"data"`
+	Page PageInfo    `json:"page"`
+}
+
+type PageInfo struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Total  int `json:"total"`
+}
+
+// registerAPIRoutes mounts routes on router behind API key authentication.
+func registerAPIRoutes(router *mux.Router, apiKey string, routes []apiRoute) {
+	protected := router.NewRoute().Subrouter()
+	protected.Use(apiKeyMiddleware(apiKey))
+	for _, route := range routes {
+		protected.HandleFunc(route.Path, serveAPI(route.Handler)).Methods(route.Method)
+	}
+}
+
+func serveAPI(handle apiHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := handle(w, r)
+		if err == nil {
+			return
+		}
+
+		var apiErr *apiError
+		switch {
+		case errors.As(err, &apiErr):
+			writeAPIError(w, apiErr.Status, apiErr.Code, apiErr.Message)
+		case errors.Is(err, usecase.ErrNotFound):
+			writeAPIError(w, http.StatusNotFound, "not_found", err.Error())
+		case errors.Is(err, usecase.ErrInvalidInput):
+			writeAPIError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		default:
+			slog.ErrorContext(r.Context(), "API request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+			writeAPIError(w, http.StatusInternalServerError, "internal_error", "internal server error")
+		}
+	}
+}
+
+// apiKeyMiddleware accepts the key in an X-API-Key header or as a bearer token.
+func apiKeyMiddleware(apiKey string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("X-API-Key")
+			if key == "" {
+				key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			}
+			if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+				writeAPIError(w, http.StatusUnauthorized, "unauthorized", "missing or invalid API key")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(body)
+}
+
+func writeData(w http.ResponseWriter, status int, data interface{}) error {
+	return writeJSON(w, status, dataResponse{Data: data})
+}
+
+func writeList(w http.ResponseWriter, data interface{}, page PageInfo) error {
+	return writeJSON(w, http.StatusOK, listResponse{Data: data, Page: page})
+}
+
+func writeAPIError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Error: ErrorBody{Code: code, Message: message}})
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return badRequest("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+func pathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)[name])
+	if err != nil {
+		return uuid.Nil, badRequest("%s must be a UUID", name)
+	}
+	return id, nil
+}
+
+// pageParams reads limit and offset from the query string.
+func pageParams(r *http.Request) (PageInfo, error) {
+	page := PageInfo{Limit: defaultPageLimit}
+	query := r.URL.Query()
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return page, badRequest("limit must be between 1 and %d", maxPageLimit)
+		}
+		page.Limit = limit
+	}
+	if v := query.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return page, badRequest("offset must be a non-negative integer")
+		}
+		page.Offset = offset
+	}
+	return page, nil
+}
+
+var pageQueryParams = []apiParam{
+	{Name: "limit", Type: "integer", Description: fmt.Sprintf("Page size, 1-%d (default %d)", maxPageLimit, defaultPageLimit)},
+	{Name: "offset", Type: "integer", Description: "Number of items to skip"},
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"smart_alert_system/internal/usecase"
+)
+
+// CalendarHandler serves the per-user iCalendar feeds. The secret token in
+// the URL is the only authentication, as calendar apps can't log in.
+type CalendarHandler struct {
+	calendarUseCase *usecase.CalendarUseCase
+}
+
+func NewCalendarHandler(calendarUseCase *usecase.CalendarUseCase) *CalendarHandler {
+	return &CalendarHandler{calendarUseCase: calendarUseCase}
+}
+
+func (h *CalendarHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/calendar/{token}.ics", h.feed).Methods("GET", "HEAD")
+}
+
+func (h *CalendarHandler) feed(w http.ResponseWriter, r *http.Request) {
+	calendar, err := h.calendarUseCase.Feed(r.Context(), mux.Vars(r)["token"])
+	if errors.Is(err, usecase.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to build calendar feed", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := calendar.Encode(&buf); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to encode calendar feed", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="smart-alert.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	buf.WriteTo(w)
+}
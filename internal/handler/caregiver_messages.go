@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/usecase"
+)
+
+// caregiverHelp explains the caregiver commands.
+const caregiverHelp = "Perintah pendamping:\n• *tambah pendamping 0812xxxx Nama*\n• *hapus pendamping 0812xxxx*\n• *aktifkan ringkasan pendamping 0812xxxx* (ringkasan harian setiap malam)\n• *matikan ringkasan pendamping 0812xxxx*"
+
+var (
+	// "tambah pendamping 0812 3456 7890 Budi", "hapus pendamping +62812…"
+	caregiverCommandPattern = regexp.MustCompile(`(?i)^(tambah|hapus)\s+pendamping\s+(\+?\d[\d\s-]*\d)\s*(.*)$`)
+	// "aktifkan ringkasan pendamping 0812…", "matikan ringkasan pendamping 0812…"
+	caregiverDigestPattern = regexp.MustCompile(`(?i)^(aktifkan|matikan)\s+ringkasan\s+pendamping\s+(\+?\d[\d\s-]*\d)$`)
+	caregiverListPattern   = regexp.MustCompile(`(?i)^(daftar\s+)?pendamping$`)
+)
+
+// answerCaregiverCommand handles the commands that authorize caregivers to be
+// alerted about the user's missed medication and health activities. ok is
+// false for any other message.
+func (h *MessageHandler) answerCaregiverCommand(ctx context.Context, user *entity.User, message string) (intent, response string, ok bool) {
+	message = strings.TrimSpace(message)
+
+	if caregiverListPattern.MatchString(message) {
+		return "list_caregivers", h.listCaregivers(ctx, user), true
+	}
+
+	if match := caregiverDigestPattern.FindStringSubmatch(message); match != nil {
+		enabled := strings.EqualFold(match[1], "aktifkan")
+		caregiver, err := h.caregiverUseCase.SetDailyDigest(ctx, user.ID, match[2], enabled)
+		if err != nil {
+			return "caregiver_digest", caregiverErrorReply(ctx, err), true
+		}
+		if enabled {
+			return "caregiver_digest", fmt.Sprintf("✓ %s akan menerima ringkasan harian jadwal obat dan kesehatan Anda setiap malam.", caregiver.DisplayName()), true
+		}
+		return "caregiver_digest", fmt.Sprintf("✓ Ringkasan harian untuk %s dimatikan. Pemberitahuan jika ada jadwal terlewat tetap dikirim.", caregiver.DisplayName()), true
+	}
+
+	match := caregiverCommandPattern.FindStringSubmatch(message)
+	if match == nil {
+		return "", "", false
+	}
+
+	if strings.EqualFold(match[1], "hapus") {
+		caregiver, err := h.caregiverUseCase.RemoveCaregiver(ctx, user.ID, match[2])
+		if err != nil {
+			return "remove_caregiver", caregiverErrorReply(ctx, err), true
+		}
+		return "remove_caregiver", fmt.Sprintf("✓ %s bukan lagi pendamping Anda dan tidak akan menerima pemberitahuan.", caregiver.DisplayName()), true
+	}
+
+	caregiver, created, err := h.caregiverUseCase.AddCaregiver(ctx, user, match[2], strings.TrimSpace(match[3]))
+	if err != nil {
+		return "add_caregiver", caregiverErrorReply(ctx, err), true
+	}
+	if !created {
+		return "add_caregiver", fmt.Sprintf("%s sudah menjadi pendamping Anda.", caregiver.DisplayName()), true
+	}
+	slog.InfoContext(ctx, "Caregiver added", "caregiver_id", caregiver.ID, "user_id", user.ID)
+	return "add_caregiver", fmt.Sprintf("✓ %s sekarang menjadi pendamping Anda. Mereka akan diberi tahu jika jadwal minum obat atau pemeriksaan kesehatan belum Anda konfirmasi.\n\nKirim *aktifkan ringkasan pendamping %s* agar mereka juga menerima ringkasan harian.",
+		caregiver.DisplayName(), caregiver.PhoneNumber), true
+}
+
+func (h *MessageHandler) listCaregivers(ctx context.Context, user *entity.User) string {
+	caregivers, err := h.caregiverUseCase.ListCaregivers(ctx, user.ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to list caregivers", "error", err)
+		return "Maaf, daftar pendamping tidak dapat dimuat saat ini. Silakan coba lagi."
+	}
+	if len(caregivers) == 0 {
+		return "Anda belum memiliki pendamping. Pendamping (misalnya keluarga) diberi tahu jika jadwal minum obat atau pemeriksaan kesehatan Anda terlewat.\n\n" + caregiverHelp
+	}
+
+	var msg strings.Builder
+	msg.WriteString("👥 Pendamping Anda:\n")
+	for i, caregiver := range caregivers {
+		fmt.Fprintf(&msg, "%d. %s (+%s)", i+1, caregiver.DisplayName(), caregiver.PhoneNumber)
+		if caregiver.DailyDigest {
+			msg.WriteString(" - ringkasan harian")
+		}
+		msg.WriteString("\n")
+	}
+	msg.WriteString("\n" + caregiverHelp)
+	return msg.String()
+}
+
+func caregiverErrorReply(ctx context.Context, err error) string {
+	switch {
+	case errors.Is(err, usecase.ErrNotFound):
+		return "Nomor tersebut bukan pendamping Anda. Kirim *pendamping* untuk melihat daftarnya."
+	case errors.Is(err, usecase.ErrTooManyCaregivers):
+		return fmt.Sprintf("Maaf, Anda sudah memiliki %d pendamping. Hapus salah satu terlebih dahulu.", usecase.MaxCaregivers)
+	case errors.Is(err, usecase.ErrInvalidInput):
+		return "Maaf, nomor WhatsApp tersebut tidak valid. Contoh: *tambah pendamping 081234567890 Budi*"
+	}
+	slog.ErrorContext(ctx, "Failed to handle caregiver command", "error", err)
+	return "Maaf, terjadi kesalahan. Silakan coba lagi."
+}
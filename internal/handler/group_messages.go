@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// groupWelcome introduces the assistant the first time it hears from a group.
+const groupWelcome = "Halo semua! 👋 Saya asisten jadwal grup ini. Kegiatan yang ditambahkan di sini masuk ke kalender bersama, dan pengingat serta ringkasan pagi dikirim ke grup ini.\n\nContoh:\n• \"Besok rapat keluarga jam 7 malam\"\n• \"Ingatkan Budi minum obat jam 8\"\n• \"Jadwal hari ini\""
+
+// groupIntents are the intents answered in groups. Anything else is taken for
+// conversation between members and gets no reply.
+var groupIntents = map[entity.IntentType]bool{
+	entity.IntentAddActivity:    true,
+	entity.IntentDeleteActivity: true,
+	entity.IntentUpdateActivity: true,
+	entity.IntentListActivities: true,
+}
+
+// processGroupMessage handles a message written in a group chat. The group's
+// shared calendar takes the place of the user: activities are added to it,
+// optionally assigned to a member, and replies go to the group. Only text
+// about the schedule and answers to reminders get a reply.
+func (h *MessageHandler) processGroupMessage(ctx context.Context, message *channel.Message) {
+	ctx, span := tracing.Start(ctx, "MessageHandler.processGroupMessage",
+		attribute.String("messaging.channel", message.Channel), attribute.String("message.kind", string(message.Kind)))
+	defer span.End()
+
+	slog.InfoContext(ctx, "Processing group message", "channel", message.Channel, "chat_id", message.ChatID,
+		"sender", message.SenderID, "body", message.Body)
+
+	identity := entity.NewUserIdentity(uuid.Nil, message.Channel, message.SenderID, message.SenderID, message.Account)
+	sender, err := h.userUseCase.GetOrCreateByIdentity(ctx, identity, message.SenderName, "Asia/Jakarta")
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get or create group member", "error", err)
+		return
+	}
+	group, err := h.groupUseCase.GetOrCreateGroup(ctx, message.Channel, message.ChatID, message.Account, "")
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get or create group", "error", err)
+		return
+	}
+	member, err := h.groupUseCase.AddMember(ctx, group.ID, sender, message.SenderName, message.SenderID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to save group member", "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "Group resolved", "group_id", group.ID, "user_id", member.UserID)
+
+	now := time.Now()
+	messageHistory := entity.NewMessageHistory(group.ID, message.Body, entity.MessageTypeIncoming)
+	messageHistory.ReceivedAt = &now
+	if err := h.messageRepo.Create(ctx, messageHistory); err != nil {
+		slog.ErrorContext(ctx, "Failed to save message", "error", err)
+	}
+
+	if group.IsFirstTime {
+		if err := h.queueReply(ctx, group.ID, "welcome:"+group.ID.String(), message.Address, groupWelcome); err != nil {
+			slog.ErrorContext(ctx, "Failed to queue group welcome message", "chat_id", message.ChatID, "error", err)
+		} else {
+			h.userUseCase.MarkAsNotFirstTime(ctx, group.ID)
+		}
+	}
+
+	if message.Kind != channel.KindText || message.Body == "" {
+		h.skipMessage(ctx, messageHistory, "")
+		return
+	}
+
+	// Reminder buttons, tapped or answered with the number or title of the
+	// choice
+	choiceID := message.ChoiceID
+	if choiceID == "" {
+		choiceID, err = h.outboxUseCase.ResolveChoice(ctx, group.ID, message.Body, time.Now().Add(-choiceReplyWindow))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to resolve choice", "error", err)
+		}
+	}
+	if choiceID != "" {
+		if intent, response, ok := h.answerChoice(ctx, group.ID, choiceID); ok {
+			h.finishMessage(ctx, group.ID, message, messageHistory, intent, response)
+			return
+		}
+	}
+	if intent, response, ok := h.postponeFromReply(ctx, group.ID, message.Body, message.ReplyToID); ok {
+		h.finishMessage(ctx, group.ID, message, messageHistory, intent, response)
+		return
+	}
+
+	parsedIntent := h.parseIntent(ctx, message.Body)
+	if !groupIntents[parsedIntent.Type] {
+		slog.DebugContext(ctx, "Not about the schedule, no reply in group", "intent", parsedIntent.Type)
+		h.skipMessage(ctx, messageHistory, string(parsedIntent.Type))
+		return
+	}
+
+	var response string
+	if parsedIntent.Type == entity.IntentAddActivity {
+		var assignee *entity.GroupMember
+		assignee, err = h.groupUseCase.FindAssignee(ctx, member, message.Body, message.Mentions)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to find assignee", "error", err)
+		}
+		response, err = h.handleAddActivity(ctx, group.ID, parsedIntent, assignee)
+	} else {
+		response, err = h.handleIntent(ctx, group.ID, parsedIntent, message.Body)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to handle intent", "intent", parsedIntent.Type, "error", err)
+		response = "Maaf, terjadi kesalahan. Silakan coba lagi."
+	}
+	h.finishMessage(ctx, group.ID, message, messageHistory, string(parsedIntent.Type), response)
+}
+
+// skipMessage marks a message processed without replying to it.
+func (h *MessageHandler) skipMessage(ctx context.Context, messageHistory *entity.MessageHistory, intent string) {
+	messageHistory.IntentDetected = intent
+	messageHistory.IsProcessed = true
+	h.messageRepo.Update(ctx, messageHistory)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"smart_alert_system/internal/infrastructure/health"
+)
+
+// HealthHandler serves the liveness and readiness probes. Liveness only says
+// the process serves HTTP, so a restart can fix it; readiness checks the
+// dependencies and says whether the instance should get traffic.
+type HealthHandler struct {
+	checker *health.Checker
+}
+
+func NewHealthHandler(checker *health.Checker) *HealthHandler {
+	return &HealthHandler{checker: checker}
+}
+
+func (h *HealthHandler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/livez", h.live).Methods("GET", "HEAD")
+	router.HandleFunc("/readyz", h.ready).Methods("GET", "HEAD")
+	// Kept for the Docker health check and existing monitors
+	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}).Methods("GET")
+}
+
+func (h *HealthHandler) live(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]string{"status": health.StatusOK})
+}
+
+// ready reports every check with its latency. It answers 503 only when a
+// critical check fails; a degraded instance still gets traffic.
+func (h *HealthHandler) ready(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Run(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready() {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, report)
+}
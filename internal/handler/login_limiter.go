@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLoginClients bounds how many client IPs are tracked before those whose
+// window has ended are dropped.
+const maxLoginClients = 10000
+
+// loginLimiter caps login requests per client IP per minute, so one client
+// can't guess codes or have codes sent faster than a person would. Counts
+// are kept in memory, so the limit applies per replica.
+type loginLimiter struct {
+	perMinute int
+	mu        sync.Mutex
+	clients   map[string]*loginWindow
+}
+
+type loginWindow struct {
+	start time.Time
+	count int
+}
+
+// newLoginLimiter returns a limiter allowing perMinute requests per IP; zero
+// or less disables it.
+func newLoginLimiter(perMinute int) *loginLimiter {
+	return &loginLimiter{
+		perMinute: perMinute,
+		clients:   make(map[string]*loginWindow),
+	}
+}
+
+// Allow counts a request from ip and reports whether it is within the limit.
+func (l *loginLimiter) Allow(ip string) bool {
+	return l.allow(ip, time.Now())
+}
+
+func (l *loginLimiter) allow(ip string, now time.Time) bool {
+	if l.perMinute <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	window, ok := l.clients[ip]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		if !ok && len(l.clients) >= maxLoginClients {
+			l.evictEnded(now)
+		}
+		window = &loginWindow{start: now}
+		l.clients[ip] = window
+	}
+	window.count++
+	return window.count <= l.perMinute
+}
+
+func (l *loginLimiter) evictEnded(now time.Time) {
+	for ip, window := range l.clients {
+		if now.Sub(window.start) >= time.Minute {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+// clientIP returns the address a request came from. Behind a reverse proxy
+// that is the proxy's, so when trustProxy is set the last X-Forwarded-For
+// entry, the one the proxy appended, is used instead.
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		forwarded := r.Header.Values("X-Forwarded-For")
+		if len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
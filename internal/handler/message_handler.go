@@ -0,0 +1,1006 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/infrastructure/ai"
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/logging"
+	"smart_alert_system/internal/infrastructure/mailbox"
+	"smart_alert_system/internal/infrastructure/metrics"
+	"smart_alert_system/internal/infrastructure/speech"
+	"smart_alert_system/internal/infrastructure/tracing"
+	"smart_alert_system/internal/usecase"
+	"smart_alert_system/internal/utils"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+type MessageHandler struct {
+	userUseCase      *usecase.UserUseCase
+	activityUseCase  *usecase.ActivityUseCase
+	aiService        ai.AIService
+	outboxUseCase    *usecase.OutboxUseCase
+	messageRepo      repository.MessageRepository
+	alertRepo        repository.AlertRepository
+	mailbox          *mailbox.Mailbox
+	senders          *senderKeys
+	calendarUseCase  *usecase.CalendarUseCase
+	imageUseCase     *usecase.ImageUseCase
+	groupUseCase     *usecase.GroupUseCase
+	caregiverUseCase *usecase.CaregiverUseCase
+	transcriber      speech.Transcriber
+	maxVoiceBytes    int64
+}
+
+func NewMessageHandler(
+	userUseCase *usecase.UserUseCase,
+	activityUseCase *usecase.ActivityUseCase,
+	aiService ai.AIService,
+	outboxUseCase *usecase.OutboxUseCase,
+	messageRepo repository.MessageRepository,
+	alertRepo repository.AlertRepository,
+	mailbox *mailbox.Mailbox,
+	calendarUseCase *usecase.CalendarUseCase,
+	imageUseCase *usecase.ImageUseCase,
+	groupUseCase *usecase.GroupUseCase,
+	caregiverUseCase *usecase.CaregiverUseCase,
+) *MessageHandler {
+	return &MessageHandler{
+		userUseCase:      userUseCase,
+		activityUseCase:  activityUseCase,
+		aiService:        aiService,
+		outboxUseCase:    outboxUseCase,
+		messageRepo:      messageRepo,
+		alertRepo:        alertRepo,
+		mailbox:          mailbox,
+		senders:          newSenderKeys(),
+		calendarUseCase:  calendarUseCase,
+		imageUseCase:     imageUseCase,
+		groupUseCase:     groupUseCase,
+		caregiverUseCase: caregiverUseCase,
+	}
+}
+
+// SetTranscriber enables voice notes: audio up to maxBytes is transcribed and
+// the transcript is handled like a text message.
+func (h *MessageHandler) SetTranscriber(transcriber speech.Transcriber, maxBytes int64) {
+	h.transcriber = transcriber
+	h.maxVoiceBytes = maxBytes
+}
+
+// Webhook returns the handler for the webhook of ch. Events are processed
+// asynchronously; the messaging service gets its 200 OK right away.
+func (h *MessageHandler) Webhook(ch channel.Channel) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(tracing.FromHeaders(r.Context(), r.Header), "MessageHandler.Webhook",
+			attribute.String("messaging.channel", ch.Name()))
+		defer span.End()
+		r = r.WithContext(ctx)
+		metrics.WebhooksReceived.WithLabelValues(ch.Name()).Inc()
+		slog.DebugContext(ctx, "Webhook request received", "channel", ch.Name(), "remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(), "content_type", r.Header.Get("Content-Type"))
+
+		if r.Method != http.MethodPost {
+			slog.WarnContext(ctx, "Webhook method not allowed", "channel", ch.Name(), "method", r.Method)
+			metrics.WebhooksRejected.WithLabelValues(ch.Name(), "method_not_allowed").Inc()
+			span.SetAttributes(attribute.String("webhook.rejected", "method_not_allowed"))
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		events, err := ch.ParseWebhook(r)
+		if errors.Is(err, channel.ErrUnauthorized) {
+			slog.WarnContext(ctx, "Rejecting unauthorized webhook", "channel", ch.Name())
+			metrics.WebhooksRejected.WithLabelValues(ch.Name(), "unauthorized").Inc()
+			span.SetAttributes(attribute.String("webhook.rejected", "unauthorized"))
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "Invalid webhook payload", "channel", ch.Name(), "error", err)
+			metrics.WebhooksRejected.WithLabelValues(ch.Name(), "invalid_payload").Inc()
+			span.SetAttributes(attribute.String("webhook.rejected", "invalid_payload"))
+			http.Error(w, "Invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		// Process events asynchronously, in order per sender
+		for _, event := range events {
+			switch event.Type {
+			case channel.EventMessage:
+				h.enqueueMessage(ctx, ch, event.Message)
+			case channel.EventAck:
+				h.enqueueAck(ctx, ch, event.Ack)
+			case channel.EventVote:
+				h.enqueuePollVote(ctx, event.Vote)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		span.SetAttributes(attribute.Int("webhook.events", len(events)))
+		slog.InfoContext(ctx, "Webhook received", "channel", ch.Name(), "events", len(events))
+	}
+}
+
+// enqueueMessage hands the message to the per-user mailbox so that messages
+// from the same sender are processed one after another, in arrival order,
+// while messages from different senders still run in parallel. Messages in a
+// group share the group's mailbox, as they all change its calendar. The
+// message is processed under the correlation ID and trace of ctx.
+func (h *MessageHandler) enqueueMessage(ctx context.Context, ch channel.Channel, message *channel.Message) {
+	var key string
+	if message.Group {
+		key = message.Channel + ":" + message.ChatID
+	} else {
+		key = h.senders.Key(message.Channel, message.SenderID)
+	}
+	jobCtx := tracing.Link(logging.Detach(ctx), ctx)
+	if err := h.mailbox.Submit(key, func() {
+		if message.Group {
+			h.processGroupMessage(jobCtx, message)
+			return
+		}
+		h.processMessage(jobCtx, ch, message)
+	}); err != nil {
+		slog.ErrorContext(ctx, "Dropping message", "channel", message.Channel, "sender", message.SenderID, "error", err)
+	}
+}
+
+// enqueueAck records delivery/read receipts for messages we sent. It shares
+// the recipient's mailbox so acks are applied after that user's pending work.
+func (h *MessageHandler) enqueueAck(ctx context.Context, ch channel.Channel, ack *channel.Ack) {
+	key := h.senders.Key(ch.Name(), ack.Recipient)
+	receivedAt := time.Now()
+	jobCtx := tracing.Link(logging.Detach(ctx), ctx)
+	if err := h.mailbox.Submit(key, func() {
+		if err := h.outboxUseCase.RecordAck(jobCtx, ack.MessageID, ack.Level, receivedAt); err != nil {
+			slog.ErrorContext(jobCtx, "Failed to record ack", "message_id", ack.MessageID, "error", err)
+		}
+	}); err != nil {
+		slog.ErrorContext(ctx, "Dropping ack", "message_id", ack.MessageID, "error", err)
+	}
+}
+
+// enqueuePollVote handles a vote in the voter's mailbox, after their earlier
+// messages.
+func (h *MessageHandler) enqueuePollVote(ctx context.Context, vote *channel.Vote) {
+	key := h.senders.Key(vote.Channel, vote.SenderID)
+	jobCtx := tracing.Link(logging.Detach(ctx), ctx)
+	if err := h.mailbox.Submit(key, func() {
+		h.processPollVote(jobCtx, vote)
+	}); err != nil {
+		slog.ErrorContext(ctx, "Dropping poll vote", "channel", vote.Channel, "sender", vote.SenderID, "error", err)
+	}
+}
+
+// defaultWelcome greets new users of accounts without their own welcome text.
+const defaultWelcome = "Halo! Selamat datang di Smart Alert System. Saya akan membantu Anda mengelola kegiatan dan memberikan rekomendasi kesehatan.\n\nAnda bisa menambahkan kegiatan dengan format:\n• \"Besok saya akan olahraga jam 6 pagi\"\n• \"Hari ini ada meeting jam 2 siang\"\n• \"Tambah kegiatan [nama kegiatan] [waktu]\"\n\nSilakan coba kirim pesan untuk menambahkan kegiatan!"
+
+// welcomeText returns the welcome message of the account the user messaged.
+func welcomeText(ch channel.Channel, account string) string {
+	if welcomer, ok := ch.(channel.Welcomer); ok {
+		if text := welcomer.Welcome(account); text != "" {
+			return text
+		}
+	}
+	return defaultWelcome
+}
+
+// processMessage handles a message the user sent on ch. Files attached to it
+// are downloaded through the account that received it, and replies go back
+// to the chat it came from.
+func (h *MessageHandler) processMessage(ctx context.Context, ch channel.Channel, message *channel.Message) {
+	ctx, span := tracing.Start(ctx, "MessageHandler.processMessage",
+		attribute.String("messaging.channel", message.Channel), attribute.String("message.kind", string(message.Kind)))
+	defer span.End()
+
+	messageContent := message.Body
+	slog.InfoContext(ctx, "Processing message", "channel", message.Channel, "sender", message.SenderID,
+		"kind", message.Kind, "body", messageContent)
+
+	// Get or create user
+	identity := entity.NewUserIdentity(uuid.Nil, message.Channel, message.SenderID, message.ChatID, message.Account)
+	user, err := h.userUseCase.GetOrCreateByIdentity(ctx, identity, message.SenderName, "Asia/Jakarta")
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get or create user", "error", err)
+		return
+	}
+	slog.DebugContext(ctx, "User resolved", "user_id", user.ID, "first_time", user.IsFirstTime)
+	if message.Channel == channel.WhatsApp {
+		// The contact's other address now queues behind this one
+		h.senders.Link(message.SenderID, identity.ChatID)
+	}
+
+	// Voice notes are transcribed and then handled like the typed message.
+	// If that fails, the user is told so instead of getting an "unknown" reply.
+	var transcript, voiceFailure string
+	voiceNote := message.Kind == channel.KindVoice
+	if voiceNote {
+		transcript, voiceFailure = h.transcribeVoiceNote(ctx, ch, message)
+		if voiceFailure == "" {
+			messageContent = transcript
+		}
+	}
+
+	// Save incoming message
+	now := time.Now()
+	messageHistory := entity.NewMessageHistory(user.ID, message.Body, entity.MessageTypeIncoming)
+	messageHistory.ReceivedAt = &now
+	messageHistory.Transcript = transcript
+	if voiceNote {
+		messageHistory.MediaType = entity.MediaTypeAudio
+	} else if message.Kind == channel.KindImage {
+		messageHistory.MediaType = entity.MediaTypeImage
+	} else if isCalendarFile(message) {
+		messageHistory.MediaType = entity.MediaTypeDocument
+	}
+	if err := h.messageRepo.Create(ctx, messageHistory); err != nil {
+		slog.ErrorContext(ctx, "Failed to save message", "error", err)
+	}
+
+	// "tautkan" asks for a code to link another channel; sending the code
+	// from that channel links it to this user
+	if code, ok := parseLinkRequest(messageContent); ok {
+		intent, response := h.linkIdentity(ctx, user, identity, code)
+		h.finishMessage(ctx, identity.UserID, message, messageHistory, intent, response)
+		return
+	}
+
+	// Check if first time user
+	if user.IsFirstTime {
+		welcomeMsg := welcomeText(ch, message.Account)
+		if err := h.queueReply(ctx, user.ID, "welcome:"+user.ID.String(), message.Address, welcomeMsg); err != nil {
+			slog.ErrorContext(ctx, "Failed to queue welcome message", "chat_id", message.ChatID, "error", err)
+		} else {
+			slog.DebugContext(ctx, "Welcome message queued", "user_id", user.ID)
+			h.userUseCase.MarkAsNotFirstTime(ctx, user.ID)
+			// After welcome message, also try to process the current message if it contains activity
+			// This allows user to add activity in the first message
+			// Continue to process the message for activity
+		}
+		// Don't return early - continue processing the message to detect activity
+	}
+
+	// Telegram sends "/start" when the user opens the bot
+	if strings.TrimSpace(messageContent) == "/start" {
+		if user.IsFirstTime {
+			// The welcome message is the answer
+			messageHistory.IntentDetected = string(entity.IntentGreeting)
+			messageHistory.IsProcessed = true
+			h.messageRepo.Update(ctx, messageHistory)
+			return
+		}
+		h.finishMessage(ctx, user.ID, message, messageHistory, string(entity.IntentGreeting), "Halo! Ada yang bisa saya bantu hari ini?")
+		return
+	}
+
+	// "tambah pendamping 0812…" lets a family member be alerted about
+	// missed medication and check-ups
+	if intent, response, ok := h.answerCaregiverCommand(ctx, user, messageContent); ok {
+		h.finishMessage(ctx, user.ID, message, messageHistory, intent, response)
+		return
+	}
+
+	// An attached .ics file is imported instead of being read as text
+	if isCalendarFile(message) {
+		response := h.importCalendarFile(ctx, ch, message, user.ID)
+		h.finishMessage(ctx, user.ID, message, messageHistory, "import_calendar", response)
+		return
+	}
+
+	if voiceFailure != "" {
+		h.finishMessage(ctx, user.ID, message, messageHistory, "", voiceFailure)
+		return
+	}
+
+	// Photos of prescriptions and schedules are read into activities, which
+	// are only created after the user confirms them
+	if message.Kind == channel.KindImage {
+		response := h.proposeFromImage(ctx, ch, message, user.ID)
+		h.finishMessage(ctx, user.ID, message, messageHistory, "image_activities", response)
+		return
+	}
+
+	// "ya" or "batal" answers the pending confirmation, if there is one
+	if intent, response, ok := h.answerConfirmation(ctx, user.ID, messageContent); ok {
+		h.finishMessage(ctx, user.ID, message, messageHistory, intent, response)
+		return
+	}
+
+	// Reminder buttons, tapped or answered with the number or title of the
+	// choice when they were sent as text
+	choiceID := message.ChoiceID
+	if choiceID == "" {
+		choiceID, err = h.outboxUseCase.ResolveChoice(ctx, user.ID, messageContent, time.Now().Add(-choiceReplyWindow))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to resolve choice", "error", err)
+		}
+	}
+	if choiceID != "" {
+		if intent, response, ok := h.answerChoice(ctx, user.ID, choiceID); ok {
+			h.finishMessage(ctx, user.ID, message, messageHistory, intent, response)
+			return
+		}
+	}
+
+	// "tunda 30 menit", "pindah ke besok" or "nanti jam 5" after a reminder
+	// postpones the reminded activity
+	if intent, response, ok := h.postponeFromReply(ctx, user.ID, messageContent, message.ReplyToID); ok {
+		h.finishMessage(ctx, user.ID, message, messageHistory, intent, response)
+		return
+	}
+
+	parsedIntent := h.parseIntent(ctx, messageContent)
+
+	messageHistory.IntentDetected = string(parsedIntent.Type)
+	messageHistory.IsProcessed = true
+	h.messageRepo.Update(ctx, messageHistory)
+
+	// Handle intent
+	response, err := h.handleIntent(ctx, user.ID, parsedIntent, messageContent)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to handle intent", "intent", parsedIntent.Type, "error", err)
+		response = "Maaf, terjadi kesalahan. Silakan coba lagi."
+	} else {
+		slog.DebugContext(ctx, "Response generated", "intent", parsedIntent.Type, "response", response)
+	}
+
+	// Send response to the chat the message came from
+	if err := h.queueReply(ctx, user.ID, incomingReplyKey(message, messageHistory), message.Address, response); err != nil {
+		slog.ErrorContext(ctx, "Failed to queue response", "chat_id", message.ChatID, "error", err)
+	} else {
+		slog.DebugContext(ctx, "Response queued", "chat_id", message.ChatID)
+	}
+}
+
+// parseIntent reads the intent of a message with AI, falling back to the
+// rule-based parser when the AI call fails.
+func (h *MessageHandler) parseIntent(ctx context.Context, messageContent string) *entity.ParsedIntent {
+	parsedIntent, err := h.aiService.ParseIntent(ctx, messageContent)
+	if err != nil {
+		slog.WarnContext(ctx, "AI intent parsing failed, using fallback parser", "error", err)
+		// Use fallback parser when AI fails
+		parsedIntent = utils.FallbackIntentParser(messageContent, time.Now())
+		parsedIntent.Source = entity.IntentSourceFallback
+	}
+	if parsedIntent.Source == "" {
+		parsedIntent.Source = entity.IntentSourceAI
+	}
+	metrics.Intents.WithLabelValues(string(parsedIntent.Type), string(parsedIntent.Source)).Inc()
+	slog.InfoContext(ctx, "Intent detected", "intent", parsedIntent.Type, "confidence", parsedIntent.Confidence, "source", parsedIntent.Source)
+	if len(parsedIntent.Entities) > 0 {
+		slog.DebugContext(ctx, "Intent entities", "payload", parsedIntent.Entities)
+	}
+	return parsedIntent
+}
+
+// finishMessage marks a message handled outside the intent pipeline as
+// processed and queues the reply.
+func (h *MessageHandler) finishMessage(ctx context.Context, userID uuid.UUID, message *channel.Message, messageHistory *entity.MessageHistory, intent, response string) {
+	messageHistory.IntentDetected = intent
+	messageHistory.IsProcessed = true
+	h.messageRepo.Update(ctx, messageHistory)
+	if err := h.queueReply(ctx, userID, incomingReplyKey(message, messageHistory), message.Address, response); err != nil {
+		slog.ErrorContext(ctx, "Failed to queue response", "chat_id", message.ChatID, "error", err)
+	}
+}
+
+// incomingReplyKey keys the reply on the incoming message so a redelivered
+// webhook doesn't make us answer twice.
+func incomingReplyKey(message *channel.Message, messageHistory *entity.MessageHistory) string {
+	if message.ID == "" {
+		return "reply:" + messageHistory.ID.String()
+	}
+	return "reply:" + message.Channel + ":" + message.ID
+}
+
+func isCalendarFile(message *channel.Message) bool {
+	media := message.Media
+	if message.Kind != channel.KindDocument || media == nil {
+		return false
+	}
+	mimetype, _, _ := strings.Cut(media.Mimetype, ";")
+	return strings.EqualFold(mimetype, "text/calendar") ||
+		strings.HasSuffix(strings.ToLower(media.Filename), ".ics")
+}
+
+// transcribeVoiceNote downloads and transcribes a voice note. If there is no
+// usable transcript, the reply explaining why is returned instead.
+func (h *MessageHandler) transcribeVoiceNote(ctx context.Context, ch channel.Channel, message *channel.Message) (string, string) {
+	if h.transcriber == nil {
+		return "", "Maaf, pesan suara belum bisa diproses. Silakan kirim pesan dalam bentuk teks."
+	}
+	media := message.Media
+	if media == nil || media.Ref == "" {
+		return "", "Maaf, pesan suara tidak dapat diunduh. Silakan kirim ulang."
+	}
+
+	slog.DebugContext(ctx, "Transcribing voice note", "mimetype", media.Mimetype)
+	data, err := ch.DownloadMedia(ctx, message.Account, media.Ref, h.maxVoiceBytes)
+	if errors.Is(err, channel.ErrMediaTooLarge) {
+		return "", "Maaf, pesan suara terlalu panjang. Silakan kirim pesan yang lebih singkat atau dalam bentuk teks."
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to download voice note", "error", err)
+		return "", "Maaf, pesan suara tidak dapat diunduh. Silakan kirim ulang."
+	}
+
+	transcript, err := h.transcriber.Transcribe(ctx, speech.Audio{
+		Data:     data,
+		Filename: media.Filename,
+		Mimetype: media.Mimetype,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to transcribe voice note", "error", err)
+		return "", "Maaf, pesan suara tidak dapat diproses saat ini. Silakan coba lagi atau kirim pesan dalam bentuk teks."
+	}
+	if transcript == "" {
+		return "", "Maaf, pesan suara tidak terdengar jelas. Silakan coba lagi atau kirim pesan dalam bentuk teks."
+	}
+
+	slog.DebugContext(ctx, "Voice note transcribed", "transcript", transcript)
+	return transcript, ""
+}
+
+// proposeFromImage reads the activities in a photo and returns the reply
+// listing them for confirmation.
+func (h *MessageHandler) proposeFromImage(ctx context.Context, ch channel.Channel, message *channel.Message, userID uuid.UUID) string {
+	media := message.Media
+	if media == nil || media.Ref == "" {
+		return "Maaf, foto tidak dapat diunduh. Silakan kirim ulang."
+	}
+
+	slog.DebugContext(ctx, "Reading image", "mimetype", media.Mimetype)
+	data, err := ch.DownloadMedia(ctx, message.Account, media.Ref, h.imageUseCase.MaxBytes())
+	if errors.Is(err, channel.ErrMediaTooLarge) {
+		return "Maaf, foto terlalu besar. Silakan kirim foto dengan ukuran lebih kecil."
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to download image", "error", err)
+		return "Maaf, foto tidak dapat diunduh. Silakan kirim ulang."
+	}
+
+	confirmation, err := h.imageUseCase.ProposeFromImage(ctx, userID, data, media.Mimetype, message.Body)
+	if errors.Is(err, usecase.ErrNoActivitiesFound) {
+		return "Maaf, saya tidak menemukan jadwal obat atau kegiatan pada foto tersebut. Coba kirim foto yang lebih jelas."
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to read image", "error", err)
+		return "Maaf, foto tidak dapat dibaca saat ini. Silakan coba lagi nanti."
+	}
+
+	var response strings.Builder
+	if confirmation.Summary != "" {
+		response.WriteString("📷 " + confirmation.Summary + "\n\n")
+	}
+	fmt.Fprintf(&response, "Saya menemukan %d kegiatan:\n\n", len(confirmation.Activities))
+	for i, activity := range confirmation.Activities {
+		fmt.Fprintf(&response, "%d. %s\n   %s", i+1, activity.Title, activity.ScheduledTime.Format("02 Jan 2006 15:04"))
+		if activity.RecurrenceRule != "" {
+			response.WriteString(" 🔁 " + describeRecurrence(activity.RecurrenceRule))
+		}
+		response.WriteString("\n")
+	}
+	response.WriteString("\nBalas *YA* untuk menambahkan semua kegiatan, atau *BATAL* untuk membatalkan.")
+	return response.String()
+}
+
+// linkRequestPattern matches "tautkan" (asking for a link code) and
+// "tautkan <kode>" (redeeming one). Telegram deep links arrive as
+// "/start <kode>".
+var linkRequestPattern = regexp.MustCompile(`(?i)^(tautkan|hubungkan|/link|/start)(?:\s+([a-z0-9]{8}))?$`)
+
+// parseLinkRequest reports whether the message is a link command and returns
+// its code, "" when the user asks for a new one.
+func parseLinkRequest(message string) (code string, ok bool) {
+	match := linkRequestPattern.FindStringSubmatch(strings.TrimSpace(message))
+	if match == nil {
+		return "", false
+	}
+	if strings.EqualFold(match[1], "/start") && match[2] == "" {
+		// A plain /start is Telegram opening the chat
+		return "", false
+	}
+	return match[2], true
+}
+
+// linkIdentity answers a link command. Without a code it creates one for the
+// user; with a code it moves identity to the user who created it.
+func (h *MessageHandler) linkIdentity(ctx context.Context, user *entity.User, identity *entity.UserIdentity, code string) (intent, response string) {
+	if code == "" {
+		code, err := h.userUseCase.CreateLinkCode(ctx, user.ID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to create link code", "error", err)
+			return "link_code", "Maaf, kode tidak dapat dibuat saat ini. Silakan coba lagi."
+		}
+		return "link_code", fmt.Sprintf("🔗 Kode penautan Anda: *%s*\n\nKirim *tautkan %s* ke asisten ini dari aplikasi lain (WhatsApp atau Telegram) dalam %d menit. Pengingat akan dikirim ke aplikasi yang terakhir Anda gunakan.",
+			code, code, int(usecase.LinkCodeTTL.Minutes()))
+	}
+
+	linked, err := h.userUseCase.LinkIdentity(ctx, identity, code)
+	if errors.Is(err, usecase.ErrInvalidLinkCode) {
+		return "link_identity", "Maaf, kode tidak valid atau sudah kedaluwarsa. Kirim *tautkan* dari akun utama Anda untuk mendapatkan kode baru."
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to link identity", "error", err)
+		return "link_identity", "Maaf, akun tidak dapat ditautkan saat ini. Silakan coba lagi."
+	}
+	slog.InfoContext(ctx, "Identity linked", "channel", identity.Channel, "sender", identity.ExternalID, "user_id", linked.ID)
+	return "link_identity", "✓ Akun berhasil ditautkan. Kegiatan dan pengingat Anda sekarang juga tersedia di sini."
+}
+
+var (
+	confirmWords = map[string]bool{"ya": true, "iya": true, "y": true, "yes": true, "ok": true, "oke": true, "setuju": true, "simpan": true, "lanjut": true, "boleh": true}
+	cancelWords  = map[string]bool{"batal": true, "batalkan": true, "tidak": true, "tdk": true, "no": true, "n": true, "jangan": true, "gak": true, "ga": true, "nggak": true, "enggak": true}
+)
+
+// answerConfirmation carries out or drops the user's pending confirmation if
+// the message is a yes or no. ok is false for any other message, which is
+// then handled as usual and leaves the confirmation pending.
+func (h *MessageHandler) answerConfirmation(ctx context.Context, userID uuid.UUID, message string) (intent, response string, ok bool) {
+	answer := strings.ToLower(strings.Trim(message, " \t\n.!,"))
+	if !confirmWords[answer] && !cancelWords[answer] {
+		return "", "", false
+	}
+
+	confirmation, err := h.imageUseCase.GetPendingConfirmation(ctx, userID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to get pending confirmation", "error", err)
+		return "", "", false
+	}
+	if confirmation == nil {
+		return "", "", false
+	}
+
+	if cancelWords[answer] {
+		if err := h.imageUseCase.Cancel(ctx, confirmation); err != nil {
+			slog.ErrorContext(ctx, "Failed to cancel confirmation", "error", err)
+			return "cancel", "Maaf, terjadi kesalahan. Silakan coba lagi.", true
+		}
+		return "cancel", "Baik, kegiatan tersebut tidak ditambahkan.", true
+	}
+
+	created, err := h.imageUseCase.Confirm(ctx, confirmation)
+	if errors.Is(err, usecase.ErrNotFound) {
+		return "", "", false
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to create confirmed activities", "created", len(created), "error", err)
+		if len(created) == 0 {
+			return "confirm", "Maaf, terjadi kesalahan saat menambahkan kegiatan. Silakan kirim ulang fotonya.", true
+		}
+		return "confirm", fmt.Sprintf("Maaf, hanya %d dari %d kegiatan yang berhasil ditambahkan.",
+			len(created), len(confirmation.Activities)), true
+	}
+	slog.InfoContext(ctx, "Confirmed activities created", "created", len(created))
+	return "confirm", fmt.Sprintf("✓ %d kegiatan berhasil ditambahkan.", len(created)), true
+}
+
+// How long after a reminder a typed "1" or "Selesai" still answers it
+const choiceReplyWindow = 12 * time.Hour
+
+// processPollVote answers a vote on a poll we sent.
+func (h *MessageHandler) processPollVote(ctx context.Context, vote *channel.Vote) {
+	if len(vote.Options) == 0 {
+		// The vote was taken back
+		return
+	}
+	option := vote.Options[0]
+	slog.InfoContext(ctx, "Processing poll vote", "poll_id", vote.PollID, "text", option)
+
+	message, choiceID, err := h.outboxUseCase.ResolvePollVote(ctx, vote.PollID, option)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to resolve poll vote", "error", err)
+		return
+	}
+	if message == nil || message.UserID == nil || choiceID == "" {
+		slog.WarnContext(ctx, "Ignoring vote on unknown poll", "poll_id", vote.PollID)
+		return
+	}
+
+	intent, response, ok := h.answerChoice(ctx, *message.UserID, choiceID)
+	if !ok {
+		return
+	}
+	slog.DebugContext(ctx, "Poll vote handled", "intent", intent)
+
+	key := "vote:" + vote.ID
+	if vote.ID == "" {
+		key = "vote:" + vote.PollID + ":" + option
+	}
+	to := channel.Address{Channel: message.Channel, Account: message.Session, ChatID: message.ChatID}
+	if err := h.queueReply(ctx, *message.UserID, key, to, response); err != nil {
+		slog.ErrorContext(ctx, "Failed to queue response", "chat_id", to.ChatID, "error", err)
+	}
+}
+
+// answerChoice carries out the choice the user picked and returns the reply.
+// ok is false for choice IDs it doesn't know.
+func (h *MessageHandler) answerChoice(ctx context.Context, userID uuid.UUID, choiceID string) (intent, response string, ok bool) {
+	action, activityID, ok := usecase.ParseReminderChoice(choiceID)
+	if !ok {
+		return "", "", false
+	}
+	intent = "reminder_" + action
+
+	activity, changed, err := h.activityUseCase.AnswerReminder(ctx, userID, action, activityID)
+	if errors.Is(err, usecase.ErrNotFound) {
+		return intent, "Maaf, kegiatan tersebut sudah tidak ada.", true
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to answer reminder", "activity_id", activityID, "error", err)
+		return intent, "Maaf, terjadi kesalahan. Silakan coba lagi.", true
+	}
+	if !changed {
+		return intent, closedActivityReply(activity), true
+	}
+
+	switch action {
+	case usecase.ReminderActionDone:
+		return intent, fmt.Sprintf("✓ Mantap! Kegiatan '%s' ditandai selesai.", activity.Title), true
+	case usecase.ReminderActionSnooze:
+		return intent, postponedReply(activity), true
+	default:
+		return intent, fmt.Sprintf("Kegiatan '%s' dibatalkan.", activity.Title), true
+	}
+}
+
+// quotedReminderWindow is how old a reminder may be for a reply quoting it
+// to still postpone its activity.
+const quotedReminderWindow = 7 * 24 * time.Hour
+
+// postponeFromReply handles replies like "tunda 30 menit", "pindah ke besok"
+// or "nanti jam 5" to a reminder: the one the message quotes, or else the
+// latest one, if the message reads as a postpone command on its own. ok is
+// false if the message isn't such a reply, or there was no reminder to
+// answer.
+func (h *MessageHandler) postponeFromReply(ctx context.Context, userID uuid.UUID, message, replyToID string) (intent, response string, ok bool) {
+	req, ok := utils.ParsePostpone(message, usecase.ReminderSnooze)
+	if !ok {
+		return "", "", false
+	}
+
+	var alertID *uuid.UUID
+	if replyToID != "" {
+		var err error
+		alertID, err = h.outboxUseCase.QuotedAlertID(ctx, replyToID, time.Now().Add(-quotedReminderWindow))
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to resolve quoted message", "error", err)
+		}
+	}
+	if alertID == nil && !req.Standalone {
+		// e.g. "nanti jam 5 saya rapat"
+		return "", "", false
+	}
+	intent = "postpone_activity"
+
+	var activity *entity.Activity
+	var changed bool
+	var err error
+	if alertID != nil {
+		activity, changed, err = h.activityUseCase.PostponeReminder(ctx, userID, *alertID, req)
+	} else {
+		activity, changed, err = h.activityUseCase.PostponeLatestReminder(ctx, userID, req)
+	}
+	switch {
+	case errors.Is(err, usecase.ErrNoRecentReminder):
+		// Not an answer to a reminder
+		return "", "", false
+	case errors.Is(err, usecase.ErrInvalidInput):
+		return intent, "Maaf, waktu tersebut sudah lewat. Coba misalnya \"tunda 30 menit\" atau \"pindah ke besok\".", true
+	case errors.Is(err, usecase.ErrNotFound):
+		return intent, "Maaf, kegiatan tersebut sudah tidak ada.", true
+	case err != nil:
+		slog.ErrorContext(ctx, "Failed to postpone activity", "error", err)
+		return intent, "Maaf, terjadi kesalahan. Silakan coba lagi.", true
+	}
+	if !changed {
+		return intent, closedActivityReply(activity), true
+	}
+	slog.InfoContext(ctx, "Activity postponed", "activity_id", activity.ID, "scheduled_time", activity.ScheduledTime,
+		"postpone_count", activity.PostponeCount)
+	return intent, postponedReply(activity), true
+}
+
+// Postponing this often gets a gentle nudge in the reply
+const postponeNudgeCount = 3
+
+func postponedReply(activity *entity.Activity) string {
+	response := fmt.Sprintf("⏰ Baik, '%s' dipindah ke %s. Saya akan mengingatkan lagi pukul %s.",
+		activity.Title, activity.ScheduledTime.Format("02 Jan 2006 15:04"), activity.ReminderTime.Format("15:04"))
+	if activity.PostponeCount >= postponeNudgeCount {
+		response += fmt.Sprintf("\n\nKegiatan ini sudah ditunda %d kali. Yuk, coba selesaikan kali ini! 💪", activity.PostponeCount)
+	}
+	return response
+}
+
+func closedActivityReply(activity *entity.Activity) string {
+	if activity.Status == entity.ActivityStatusCancelled {
+		return fmt.Sprintf("Kegiatan '%s' sudah dibatalkan sebelumnya.", activity.Title)
+	}
+	return fmt.Sprintf("Kegiatan '%s' sudah selesai sebelumnya.", activity.Title)
+}
+
+var recurrenceFrequencies = map[string]string{
+	"DAILY":   "setiap hari",
+	"WEEKLY":  "setiap minggu",
+	"MONTHLY": "setiap bulan",
+	"YEARLY":  "setiap tahun",
+}
+
+// describeRecurrence gives a short Indonesian description of an RRULE, e.g.
+// "setiap hari, 5 kali".
+func describeRecurrence(rule string) string {
+	description := "berulang"
+	var count string
+	for _, part := range strings.Split(rule, ";") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "FREQ":
+			if frequency, ok := recurrenceFrequencies[value]; ok {
+				description = frequency
+			}
+		case "COUNT":
+			count = value
+		}
+	}
+	if count != "" {
+		description += ", " + count + " kali"
+	}
+	return description
+}
+
+// importCalendarFile imports an .ics file sent by the user and returns the
+// reply describing the result.
+func (h *MessageHandler) importCalendarFile(ctx context.Context, ch channel.Channel, message *channel.Message, userID uuid.UUID) string {
+	media := message.Media
+	slog.DebugContext(ctx, "Importing calendar file", "filename", media.Filename)
+	if media.Ref == "" {
+		return "Maaf, file kalender tidak dapat diunduh. Silakan kirim ulang."
+	}
+
+	data, err := ch.DownloadMedia(ctx, message.Account, media.Ref, h.calendarUseCase.MaxBytes())
+	if errors.Is(err, channel.ErrMediaTooLarge) {
+		return "Maaf, file kalender terlalu besar."
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to download calendar file", "error", err)
+		return "Maaf, file kalender tidak dapat diunduh. Silakan kirim ulang."
+	}
+
+	result, err := h.calendarUseCase.ImportCalendar(ctx, userID, bytes.NewReader(data))
+	if errors.Is(err, usecase.ErrInvalidInput) {
+		return "Maaf, file tersebut bukan file kalender (.ics) yang valid."
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to import calendar", "error", err)
+		return "Maaf, terjadi kesalahan saat mengimpor kalender. Silakan coba lagi."
+	}
+
+	slog.InfoContext(ctx, "Calendar imported", "created", result.Created, "updated", result.Updated,
+		"unchanged", result.Unchanged, "skipped", result.Skipped)
+	response := fmt.Sprintf("📅 Kalender berhasil diimpor.\n\n• Kegiatan baru: %d\n• Diperbarui: %d\n• Tidak berubah: %d",
+		result.Created, result.Updated, result.Unchanged)
+	if result.Skipped > 0 {
+		response += fmt.Sprintf("\n• Dilewati: %d (sudah lama lewat atau tidak terbaca)", result.Skipped)
+	}
+	return response
+}
+
+// queueReply records the outgoing message in message_history and puts it in
+// the outbox. The dispatcher fills in SentAt once the channel accepts it.
+func (h *MessageHandler) queueReply(ctx context.Context, userID uuid.UUID, idempotencyKey string, to channel.Address, text string) error {
+	outgoingMsg := entity.NewMessageHistory(userID, text, entity.MessageTypeOutgoing)
+	outgoingMsg.AIResponse = text
+	if err := h.messageRepo.Create(ctx, outgoingMsg); err != nil {
+		return fmt.Errorf("failed to save outgoing message: %w", err)
+	}
+
+	_, err := h.outboxUseCase.Enqueue(ctx, usecase.OutboundRequest{
+		IdempotencyKey:   idempotencyKey,
+		Channel:          to.Channel,
+		Session:          to.Account,
+		ChatID:           to.ChatID,
+		Body:             text,
+		UserID:           &userID,
+		MessageHistoryID: &outgoingMsg.ID,
+	})
+	return err
+}
+
+func (h *MessageHandler) handleIntent(ctx context.Context, userID uuid.UUID, intent *entity.ParsedIntent, originalMessage string) (string, error) {
+	switch intent.Type {
+	case entity.IntentAddActivity:
+		return h.handleAddActivity(ctx, userID, intent, nil)
+	case entity.IntentDeleteActivity:
+		return h.handleDeleteActivity(ctx, userID, intent)
+	case entity.IntentUpdateActivity:
+		return h.handleUpdateActivity(ctx, userID, intent)
+	case entity.IntentListActivities:
+		return h.handleListActivities(ctx, userID)
+	case entity.IntentQuestion:
+		return h.handleQuestion(ctx, userID, originalMessage)
+	case entity.IntentGreeting:
+		return "Halo! Ada yang bisa saya bantu hari ini?", nil
+	default:
+		return "Maaf, saya belum memahami pesan Anda. Silakan coba lagi dengan format yang lebih jelas.", nil
+	}
+}
+
+// handleAddActivity adds the activity of the intent. In a group, assignee is
+// the member it is for, nil for the whole group.
+func (h *MessageHandler) handleAddActivity(ctx context.Context, userID uuid.UUID, intent *entity.ParsedIntent, assignee *entity.GroupMember) (string, error) {
+	data := extractActivityData(intent.Entities, time.Now())
+	if assignee != nil {
+		data.AssigneeID = &assignee.UserID
+	}
+
+	// If title is empty, use description or ask user
+	if data.Title == "" {
+		if data.Description != "" {
+			data.Title = data.Description
+			data.Description = ""
+		} else {
+			return "Maaf, saya tidak dapat menemukan judul kegiatan. Silakan coba lagi dengan format: 'Tambah kegiatan [judul] [waktu]'", nil
+		}
+	}
+
+	activity, err := h.activityUseCase.CreateActivity(ctx, userID, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to create activity: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Activity created", "activity_id", activity.ID, "scheduled_time", activity.ScheduledTime)
+
+	response := fmt.Sprintf("✓ Kegiatan '%s' berhasil ditambahkan untuk %s",
+		activity.Title, activity.ScheduledTime.Format("02 Jan 2006 15:04"))
+	if assignee != nil {
+		response += "\n👤 " + assignee.DisplayName
+	}
+	return response, nil
+}
+
+func (h *MessageHandler) handleDeleteActivity(ctx context.Context, userID uuid.UUID, intent *entity.ParsedIntent) (string, error) {
+	activityIDStr, ok := intent.Entities["activity_id"].(string)
+	if !ok {
+		return "Maaf, ID kegiatan tidak ditemukan. Silakan coba lagi.", nil
+	}
+
+	activityID, err := uuid.Parse(activityIDStr)
+	if err != nil {
+		return "Maaf, ID kegiatan tidak valid.", nil
+	}
+
+	if err := h.activityUseCase.DeleteActivity(ctx, activityID); err != nil {
+		return "", fmt.Errorf("failed to delete activity: %w", err)
+	}
+
+	return "✓ Kegiatan berhasil dihapus.", nil
+}
+
+func (h *MessageHandler) handleUpdateActivity(ctx context.Context, userID uuid.UUID, intent *entity.ParsedIntent) (string, error) {
+	activityIDStr, ok := intent.Entities["activity_id"].(string)
+	if !ok {
+		return "Maaf, ID kegiatan tidak ditemukan.", nil
+	}
+
+	activityID, err := uuid.Parse(activityIDStr)
+	if err != nil {
+		return "Maaf, ID kegiatan tidak valid.", nil
+	}
+
+	data := extractUpdateActivityData(intent.Entities)
+	if err := h.activityUseCase.UpdateActivity(ctx, activityID, data); err != nil {
+		return "", fmt.Errorf("failed to update activity: %w", err)
+	}
+
+	return "✓ Kegiatan berhasil diupdate.", nil
+}
+
+func (h *MessageHandler) handleListActivities(ctx context.Context, userID uuid.UUID) (string, error) {
+	activities, err := h.activityUseCase.GetTodayActivities(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get activities: %w", err)
+	}
+
+	if len(activities) == 0 {
+		return "Anda tidak memiliki kegiatan untuk hari ini.", nil
+	}
+
+	response := "📋 Kegiatan Hari Ini:\n\n"
+	for i, activity := range activities {
+		response += fmt.Sprintf("%d. %s - %s\n   Waktu: %s\n   Status: %s\n\n",
+			i+1, activity.Title, activity.Description,
+			activity.ScheduledTime.Format("15:04"), activity.Status)
+	}
+
+	return response, nil
+}
+
+func (h *MessageHandler) handleQuestion(ctx context.Context, userID uuid.UUID, question string) (string, error) {
+	// Use AI to answer general questions
+	_, err := h.aiService.ParseIntent(ctx, question)
+	if err != nil {
+		return "Maaf, saya tidak dapat menjawab pertanyaan tersebut saat ini.", nil
+	}
+
+	// For now, return a simple response
+	// In production, you'd want a dedicated Q&A endpoint
+	return "Terima kasih atas pertanyaannya. Fitur ini sedang dalam pengembangan.", nil
+}
+
+func extractActivityData(entities map[string]interface{}, baseTime time.Time) entity.ActivityIntentData {
+	data := entity.ActivityIntentData{}
+
+	// Extract title
+	if title, ok := entities["title"].(string); ok && title != "" {
+		data.Title = title
+	}
+
+	// Extract description
+	if desc, ok := entities["description"].(string); ok && desc != "" {
+		data.Description = desc
+	}
+
+	// Extract scheduled_time
+	if timeStr, ok := entities["scheduled_time"].(string); ok && timeStr != "" {
+		// Try parsing as ISO 8601 first
+		if parsedTime, err := utils.ParseISO8601Time(timeStr); err == nil {
+			data.ScheduledTime = parsedTime
+		} else {
+			// Try parsing as natural language (Indonesian)
+			if parsedTime, err := utils.ParseTimeFromText(timeStr, baseTime); err == nil && parsedTime != nil {
+				data.ScheduledTime = parsedTime
+			}
+		}
+	}
+
+	// Extract priority
+	if priority, ok := entities["priority"].(float64); ok {
+		data.Priority = int(priority)
+	} else if priorityStr, ok := entities["priority"].(string); ok {
+		if p, err := strconv.Atoi(priorityStr); err == nil {
+			data.Priority = p
+		}
+	}
+
+	// Extract category_id if provided
+	if catIDStr, ok := entities["category_id"].(string); ok {
+		if catID, err := uuid.Parse(catIDStr); err == nil {
+			data.CategoryID = &catID
+		}
+	}
+
+	return data
+}
+
+func extractUpdateActivityData(entities map[string]interface{}) entity.UpdateActivityIntentData {
+	data := entity.UpdateActivityIntentData{}
+
+	if idStr, ok := entities["activity_id"].(string); ok {
+		if id, err := uuid.Parse(idStr); err == nil {
+			data.ActivityID = id
+		}
+	}
+	if title, ok := entities["title"].(string); ok {
+		data.Title = &title
+	}
+	if desc, ok := entities["description"].(string); ok {
+		data.Description = &desc
+	}
+
+	return data
+}
@@ -0,0 +1,254 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var pathParamPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	uuidType = reflect.TypeOf(uuid.UUID{})
+)
+
+// buildOpenAPI generates an OpenAPI 3 document from the route table. Schemas
+// are derived from the request and response types by reflection, following
+// their json tags.
+func buildOpenAPI(title, version, basePath string, routes []apiRoute) map[string]interface{} {
+	b := &openAPIBuilder{schemas: map[string]interface{}{}}
+	b.schemaFor(reflect.TypeOf(ErrorResponse{}))
+
+	paths := map[string]map[string]interface{}{}
+	for _, route := range routes {
+		if paths[route.Path] == nil {
+			paths[route.Path] = map[string]interface{}{}
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = b.operation(route)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"servers": []interface{}{
+			map[string]interface{}{"url": basePath},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"ApiKeyAuth": []string{}},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"ApiKeyAuth": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+			"schemas": b.schemas,
+		},
+	}
+}
+
+type openAPIBuilder struct {
+	schemas map[string]interface{}
+}
+
+func (b *openAPIBuilder) operation(route apiRoute) map[string]interface{} {
+	var params []interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string", "format": "uuid"},
+		})
+	}
+	for _, param := range route.Query {
+		params = append(params, map[string]interface{}{
+			"name":        param.Name,
+			"in":          "query",
+			"description": param.Description,
+			"schema":      map[string]interface{}{"type": param.Type},
+		})
+	}
+
+	op := map[string]interface{}{
+		"summary":   route.Summary,
+		"tags":      []string{route.Tag},
+		"responses": b.responses(route),
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+	if route.Request != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(b.schemaFor(reflect.TypeOf(route.Request))),
+		}
+	}
+	return op
+}
+
+func (b *openAPIBuilder) responses(route apiRoute) map[string]interface{} {
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if route.Response != nil {
+		data := b.schemaFor(reflect.TypeOf(route.Response))
+		envelope := map[string]interface{}{
+			"type":     "object",
+			"required": []string{"data"},
+			"properties": map[string]interface{}{
+				"data": data,
+			},
+		}
+		if route.List {
+			envelope["required"] = []string{"data", "page"}
+			envelope["properties"] = map[string]interface{}{
+				"data": map[string]interface{}{"type": "array", "items": data},
+				"page": b.schemaFor(reflect.TypeOf(PageInfo{})),
+			}
+		}
+		success["content"] = jsonContent(envelope)
+	}
+
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content":     jsonContent(schemaRef("ErrorResponse")),
+		}
+	}
+
+	responses := map[string]interface{}{
+		strconv.Itoa(status): success,
+		"401":                errorResponse("Missing or invalid API key"),
+		"500":                errorResponse("Internal error"),
+	}
+	if route.Request != nil || len(route.Query) > 0 || strings.Contains(route.Path, "{") {
+		responses["400"] = errorResponse("Invalid request")
+	}
+	if strings.Contains(route.Path, "{") {
+		responses["404"] = errorResponse("Not found")
+	}
+	return responses
+}
+
+func (b *openAPIBuilder) schemaFor(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		inner := b.schemaFor(t.Elem())
+		if _, isRef := inner["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{inner}, "nullable": true}
+		}
+		inner["nullable"] = true
+		return inner
+	}
+
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case uuidType:
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.objectSchema(t)
+		}
+		name := schemaName(t)
+		if _, ok := b.schemas[name]; !ok {
+			// Register first so recursive types terminate
+			b.schemas[name] = map[string]interface{}{}
+			b.schemas[name] = b.objectSchema(t)
+		}
+		return schemaRef(name)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// objectSchema lists the json fields of a struct. Embedded structs without a
+// json tag are flattened, like encoding/json does. Fields that are neither
+// pointers nor omitempty are marked required.
+func (b *openAPIBuilder) objectSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+				collect(field.Type)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+
+			name, opts, _ := strings.Cut(tag, ",")
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = b.schemaFor(field.Type)
+			if field.Type.Kind() != reflect.Ptr && !strings.Contains(opts, "omitempty") {
+				required = append(required, name)
+			}
+		}
+	}
+	collect(t)
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
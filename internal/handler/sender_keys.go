@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"sync"
+
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/whatsapp"
+)
+
+// senderKeys names the mailbox of each sender without touching the database,
+// so the webhook can queue a message before its sender is known.
+//
+// WhatsApp addresses one person by LID ("…@lid") or by phone number
+// ("…@c.us"). Each address is its own key until a processed message shows
+// both belong to the same contact; from then on the other address shares the
+// key of the one the message came from. That key was already in use, so a
+// burst from a new sender stays in one mailbox while the contact is created.
+type senderKeys struct {
+	mu sync.Mutex
+	// aliases maps an address to the key of the address it was linked to.
+	// It holds one entry per contact seen under both addresses.
+	aliases map[string]string
+}
+
+func newSenderKeys() *senderKeys {
+	return &senderKeys{aliases: make(map[string]string)}
+}
+
+// Key returns the mailbox key of senderID on channelName.
+func (k *senderKeys) Key(channelName, senderID string) string {
+	if channelName != channel.WhatsApp {
+		return channelName + ":" + senderID
+	}
+	address := normalizeAddress(senderID)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return channelName + ":" + k.resolve(address)
+}
+
+// Link records that the WhatsApp addresses from and to belong to the same
+// contact: to takes the key of from. An address linked before keeps its key.
+func (k *senderKeys) Link(from, to string) {
+	from, to = normalizeAddress(from), normalizeAddress(to)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, linked := k.aliases[to]; linked {
+		return
+	}
+	if key := k.resolve(from); key != to {
+		k.aliases[to] = key
+	}
+}
+
+func (k *senderKeys) resolve(address string) string {
+	if key, ok := k.aliases[address]; ok {
+		return key
+	}
+	return address
+}
+
+// normalizeAddress writes a WhatsApp chat ID the same way whatever device
+// suffix or server name it came with.
+func normalizeAddress(chatID string) string {
+	lid, phoneNumber := whatsapp.ParseChatID(chatID)
+	switch {
+	case lid != "":
+		return whatsapp.LIDChatID(lid)
+	case phoneNumber != "":
+		return whatsapp.PhoneChatID(phoneNumber)
+	}
+	return chatID
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/mailbox"
+)
+
+const (
+	testLID   = "123456789012345@lid"
+	testPhone = "6281234567890@c.us"
+)
+
+// A new sender's messages share a mailbox before, while and after their
+// contact is created, so they are processed one at a time in order.
+func TestSenderKeysKeepFirstMessagesInOrder(t *testing.T) {
+	keys := newSenderKeys()
+	m := mailbox.NewMailbox()
+
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) {
+		mu.Lock()
+		ran = append(ran, name)
+		mu.Unlock()
+	}
+
+	// "tambah rapat jam 3" is being processed: the contact is created only
+	// once the second message is queued
+	release := make(chan struct{})
+	first := keys.Key(channel.WhatsApp, testLID)
+	m.Submit(first, func() {
+		<-release
+		keys.Link(testLID, testPhone)
+		record("tambah rapat jam 3")
+	})
+
+	second := keys.Key(channel.WhatsApp, testLID)
+	m.Submit(second, func() { record("eh jam 4 saja") })
+	close(release)
+
+	// Only written after the contact exists, from the phone number
+	waitFor(t, m, first)
+	third := keys.Key(channel.WhatsApp, testPhone)
+	m.Submit(third, func() { record("makasih") })
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if first != second || second != third {
+		t.Errorf("keys %q, %q, %q differ", first, second, third)
+	}
+	want := []string{"tambah rapat jam 3", "eh jam 4 saja", "makasih"}
+	if len(ran) != len(want) {
+		t.Fatalf("ran %q, want %q", ran, want)
+	}
+	for i := range want {
+		if ran[i] != want[i] {
+			t.Fatalf("ran %q, want %q", ran, want)
+		}
+	}
+}
+
+// waitFor waits until the jobs queued under key so far have run.
+func waitFor(t *testing.T, m *mailbox.Mailbox, key string) {
+	t.Helper()
+	done := make(chan struct{})
+	if err := m.Submit(key, func() { close(done) }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	<-done
+}
+
+func TestSenderKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		links   [][2]string
+		channel string
+		sender  string
+		want    string
+	}{
+		{name: "phone number", channel: channel.WhatsApp, sender: testPhone, want: "whatsapp:" + testPhone},
+		{name: "device suffix and server name", channel: channel.WhatsApp, sender: "6281234567890:12@s.whatsapp.net", want: "whatsapp:" + testPhone},
+		{name: "unlinked LID", channel: channel.WhatsApp, sender: testLID, want: "whatsapp:" + testLID},
+		{
+			name:    "phone number linked from a LID",
+			links:   [][2]string{{testLID, testPhone}},
+			channel: channel.WhatsApp, sender: testPhone, want: "whatsapp:" + testLID,
+		},
+		{
+			name:    "address linked from keeps its key",
+			links:   [][2]string{{testLID, testPhone}},
+			channel: channel.WhatsApp, sender: testLID, want: "whatsapp:" + testLID,
+		},
+		{
+			name:    "a linked address is not relinked",
+			links:   [][2]string{{testLID, testPhone}, {"999999999999999@lid", testPhone}},
+			channel: channel.WhatsApp, sender: testPhone, want: "whatsapp:" + testLID,
+		},
+		{
+			name:    "linking to itself",
+			links:   [][2]string{{testPhone, testPhone}},
+			channel: channel.WhatsApp, sender: testPhone, want: "whatsapp:" + testPhone,
+		},
+		{name: "other channels", channel: channel.Telegram, sender: "12345678", want: "telegram:12345678"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := newSenderKeys()
+			for _, link := range tt.links {
+				keys.Link(link[0], link[1])
+			}
+			if got := keys.Key(tt.channel, tt.sender); got != tt.want {
+				t.Errorf("Key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
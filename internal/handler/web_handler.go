@@ -0,0 +1,1044 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/usecase"
+)
+
+//go:embed web
+var webFS embed.FS
+
+const (
+	sessionCookieName    = "sas_session"
+	loginTokenCookieName = "sas_login"
+	webDateLayout        = "2006-01-02"
+	webMonthLayout       = "2006-01"
+	// Room for multipart headers and other fields around an uploaded file
+	webFormOverhead = 64 << 10
+)
+
+var (
+	dayNames   = []string{"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu"}
+	monthNames = []string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli",
+		"Agustus", "September", "Oktober", "November", "Desember"}
+	statusLabels = map[entity.ActivityStatus]string{
+		entity.ActivityStatusPending:   "Belum selesai",
+		entity.ActivityStatusCompleted: "Selesai",
+		entity.ActivityStatusCancelled: "Dibatalkan",
+		entity.ActivityStatusOverdue:   "Terlewat",
+	}
+	historyRanges = []int{7, 14, 30, 90}
+)
+
+// WebConfig holds the dashboard's deployment settings.
+type WebConfig struct {
+	// SecureCookies marks cookies HTTPS-only.
+	SecureCookies bool
+	// PublicURL is the externally visible base URL, e.g.
+	// "https://alert.example.com", used for links shown to users. When empty
+	// it is derived from the request.
+	PublicURL string
+	// LoginRatePerIP caps login requests per client IP per minute; zero
+	// disables the limit.
+	LoginRatePerIP int
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For. Only set
+	// it behind a reverse proxy, as clients can send the header themselves.
+	TrustProxyHeaders bool
+}
+
+// WebHandler serves the user dashboard: a server-rendered UI where users log
+// in with a code sent over WhatsApp and manage their schedule.
+type WebHandler struct {
+	authUseCase     *usecase.WebAuthUseCase
+	activityUseCase *usecase.ActivityUseCase
+	healthUseCase   *usecase.HealthUseCase
+	calendarUseCase *usecase.CalendarUseCase
+	location        *time.Location
+	config          WebConfig
+	loginLimiter    *loginLimiter
+	pages           map[string]*template.Template
+}
+
+func NewWebHandler(
+	authUseCase *usecase.WebAuthUseCase,
+	activityUseCase *usecase.ActivityUseCase,
+	healthUseCase *usecase.HealthUseCase,
+	calendarUseCase *usecase.CalendarUseCase,
+	location *time.Location,
+	config WebConfig,
+) *WebHandler {
+	config.PublicURL = strings.TrimSuffix(config.PublicURL, "/")
+	return &WebHandler{
+		authUseCase:     authUseCase,
+		activityUseCase: activityUseCase,
+		healthUseCase:   healthUseCase,
+		calendarUseCase: calendarUseCase,
+		location:        location,
+		config:          config,
+		loginLimiter:    newLoginLimiter(config.LoginRatePerIP),
+		pages:           parseWebPages(),
+	}
+}
+
+// webPage is the data passed to every template.
+type webPage struct {
+	Title  string
+	User   *entity.User
+	CSRF   string
+	Loc    *time.Location
+	Error  string
+	Notice string
+	Data   interface{}
+}
+
+// webSession is the logged-in user of a request, set by requireSession.
+type webSession struct {
+	session *entity.WebSession
+	user    *entity.User
+	loc     *time.Location
+}
+
+type webSessionKey struct{}
+
+var templateFuncs = template.FuncMap{
+	"clock": func(t time.Time, loc *time.Location) string {
+		return t.In(loc).Format("15:04")
+	},
+	"dateTime": func(t time.Time, loc *time.Location) string {
+		return t.In(loc).Format("02/01/2006 15:04")
+	},
+	"isoDate": func(t time.Time) string {
+		return t.Format(webDateLayout)
+	},
+	"dayName": func(t time.Time) string {
+		return dayNames[t.Weekday()]
+	},
+	"monthName": func(t time.Time) string {
+		return monthNames[t.Month()-1]
+	},
+	"statusLabel": func(status entity.ActivityStatus) string {
+		if label, ok := statusLabels[status]; ok {
+			return label
+		}
+		return string(status)
+	},
+	"lines": func(items []string) string {
+		return strings.Join(items, "\n")
+	},
+	"activityItem": func(page webPage, activity *entity.Activity) map[string]interface{} {
+		return map[string]interface{}{"Page": page, "Activity": activity}
+	},
+}
+
+func parseWebPages() map[string]*template.Template {
+	pages := map[string]*template.Template{}
+	for _, name := range []string{"login", "week", "calendar", "activity_form", "history", "profile", "import"} {
+		pages[name] = template.Must(template.New(name).Funcs(templateFuncs).ParseFS(webFS,
+			"web/templates/layout.html", "web/templates/"+name+".html"))
+	}
+	return pages
+}
+
+func (h *WebHandler) RegisterRoutes(router *mux.Router) {
+	static, err := fs.Sub(webFS, "web/static")
+	if err != nil {
+		panic(err)
+	}
+	router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.FS(static)))).Methods("GET")
+
+	router.Handle("/", http.RedirectHandler("/app/week", http.StatusSeeOther)).Methods("GET")
+	router.Handle("/app", http.RedirectHandler("/app/week", http.StatusSeeOther)).Methods("GET")
+	router.HandleFunc("/login", h.showLogin).Methods("GET")
+	router.HandleFunc("/login", h.requestLoginCode).Methods("POST")
+	router.HandleFunc("/login/verify", h.verifyLoginCode).Methods("POST")
+
+	app := router.PathPrefix("/app").Subrouter()
+	app.Use(h.requireSession)
+	app.HandleFunc("/logout", h.logout).Methods("POST")
+	app.HandleFunc("/week", h.week).Methods("GET")
+	app.HandleFunc("/calendar", h.calendar).Methods("GET")
+	app.HandleFunc("/calendar/feed", h.resetCalendarFeed).Methods("POST")
+	app.HandleFunc("/activities/new", h.newActivity).Methods("GET")
+	app.HandleFunc("/activities", h.createActivity).Methods("POST")
+	app.HandleFunc("/activities/{id}/edit", h.editActivity).Methods("GET")
+	app.HandleFunc("/activities/{id}", h.updateActivity).Methods("POST")
+	app.HandleFunc("/activities/{id}/complete", h.completeActivity).Methods("POST")
+	app.HandleFunc("/activities/{id}/delete", h.deleteActivity).Methods("POST")
+	app.HandleFunc("/history", h.history).Methods("GET")
+	app.HandleFunc("/profile", h.showProfile).Methods("GET")
+	app.HandleFunc("/profile", h.saveProfile).Methods("POST")
+	app.HandleFunc("/import", h.showImport).Methods("GET")
+	app.HandleFunc("/import/file", h.importFile).Methods("POST")
+	app.HandleFunc("/import/subscriptions", h.subscribeCalendar).Methods("POST")
+	app.HandleFunc("/import/subscriptions/{id}/delete", h.unsubscribeCalendar).Methods("POST")
+}
+
+// requireSession redirects to the login page without a valid session cookie
+// and rejects POSTs without the session's CSRF token.
+func (h *WebHandler) requireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if cookie, err := r.Cookie(sessionCookieName); err == nil {
+			token = cookie.Value
+		}
+
+		session, user, err := h.authUseCase.Authenticate(r.Context(), token)
+		if err != nil {
+			h.serverError(w, r, err)
+			return
+		}
+		if session == nil {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+
+		// Forms are small, but calendar uploads may be up to the import limit
+		r.Body = http.MaxBytesReader(w, r.Body, h.calendarUseCase.MaxBytes()+webFormOverhead)
+		if r.Method == http.MethodPost &&
+			subtle.ConstantTimeCompare([]byte(r.PostFormValue("csrf_token")), []byte(session.CSRFToken)) != 1 {
+			http.Error(w, "Invalid CSRF token", http.StatusForbidden)
+			return
+		}
+
+		loc := h.location
+		if user.Timezone != "" {
+			if userLoc, err := time.LoadLocation(user.Timezone); err == nil {
+				loc = userLoc
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), webSessionKey{}, &webSession{session: session, user: user, loc: loc})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func currentSession(r *http.Request) *webSession {
+	return r.Context().Value(webSessionKey{}).(*webSession)
+}
+
+func (h *WebHandler) page(r *http.Request, title string, data interface{}) webPage {
+	s := currentSession(r)
+	return webPage{Title: title, User: s.user, CSRF: s.session.CSRFToken, Loc: s.loc, Data: data}
+}
+
+// render executes the page into a buffer first so template errors don't
+// leave a half-written response.
+func (h *WebHandler) render(w http.ResponseWriter, status int, name string, page webPage) {
+	var buf bytes.Buffer
+	if err := h.pages[name].ExecuteTemplate(&buf, "layout", page); err != nil {
+		slog.Error("Failed to render page", "page", name, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+func (h *WebHandler) serverError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrNotFound):
+		http.NotFound(w, r)
+	case errors.Is(err, usecase.ErrInvalidInput):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		slog.ErrorContext(r.Context(), "Web request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// Login
+
+type loginData struct {
+	Step       string
+	Number     string
+	LoginToken string
+}
+
+// loginToken returns the anti-CSRF token of the login form, a random value
+// kept in a cookie and echoed in the form (double submit).
+func (h *WebHandler) loginToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(loginTokenCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	token := uuid.NewString()
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginTokenCookieName,
+		Value:    token,
+		Path:     "/login",
+		HttpOnly: true,
+		Secure:   h.config.SecureCookies,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+func (h *WebHandler) validLoginToken(r *http.Request) bool {
+	cookie, err := r.Cookie(loginTokenCookieName)
+	return err == nil && cookie.Value != "" &&
+		subtle.ConstantTimeCompare([]byte(r.PostFormValue("login_token")), []byte(cookie.Value)) == 1
+}
+
+func (h *WebHandler) renderLogin(w http.ResponseWriter, r *http.Request, status int, data loginData, errMsg string) {
+	data.LoginToken = h.loginToken(w, r)
+	h.render(w, status, "login", webPage{Title: "Masuk", Error: errMsg, Data: data})
+}
+
+func (h *WebHandler) showLogin(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if session, _, err := h.authUseCase.Authenticate(r.Context(), cookie.Value); err == nil && session != nil {
+			http.Redirect(w, r, "/app/week", http.StatusSeeOther)
+			return
+		}
+	}
+	h.renderLogin(w, r, http.StatusOK, loginData{}, "")
+}
+
+// allowLogin applies the per-IP limit to a login request and renders the
+// login page if it is over.
+func (h *WebHandler) allowLogin(w http.ResponseWriter, r *http.Request, data loginData) bool {
+	if h.loginLimiter.Allow(clientIP(r, h.config.TrustProxyHeaders)) {
+		return true
+	}
+	h.renderLogin(w, r, http.StatusTooManyRequests, data, "Terlalu banyak percobaan, silakan coba lagi beberapa menit lagi.")
+	return false
+}
+
+func (h *WebHandler) requestLoginCode(w http.ResponseWriter, r *http.Request) {
+	number := strings.TrimSpace(r.PostFormValue("number"))
+	if !h.allowLogin(w, r, loginData{Number: number}) {
+		return
+	}
+	if !h.validLoginToken(r) {
+		h.renderLogin(w, r, http.StatusForbidden, loginData{Number: number}, "Sesi formulir kedaluwarsa, silakan coba lagi.")
+		return
+	}
+	if usecase.NormalizeWhatsAppNumber(number) == "" {
+		h.renderLogin(w, r, http.StatusBadRequest, loginData{Number: number}, "Masukkan nomor WhatsApp yang valid.")
+		return
+	}
+
+	if err := h.authUseCase.RequestLoginCode(r.Context(), number); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to send web login code", "error", err)
+		h.renderLogin(w, r, http.StatusInternalServerError, loginData{Number: number}, "Gagal mengirim kode, silakan coba lagi.")
+		return
+	}
+	h.renderLogin(w, r, http.StatusOK, loginData{Step: "code", Number: number}, "")
+}
+
+func (h *WebHandler) verifyLoginCode(w http.ResponseWriter, r *http.Request) {
+	number := strings.TrimSpace(r.PostFormValue("number"))
+	data := loginData{Step: "code", Number: number}
+	if !h.allowLogin(w, r, data) {
+		return
+	}
+	if !h.validLoginToken(r) {
+		h.renderLogin(w, r, http.StatusForbidden, data, "Sesi formulir kedaluwarsa, silakan coba lagi.")
+		return
+	}
+
+	login, err := h.authUseCase.VerifyLoginCode(r.Context(), number, r.PostFormValue("code"))
+	if errors.Is(err, usecase.ErrInvalidLoginCode) {
+		h.renderLogin(w, r, http.StatusUnauthorized, data, "Kode salah atau sudah kedaluwarsa. Setelah terlalu banyak percobaan, coba lagi nanti.")
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to verify web login code", "error", err)
+		h.renderLogin(w, r, http.StatusInternalServerError, data, "Terjadi kesalahan, silakan coba lagi.")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    login.Token,
+		Path:     "/",
+		Expires:  login.Session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   h.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/app/week", http.StatusSeeOther)
+}
+
+func (h *WebHandler) logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.authUseCase.Logout(r.Context(), currentSession(r).session); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to delete web session", "error", err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.config.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+// Schedule views
+
+type webDay struct {
+	Date       time.Time
+	InMonth    bool
+	IsToday    bool
+	Activities []*entity.Activity
+}
+
+type weekData struct {
+	Label      string
+	Prev, Next string
+	Days       []webDay
+}
+
+type calendarData struct {
+	Month      time.Time
+	Prev, Next string
+	Weeks      [][]webDay
+	// FeedURL is the user's iCalendar subscription URL
+	FeedURL string
+}
+
+func (h *WebHandler) week(w http.ResponseWriter, r *http.Request) {
+	s := currentSession(r)
+	day := startOfDay(time.Now(), s.loc)
+	if v := r.URL.Query().Get("date"); v != "" {
+		parsed, err := time.ParseInLocation(webDateLayout, v, s.loc)
+		if err != nil {
+			http.Error(w, "date must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		day = parsed
+	}
+
+	// Weeks start on Monday
+	start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	days, err := h.days(r, start, 7)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+
+	end := start.AddDate(0, 0, 6)
+	data := weekData{
+		Label: fmt.Sprintf("%d %s – %d %s %d", start.Day(), monthNames[start.Month()-1],
+			end.Day(), monthNames[end.Month()-1], end.Year()),
+		Prev: start.AddDate(0, 0, -7).Format(webDateLayout),
+		Next: start.AddDate(0, 0, 7).Format(webDateLayout),
+		Days: days,
+	}
+	h.render(w, http.StatusOK, "week", h.page(r, "Minggu Ini", data))
+}
+
+func (h *WebHandler) calendar(w http.ResponseWriter, r *http.Request) {
+	s := currentSession(r)
+	now := time.Now().In(s.loc)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, s.loc)
+	if v := r.URL.Query().Get("month"); v != "" {
+		parsed, err := time.ParseInLocation(webMonthLayout, v, s.loc)
+		if err != nil {
+			http.Error(w, "month must be YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	// Full weeks from the Monday before the 1st to the Sunday after the last day
+	start := month.AddDate(0, 0, -((int(month.Weekday()) + 6) % 7))
+	last := month.AddDate(0, 1, -1)
+	end := last.AddDate(0, 0, (7-int(last.Weekday()))%7)
+	days, err := h.days(r, start, daysBetween(start, end)+1)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+
+	var weeks [][]webDay
+	for i := 0; i < len(days); i += 7 {
+		for j := i; j < i+7; j++ {
+			days[j].InMonth = days[j].Date.Month() == month.Month()
+		}
+		weeks = append(weeks, days[i:i+7])
+	}
+
+	token, err := h.calendarUseCase.CalendarToken(r.Context(), s.user.ID)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+
+	data := calendarData{
+		Month:   month,
+		Prev:    month.AddDate(0, -1, 0).Format(webMonthLayout),
+		Next:    month.AddDate(0, 1, 0).Format(webMonthLayout),
+		Weeks:   weeks,
+		FeedURL: h.publicURL(r) + "/calendar/" + token + ".ics",
+	}
+	page := h.page(r, "Kalender", data)
+	if r.URL.Query().Get("feed") == "reset" {
+		page.Notice = "Link kalender diganti. Link lama tidak berlaku lagi."
+	}
+	h.render(w, http.StatusOK, "calendar", page)
+}
+
+// resetCalendarFeed replaces the feed token, e.g. after the link was shared
+// by mistake.
+func (h *WebHandler) resetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.calendarUseCase.ResetCalendarToken(r.Context(), currentSession(r).user.ID); err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/app/calendar?feed=reset", http.StatusSeeOther)
+}
+
+func (h *WebHandler) publicURL(r *http.Request) string {
+	if h.config.PublicURL != "" {
+		return h.config.PublicURL
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// days loads the user's activities for n days from start and groups them by
+// local date.
+func (h *WebHandler) days(r *http.Request, start time.Time, n int) ([]webDay, error) {
+	s := currentSession(r)
+	activities, err := h.activityUseCase.GetUserActivitiesBetween(r.Context(), s.user.ID, start, start.AddDate(0, 0, n))
+	if err != nil {
+		return nil, err
+	}
+
+	today := startOfDay(time.Now(), s.loc)
+	days := make([]webDay, n)
+	for i := range days {
+		days[i].Date = start.AddDate(0, 0, i)
+		days[i].IsToday = days[i].Date.Equal(today)
+	}
+	for _, activity := range activities {
+		i := daysBetween(start, startOfDay(activity.ScheduledTime, s.loc))
+		if i >= 0 && i < n {
+			days[i].Activities = append(days[i].Activities, activity)
+		}
+	}
+	return days, nil
+}
+
+// Activity form
+
+type activityForm struct {
+	Title          string
+	Description    string
+	Date           string
+	Time           string
+	Priority       int
+	Status         entity.ActivityStatus
+	RecurrenceRule string
+}
+
+type recurrenceOption struct {
+	Rule  string
+	Label string
+}
+
+var recurrenceOptions = []recurrenceOption{
+	{"", "Tidak berulang"},
+	{"FREQ=DAILY", "Setiap hari"},
+	{"FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", "Setiap hari kerja (Senin-Jumat)"},
+	{"FREQ=WEEKLY", "Setiap minggu"},
+	{"FREQ=MONTHLY", "Setiap bulan"},
+}
+
+type activityFormData struct {
+	Activity   *entity.Activity
+	Action     string
+	Back       string
+	Form       activityForm
+	Priorities []int
+	Statuses   []entity.ActivityStatus
+	Recurrence []recurrenceOption
+}
+
+func (h *WebHandler) renderActivityForm(w http.ResponseWriter, r *http.Request, status int, activity *entity.Activity, form activityForm, errMsg string) {
+	data := activityFormData{
+		Activity:   activity,
+		Action:     "/app/activities",
+		Back:       "/app/week?date=" + form.Date,
+		Form:       form,
+		Priorities: []int{1, 2, 3, 4, 5},
+		Statuses: []entity.ActivityStatus{
+			entity.ActivityStatusPending,
+			entity.ActivityStatusCompleted,
+			entity.ActivityStatusCancelled,
+			entity.ActivityStatusOverdue,
+		},
+		Recurrence: recurrenceOptions,
+	}
+	// Keep rules set elsewhere (admin API, calendar import) selectable
+	known := false
+	for _, option := range recurrenceOptions {
+		known = known || option.Rule == form.RecurrenceRule
+	}
+	if !known {
+		data.Recurrence = append(data.Recurrence, recurrenceOption{form.RecurrenceRule, form.RecurrenceRule})
+	}
+	title := "Kegiatan Baru"
+	if activity != nil {
+		data.Action = "/app/activities/" + activity.ID.String()
+		title = "Ubah Kegiatan"
+	}
+
+	page := h.page(r, title, data)
+	page.Error = errMsg
+	h.render(w, status, "activity_form", page)
+}
+
+func (h *WebHandler) newActivity(w http.ResponseWriter, r *http.Request) {
+	s := currentSession(r)
+	// Default to the next full hour on the chosen day
+	now := time.Now().In(s.loc)
+	form := activityForm{
+		Date:     now.Format(webDateLayout),
+		Time:     now.Truncate(time.Hour).Add(time.Hour).Format("15:04"),
+		Priority: 3,
+	}
+	if v := r.URL.Query().Get("date"); v != "" {
+		if _, err := time.Parse(webDateLayout, v); err == nil {
+			form.Date = v
+		}
+	}
+	h.renderActivityForm(w, r, http.StatusOK, nil, form, "")
+}
+
+func (h *WebHandler) createActivity(w http.ResponseWriter, r *http.Request) {
+	s := currentSession(r)
+	form, scheduledTime, errMsg := parseActivityForm(r, s.loc)
+	if errMsg != "" {
+		h.renderActivityForm(w, r, http.StatusBadRequest, nil, form, errMsg)
+		return
+	}
+
+	_, err := h.activityUseCase.CreateActivity(r.Context(), s.user.ID, entity.ActivityIntentData{
+		Title:          form.Title,
+		Description:    form.Description,
+		ScheduledTime:  &scheduledTime,
+		Priority:       form.Priority,
+		RecurrenceRule: form.RecurrenceRule,
+	})
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/app/week?date="+form.Date, http.StatusSeeOther)
+}
+
+func (h *WebHandler) editActivity(w http.ResponseWriter, r *http.Request) {
+	s := currentSession(r)
+	activity, err := h.ownedActivity(r)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+
+	local := activity.ScheduledTime.In(s.loc)
+	form := activityForm{
+		Title:          activity.Title,
+		Description:    activity.Description,
+		Date:           local.Format(webDateLayout),
+		Time:           local.Format("15:04"),
+		Priority:       activity.Priority,
+		Status:         activity.Status,
+		RecurrenceRule: activity.RecurrenceRule,
+	}
+	h.renderActivityForm(w, r, http.StatusOK, activity, form, "")
+}
+
+func (h *WebHandler) updateActivity(w http.ResponseWriter, r *http.Request) {
+	s := currentSession(r)
+	activity, err := h.ownedActivity(r)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+
+	form, scheduledTime, errMsg := parseActivityForm(r, s.loc)
+	if _, ok := statusLabels[form.Status]; !ok && errMsg == "" {
+		errMsg = "Status tidak valid."
+	}
+	if errMsg != "" {
+		h.renderActivityForm(w, r, http.StatusBadRequest, activity, form, errMsg)
+		return
+	}
+
+	status := string(form.Status)
+	err = h.activityUseCase.UpdateActivity(r.Context(), activity.ID, entity.UpdateActivityIntentData{
+		ActivityID:     activity.ID,
+		Title:          &form.Title,
+		Description:    &form.Description,
+		ScheduledTime:  &scheduledTime,
+		Status:         &status,
+		Priority:       &form.Priority,
+		RecurrenceRule: &form.RecurrenceRule,
+	})
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/app/week?date="+form.Date, http.StatusSeeOther)
+}
+
+func (h *WebHandler) completeActivity(w http.ResponseWriter, r *http.Request) {
+	activity, err := h.ownedActivity(r)
+	if err == nil {
+		err = h.activityUseCase.CompleteActivity(r.Context(), activity.ID)
+	}
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	h.redirectToWeekOf(w, r, activity)
+}
+
+func (h *WebHandler) deleteActivity(w http.ResponseWriter, r *http.Request) {
+	activity, err := h.ownedActivity(r)
+	if err == nil {
+		err = h.activityUseCase.DeleteActivity(r.Context(), activity.ID)
+	}
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	h.redirectToWeekOf(w, r, activity)
+}
+
+func (h *WebHandler) redirectToWeekOf(w http.ResponseWriter, r *http.Request, activity *entity.Activity) {
+	date := activity.ScheduledTime.In(currentSession(r).loc).Format(webDateLayout)
+	http.Redirect(w, r, "/app/week?date="+date, http.StatusSeeOther)
+}
+
+// ownedActivity loads the activity in the URL. Other users' activities are
+// reported as not found.
+func (h *WebHandler) ownedActivity(r *http.Request) (*entity.Activity, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, fmt.Errorf("activity %w", usecase.ErrNotFound)
+	}
+	activity, err := h.activityUseCase.GetActivity(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if activity.UserID != currentSession(r).user.ID {
+		return nil, fmt.Errorf("activity %w", usecase.ErrNotFound)
+	}
+	return activity, nil
+}
+
+// parseActivityForm reads the activity form. The form is returned even when
+// invalid so it can be shown again with the error message.
+func parseActivityForm(r *http.Request, loc *time.Location) (activityForm, time.Time, string) {
+	form := activityForm{
+		Title:          strings.TrimSpace(r.PostFormValue("title")),
+		Description:    strings.TrimSpace(r.PostFormValue("description")),
+		Date:           r.PostFormValue("date"),
+		Time:           r.PostFormValue("time"),
+		Status:         entity.ActivityStatus(r.PostFormValue("status")),
+		RecurrenceRule: r.PostFormValue("recurrence_rule"),
+	}
+	form.Priority, _ = strconv.Atoi(r.PostFormValue("priority"))
+
+	if form.Title == "" {
+		return form, time.Time{}, "Judul wajib diisi."
+	}
+	if len(form.Title) > 255 {
+		return form, time.Time{}, "Judul maksimal 255 karakter."
+	}
+	if form.Priority < 1 || form.Priority > 5 {
+		return form, time.Time{}, "Prioritas harus antara 1 dan 5."
+	}
+	scheduledTime, err := time.ParseInLocation(webDateLayout+" 15:04", form.Date+" "+form.Time, loc)
+	if err != nil {
+		return form, time.Time{}, "Tanggal atau jam tidak valid."
+	}
+	return form, scheduledTime, ""
+}
+
+// Completion history
+
+type historyBar struct {
+	Label           string
+	Scheduled       int
+	Completed       int
+	X, Width        float64
+	ScheduledY      float64
+	ScheduledHeight float64
+	CompletedY      float64
+	CompletedHeight float64
+	LabelX          float64
+	ShowLabel       bool
+}
+
+type historyChart struct {
+	Width, Height float64
+	BaseY, LabelY float64
+	Bars          []historyBar
+}
+
+type historyData struct {
+	Days      int
+	Ranges    []int
+	Scheduled int
+	Completed int
+	Rate      int
+	Chart     historyChart
+}
+
+func (h *WebHandler) history(w http.ResponseWriter, r *http.Request) {
+	s := currentSession(r)
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > 366 {
+			http.Error(w, "days must be between 1 and 366", http.StatusBadRequest)
+			return
+		}
+		days = parsed
+	}
+
+	history, err := h.activityUseCase.GetCompletionHistory(r.Context(), s.user.ID, days, s.loc)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+
+	data := historyData{Days: days, Ranges: historyRanges, Chart: completionChart(history)}
+	for _, day := range history {
+		data.Scheduled += day.Scheduled
+		data.Completed += day.Completed
+	}
+	if data.Scheduled > 0 {
+		data.Rate = data.Completed * 100 / data.Scheduled
+	}
+	h.render(w, http.StatusOK, "history", h.page(r, "Riwayat", data))
+}
+
+// completionChart lays out a bar chart with one bar per day: the scheduled
+// count behind the completed count.
+func completionChart(history []usecase.DailyCompletion) historyChart {
+	const width, plotHeight, labelSpace = 720.0, 200.0, 20.0
+	chart := historyChart{Width: width, Height: plotHeight + labelSpace, BaseY: plotHeight, LabelY: plotHeight + 14}
+	if len(history) == 0 {
+		return chart
+	}
+
+	maxCount := 1
+	for _, day := range history {
+		if day.Scheduled > maxCount {
+			maxCount = day.Scheduled
+		}
+	}
+
+	slot := width / float64(len(history))
+	// Label about every 60px so long ranges stay readable
+	labelEvery := int(60/slot) + 1
+	for i, day := range history {
+		scheduledHeight := plotHeight * float64(day.Scheduled) / float64(maxCount)
+		completedHeight := plotHeight * float64(day.Completed) / float64(maxCount)
+		chart.Bars = append(chart.Bars, historyBar{
+			Label:           fmt.Sprintf("%d/%d", day.Date.Day(), int(day.Date.Month())),
+			Scheduled:       day.Scheduled,
+			Completed:       day.Completed,
+			X:               svgCoord(float64(i)*slot + slot*0.15),
+			Width:           svgCoord(slot * 0.7),
+			ScheduledY:      svgCoord(plotHeight - scheduledHeight),
+			ScheduledHeight: svgCoord(scheduledHeight),
+			CompletedY:      svgCoord(plotHeight - completedHeight),
+			CompletedHeight: svgCoord(completedHeight),
+			LabelX:          svgCoord(float64(i)*slot + slot/2),
+			ShowLabel:       (len(history)-1-i)%labelEvery == 0,
+		})
+	}
+	return chart
+}
+
+func svgCoord(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// Health profile
+
+func (h *WebHandler) showProfile(w http.ResponseWriter, r *http.Request) {
+	profile, err := h.healthUseCase.GetProfile(r.Context(), currentSession(r).user.ID)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+
+	page := h.page(r, "Profil Kesehatan", profile)
+	if r.URL.Query().Get("saved") != "" {
+		page.Notice = "Profil kesehatan tersimpan."
+	}
+	h.render(w, http.StatusOK, "profile", page)
+}
+
+func (h *WebHandler) saveProfile(w http.ResponseWriter, r *http.Request) {
+	input := usecase.HealthProfileInput{
+		Gender:              r.PostFormValue("gender"),
+		MedicalConditions:   strings.Split(r.PostFormValue("medical_conditions"), "\n"),
+		Allergies:           strings.Split(r.PostFormValue("allergies"), "\n"),
+		Medications:         strings.Split(r.PostFormValue("medications"), "\n"),
+		ActivityPreferences: strings.Split(r.PostFormValue("activity_preferences"), "\n"),
+		HealthGoals:         strings.Split(r.PostFormValue("health_goals"), "\n"),
+	}
+
+	var errMsg string
+	if v := strings.TrimSpace(r.PostFormValue("age")); v != "" {
+		age, err := strconv.Atoi(v)
+		if err != nil {
+			errMsg = "Usia harus berupa angka."
+		}
+		input.Age = &age
+	}
+	if len(input.Gender) > 20 {
+		errMsg = "Jenis kelamin maksimal 20 karakter."
+	}
+
+	if errMsg == "" {
+		err := h.healthUseCase.SaveProfile(r.Context(), currentSession(r).user.ID, input)
+		if err == nil {
+			http.Redirect(w, r, "/app/profile?saved=1", http.StatusSeeOther)
+			return
+		}
+		if !errors.Is(err, usecase.ErrInvalidInput) {
+			h.serverError(w, r, err)
+			return
+		}
+		errMsg = "Usia harus antara 1 dan 130."
+	}
+
+	page := h.page(r, "Profil Kesehatan", input)
+	page.Error = errMsg
+	h.render(w, http.StatusBadRequest, "profile", page)
+}
+
+// Import
+
+type importData struct {
+	URL           string
+	Subscriptions []*entity.CalendarSubscription
+}
+
+func (h *WebHandler) showImport(w http.ResponseWriter, r *http.Request) {
+	page := h.page(r, "Impor Kalender", nil)
+	query := r.URL.Query()
+	switch {
+	case query.Get("created") != "":
+		page.Notice = fmt.Sprintf("Kalender diimpor: %s kegiatan baru, %s diperbarui, %s tidak berubah, %s dilewati.",
+			query.Get("created"), query.Get("updated"), query.Get("unchanged"), query.Get("skipped"))
+	case query.Get("deleted") != "":
+		page.Notice = "URL kalender dihapus. Kegiatan yang sudah diimpor tetap tersimpan."
+	}
+	h.renderImport(w, r, http.StatusOK, page, "")
+}
+
+func (h *WebHandler) importFile(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.renderImportError(w, r, "Pilih file .ics untuk diimpor.", "")
+		return
+	}
+	defer file.Close()
+
+	result, err := h.calendarUseCase.ImportCalendar(r.Context(), currentSession(r).user.ID, file)
+	if errors.Is(err, usecase.ErrInvalidInput) {
+		h.renderImportError(w, r, "File tersebut bukan file kalender (.ics) yang valid atau terlalu besar.", "")
+		return
+	}
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, importResultURL(result), http.StatusSeeOther)
+}
+
+func (h *WebHandler) subscribeCalendar(w http.ResponseWriter, r *http.Request) {
+	calendarURL := strings.TrimSpace(r.PostFormValue("url"))
+	_, result, err := h.calendarUseCase.Subscribe(r.Context(), currentSession(r).user.ID, calendarURL)
+	if errors.Is(err, usecase.ErrInvalidInput) {
+		slog.WarnContext(r.Context(), "Calendar subscription rejected", "error", err)
+		h.renderImportError(w, r, "URL kalender tidak dapat ditambahkan. Pastikan URL benar, bisa diakses dari server, dan belum pernah ditambahkan.", calendarURL)
+		return
+	}
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, importResultURL(result), http.StatusSeeOther)
+}
+
+func (h *WebHandler) unsubscribeCalendar(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := h.calendarUseCase.Unsubscribe(r.Context(), currentSession(r).user.ID, id); err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	http.Redirect(w, r, "/app/import?deleted=1", http.StatusSeeOther)
+}
+
+func (h *WebHandler) renderImportError(w http.ResponseWriter, r *http.Request, errMsg, calendarURL string) {
+	page := h.page(r, "Impor Kalender", nil)
+	page.Error = errMsg
+	h.renderImport(w, r, http.StatusBadRequest, page, calendarURL)
+}
+
+func (h *WebHandler) renderImport(w http.ResponseWriter, r *http.Request, status int, page webPage, calendarURL string) {
+	subscriptions, err := h.calendarUseCase.GetSubscriptions(r.Context(), currentSession(r).user.ID)
+	if err != nil {
+		h.serverError(w, r, err)
+		return
+	}
+	page.Data = importData{URL: calendarURL, Subscriptions: subscriptions}
+	h.render(w, status, "import", page)
+}
+
+func importResultURL(result *usecase.CalendarImportResult) string {
+	return fmt.Sprintf("/app/import?created=%d&updated=%d&unchanged=%d&skipped=%d",
+		result.Created, result.Updated, result.Unchanged, result.Skipped)
+}
+
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// daysBetween counts calendar days from a to b, both at midnight. Rounding
+// keeps DST changes from shifting the count.
+func daysBetween(a, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / 24))
+}
@@ -6,15 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
 	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/metrics"
+	"smart_alert_system/internal/infrastructure/tracing"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type AIService interface {
@@ -22,13 +25,17 @@ type AIService interface {
 	GenerateHealthRecommendation(ctx context.Context, userID uuid.UUID, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error)
 	GenerateMorningAlert(ctx context.Context, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error)
 	GenerateEveningSummary(ctx context.Context, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error)
+	ExtractFromImage(ctx context.Context, image []byte, mimetype, caption string, now time.Time) (*entity.ImageExtraction, error)
 }
 
 type OpenAIService struct {
-	apiKey  string
-	model   string
-	client  *http.Client
-	baseURL string
+	apiKey      string
+	model       string
+	visionModel string
+	client      *http.Client
+	baseURL     string
+	// provider labels the LLM metrics, e.g. "openai" or "ollama"
+	provider string
 }
 
 func NewOpenAIService(apiKey, model, baseURL string) *OpenAIService {
@@ -38,13 +45,19 @@ func NewOpenAIService(apiKey, model, baseURL string) *OpenAIService {
 	}
 
 	return &OpenAIService{
-		apiKey:  apiKey,
-		model:   model,
-		client:  &http.Client{Timeout: 120 * time.Second}, // Longer timeout for local Ollama
-		baseURL: baseURL,
+		apiKey:   apiKey,
+		model:    model,
+		client:   &http.Client{Timeout: 120 * time.Second}, // Longer timeout for local Ollama
+		baseURL:  baseURL,
+		provider: "openai",
 	}
 }
 
+// SetProvider sets the provider name the LLM metrics are labeled with.
+func (s *OpenAIService) SetProvider(provider string) {
+	s.provider = provider
+}
+
 type OpenAIRequest struct {
 	Model    string    `json:"model"`
 	Messages []Message `json:"messages"`
@@ -57,33 +70,89 @@ type Message struct {
 
 type OpenAIResponse struct {
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Usage is the token count of a completion. Ollama reports it too.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
 }
 
 type Choice struct {
 	Message Message `json:"message"`
 }
 
-func (s *OpenAIService) callAPI(prompt string) (string, error) {
+func (s *OpenAIService) callAPI(ctx context.Context, operation, prompt string) (string, error) {
 	// Note: API key validation removed - Ollama doesn't need API key
 	// For OpenAI, API key should be set but we don't fail here to allow Ollama usage
+	reqBody := OpenAIRequest{
+		Model: s.model,
+		Messages: []Message{
+			{Role: "user", Content: prompt},
+		},
+	}
+	if persona := PersonaFrom(ctx); persona != "" {
+		reqBody.Messages = append([]Message{{Role: "system", Content: personaPrompt(persona)}}, reqBody.Messages...)
+	}
 
-	url := fmt.Sprintf("%s/chat/completions", s.baseURL)
+	return s.complete(ctx, operation, s.model, reqBody)
+}
 
+// callAPIWithSystem calls API with system message (for Ollama)
+func (s *OpenAIService) callAPIWithSystem(ctx context.Context, operation, systemPrompt, userPrompt string) (string, error) {
 	reqBody := OpenAIRequest{
 		Model: s.model,
 		Messages: []Message{
-			{Role: "user", Content: prompt},
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: userPrompt},
 		},
 	}
 
+	return s.complete(ctx, operation, s.model, reqBody)
+}
+
+// complete posts a chat completion request and returns the text of the first
+// choice. Latency, token usage and errors are recorded per operation, and the
+// request is traced as a client span.
+func (s *OpenAIService) complete(ctx context.Context, operation, model string, reqBody interface{}) (string, error) {
+	ctx, span := tracing.StartClient(ctx, "chat "+model,
+		attribute.String("gen_ai.system", s.provider),
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.request.model", model),
+		attribute.String("ai.operation", operation))
+
+	start := time.Now()
+	content, usage, err := s.post(ctx, reqBody)
+	metrics.LLMRequestDuration.WithLabelValues(s.provider, model, operation).Observe(metrics.Since(start))
+	if err != nil {
+		metrics.LLMErrors.WithLabelValues(s.provider, model, operation).Inc()
+		tracing.End(span, err)
+		return "", err
+	}
+	recordUsage(ctx, usage)
+	if usage != nil {
+		metrics.LLMTokens.WithLabelValues(s.provider, model, metrics.TokensPrompt).Add(float64(usage.PromptTokens))
+		metrics.LLMTokens.WithLabelValues(s.provider, model, metrics.TokensCompletion).Add(float64(usage.CompletionTokens))
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", usage.CompletionTokens))
+	}
+	tracing.End(span, nil)
+	return content, nil
+}
+
+func (s *OpenAIService) post(ctx context.Context, reqBody interface{}) (string, *Usage, error) {
+	url := fmt.Sprintf("%s/chat/completions", s.baseURL)
+
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -94,75 +163,59 @@ func (s *OpenAIService) callAPI(prompt string) (string, error) {
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return "", nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
 	var openAIResp OpenAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return "", nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return "", nil, fmt.Errorf("no choices in response")
 	}
 
-	return strings.TrimSpace(openAIResp.Choices[0].Message.Content), nil
+	return strings.TrimSpace(openAIResp.Choices[0].Message.Content), openAIResp.Usage, nil
 }
 
-// callAPIWithSystem calls API with system message (for Ollama)
-func (s *OpenAIService) callAPIWithSystem(systemPrompt, userPrompt string) (string, error) {
-	url := fmt.Sprintf("%s/chat/completions", s.baseURL)
-
-	reqBody := OpenAIRequest{
-		Model: s.model,
-		Messages: []Message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+// Ping checks that the provider is reachable and accepts our key by listing
+// its models, which costs no tokens. OpenAI and Ollama both serve /models.
+func (s *OpenAIService) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/models", nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
-
-	req.Header.Set("Content-Type", "application/json")
 	if s.apiKey != "" {
 		req.Header.Set("Authorization", "Bearer "+s.apiKey)
 	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
-	}
-
-	var openAIResp OpenAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&openAIResp); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
 	}
+	return nil
+}
 
-	if len(openAIResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
-	}
+// Provider returns the provider name, e.g. "openai" or "ollama".
+func (s *OpenAIService) Provider() string {
+	return s.provider
+}
 
-	return strings.TrimSpace(openAIResp.Choices[0].Message.Content), nil
+// Model returns the chat model.
+func (s *OpenAIService) Model() string {
+	return s.model
 }
 
 func (s *OpenAIService) ParseIntent(ctx context.Context, message string) (*entity.ParsedIntent, error) {
@@ -196,6 +249,9 @@ Output: {"intent":"add_activity","confidence":0.9,"entities":{"title":"olahraga"
 Input: "Lihat kegiatan hari ini"
 Output: {"intent":"list_activities","confidence":0.9,"entities":{}}
 
+Input: "Ingatkan Budi minum obat jam 8"
+Output: {"intent":"add_activity","confidence":0.9,"entities":{"title":"minum obat","scheduled_time":"jam 8"}}
+
 REMEMBER: Return ONLY JSON, nothing else. Start with { and end with }.`
 
 	userPrompt := fmt.Sprintf(`Analyze this WhatsApp message and return JSON:
@@ -210,13 +266,13 @@ Message: "%s"`, message)
 
 	if useSystemMessage {
 		// For Ollama, use system message
-		response, err = s.callAPIWithSystem(systemPrompt, userPrompt)
+		response, err = s.callAPIWithSystem(ctx, "parse_intent", systemPrompt, userPrompt)
 	} else {
 		// For OpenAI, use combined prompt
 		combinedPrompt := fmt.Sprintf(`%s
 
 %s`, systemPrompt, userPrompt)
-		response, err = s.callAPI(combinedPrompt)
+		response, err = s.callAPI(ctx, "parse_intent", combinedPrompt)
 	}
 
 	if err != nil {
@@ -239,14 +295,14 @@ Message: "%s"`, message)
 
 	if err := json.Unmarshal([]byte(cleanedResponse), &result); err != nil {
 		// Try to extract intent and entities from text response
-		log.Printf("⚠️  Failed to parse JSON response. Trying to extract from text...")
+		slog.DebugContext(ctx, "AI response is not JSON, extracting intent from text", "error", err)
 		if extractedIntent := extractIntentFromText(response, message); extractedIntent != nil {
-			log.Printf("✓ Successfully extracted intent from text: %s", extractedIntent.Type)
+			slog.DebugContext(ctx, "Extracted intent from AI text", "intent", extractedIntent.Type)
 			return extractedIntent, nil
 		}
 
 		// If extraction fails, return error to trigger fallback parser
-		log.Printf("⚠️  Could not extract intent from text. Will use fallback parser.")
+		slog.WarnContext(ctx, "Could not extract intent from AI text, using fallback parser", "model", s.model)
 		return nil, fmt.Errorf("ai_parse_failed")
 	}
 
@@ -264,10 +320,10 @@ Message: "%s"`, message)
 				extractedTitle := extractTitleFromMessage(message)
 				if extractedTitle != "" {
 					result.Entities["title"] = extractedTitle
-					log.Printf("⚠️  AI title '%s' doesn't match message, using extracted: '%s'", title, extractedTitle)
+					slog.DebugContext(ctx, "AI title doesn't match message, using extracted title", "response", title, "text", extractedTitle)
 				} else {
 					delete(result.Entities, "title")
-					log.Printf("⚠️  AI title '%s' doesn't match message and extraction failed, removing", title)
+					slog.DebugContext(ctx, "AI title doesn't match message and extraction failed, removing", "response", title)
 				}
 			}
 		} else {
@@ -275,7 +331,7 @@ Message: "%s"`, message)
 			extractedTitle := extractTitleFromMessage(message)
 			if extractedTitle != "" {
 				result.Entities["title"] = extractedTitle
-				log.Printf("✓ Extracted title from message: '%s'", extractedTitle)
+				slog.DebugContext(ctx, "Extracted title from message", "text", extractedTitle)
 			}
 		}
 
@@ -322,7 +378,7 @@ Message: "%s"`, message)
 				}
 
 				if timeMatch != "" || dayMatch != "" {
-					log.Printf("⚠️  AI time '%s' doesn't match message, using extracted: '%s'", timeStr, result.Entities["scheduled_time"])
+					slog.DebugContext(ctx, "AI time doesn't match message, using extracted time", "response", timeStr, "text", result.Entities["scheduled_time"])
 				}
 			}
 		} else {
@@ -360,7 +416,7 @@ Message: "%s"`, message)
 			}
 
 			if timeMatch != "" || dayMatch != "" {
-				log.Printf("✓ Extracted time from message: '%s'", result.Entities["scheduled_time"])
+				slog.DebugContext(ctx, "Extracted time from message", "text", result.Entities["scheduled_time"])
 			}
 		}
 
@@ -376,9 +432,10 @@ Message: "%s"`, message)
 	}
 
 	return &entity.ParsedIntent{
-		Type:       entity.IntentType(result.Intent),
+		Type:       entity.ParseIntentType(result.Intent),
 		Confidence: result.Confidence,
 		Entities:   result.Entities,
+		Source:     entity.IntentSourceAI,
 	}, nil
 }
 
@@ -394,7 +451,7 @@ Health Profile: %s
 
 Generate a concise, helpful health recommendation in Indonesian.`, activitiesStr, formatHealthProfileForAI(healthProfile))
 
-	return s.callAPI(prompt)
+	return s.callAPI(ctx, "health_recommendation", prompt)
 }
 
 func (s *OpenAIService) GenerateMorningAlert(ctx context.Context, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error) {
@@ -411,7 +468,7 @@ Health Profile: %s
 
 Make it warm, encouraging, and concise.`, activitiesStr, formatHealthProfileForAI(healthProfile))
 
-	return s.callAPI(prompt)
+	return s.callAPI(ctx, "morning_alert", prompt)
 }
 
 func (s *OpenAIService) GenerateEveningSummary(ctx context.Context, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error) {
@@ -429,7 +486,7 @@ Health Profile: %s
 
 Make it reflective, encouraging, and actionable.`, activitiesStr, formatHealthProfileForAI(healthProfile))
 
-	return s.callAPI(prompt)
+	return s.callAPI(ctx, "evening_summary", prompt)
 }
 
 func formatActivitiesForAI(activities []*entity.Activity) string {
@@ -593,6 +650,7 @@ func extractIntentFromText(aiResponse, originalMessage string) *entity.ParsedInt
 			Type:       intentType,
 			Confidence: confidence,
 			Entities:   entities,
+			Source:     entity.IntentSourceTextExtraction,
 		}
 	}
 
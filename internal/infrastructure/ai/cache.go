@@ -0,0 +1,243 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/cache"
+	"smart_alert_system/internal/infrastructure/metrics"
+
+	"github.com/google/uuid"
+)
+
+// cacheKeyVersion is part of every cache key. Bump it when a prompt changes,
+// so responses to the old prompt are no longer served.
+const cacheKeyVersion = "1"
+
+// CachedService is an AIService that caches LLM responses. Intents are keyed
+// by the normalized message, generated messages by a hash of the prompt
+// inputs (activities, health profile and persona). Identical requests made
+// while one is in flight wait for it instead of calling the LLM again.
+// Errors are never cached.
+type CachedService struct {
+	next   *OpenAIService
+	cache  cache.Cache
+	ttl    time.Duration
+	flight flight
+}
+
+func NewCachedService(next *OpenAIService, c cache.Cache, ttl time.Duration) *CachedService {
+	return &CachedService{next: next, cache: c, ttl: ttl, flight: flight{calls: make(map[string]*flightCall)}}
+}
+
+// cachedResponse is a cache entry: the response and the tokens it cost, which
+// every hit saves.
+type cachedResponse struct {
+	Value            json.RawMessage `json:"value"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+}
+
+func (s *CachedService) ParseIntent(ctx context.Context, message string) (*entity.ParsedIntent, error) {
+	// The persona only changes generated messages, not intent parsing
+	return cached(ctx, s, "parse_intent", []string{normalizeMessage(message)},
+		func(ctx context.Context) (*entity.ParsedIntent, error) {
+			return s.next.ParseIntent(ctx, message)
+		})
+}
+
+// GenerateHealthRecommendation is keyed like the other messages; userID is not
+// part of the prompt.
+func (s *CachedService) GenerateHealthRecommendation(ctx context.Context, userID uuid.UUID, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error) {
+	return cached(ctx, s, "health_recommendation", promptInputs(ctx, activities, healthProfile),
+		func(ctx context.Context) (string, error) {
+			return s.next.GenerateHealthRecommendation(ctx, userID, activities, healthProfile)
+		})
+}
+
+func (s *CachedService) GenerateMorningAlert(ctx context.Context, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error) {
+	return cached(ctx, s, "morning_alert", promptInputs(ctx, activities, healthProfile),
+		func(ctx context.Context) (string, error) {
+			return s.next.GenerateMorningAlert(ctx, activities, healthProfile)
+		})
+}
+
+func (s *CachedService) GenerateEveningSummary(ctx context.Context, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) (string, error) {
+	return cached(ctx, s, "evening_summary", promptInputs(ctx, activities, healthProfile),
+		func(ctx context.Context) (string, error) {
+			return s.next.GenerateEveningSummary(ctx, activities, healthProfile)
+		})
+}
+
+// ExtractFromImage is not cached: photos rarely repeat, and relative dates
+// in them are resolved against now.
+func (s *CachedService) ExtractFromImage(ctx context.Context, image []byte, mimetype, caption string, now time.Time) (*entity.ImageExtraction, error) {
+	return s.next.ExtractFromImage(ctx, image, mimetype, caption, now)
+}
+
+// cached returns the cached response for operation and inputs, or calls the
+// LLM through call and caches its response. Cache errors are logged and
+// treated as misses, so a broken cache never breaks the bot.
+func cached[T any](ctx context.Context, s *CachedService, operation string, inputs []string, call func(context.Context) (T, error)) (T, error) {
+	var zero T
+	key := cacheKey(operation, s.next.Model(), inputs)
+
+	data, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to read LLM cache", "operation", operation, "error", err)
+	}
+	result := metrics.CacheHit
+	if !ok {
+		var shared bool
+		data, shared, err = s.flight.do(ctx, key, func() ([]byte, error) {
+			return s.fill(ctx, operation, key, func(ctx context.Context) (interface{}, error) { return call(ctx) })
+		})
+		if err != nil {
+			return zero, err
+		}
+		result = metrics.CacheMiss
+		if shared {
+			result = metrics.CacheShared
+		}
+	}
+
+	var entry cachedResponse
+	var value T
+	if err := json.Unmarshal(data, &entry); err == nil {
+		err = json.Unmarshal(entry.Value, &value)
+	}
+	if err != nil {
+		// A corrupt or outdated entry; ask the LLM instead
+		slog.WarnContext(ctx, "Failed to decode LLM cache entry", "operation", operation, "error", err)
+		return call(ctx)
+	}
+
+	metrics.LLMCacheRequests.WithLabelValues(operation, result).Inc()
+	if result != metrics.CacheMiss {
+		provider, model := s.next.Provider(), s.next.Model()
+		metrics.LLMTokensSaved.WithLabelValues(provider, model, metrics.TokensPrompt).Add(float64(entry.PromptTokens))
+		metrics.LLMTokensSaved.WithLabelValues(provider, model, metrics.TokensCompletion).Add(float64(entry.CompletionTokens))
+		slog.DebugContext(ctx, "LLM cache hit", "operation", operation, "result", result)
+	}
+	return value, nil
+}
+
+// fill calls the LLM, recording the tokens it uses, and caches the response.
+func (s *CachedService) fill(ctx context.Context, operation, key string, call func(context.Context) (interface{}, error)) ([]byte, error) {
+	ctx, usage := withUsageRecorder(ctx)
+	value, err := call(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(cachedResponse{
+		Value:            raw,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cache.Set(ctx, key, data, s.ttl); err != nil {
+		slog.WarnContext(ctx, "Failed to write LLM cache", "operation", operation, "error", err)
+	}
+	return data, nil
+}
+
+// normalizeMessage folds case and whitespace, so "Hi" and " hi " share an
+// entry.
+func normalizeMessage(message string) string {
+	return strings.ToLower(strings.Join(strings.Fields(message), " "))
+}
+
+// promptInputs returns what a generated message depends on: exactly what the
+// prompt includes, plus the persona.
+func promptInputs(ctx context.Context, activities []*entity.Activity, healthProfile *entity.UserHealthProfile) []string {
+	return []string{PersonaFrom(ctx), formatActivitiesForAI(activities), formatHealthProfileForAI(healthProfile)}
+}
+
+// cacheKey hashes the inputs, so keys have a fixed length and the cache holds
+// no message text in its keys.
+func cacheKey(operation, model string, inputs []string) string {
+	h := sha256.New()
+	for _, part := range append([]string{cacheKeyVersion, operation, model}, inputs...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type usageKey struct{}
+
+// usageRecorder adds up the tokens of the completions made with a context.
+type usageRecorder struct {
+	mu sync.Mutex
+	Usage
+}
+
+func withUsageRecorder(ctx context.Context) (context.Context, *usageRecorder) {
+	recorder := &usageRecorder{}
+	return context.WithValue(ctx, usageKey{}, recorder), recorder
+}
+
+// recordUsage adds usage to the recorder in ctx, if any.
+func recordUsage(ctx context.Context, usage *Usage) {
+	recorder, ok := ctx.Value(usageKey{}).(*usageRecorder)
+	if !ok || usage == nil {
+		return
+	}
+	recorder.mu.Lock()
+	recorder.PromptTokens += usage.PromptTokens
+	recorder.CompletionTokens += usage.CompletionTokens
+	recorder.mu.Unlock()
+}
+
+// flight lets concurrent callers of the same key share one call.
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// do runs fn for key unless a call for key is already running, in which case
+// it waits for that call's result and reports it as shared.
+func (f *flight) do(ctx context.Context, key string, fn func() ([]byte, error)) ([]byte, bool, error) {
+	f.mu.Lock()
+	if call, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.value, true, call.err
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+	call := &flightCall{done: make(chan struct{})}
+	f.calls[key] = call
+	f.mu.Unlock()
+
+	call.value, call.err = fn()
+
+	f.mu.Lock()
+	delete(f.calls, key)
+	f.mu.Unlock()
+	close(call.done)
+	return call.value, false, call.err
+}
@@ -0,0 +1,26 @@
+package ai
+
+import "context"
+
+type personaKey struct{}
+
+// WithPersona makes the messages generated with ctx (alerts, summaries,
+// recommendations) written as persona, e.g. "Suster Ani dari Klinik Sehat".
+// Intent parsing and image reading are not affected.
+func WithPersona(ctx context.Context, persona string) context.Context {
+	if persona == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, personaKey{}, persona)
+}
+
+// PersonaFrom returns the persona set with WithPersona, "" if there is none.
+func PersonaFrom(ctx context.Context) string {
+	persona, _ := ctx.Value(personaKey{}).(string)
+	return persona
+}
+
+func personaPrompt(persona string) string {
+	return "You are writing WhatsApp messages to a user as: " + persona +
+		". Stay in this persona, but keep the requested language and format."
+}
@@ -0,0 +1,166 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"smart_alert_system/internal/domain/entity"
+)
+
+// Vision requests use the OpenAI content-part format, which Ollama's
+// OpenAI-compatible API also accepts for vision models (llava,
+// llama3.2-vision, ...).
+type visionRequest struct {
+	Model    string          `json:"model"`
+	Messages []visionMessage `json:"messages"`
+}
+
+type visionMessage struct {
+	Role    string        `json:"role"`
+	Content []contentPart `json:"content"`
+}
+
+type contentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *imageURL `json:"image_url,omitempty"`
+}
+
+type imageURL struct {
+	URL string `json:"url"`
+}
+
+// SetVisionModel sets the model used for images. Without it, the chat model
+// is used, which then has to be vision-capable.
+func (s *OpenAIService) SetVisionModel(model string) {
+	s.visionModel = model
+}
+
+func (s *OpenAIService) callVisionAPI(ctx context.Context, operation, systemPrompt, userPrompt string, image []byte, mimetype string) (string, error) {
+	model := s.visionModel
+	if model == "" {
+		model = s.model
+	}
+	if mimetype == "" {
+		mimetype = "image/jpeg"
+	}
+	dataURL := "data:" + mimetype + ";base64," + base64.StdEncoding.EncodeToString(image)
+
+	reqBody := visionRequest{
+		Model: model,
+		Messages: []visionMessage{
+			{Role: "system", Content: []contentPart{{Type: "text", Text: systemPrompt}}},
+			{Role: "user", Content: []contentPart{
+				{Type: "text", Text: userPrompt},
+				{Type: "image_url", ImageURL: &imageURL{URL: dataURL}},
+			}},
+		},
+	}
+
+	return s.complete(ctx, operation, model, reqBody)
+}
+
+// ExtractFromImage reads medication schedules, class or work schedules and
+// appointments from a photo. Times are read in now's location; now is also
+// the reference for relative dates like "mulai besok".
+func (s *OpenAIService) ExtractFromImage(ctx context.Context, image []byte, mimetype, caption string, now time.Time) (*entity.ImageExtraction, error) {
+	systemPrompt := `You are a JSON-only response bot. You MUST respond with ONLY valid JSON, no explanations, no markdown, no code blocks.
+
+Your task: read a photo sent by an Indonesian user of a reminder app (prescription label, class or work schedule, appointment card) and turn it into activities to remind them of.
+
+JSON format:
+{
+  "kind": "medication" | "schedule" | "appointment" | "other",
+  "summary": "one short sentence in Indonesian describing the photo",
+  "activities": [
+    {
+      "title": "short Indonesian title, e.g. \"Minum Amoxicillin 500 mg\" or \"Kuliah Kalkulus\"",
+      "description": "dose, room, lecturer, doctor or other details from the photo",
+      "start": "YYYY-MM-DD HH:MM, first occurrence in local time",
+      "recurrence": "RFC 5545 RRULE without the RRULE: prefix, or empty",
+      "category": "one of: Kesehatan, Belajar, Kerja, Olahraga, Makan, Sosial, Lainnya",
+      "priority": 1-5
+    }
+  ]
+}
+
+Rules:
+- Medication: one activity per daily dose. "3x1" or "3 kali sehari" means 07:00, 13:00 and 19:00; "2x1" means 07:00 and 19:00; "1x1" means 07:00; "sebelum makan" moves each dose 30 minutes earlier. Use FREQ=DAILY, with COUNT set to the number of days if the label gives a duration. Start at the next dose time after the current time. Priority 4.
+- Class or work schedule: one activity per weekly slot with FREQ=WEEKLY;BYDAY=MO (TU, WE, TH, FR, SA, SU), starting at the next occurrence of that day. Priority 3.
+- Appointment card: one activity at the appointment date and time, no recurrence. Priority 4.
+- Only include what is in the photo or the caption. If nothing can be scheduled, return "activities": [].
+
+REMEMBER: Return ONLY JSON, nothing else. Start with { and end with }.`
+
+	userPrompt := fmt.Sprintf("Current time: %s (%s).", now.Format("Monday 2006-01-02 15:04"), now.Location())
+	if caption != "" {
+		userPrompt += fmt.Sprintf("\nCaption from the user: \"%s\"", caption)
+	}
+	userPrompt += "\nRead the photo and return JSON."
+
+	response, err := s.callVisionAPI(ctx, "extract_image", systemPrompt, userPrompt, image, mimetype)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Kind       string `json:"kind"`
+		Summary    string `json:"summary"`
+		Activities []struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			Start       string `json:"start"`
+			Recurrence  string `json:"recurrence"`
+			Category    string `json:"category"`
+			Priority    int    `json:"priority"`
+		} `json:"activities"`
+	}
+	if err := json.Unmarshal([]byte(cleanJSONResponse(response)), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse image extraction: %w", err)
+	}
+
+	extraction := &entity.ImageExtraction{
+		Kind:    result.Kind,
+		Summary: strings.TrimSpace(result.Summary),
+	}
+	if extraction.Kind == "" {
+		extraction.Kind = entity.ImageKindOther
+	}
+	for _, item := range result.Activities {
+		title := strings.TrimSpace(item.Title)
+		start, ok := parseExtractedTime(item.Start, now.Location())
+		if title == "" || !ok {
+			continue
+		}
+		extraction.Activities = append(extraction.Activities, entity.ExtractedActivity{
+			Title:          title,
+			Description:    strings.TrimSpace(item.Description),
+			ScheduledTime:  start,
+			RecurrenceRule: strings.TrimPrefix(strings.TrimSpace(item.Recurrence), "RRULE:"),
+			Category:       strings.TrimSpace(item.Category),
+			Priority:       item.Priority,
+		})
+	}
+	return extraction, nil
+}
+
+var extractedTimeLayouts = []string{
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+}
+
+func parseExtractedTime(value string, loc *time.Location) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range extractedTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+// purgeInterval is how often Set deletes expired rows.
+const purgeInterval = time.Hour
+
+// Postgres is a cache in the llm_cache table, shared by all instances and
+// kept across restarts. Values must be JSON.
+type Postgres struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastPurge time.Time
+}
+
+func NewPostgres(db *database.PostgresDB) *Postgres {
+	return &Postgres{db: db.DB}
+}
+
+func (c *Postgres) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	var value []byte
+	err := c.db.QueryRowContext(ctx,
+		`SELECT value FROM llm_cache WHERE key = $1 AND expires_at > $2`, key, time.Now()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get cache entry: %w", err)
+	}
+	return value, true, nil
+}
+
+func (c *Postgres) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	now := time.Now()
+	query := `INSERT INTO llm_cache (key, value, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4)
+	          ON CONFLICT (key) DO UPDATE SET
+	          value = EXCLUDED.value, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at`
+	if _, err := c.db.ExecContext(ctx, query, key, string(value), now.Add(ttl), now); err != nil {
+		return fmt.Errorf("failed to set cache entry: %w", err)
+	}
+
+	if c.purgeDue(now) {
+		if _, err := c.db.ExecContext(ctx, `DELETE FROM llm_cache WHERE expires_at <= $1`, now); err != nil {
+			slog.WarnContext(ctx, "Failed to purge expired LLM cache entries", "error", err)
+		}
+	}
+	return nil
+}
+
+// purgeDue reports whether expired rows should be purged now, at most once
+// per purgeInterval per instance.
+func (c *Postgres) purgeDue(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Sub(c.lastPurge) < purgeInterval {
+		return false
+	}
+	c.lastPurge = now
+	return true
+}
@@ -0,0 +1,172 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"smart_alert_system/internal/domain/entity"
+)
+
+// Names of the supported channels, as stored in user_identities.channel and
+// outbound_messages.channel.
+const (
+	WhatsApp = "whatsapp"
+	Telegram = "telegram"
+)
+
+var (
+	// ErrMediaTooLarge is returned by DownloadMedia for files over the limit.
+	ErrMediaTooLarge = errors.New("media file too large")
+	// ErrUnauthorized is returned by ParseWebhook for requests that don't
+	// come from the messaging service.
+	ErrUnauthorized = errors.New("webhook request not authorized")
+	// ErrPermanent is wrapped by Send errors that retrying won't fix, e.g.
+	// a bad chat ID or a blocked bot.
+	ErrPermanent = errors.New("permanent send error")
+)
+
+// ThrottledError is returned by Send when the message may not be sent yet
+// because of the channel's send limits. Nothing was sent; the message should
+// be retried after RetryAfter without counting as a failed attempt.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("send throttled, retry in %s", e.RetryAfter.Round(time.Millisecond))
+}
+
+// Channel is a messaging service users talk to the assistant through.
+type Channel interface {
+	// Name is the channel's name, e.g. WhatsApp or Telegram.
+	Name() string
+	// ParseWebhook reads a webhook request into the events the app handles.
+	// Events of other kinds are left out.
+	ParseWebhook(r *http.Request) ([]Event, error)
+	// Send delivers an outbox message to message.ChatID, through the
+	// account in message.Session, and returns the service's message ID.
+	// Errors that retrying won't fix wrap ErrPermanent.
+	Send(ctx context.Context, message *entity.OutboundMessage) (string, error)
+	// DownloadMedia fetches a file of an incoming message through the
+	// account that received it. It returns ErrMediaTooLarge for files over
+	// maxBytes.
+	DownloadMedia(ctx context.Context, account, ref string, maxBytes int64) ([]byte, error)
+}
+
+// Welcomer is implemented by channels with their own welcome text per
+// account. An empty text means the built-in one.
+type Welcomer interface {
+	Welcome(account string) string
+}
+
+// Address is where a message came from and where replies to it go.
+type Address struct {
+	Channel string
+	// Account is the number or bot that received the message, e.g. the
+	// Waha session. Empty for the channel's default account.
+	Account string
+	ChatID  string
+}
+
+// MessageKind says what a message carries besides its text.
+type MessageKind string
+
+const (
+	KindText     MessageKind = "text"
+	KindVoice    MessageKind = "voice"
+	KindImage    MessageKind = "image"
+	KindDocument MessageKind = "document"
+	// KindOther is media the app doesn't read, e.g. stickers
+	KindOther MessageKind = "other"
+)
+
+// Media is a file attached to an incoming message. Ref is what the channel's
+// DownloadMedia takes: a URL for Waha, a file ID for Telegram. It is empty if
+// the file can't be downloaded.
+type Media struct {
+	Ref      string
+	Mimetype string
+	Filename string
+}
+
+// Message is an incoming message from a user, the same for every channel.
+type Message struct {
+	Address
+	ID string
+	// SenderID identifies the user on the channel: the WhatsApp chat ID
+	// ("…@c.us" or "…@lid") or the Telegram user ID.
+	SenderID   string
+	SenderName string
+	Body       string
+	Kind       MessageKind
+	Media      *Media
+	// Group reports whether the message was written in a group chat; ChatID
+	// is then the group's and SenderID the member's.
+	Group bool
+	// Mentions are the chat IDs of the users the message @mentions
+	Mentions []string
+	// ChoiceID is the ID of the button or list row the message answers, ""
+	// if it isn't such an answer.
+	ChoiceID string
+	// ReplyToID is the ID of the message this one quotes, "" if it quotes
+	// none. For our own messages it matches the ID Send returned, possibly
+	// without the chat prefix Waha adds.
+	ReplyToID string
+	Timestamp time.Time
+}
+
+// Ack is a delivery or read receipt for a message we sent.
+type Ack struct {
+	MessageID string
+	// Recipient is the sender ID of the user the message went to
+	Recipient string
+	// Level is the Waha ack level, see entity.DeliveryStatusFromAck
+	Level int
+}
+
+// Vote is an answer to a poll we sent.
+type Vote struct {
+	Address
+	ID       string
+	SenderID string
+	PollID   string
+	Options  []string
+}
+
+type EventType string
+
+const (
+	EventMessage EventType = "message"
+	EventAck     EventType = "ack"
+	EventVote    EventType = "vote"
+)
+
+// Event is one thing a webhook reported; the field matching Type is set.
+type Event struct {
+	Type    EventType
+	Message *Message
+	Ack     *Ack
+	Vote    *Vote
+}
+
+// Channels are the channels the server is connected to.
+type Channels struct {
+	byName map[string]Channel
+}
+
+func NewChannels(channels ...Channel) *Channels {
+	byName := make(map[string]Channel, len(channels))
+	for _, ch := range channels {
+		byName[ch.Name()] = ch
+	}
+	return &Channels{byName: byName}
+}
+
+// Get returns the channel with this name, or false if it isn't connected.
+func (c *Channels) Get(name string) (Channel, bool) {
+	ch, ok := c.byName[name]
+	return ch, ok
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
 )
 
@@ -12,7 +13,9 @@ type PostgresDB struct {
 }
 
 func NewPostgresDB(databaseURL string) (*PostgresDB, error) {
-	db, err := sql.Open("postgres", databaseURL)
+	// Queries are traced as spans named after the repository method
+	db, err := otelsql.Open("postgres", databaseURL, otelsql.WithSpanNameFormatter(spanName),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitRows: true, OmitConnResetSession: true}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
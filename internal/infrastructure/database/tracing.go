@@ -0,0 +1,52 @@
+package database
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/XSAM/otelsql"
+)
+
+const repositoryPackage = "smart_alert_system/internal/infrastructure/repository."
+
+var receiverReplacer = strings.NewReplacer("(", "", ")", "", "*", "")
+
+// spanName names a query span after the repository method that ran it, e.g.
+// "activityRepository.GetByID", so a trace shows which call was slow. Shared
+// helpers like list or scanOne are skipped in favor of the exported method
+// that called them. Queries from outside the repositories keep the
+// database/sql method name.
+func spanName(ctx context.Context, method otelsql.Method, query string) string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+
+	helper := ""
+	for {
+		frame, more := frames.Next()
+		if name, ok := strings.CutPrefix(frame.Function, repositoryPackage); ok && !strings.Contains(name, ".func") {
+			name = receiverReplacer.Replace(name)
+			if isExported(name[strings.LastIndex(name, ".")+1:]) {
+				return name
+			}
+			if helper == "" {
+				helper = name
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	if helper != "" {
+		return helper
+	}
+	return string(method)
+}
+
+func isExported(name string) bool {
+	for _, r := range name {
+		return unicode.IsUpper(r)
+	}
+	return false
+}
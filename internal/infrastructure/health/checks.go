@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"smart_alert_system/internal/infrastructure/database"
+	"smart_alert_system/internal/infrastructure/scheduler"
+	"smart_alert_system/internal/infrastructure/whatsapp"
+)
+
+// Database pings Postgres and reports the connection pool stats.
+func Database(db *database.PostgresDB) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		stats := db.DB.Stats()
+		details := map[string]interface{}{
+			"open_connections": stats.OpenConnections,
+			"in_use":           stats.InUse,
+			"idle":             stats.Idle,
+			"max_open":         stats.MaxOpenConnections,
+			"wait_count":       stats.WaitCount,
+			"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+		}
+		if err := db.DB.PingContext(ctx); err != nil {
+			return details, fmt.Errorf("ping failed: %w", err)
+		}
+		return details, nil
+	}
+}
+
+// WahaSession checks that a Waha session is connected to WhatsApp.
+func WahaSession(client *whatsapp.WahaClient) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		info, err := client.SessionStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		details := map[string]interface{}{"status": info.Status}
+		if info.Status != whatsapp.SessionWorking {
+			return details, fmt.Errorf("session %s is %s", client.Session(), info.Status)
+		}
+		return details, nil
+	}
+}
+
+// LLMProber is an LLM provider that can be probed without spending tokens.
+type LLMProber interface {
+	Ping(ctx context.Context) error
+	Provider() string
+	Model() string
+}
+
+// LLM checks that the LLM provider is reachable. Wrap it in Cached, as
+// hosted providers rate limit even free calls.
+func LLM(llm LLMProber) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		details := map[string]interface{}{"provider": llm.Provider(), "model": llm.Model()}
+		return details, llm.Ping(ctx)
+	}
+}
+
+// Scheduler checks that the scheduler is running and that its every-minute
+// job fired within maxTickAge, i.e. cron isn't stuck.
+func Scheduler(s *scheduler.Scheduler, maxTickAge time.Duration) CheckFunc {
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		state := s.State()
+		details := map[string]interface{}{
+			"running":      state.Running,
+			"leader":       state.Leader,
+			"last_tick":    state.LastTick,
+			"next_morning": state.NextMorning,
+			"next_evening": state.NextEvening,
+		}
+		if !state.Running {
+			return details, fmt.Errorf("scheduler is not running")
+		}
+		if age := time.Since(state.LastTick); age > maxTickAge {
+			return details, fmt.Errorf("scheduler last ticked %s ago", age.Round(time.Second))
+		}
+		return details, nil
+	}
+}
@@ -0,0 +1,145 @@
+// Package health runs readiness checks against the server's dependencies and
+// reports each one with its latency.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check statuses.
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDegraded = "degraded"
+)
+
+// CheckFunc checks one dependency. Details are reported even when it fails,
+// e.g. connection pool stats next to a failed ping.
+type CheckFunc func(ctx context.Context) (details map[string]interface{}, err error)
+
+// Check is a named dependency check. A failed critical check makes the server
+// not ready; a failed non-critical one only degrades it, for dependencies the
+// server can work around, like the LLM with its fallback parser.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      CheckFunc
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name      string                 `json:"name"`
+	Status    string                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMS float64                `json:"latency_ms"`
+	Error     string                 `json:"error,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report is the outcome of all checks. Status is "fail" if a critical check
+// failed, "degraded" if only non-critical ones did, and "ok" otherwise.
+type Report struct {
+	Status    string    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+// Ready reports whether the server should receive traffic.
+func (r Report) Ready() bool {
+	return r.Status != StatusFail
+}
+
+// Checker runs checks concurrently, each bounded by a timeout.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a check. Checks are reported in the order they were added.
+func (c *Checker) Add(name string, critical bool, run CheckFunc) {
+	c.checks = append(c.checks, Check{Name: name, Critical: critical, Run: run})
+}
+
+// Run runs all checks and returns the report.
+func (c *Checker) Run(ctx context.Context) Report {
+	results := make([]Result, len(c.checks))
+
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, CheckedAt: time.Now(), Checks: results}
+	for _, result := range results {
+		if result.Status != StatusFail {
+			continue
+		}
+		if result.Critical {
+			report.Status = StatusFail
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	details, err := check.Run(ctx)
+	result := Result{
+		Name:      check.Name,
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:   details,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Cached wraps a check that is slow or costly, like an LLM probe, so it runs
+// at most once per ttl; in between, the last outcome is reported with the
+// time it was checked. Concurrent callers share one run.
+func Cached(ttl time.Duration, run CheckFunc) CheckFunc {
+	var (
+		mu        sync.Mutex
+		checkedAt time.Time
+		details   map[string]interface{}
+		err       error
+	)
+	return func(ctx context.Context) (map[string]interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if checkedAt.IsZero() || time.Since(checkedAt) >= ttl {
+			details, err = run(ctx)
+			checkedAt = time.Now()
+		}
+
+		cached := map[string]interface{}{"checked_at": checkedAt}
+		for key, value := range details {
+			cached[key] = value
+		}
+		return cached, err
+	}
+}
@@ -0,0 +1,77 @@
+package ical
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrNotModified is returned by Fetch when the calendar still has the ETag
+// it was last fetched with.
+var ErrNotModified = errors.New("calendar not modified")
+
+// ErrTooLarge is returned for calendars bigger than the configured limit.
+var ErrTooLarge = errors.New("calendar too large")
+
+// Fetcher downloads calendars from subscription URLs.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewFetcher(timeout time.Duration, maxBytes int64) *Fetcher {
+	return &Fetcher{
+		client:   &http.Client{Timeout: timeout},
+		maxBytes: maxBytes,
+	}
+}
+
+// Fetch downloads and parses the calendar at url, resolving floating times in
+// loc. If etag is set, the server may answer ErrNotModified. The new ETag is
+// returned with the calendar.
+func (f *Fetcher) Fetch(ctx context.Context, url, etag string, loc *time.Location) (*Calendar, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/calendar")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch calendar: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, etag, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	calendar, err := ParseLimit(resp.Body, loc, f.maxBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	return calendar, resp.Header.Get("ETag"), nil
+}
+
+// ParseLimit is Parse for untrusted input: it fails with ErrTooLarge instead
+// of reading more than maxBytes.
+func ParseLimit(r io.Reader, loc *time.Location, maxBytes int64) (*Calendar, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, ErrTooLarge
+	}
+	return Parse(bytes.NewReader(data), loc)
+}
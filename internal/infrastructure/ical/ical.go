@@ -0,0 +1,306 @@
+// Package ical reads and writes iCalendar (RFC 5545) data.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	// Lines longer than this many octets must be folded (RFC 5545 3.1)
+	maxLineOctets = 75
+)
+
+type EventStatus string
+
+const (
+	StatusTentative EventStatus = "TENTATIVE"
+	StatusConfirmed EventStatus = "CONFIRMED"
+	StatusCancelled EventStatus = "CANCELLED"
+)
+
+type Calendar struct {
+	ProdID string
+	// Name is shown by calendar apps that support X-WR-CALNAME.
+	Name string
+	// RefreshInterval hints how often subscribers should poll the feed.
+	RefreshInterval time.Duration
+	// Location is the time zone event times are written in. Recurrence
+	// rules are expanded in that zone, so "every Monday 06:00 WIB" stays on
+	// Monday even though it is Sunday in UTC. Only zones without daylight
+	// saving are supported; other zones fall back to UTC.
+	Location *time.Location
+	Events   []Event
+	// Invalid counts events Parse dropped because they couldn't be read.
+	Invalid int
+}
+
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	// AllDay events have a date but no time of day.
+	AllDay     bool
+	Status     EventStatus
+	Categories []string
+	// RRule is an RFC 5545 recurrence rule without the "RRULE:" prefix,
+	// e.g. "FREQ=WEEKLY;BYDAY=MO,WE".
+	RRule string
+	// Priority is 1 (highest) to 9 (lowest); 0 leaves it undefined.
+	Priority     int
+	Created      time.Time
+	LastModified time.Time
+	Alarms       []Alarm
+
+	// duration is a parsed DURATION, used to fill End
+	duration time.Duration
+}
+
+// Alarm is a display alarm relative to the event start.
+type Alarm struct {
+	Before      time.Duration
+	Description string
+
+	// at is a parsed absolute trigger, converted to Before once the event
+	// start is known
+	at time.Time
+}
+
+// Encode writes the calendar as an iCalendar stream.
+func (c *Calendar) Encode(w io.Writer) error {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+
+	lw.line("BEGIN", "VCALENDAR")
+	lw.line("VERSION", "2.0")
+	lw.line("PRODID", c.ProdID)
+	lw.line("CALSCALE", "GREGORIAN")
+	lw.line("METHOD", "PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME", escapeText(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		lw.line("REFRESH-INTERVAL;VALUE=DURATION", formatDuration(c.RefreshInterval))
+		lw.line("X-PUBLISHED-TTL", formatDuration(c.RefreshInterval))
+	}
+
+	now := time.Now()
+	tz := fixedZone(c.Location, now)
+	if tz != nil {
+		tz.encode(lw)
+	}
+	for _, event := range c.Events {
+		event.encode(lw, tz, now)
+	}
+
+	lw.line("END", "VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return lw.w.Flush()
+}
+
+func (e *Event) encode(lw *lineWriter, tz *timeZone, now time.Time) {
+	stamp := e.LastModified
+	if stamp.IsZero() {
+		stamp = now
+	}
+
+	lw.line("BEGIN", "VEVENT")
+	lw.line("UID", e.UID)
+	lw.line("DTSTAMP", formatTime(stamp))
+	switch {
+	case e.AllDay:
+		lw.line("DTSTART;VALUE=DATE", e.Start.Format(dateLayout))
+		if !e.End.IsZero() {
+			lw.line("DTEND;VALUE=DATE", e.End.Format(dateLayout))
+		}
+	default:
+		tz.timeLine(lw, "DTSTART", e.Start)
+		if !e.End.IsZero() {
+			tz.timeLine(lw, "DTEND", e.End)
+		}
+	}
+	if e.RRule != "" {
+		lw.line("RRULE", e.RRule)
+	}
+	lw.line("SUMMARY", escapeText(e.Summary))
+	if e.Description != "" {
+		lw.line("DESCRIPTION", escapeText(e.Description))
+	}
+	if e.Status != "" {
+		lw.line("STATUS", string(e.Status))
+	}
+	if len(e.Categories) > 0 {
+		escaped := make([]string, len(e.Categories))
+		for i, category := range e.Categories {
+			escaped[i] = escapeText(category)
+		}
+		lw.line("CATEGORIES", strings.Join(escaped, ","))
+	}
+	if e.Priority > 0 {
+		lw.line("PRIORITY", fmt.Sprint(e.Priority))
+	}
+	if !e.Created.IsZero() {
+		lw.line("CREATED", formatTime(e.Created))
+	}
+	if !e.LastModified.IsZero() {
+		lw.line("LAST-MODIFIED", formatTime(e.LastModified))
+	}
+	for _, alarm := range e.Alarms {
+		lw.line("BEGIN", "VALARM")
+		lw.line("ACTION", "DISPLAY")
+		lw.line("TRIGGER", formatDuration(-alarm.Before))
+		lw.line("DESCRIPTION", escapeText(alarm.Description))
+		lw.line("END", "VALARM")
+	}
+	lw.line("END", "VEVENT")
+}
+
+// timeZone is a zone with a constant UTC offset, written as a VTIMEZONE with
+// a single STANDARD observance.
+type timeZone struct {
+	loc    *time.Location
+	offset int
+	abbrev string
+}
+
+// fixedZone returns loc as a timeZone if its offset is the same all year,
+// nil otherwise.
+func fixedZone(loc *time.Location, now time.Time) *timeZone {
+	if loc == nil || loc == time.UTC {
+		return nil
+	}
+	year := now.In(loc).Year()
+	abbrev, january := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+	_, july := time.Date(year, time.July, 1, 0, 0, 0, 0, loc).Zone()
+	if january != july {
+		return nil
+	}
+	return &timeZone{loc: loc, offset: january, abbrev: abbrev}
+}
+
+func (tz *timeZone) encode(lw *lineWriter) {
+	offset := formatOffset(tz.offset)
+	lw.line("BEGIN", "VTIMEZONE")
+	lw.line("TZID", tz.loc.String())
+	lw.line("BEGIN", "STANDARD")
+	lw.line("DTSTART", "19700101T000000")
+	lw.line("TZOFFSETFROM", offset)
+	lw.line("TZOFFSETTO", offset)
+	lw.line("TZNAME", escapeText(tz.abbrev))
+	lw.line("END", "STANDARD")
+	lw.line("END", "VTIMEZONE")
+}
+
+// timeLine writes a DATE-TIME property in the zone, or in UTC if tz is nil.
+func (tz *timeZone) timeLine(lw *lineWriter, name string, t time.Time) {
+	if tz == nil {
+		lw.line(name, formatTime(t))
+		return
+	}
+	lw.line(name+";TZID="+tz.loc.String(), t.In(tz.loc).Format(localLayout))
+}
+
+// formatOffset formats a UTC offset in seconds as "+0700".
+func formatOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// lineWriter writes content lines with CRLF endings, folding long lines.
+// The first error is kept and later writes are skipped.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(name, value string) {
+	if lw.err != nil {
+		return
+	}
+
+	line := name + ":" + value
+	limit := maxLineOctets
+	var b strings.Builder
+	for len(line) > limit {
+		// Never split a multi-byte UTF-8 character
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts toward the limit
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+
+	_, lw.err = lw.w.WriteString(b.String())
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(utcLayout)
+}
+
+// formatDuration formats d as an RFC 5545 duration, e.g. "-PT15M" or "P1DT2H".
+func formatDuration(d time.Duration) string {
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	b.WriteByte('P')
+
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		fmt.Fprintf(&b, "%dD", days)
+	}
+	if d == 0 {
+		if days == 0 {
+			b.WriteString("T0S")
+		}
+		return b.String()
+	}
+
+	b.WriteByte('T')
+	if h := d / time.Hour; h > 0 {
+		fmt.Fprintf(&b, "%dH", h)
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		fmt.Fprintf(&b, "%dM", m)
+		d -= m * time.Minute
+	}
+	if s := d / time.Second; s > 0 {
+		fmt.Fprintf(&b, "%dS", s)
+	}
+	return b.String()
+}
@@ -0,0 +1,249 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+	"unicode/utf8"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+// crlf joins lines the way Encode writes them.
+func crlf(lines ...string) string {
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestEncode(t *testing.T) {
+	jakarta := mustLoadLocation(t, "Asia/Jakarta")
+	newYork := mustLoadLocation(t, "America/New_York")
+	modified := time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC)
+
+	medication := Event{
+		UID:          "act-1@smart-alert",
+		Summary:      "Minum obat; setelah makan",
+		Description:  "Dosis: 1 tablet\nJangan lupa",
+		Start:        time.Date(2024, time.March, 11, 6, 0, 0, 0, jakarta),
+		End:          time.Date(2024, time.March, 11, 6, 30, 0, 0, jakarta),
+		Status:       StatusConfirmed,
+		Categories:   []string{"Kesehatan", "Obat, vitamin"},
+		RRule:        "FREQ=WEEKLY;BYDAY=MO",
+		Priority:     1,
+		Created:      time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+		LastModified: modified,
+		Alarms:       []Alarm{{Before: 15 * time.Minute, Description: "Minum obat"}},
+	}
+	checkup := Event{
+		UID:          "act-2@smart-alert",
+		Summary:      "Kontrol ke dokter",
+		Start:        time.Date(2024, time.March, 15, 0, 0, 0, 0, jakarta),
+		End:          time.Date(2024, time.March, 16, 0, 0, 0, 0, jakarta),
+		AllDay:       true,
+		Status:       StatusTentative,
+		LastModified: modified,
+	}
+	header := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Smart Alert System//ID",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+
+	tests := []struct {
+		name     string
+		calendar Calendar
+		want     []string
+	}{
+		{
+			name: "fixed zone",
+			calendar: Calendar{
+				ProdID:          "-//Smart Alert System//ID",
+				Name:            "Kegiatan Budi",
+				RefreshInterval: time.Hour,
+				Location:        jakarta,
+				Events:          []Event{medication},
+			},
+			want: append(header,
+				"X-WR-CALNAME:Kegiatan Budi",
+				"REFRESH-INTERVAL;VALUE=DURATION:PT1H",
+				"X-PUBLISHED-TTL:PT1H",
+				"BEGIN:VTIMEZONE",
+				"TZID:Asia/Jakarta",
+				"BEGIN:STANDARD",
+				"DTSTART:19700101T000000",
+				"TZOFFSETFROM:+0700",
+				"TZOFFSETTO:+0700",
+				"TZNAME:WIB",
+				"END:STANDARD",
+				"END:VTIMEZONE",
+				"BEGIN:VEVENT",
+				"UID:act-1@smart-alert",
+				"DTSTAMP:20240302T100000Z",
+				"DTSTART;TZID=Asia/Jakarta:20240311T060000",
+				"DTEND;TZID=Asia/Jakarta:20240311T063000",
+				"RRULE:FREQ=WEEKLY;BYDAY=MO",
+				`SUMMARY:Minum obat\; setelah makan`,
+				`DESCRIPTION:Dosis: 1 tablet\nJangan lupa`,
+				"STATUS:CONFIRMED",
+				`CATEGORIES:Kesehatan,Obat\, vitamin`,
+				"PRIORITY:1",
+				"CREATED:20240301T100000Z",
+				"LAST-MODIFIED:20240302T100000Z",
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				"TRIGGER:-PT15M",
+				"DESCRIPTION:Minum obat",
+				"END:VALARM",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			),
+		},
+		{
+			name: "zone with daylight saving falls back to UTC",
+			calendar: Calendar{
+				ProdID:   "-//Smart Alert System//ID",
+				Location: newYork,
+				Events: []Event{{
+					UID:          "act-3@smart-alert",
+					Summary:      "Jalan pagi",
+					Start:        time.Date(2024, time.July, 1, 7, 0, 0, 0, newYork),
+					LastModified: modified,
+				}},
+			},
+			want: append(header,
+				"BEGIN:VEVENT",
+				"UID:act-3@smart-alert",
+				"DTSTAMP:20240302T100000Z",
+				"DTSTART:20240701T110000Z",
+				"SUMMARY:Jalan pagi",
+				"LAST-MODIFIED:20240302T100000Z",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			),
+		},
+		{
+			name: "all-day event",
+			calendar: Calendar{
+				ProdID: "-//Smart Alert System//ID",
+				Events: []Event{checkup},
+			},
+			want: append(header,
+				"BEGIN:VEVENT",
+				"UID:act-2@smart-alert",
+				"DTSTAMP:20240302T100000Z",
+				"DTSTART;VALUE=DATE:20240315",
+				"DTEND;VALUE=DATE:20240316",
+				"SUMMARY:Kontrol ke dokter",
+				"STATUS:TENTATIVE",
+				"LAST-MODIFIED:20240302T100000Z",
+				"END:VEVENT",
+				"END:VCALENDAR",
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.calendar.Encode(&buf); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if got, want := buf.String(), crlf(tt.want...); got != want {
+				t.Errorf("Encode wrote\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestEncodeFoldsLongLines(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{"ascii", strings.Repeat("Olahraga pagi di taman kota ", 10)},
+		{"multi-byte", strings.Repeat("Senam 🏃 pagi — ", 20)},
+		{"exactly the limit", strings.Repeat("a", maxLineOctets-len("SUMMARY:"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar := Calendar{Events: []Event{{
+				UID:          "long",
+				Summary:      tt.summary,
+				Start:        time.Date(2024, time.March, 11, 6, 0, 0, 0, time.UTC),
+				LastModified: time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC),
+			}}}
+			var buf bytes.Buffer
+			if err := calendar.Encode(&buf); err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			out := buf.String()
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatal("output does not end with CRLF")
+			}
+			for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Errorf("line of %d octets: %q", len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line splits a UTF-8 character: %q", line)
+				}
+			}
+
+			unfolded := strings.ReplaceAll(out, "\r\n ", "")
+			if !strings.Contains(unfolded, "\r\nSUMMARY:"+tt.summary+"\r\n") {
+				t.Errorf("unfolded output does not contain the summary:\n%s", unfolded)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "PT0S"},
+		{-15 * time.Minute, "-PT15M"},
+		{time.Hour, "PT1H"},
+		{90 * time.Second, "PT1M30S"},
+		{24 * time.Hour, "P1D"},
+		{26 * time.Hour, "P1DT2H"},
+		{-(2*24*time.Hour + 30*time.Minute), "-P2DT30M"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%s) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Minum obat", "Minum obat"},
+		{"a;b,c", `a\;b\,c`},
+		{`C:\obat`, `C:\\obat`},
+		{"baris 1\r\nbaris 2\nbaris 3\rbaris 4", `baris 1\nbaris 2\nbaris 3\nbaris 4`},
+	}
+
+	for _, tt := range tests {
+		if got := escapeText(tt.text); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
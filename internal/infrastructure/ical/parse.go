@@ -0,0 +1,449 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	dateLayout = "20060102"
+	// Longest unfolded content line accepted, a guard against garbage input
+	maxContentLine = 64 * 1024
+)
+
+// ErrNoCalendar is returned when the input holds no VCALENDAR.
+var ErrNoCalendar = errors.New("no VCALENDAR found")
+
+// Parse reads an iCalendar stream. Times with a TZID are resolved via the
+// IANA database, falling back to the offset declared in the stream's
+// VTIMEZONE; floating times and all-day dates are taken to be in loc.
+//
+// Only the parts of VEVENT that map onto activities are kept. Events that
+// override one instance of a recurring event (RECURRENCE-ID) are skipped, as
+// are events that can't be read; the latter are counted in Invalid.
+func Parse(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{loc: loc, zones: map[string]*time.Location{}}
+	// Zones must be known before event times are parsed, and a VTIMEZONE may
+	// come after the events that use it
+	for _, l := range lines {
+		p.scanZone(l)
+	}
+
+	calendar := &Calendar{}
+	found := false
+	var event *Event
+	var alarm *Alarm
+	skip := false
+	depth := 0
+	for _, l := range lines {
+		switch {
+		case l.name == "BEGIN":
+			depth++
+			switch strings.ToUpper(l.value) {
+			case "VCALENDAR":
+				found = true
+			case "VEVENT":
+				event, skip = &Event{}, false
+			case "VALARM":
+				if event != nil {
+					alarm = &Alarm{}
+				}
+			}
+		case l.name == "END":
+			depth--
+			switch strings.ToUpper(l.value) {
+			case "VEVENT":
+				if event != nil && !skip {
+					if finishEvent(event) {
+						calendar.Events = append(calendar.Events, *event)
+					} else {
+						calendar.Invalid++
+					}
+				}
+				event = nil
+			case "VALARM":
+				if event != nil && alarm != nil {
+					event.Alarms = append(event.Alarms, *alarm)
+				}
+				alarm = nil
+			}
+		case alarm != nil:
+			p.alarmProperty(alarm, l)
+		case event != nil && !skip:
+			if l.name == "RECURRENCE-ID" {
+				skip = true
+			}
+			if err := p.eventProperty(event, l); err != nil {
+				skip = true
+				calendar.Invalid++
+			}
+		case depth == 1 && l.name == "X-WR-CALNAME":
+			calendar.Name = unescapeText(l.value)
+		case depth == 1 && l.name == "PRODID":
+			calendar.ProdID = l.value
+		}
+	}
+	if !found {
+		return nil, ErrNoCalendar
+	}
+	return calendar, nil
+}
+
+// contentLine is one unfolded "NAME;PARAM=VALUE:value" line.
+type contentLine struct {
+	num    int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold joins folded lines and splits each into name, parameters and value.
+func unfold(r io.Reader) ([]contentLine, error) {
+	reader := bufio.NewReader(r)
+	var raw []string
+	var num []int
+	for n := 1; ; n++ {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			if (line[0] == ' ' || line[0] == '\t') && len(raw) > 0 {
+				last := len(raw) - 1
+				raw[last] += line[1:]
+				if len(raw[last]) > maxContentLine {
+					return nil, fmt.Errorf("line %d: content line too long", num[last])
+				}
+			} else {
+				raw = append(raw, line)
+				num = append(num, n)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	lines := make([]contentLine, 0, len(raw))
+	for i, s := range raw {
+		l, err := splitContentLine(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", num[i], err)
+		}
+		l.num = num[i]
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+func splitContentLine(s string) (contentLine, error) {
+	l := contentLine{params: map[string]string{}}
+
+	// The value starts at the first colon outside a quoted parameter value
+	quoted := false
+	colon := -1
+	for i := 0; i < len(s) && colon < 0; i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				colon = i
+			}
+		}
+	}
+	if colon < 0 {
+		return l, fmt.Errorf("missing ':' in %q", s)
+	}
+	l.value = s[colon+1:]
+
+	parts := splitOutsideQuotes(s[:colon], ';')
+	l.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		l.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+	return l, nil
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+type parser struct {
+	loc   *time.Location
+	zones map[string]*time.Location
+
+	// VTIMEZONE being scanned
+	zoneID     string
+	zoneOffset string
+	inStandard bool
+}
+
+// scanZone collects a fixed-offset fallback for each VTIMEZONE, taken from
+// its STANDARD observance.
+func (p *parser) scanZone(l contentLine) {
+	switch {
+	case l.name == "BEGIN" && strings.EqualFold(l.value, "VTIMEZONE"):
+		p.zoneID, p.zoneOffset = "", ""
+	case l.name == "BEGIN" && strings.EqualFold(l.value, "STANDARD"):
+		p.inStandard = true
+	case l.name == "END" && strings.EqualFold(l.value, "STANDARD"):
+		p.inStandard = false
+	case l.name == "END" && strings.EqualFold(l.value, "VTIMEZONE"):
+		if p.zoneID == "" || p.zoneOffset == "" {
+			return
+		}
+		if offset, err := parseOffset(p.zoneOffset); err == nil {
+			p.zones[p.zoneID] = time.FixedZone(p.zoneID, offset)
+		}
+	case l.name == "TZID" && !p.inStandard:
+		p.zoneID = l.value
+	case l.name == "TZOFFSETTO" && p.inStandard && p.zoneOffset == "":
+		p.zoneOffset = l.value
+	}
+}
+
+// zone resolves a TZID, preferring the IANA database over the VTIMEZONE
+// fallback because the latter ignores daylight saving.
+func (p *parser) zone(tzid string) *time.Location {
+	if tzid == "" {
+		return p.loc
+	}
+	if loc, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+		return loc
+	}
+	if loc, ok := p.zones[tzid]; ok {
+		return loc
+	}
+	return p.loc
+}
+
+func (p *parser) eventProperty(e *Event, l contentLine) error {
+	var err error
+	switch l.name {
+	case "UID":
+		e.UID = l.value
+	case "SUMMARY":
+		e.Summary = unescapeText(l.value)
+	case "DESCRIPTION":
+		e.Description = unescapeText(l.value)
+	case "DTSTART":
+		e.Start, e.AllDay, err = p.parseTime(l)
+	case "DTEND":
+		e.End, _, err = p.parseTime(l)
+	case "DURATION":
+		var d time.Duration
+		if d, err = parseDuration(l.value); err == nil {
+			e.duration = d
+		}
+	case "RRULE":
+		e.RRule = l.value
+	case "STATUS":
+		e.Status = EventStatus(strings.ToUpper(l.value))
+	case "CATEGORIES":
+		for _, category := range splitText(l.value) {
+			if category = strings.TrimSpace(category); category != "" {
+				e.Categories = append(e.Categories, category)
+			}
+		}
+	case "PRIORITY":
+		// An unreadable priority isn't worth rejecting the event for
+		e.Priority, _ = strconv.Atoi(l.value)
+	case "CREATED":
+		e.Created, _, err = p.parseTime(l)
+	case "LAST-MODIFIED":
+		e.LastModified, _, err = p.parseTime(l)
+	}
+	if err != nil {
+		return fmt.Errorf("line %d: invalid %s: %w", l.num, l.name, err)
+	}
+	return nil
+}
+
+func (p *parser) alarmProperty(a *Alarm, l contentLine) {
+	switch l.name {
+	case "DESCRIPTION":
+		a.Description = unescapeText(l.value)
+	case "TRIGGER":
+		if strings.EqualFold(l.params["VALUE"], "DATE-TIME") {
+			if at, _, err := p.parseTime(l); err == nil {
+				a.at = at
+			}
+			return
+		}
+		// Alarms relative to the end of the event aren't supported
+		if strings.EqualFold(l.params["RELATED"], "END") {
+			return
+		}
+		if d, err := parseDuration(l.value); err == nil {
+			a.Before = -d
+		}
+	}
+}
+
+// finishEvent fills in fields derived from others. It returns false if the
+// event is unusable.
+func finishEvent(e *Event) bool {
+	if e.Start.IsZero() {
+		return false
+	}
+	if e.End.IsZero() {
+		switch {
+		case e.duration != 0:
+			e.End = e.Start.Add(e.duration)
+		case e.AllDay:
+			e.End = e.Start.AddDate(0, 0, 1)
+		}
+	}
+	for i := range e.Alarms {
+		if !e.Alarms[i].at.IsZero() {
+			e.Alarms[i].Before = e.Start.Sub(e.Alarms[i].at)
+		}
+	}
+	return true
+}
+
+// parseTime reads a DATE or DATE-TIME value, reporting whether it was a date.
+func (p *parser) parseTime(l contentLine) (time.Time, bool, error) {
+	value := l.value
+	if strings.EqualFold(l.params["VALUE"], "DATE") || len(value) == len(dateLayout) {
+		t, err := time.ParseInLocation(dateLayout, value, p.loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(utcLayout, value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation(localLayout, value, p.zone(l.params["TZID"]))
+	return t, false, err
+}
+
+// parseDuration parses an RFC 5545 duration such as "-PT15M" or "P1DT2H".
+func parseDuration(s string) (time.Duration, error) {
+	rest := s
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(rest, "-"):
+		sign, rest = -1, rest[1:]
+	case strings.HasPrefix(rest, "+"):
+		rest = rest[1:]
+	}
+	if !strings.HasPrefix(rest, "P") || len(rest) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	rest = rest[1:]
+
+	var d time.Duration
+	inTime := false
+	number := ""
+	for _, c := range rest {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		number = ""
+		switch {
+		case c == 'W' && !inTime:
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return sign * d, nil
+}
+
+// parseOffset parses a UTC offset such as "+0700" or "-053000" into seconds.
+func parseOffset(s string) (int, error) {
+	if len(s) != 5 && len(s) != 7 || (s[0] != '+' && s[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	hours, err1 := strconv.Atoi(s[1:3])
+	minutes, err2 := strconv.Atoi(s[3:5])
+	seconds := 0
+	var err3 error
+	if len(s) == 7 {
+		seconds, err3 = strconv.Atoi(s[5:7])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("invalid UTC offset %q", s)
+	}
+	offset := hours*3600 + minutes*60 + seconds
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+var textUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	`\;`, ";",
+	`\,`, ",",
+	`\n`, "\n",
+	`\N`, "\n",
+)
+
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
+
+// splitText splits a multi-valued TEXT property on unescaped commas and
+// unescapes each value.
+func splitText(s string) []string {
+	var values []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			values = append(values, unescapeText(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(values, unescapeText(s[start:]))
+}
@@ -0,0 +1,464 @@
+package ical
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendarOf wraps content lines in a VCALENDAR, separated by CRLF.
+func calendarOf(lines ...string) string {
+	return crlf(append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR")...)
+}
+
+// equalEvents compares the fields Parse fills in, times by instant.
+func equalEvents(got, want Event) bool {
+	if len(got.Alarms) != len(want.Alarms) {
+		return false
+	}
+	for i := range got.Alarms {
+		if got.Alarms[i].Before != want.Alarms[i].Before || got.Alarms[i].Description != want.Alarms[i].Description {
+			return false
+		}
+	}
+	return got.UID == want.UID &&
+		got.Summary == want.Summary &&
+		got.Description == want.Description &&
+		got.Start.Equal(want.Start) &&
+		got.End.Equal(want.End) &&
+		got.AllDay == want.AllDay &&
+		got.Status == want.Status &&
+		slices.Equal(got.Categories, want.Categories) &&
+		got.RRule == want.RRule &&
+		got.Priority == want.Priority &&
+		got.Created.Equal(want.Created) &&
+		got.LastModified.Equal(want.LastModified)
+}
+
+func TestParse(t *testing.T) {
+	jakarta := mustLoadLocation(t, "Asia/Jakarta")
+	newYork := mustLoadLocation(t, "America/New_York")
+
+	tests := []struct {
+		name        string
+		ics         string
+		want        []Event
+		wantInvalid int
+	}{
+		{
+			name: "folded lines",
+			ics: "BEGIN:VCALENDAR\r\n" +
+				"BEGIN:VEVENT\r\n" +
+				"UID:fold-1\r\n" +
+				"DTSTART:20240311T\r\n 010000Z\r\n" +
+				"SUMMARY:Olahraga pagi di taman kota bersama teman-teman kantor dan kelu\r\n" +
+				" arga besar\r\n" +
+				"DESCRIPTION:Bawa air \r\n\tminum\r\n" +
+				"END:VEVENT\r\n" +
+				"END:VCALENDAR\r\n",
+			want: []Event{{
+				UID:         "fold-1",
+				Summary:     "Olahraga pagi di taman kota bersama teman-teman kantor dan keluarga besar",
+				Description: "Bawa air minum",
+				Start:       time.Date(2024, time.March, 11, 1, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "bare LF line endings",
+			ics: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:lf-1\nDTSTART:20240311T010000Z\n" +
+				"SUMMARY:Rapat\n  tim\nEND:VEVENT\nEND:VCALENDAR",
+			want: []Event{{
+				UID:     "lf-1",
+				Summary: "Rapat tim",
+				Start:   time.Date(2024, time.March, 11, 1, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "TZID from the IANA database",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:tz-1",
+				"DTSTART;TZID=America/New_York:20240701T070000",
+				"DTEND;TZID=America/New_York:20240701T080000",
+				"SUMMARY:Jalan pagi",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:     "tz-1",
+				Summary: "Jalan pagi",
+				Start:   time.Date(2024, time.July, 1, 7, 0, 0, 0, newYork),
+				End:     time.Date(2024, time.July, 1, 8, 0, 0, 0, newYork),
+			}},
+		},
+		{
+			name: "quoted TZID with a leading slash",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:tz-2",
+				`DTSTART;TZID="/Asia/Jakarta":20240311T060000`,
+				"SUMMARY:Minum obat",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:     "tz-2",
+				Summary: "Minum obat",
+				Start:   time.Date(2024, time.March, 11, 6, 0, 0, 0, jakarta),
+			}},
+		},
+		{
+			name: "unknown TZID falls back to the VTIMEZONE after the event",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:tz-3",
+				"DTSTART;TZID=Kantor Pusat:20240311T090000",
+				"SUMMARY:Rapat",
+				"END:VEVENT",
+				"BEGIN:VTIMEZONE",
+				"TZID:Kantor Pusat",
+				"BEGIN:DAYLIGHT",
+				"TZOFFSETTO:+0900",
+				"END:DAYLIGHT",
+				"BEGIN:STANDARD",
+				"DTSTART:19700101T000000",
+				"TZOFFSETFROM:+0800",
+				"TZOFFSETTO:+0800",
+				"END:STANDARD",
+				"END:VTIMEZONE",
+			),
+			want: []Event{{
+				UID:     "tz-3",
+				Summary: "Rapat",
+				Start:   time.Date(2024, time.March, 11, 1, 0, 0, 0, time.UTC),
+			}},
+		},
+		{
+			name: "unknown TZID without VTIMEZONE is taken to be local",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:tz-4",
+				"DTSTART;TZID=Kantor Cabang:20240311T090000",
+				"SUMMARY:Rapat",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:     "tz-4",
+				Summary: "Rapat",
+				Start:   time.Date(2024, time.March, 11, 9, 0, 0, 0, jakarta),
+			}},
+		},
+		{
+			name: "floating and UTC times",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:float-1",
+				"DTSTART:20240311T060000",
+				"DTEND:20240311T010000Z",
+				"SUMMARY:Sarapan",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:     "float-1",
+				Summary: "Sarapan",
+				Start:   time.Date(2024, time.March, 11, 6, 0, 0, 0, jakarta),
+				End:     time.Date(2024, time.March, 11, 8, 0, 0, 0, jakarta),
+			}},
+		},
+		{
+			name: "all-day events",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:day-1",
+				"DTSTART;VALUE=DATE:20240315",
+				"SUMMARY:Kontrol ke dokter",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:day-2",
+				"DTSTART:20240320",
+				"DTEND:20240323",
+				"SUMMARY:Cuti",
+				"END:VEVENT",
+			),
+			want: []Event{
+				{
+					UID:     "day-1",
+					Summary: "Kontrol ke dokter",
+					Start:   time.Date(2024, time.March, 15, 0, 0, 0, 0, jakarta),
+					End:     time.Date(2024, time.March, 16, 0, 0, 0, 0, jakarta),
+					AllDay:  true,
+				},
+				{
+					UID:     "day-2",
+					Summary: "Cuti",
+					Start:   time.Date(2024, time.March, 20, 0, 0, 0, 0, jakarta),
+					End:     time.Date(2024, time.March, 23, 0, 0, 0, 0, jakarta),
+					AllDay:  true,
+				},
+			},
+		},
+		{
+			name: "properties, duration and alarms",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:full-1",
+				"DTSTART:20240311T010000Z",
+				"DURATION:PT45M",
+				`SUMMARY:Minum obat\; setelah makan`,
+				`DESCRIPTION:Dosis: 1 tablet\nJangan lupa`,
+				"STATUS:confirmed",
+				`CATEGORIES:Kesehatan,Obat\, vitamin, `,
+				"RRULE:FREQ=WEEKLY;BYDAY=MO",
+				"PRIORITY:1",
+				"CREATED:20240301T100000Z",
+				"LAST-MODIFIED:20240302T100000Z",
+				"BEGIN:VALARM",
+				"ACTION:DISPLAY",
+				"TRIGGER:-PT15M",
+				"DESCRIPTION:Sebentar lagi",
+				"END:VALARM",
+				"BEGIN:VALARM",
+				"TRIGGER;VALUE=DATE-TIME:20240311T000000Z",
+				"END:VALARM",
+				"BEGIN:VALARM",
+				"TRIGGER;RELATED=END:-PT5M",
+				"END:VALARM",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:          "full-1",
+				Summary:      "Minum obat; setelah makan",
+				Description:  "Dosis: 1 tablet\nJangan lupa",
+				Start:        time.Date(2024, time.March, 11, 1, 0, 0, 0, time.UTC),
+				End:          time.Date(2024, time.March, 11, 1, 45, 0, 0, time.UTC),
+				Status:       StatusConfirmed,
+				Categories:   []string{"Kesehatan", "Obat, vitamin"},
+				RRule:        "FREQ=WEEKLY;BYDAY=MO",
+				Priority:     1,
+				Created:      time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+				LastModified: time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC),
+				Alarms: []Alarm{
+					{Before: 15 * time.Minute, Description: "Sebentar lagi"},
+					{Before: time.Hour},
+					{},
+				},
+			}},
+		},
+		{
+			name: "overrides and unreadable events are skipped",
+			ics: calendarOf(
+				"BEGIN:VEVENT",
+				"UID:series-1",
+				"DTSTART:20240311T010000Z",
+				"RRULE:FREQ=DAILY",
+				"SUMMARY:Jalan kaki",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:series-1",
+				"RECURRENCE-ID:20240312T010000Z",
+				"DTSTART:20240312T020000Z",
+				"SUMMARY:Jalan kaki (digeser)",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:bad-1",
+				"DTSTART:20241340T250000",
+				"SUMMARY:Tanggal rusak",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:bad-2",
+				"SUMMARY:Tanpa waktu",
+				"END:VEVENT",
+				"BEGIN:VEVENT",
+				"UID:bad-3",
+				"DTSTART:20240311T010000Z",
+				"DURATION:1 jam",
+				"END:VEVENT",
+			),
+			want: []Event{{
+				UID:     "series-1",
+				Summary: "Jalan kaki",
+				Start:   time.Date(2024, time.March, 11, 1, 0, 0, 0, time.UTC),
+				RRule:   "FREQ=DAILY",
+			}},
+			wantInvalid: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calendar, err := Parse(strings.NewReader(tt.ics), jakarta)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if calendar.Invalid != tt.wantInvalid {
+				t.Errorf("Invalid = %d, want %d", calendar.Invalid, tt.wantInvalid)
+			}
+			if len(calendar.Events) != len(tt.want) {
+				t.Fatalf("got %d events, want %d: %+v", len(calendar.Events), len(tt.want), calendar.Events)
+			}
+			for i := range tt.want {
+				if !equalEvents(calendar.Events[i], tt.want[i]) {
+					t.Errorf("event %d:\ngot  %+v\nwant %+v", i, calendar.Events[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		ics  string
+		want error
+	}{
+		{"empty", "", ErrNoCalendar},
+		{"not a calendar", crlf("BEGIN:VCARD", "FN:Budi", "END:VCARD"), ErrNoCalendar},
+		{"line without a colon", calendarOf("BEGIN:VEVENT", "SUMMARY Rapat", "END:VEVENT"), nil},
+		{"folded line too long", calendarOf("DESCRIPTION:" + strings.Repeat("\r\n "+strings.Repeat("x", 74), 1000)), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.ics), time.UTC)
+			if err == nil {
+				t.Fatal("Parse succeeded, want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Parse = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReadsCalendarName(t *testing.T) {
+	ics := calendarOf(
+		"PRODID:-//Google Inc//Google Calendar 70.9054//EN",
+		`X-WR-CALNAME:Jadwal\, Kuliah`,
+		"BEGIN:VEVENT",
+		"UID:name-1",
+		"DTSTART:20240311T010000Z",
+		"X-WR-CALNAME:Bukan nama kalender",
+		"END:VEVENT",
+	)
+	calendar, err := Parse(strings.NewReader(ics), time.UTC)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if calendar.Name != "Jadwal, Kuliah" {
+		t.Errorf("Name = %q, want %q", calendar.Name, "Jadwal, Kuliah")
+	}
+	if calendar.ProdID != "-//Google Inc//Google Calendar 70.9054//EN" {
+		t.Errorf("ProdID = %q", calendar.ProdID)
+	}
+}
+
+// What Encode writes, Parse reads back.
+func TestParseReadsEncodedCalendar(t *testing.T) {
+	jakarta := mustLoadLocation(t, "Asia/Jakarta")
+	want := []Event{
+		{
+			UID:          "act-1@smart-alert",
+			Summary:      strings.Repeat("Minum obat; setelah makan, ", 5),
+			Description:  "Dosis: 1 tablet\nJangan lupa \\ ya",
+			Start:        time.Date(2024, time.March, 11, 6, 0, 0, 0, jakarta),
+			End:          time.Date(2024, time.March, 11, 6, 30, 0, 0, jakarta),
+			Status:       StatusConfirmed,
+			Categories:   []string{"Kesehatan", "Obat, vitamin"},
+			RRule:        "FREQ=WEEKLY;BYDAY=MO",
+			Priority:     3,
+			Created:      time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+			LastModified: time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC),
+			Alarms:       []Alarm{{Before: 15 * time.Minute, Description: "Minum obat"}},
+		},
+		{
+			UID:          "act-2@smart-alert",
+			Summary:      "Kontrol ke dokter",
+			Start:        time.Date(2024, time.March, 15, 0, 0, 0, 0, jakarta),
+			End:          time.Date(2024, time.March, 16, 0, 0, 0, 0, jakarta),
+			AllDay:       true,
+			Status:       StatusTentative,
+			LastModified: time.Date(2024, time.March, 2, 10, 0, 0, 0, time.UTC),
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (&Calendar{ProdID: "-//Smart Alert System//ID", Name: "Kegiatan", Location: jakarta, Events: want}).Encode(&buf); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	calendar, err := Parse(&buf, jakarta)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if calendar.Name != "Kegiatan" || calendar.Invalid != 0 || len(calendar.Events) != len(want) {
+		t.Fatalf("got %+v", calendar)
+	}
+	for i := range want {
+		if !equalEvents(calendar.Events[i], want[i]) {
+			t.Errorf("event %d:\ngot  %+v\nwant %+v", i, calendar.Events[i], want[i])
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "PT15M", want: 15 * time.Minute},
+		{value: "-PT15M", want: -15 * time.Minute},
+		{value: "+PT1H30M", want: 90 * time.Minute},
+		{value: "P1DT2H", want: 26 * time.Hour},
+		{value: "P2W", want: 14 * 24 * time.Hour},
+		{value: "PT0S", want: 0},
+		{value: "", wantErr: true},
+		{value: "P", wantErr: true},
+		{value: "PT", wantErr: true},
+		{value: "15M", wantErr: true},
+		{value: "PT15", wantErr: true},
+		{value: "P1H", wantErr: true},
+		{value: "PTM", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseDuration(%q) = %s, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseDuration(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "+0700", want: 7 * 3600},
+		{value: "-0530", want: -(5*3600 + 30*60)},
+		{value: "+053045", want: 5*3600 + 30*60 + 45},
+		{value: "0700", wantErr: true},
+		{value: "+07", wantErr: true},
+		{value: "+07a0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseOffset(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseOffset(%q) = %d, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseOffset(%q) = %d, %v, want %d", tt.value, got, err, tt.want)
+		}
+	}
+}
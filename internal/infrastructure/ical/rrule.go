@@ -0,0 +1,53 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	supportedFreqs = map[string]bool{"DAILY": true, "WEEKLY": true, "MONTHLY": true, "YEARLY": true}
+	rruleParts     = map[string]bool{
+		"FREQ": true, "INTERVAL": true, "COUNT": true, "UNTIL": true, "WKST": true,
+		"BYDAY": true, "BYMONTHDAY": true, "BYMONTH": true, "BYSETPOS": true,
+	}
+)
+
+// NormalizeRRule validates a recurrence rule and returns it upper-cased and
+// without the "RRULE:" prefix. Only rules we can store and publish are
+// accepted: FREQ of DAILY, WEEKLY, MONTHLY or YEARLY plus the common parts.
+func NormalizeRRule(rule string) (string, error) {
+	rule = strings.ToUpper(strings.TrimSpace(rule))
+	rule = strings.TrimPrefix(rule, "RRULE:")
+	if rule == "" {
+		return "", fmt.Errorf("empty recurrence rule")
+	}
+
+	seen := map[string]string{}
+	for _, part := range strings.Split(rule, ";") {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return "", fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		if !rruleParts[name] {
+			return "", fmt.Errorf("unsupported recurrence rule part %q", name)
+		}
+		if _, dup := seen[name]; dup {
+			return "", fmt.Errorf("duplicate recurrence rule part %q", name)
+		}
+		for _, r := range value {
+			if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == ',' || r == '+' || r == '-') {
+				return "", fmt.Errorf("invalid value %q for %s", value, name)
+			}
+		}
+		seen[name] = value
+	}
+
+	if !supportedFreqs[seen["FREQ"]] {
+		return "", fmt.Errorf("unsupported FREQ %q", seen["FREQ"])
+	}
+	if seen["COUNT"] != "" && seen["UNTIL"] != "" {
+		return "", fmt.Errorf("COUNT and UNTIL must not both be set")
+	}
+	return rule, nil
+}
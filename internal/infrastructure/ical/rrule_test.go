@@ -0,0 +1,49 @@
+package ical
+
+import "testing"
+
+func TestNormalizeRRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{rule: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{rule: "rrule:freq=weekly;byday=mo,we", want: "FREQ=WEEKLY;BYDAY=MO,WE"},
+		{rule: "  FREQ=MONTHLY;BYDAY=-1FR  ", want: "FREQ=MONTHLY;BYDAY=-1FR"},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=15;COUNT=6", want: "FREQ=MONTHLY;BYMONTHDAY=15;COUNT=6"},
+		{rule: "FREQ=YEARLY;BYMONTH=8;BYMONTHDAY=17", want: "FREQ=YEARLY;BYMONTH=8;BYMONTHDAY=17"},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;UNTIL=20241231T170000Z;WKST=SU", want: "FREQ=WEEKLY;INTERVAL=2;UNTIL=20241231T170000Z;WKST=SU"},
+		{rule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", want: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{rule: "", wantErr: true},
+		{rule: "RRULE:", wantErr: true},
+		{rule: "BYDAY=MO", wantErr: true},
+		{rule: "FREQ=HOURLY", wantErr: true},
+		{rule: "FREQ=DAILY;BYHOUR=6", wantErr: true},
+		{rule: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=3;UNTIL=20241231T170000Z", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT", wantErr: true},
+		{rule: "FREQ=DAILY;COUNT=", wantErr: true},
+		{rule: "FREQ=DAILY;", wantErr: true},
+		{rule: "FREQ=WEEKLY;BYDAY=MO TU", wantErr: true},
+		{rule: "FREQ=DAILY\r\nX-INJECTED:1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := NormalizeRRule(tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("NormalizeRRule = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeRRule: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeRRule = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// CorrelationHeader carries the correlation ID of an HTTP request and its
+// response.
+const CorrelationHeader = "X-Request-ID"
+
+type correlationKey struct{}
+
+// WithCorrelationID returns ctx carrying the correlation ID.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, correlationKey{}, id)
+}
+
+// CorrelationID returns the correlation ID of ctx, "" if it has none.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationKey{}).(string)
+	return id
+}
+
+// NewCorrelationID returns a new random correlation ID.
+func NewCorrelationID() string {
+	return uuid.NewString()
+}
+
+// Detach returns a background context with the correlation ID of ctx, for
+// work that outlives the request, like messages processed after the webhook
+// has been answered.
+func Detach(ctx context.Context) context.Context {
+	return WithCorrelationID(context.Background(), CorrelationID(ctx))
+}
+
+// Middleware gives every request a correlation ID, taken from its
+// X-Request-ID header if the caller set one, and echoes it in the response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(CorrelationHeader)
+		if id == "" || len(id) > 64 {
+			id = NewCorrelationID()
+		}
+		w.Header().Set(CorrelationHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithCorrelationID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,137 @@
+// Package logging sets up structured JSON logging with log/slog. Records
+// logged with a context carry the correlation ID of the request they belong
+// to and its trace ID, if any, and attributes that hold personal data are
+// redacted unless redaction is turned off for debugging.
+//
+// Personal data is recognized by attribute key, so log it under one of the
+// keys below rather than inside the message:
+//
+//   - "body", "text", "transcript", "response", "payload": message contents,
+//     replaced by their length
+//   - "phone", "chat_id", "sender", "recipient": phone numbers and chat IDs,
+//     all but the last 4 digits masked
+//   - "message_id", "poll_id": message IDs, which on WhatsApp embed the chat
+//     ID ("true_6281234567890@c.us_3EB0…"), with the chat ID masked
+//
+// Errors often quote what they failed on, e.g. a Waha error body naming the
+// chat, so chat IDs and phone numbers in "error" attributes are masked too.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Config controls the logger built by Setup.
+type Config struct {
+	Level slog.Level
+	// RedactPII masks message contents, phone numbers and chat IDs. Turn it
+	// off only to debug.
+	RedactPII bool
+}
+
+// Setup builds the JSON logger writing to w and makes it the default for
+// both slog and the standard log package.
+func Setup(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	if cfg.RedactPII {
+		opts.ReplaceAttr = redact
+	}
+	logger := slog.New(&contextHandler{Handler: slog.NewJSONHandler(w, opts)})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error".
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", level)
+	}
+	return l, nil
+}
+
+var (
+	contentKeys = map[string]bool{"body": true, "text": true, "transcript": true, "response": true, "payload": true}
+	addressKeys = map[string]bool{"phone": true, "chat_id": true, "sender": true, "recipient": true}
+	// Keys whose values may embed an address among other things
+	embeddingKeys = map[string]bool{"message_id": true, "poll_id": true}
+	// Chat IDs and runs of digits as long as a phone number, LID or Telegram
+	// chat ID
+	embeddedAddress = regexp.MustCompile(`\+?[0-9]{8,}(@[a-z.]+)?`)
+)
+
+// redact replaces the values of attributes that hold personal data.
+func redact(groups []string, a slog.Attr) slog.Attr {
+	switch {
+	case contentKeys[a.Key]:
+		if a.Value.Kind() == slog.KindString {
+			return slog.String(a.Key, fmt.Sprintf("[redacted %d chars]", len(a.Value.String())))
+		}
+		return slog.String(a.Key, "[redacted]")
+	case addressKeys[a.Key]:
+		if a.Value.Kind() == slog.KindString {
+			return slog.String(a.Key, MaskAddress(a.Value.String()))
+		}
+		return slog.String(a.Key, "[redacted]")
+	case a.Key == "error":
+		return slog.String(a.Key, MaskAddresses(a.Value.String()))
+	case embeddingKeys[a.Key]:
+		// Our own IDs are UUIDs or integers, logged as such
+		if a.Value.Kind() == slog.KindString {
+			return slog.String(a.Key, MaskAddresses(a.Value.String()))
+		}
+	}
+	return a
+}
+
+// MaskAddress masks all but the last 4 characters of the user part of a phone
+// number or chat ID: "6281234567890@c.us" becomes "*********7890@c.us".
+func MaskAddress(address string) string {
+	user, server, found := strings.Cut(address, "@")
+	if len(user) > 4 {
+		user = strings.Repeat("*", len(user)-4) + user[len(user)-4:]
+	} else {
+		user = strings.Repeat("*", len(user))
+	}
+	if found {
+		return user + "@" + server
+	}
+	return user
+}
+
+// MaskAddresses masks the phone numbers and chat IDs in text with
+// MaskAddress.
+func MaskAddresses(text string) string {
+	return embeddedAddress.ReplaceAllStringFunc(text, MaskAddress)
+}
+
+// contextHandler adds the correlation ID and the trace of the record's
+// context.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		r.AddAttrs(slog.String("correlation_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
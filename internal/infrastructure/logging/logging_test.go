@@ -0,0 +1,52 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRedact(t *testing.T) {
+	outboxID := uuid.MustParse("12345678-1234-1234-1234-123456789012")
+
+	tests := []struct {
+		name  string
+		key   string
+		value any
+		want  any
+	}{
+		{"message text", "text", "Besok saya olahraga jam 6", "[redacted 25 chars]"},
+		{"chat ID", "chat_id", "6281234567890@c.us", "*********7890@c.us"},
+		{"LID sender", "sender", "123456789012345@lid", "***********2345@lid"},
+		{"non-string address", "phone", 6281234567890, "[redacted]"},
+		{"chat ID in an error", "error", errors.New(`unexpected status code 400: {"chatId":"6281234567890@c.us"}`),
+			`unexpected status code 400: {"chatId":"*********7890@c.us"}`},
+		{"c.us chat ID in a message ID", "message_id", "true_6281234567890@c.us_3EB0C431C26A1916E5A7",
+			"true_*********7890@c.us_3EB0C431C26A1916E5A7"},
+		{"LID in a poll ID", "poll_id", "false_123456789012345@lid_3EB0AB12",
+			"false_***********2345@lid_3EB0AB12"},
+		{"outbox message ID", "message_id", outboxID, outboxID.String()},
+		{"Telegram message ID", "message_id", 42, float64(42)},
+		{"other keys", "user_id", "6281234567890", "6281234567890"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: redact}))
+			logger.Info("test", tt.key, tt.value)
+
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("decode %q: %v", buf.String(), err)
+			}
+			if got := record[tt.key]; got != tt.want {
+				t.Errorf("%s = %#v, want %#v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// ErrClosed is returned by Submit once the mailbox has been shut down.
+var ErrClosed = errors.New("mailbox closed")
+
+// Mailbox runs jobs sequentially per key while different keys run in parallel.
+// Each key gets its own goroutine only while it has queued work, so idle users
+// don't hold on to goroutines.
+type Mailbox struct {
+	mu     sync.Mutex
+	queues map[string][]func()
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewMailbox() *Mailbox {
+	return &Mailbox{
+		queues: make(map[string][]func()),
+	}
+}
+
+// Submit queues job behind every job previously submitted for the same key.
+func (m *Mailbox) Submit(key string, job func()) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return ErrClosed
+	}
+
+	queue, running := m.queues[key]
+	m.queues[key] = append(queue, job)
+	if !running {
+		m.wg.Add(1)
+		go m.drain(key)
+	}
+
+	return nil
+}
+
+// Pending returns the number of jobs waiting (not yet started) for key.
+func (m *Mailbox) Pending(key string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.queues[key])
+}
+
+// Shutdown stops accepting new jobs and waits for queued jobs to finish.
+func (m *Mailbox) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Mailbox) drain(key string) {
+	defer m.wg.Done()
+
+	for {
+		m.mu.Lock()
+		queue := m.queues[key]
+		if len(queue) == 0 {
+			delete(m.queues, key)
+			m.mu.Unlock()
+			return
+		}
+		job := queue[0]
+		queue[0] = nil
+		m.queues[key] = queue[1:]
+		m.mu.Unlock()
+
+		m.run(key, job)
+	}
+}
+
+func (m *Mailbox) run(key string, job func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Mailbox job panicked", "sender", key, "panic", r)
+		}
+	}()
+	job()
+}
@@ -0,0 +1,150 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitRunsJobsInOrderPerKey(t *testing.T) {
+	const submitters, jobsPerSubmitter = 20, 50
+
+	m := NewMailbox()
+	var (
+		mu      sync.Mutex
+		ran     = make(map[int][]int)
+		active  atomic.Int32
+		overlap atomic.Bool
+	)
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for s := 0; s < submitters; s++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			for j := 0; j < jobsPerSubmitter; j++ {
+				err := m.Submit("chat", func() {
+					if active.Add(1) > 1 {
+						overlap.Store(true)
+					}
+					mu.Lock()
+					ran[s] = append(ran[s], j)
+					mu.Unlock()
+					active.Add(-1)
+				})
+				if err != nil {
+					t.Errorf("Submit: %v", err)
+				}
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if overlap.Load() {
+		t.Error("jobs for the same key ran concurrently")
+	}
+	for s := 0; s < submitters; s++ {
+		if len(ran[s]) != jobsPerSubmitter {
+			t.Fatalf("submitter %d: %d jobs ran, want %d", s, len(ran[s]), jobsPerSubmitter)
+		}
+		for j, got := range ran[s] {
+			if got != j {
+				t.Fatalf("submitter %d: job %d ran at position %d", s, got, j)
+			}
+		}
+	}
+}
+
+func TestSubmitRunsDifferentKeysInParallel(t *testing.T) {
+	m := NewMailbox()
+	defer m.Shutdown(context.Background())
+
+	// Each job waits for the other to start, which only happens if they run
+	// at the same time
+	var started sync.WaitGroup
+	started.Add(2)
+	both := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(both)
+	}()
+
+	done := make(chan string, 2)
+	for _, key := range []string{"alice", "bob"} {
+		m.Submit(key, func() {
+			started.Done()
+			select {
+			case <-both:
+				done <- key
+			case <-time.After(2 * time.Second):
+				done <- ""
+			}
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		if key := <-done; key == "" {
+			t.Fatal("jobs for different keys did not run in parallel")
+		}
+	}
+}
+
+func TestShutdownDrainsQueuedJobs(t *testing.T) {
+	m := NewMailbox()
+
+	var ran atomic.Int32
+	for i := 0; i < 10; i++ {
+		m.Submit("chat", func() {
+			time.Sleep(5 * time.Millisecond)
+			ran.Add(1)
+		})
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := ran.Load(); got != 10 {
+		t.Errorf("%d jobs ran before Shutdown returned, want 10", got)
+	}
+	if err := m.Submit("chat", func() {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit after Shutdown = %v, want ErrClosed", err)
+	}
+}
+
+func TestShutdownHonorsContext(t *testing.T) {
+	m := NewMailbox()
+	release := make(chan struct{})
+	defer close(release)
+	m.Submit("chat", func() { <-release })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestPanickingJobDoesNotStopLaterJobs(t *testing.T) {
+	m := NewMailbox()
+
+	var ran atomic.Bool
+	m.Submit("chat", func() { panic("boom") })
+	m.Submit("chat", func() { ran.Store(true) })
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !ran.Load() {
+		t.Error("job after a panicking job did not run")
+	}
+}
@@ -0,0 +1,116 @@
+// Package metrics defines the Prometheus metrics of the server and serves
+// them on /metrics. Metrics are package variables so any layer can record
+// them without passing a registry around.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "smart_alert"
+
+// LLM token kinds.
+const (
+	TokensPrompt     = "prompt"
+	TokensCompletion = "completion"
+)
+
+// LLM cache results. A shared request waited for an identical one in flight.
+const (
+	CacheHit    = "hit"
+	CacheMiss   = "miss"
+	CacheShared = "shared"
+)
+
+// Alert outcomes.
+const (
+	AlertSent   = "sent"
+	AlertFailed = "failed"
+)
+
+// Registry holds the metrics below plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	WebhooksReceived = counterVec("webhooks_received_total",
+		"Webhook requests received, by channel.", "channel")
+	WebhooksRejected = counterVec("webhooks_rejected_total",
+		"Webhook requests rejected, by channel and reason.", "channel", "reason")
+
+	Intents = counterVec("intents_total",
+		"Detected intents, by intent type and source (ai, text_extraction, fallback).", "intent", "source")
+
+	LLMRequestDuration = histogramVec("llm_request_duration_seconds",
+		"Latency of LLM requests, by provider, model and operation.",
+		[]float64{0.25, 0.5, 1, 2, 5, 10, 20, 30, 60, 120}, "provider", "model", "operation")
+	LLMTokens = counterVec("llm_tokens_total",
+		"LLM tokens used, by provider, model and kind (prompt, completion).", "provider", "model", "kind")
+	LLMErrors = counterVec("llm_errors_total",
+		"Failed LLM requests, by provider, model and operation.", "provider", "model", "operation")
+
+	LLMCacheRequests = counterVec("llm_cache_requests_total",
+		"LLM cache lookups, by operation and result (hit, miss, shared).", "operation", "result")
+	LLMTokensSaved = counterVec("llm_tokens_saved_total",
+		"LLM tokens not spent thanks to the cache, by provider, model and kind (prompt, completion).", "provider", "model", "kind")
+
+	WahaSendDuration = histogramVec("waha_send_duration_seconds",
+		"Latency of Waha send requests, by session and endpoint.",
+		prometheus.DefBuckets, "session", "endpoint")
+	WahaSendFailures = counterVec("waha_send_failures_total",
+		"Failed Waha send requests, by session and endpoint.", "session", "endpoint")
+
+	Alerts = counterVec("alerts_total",
+		"Alerts, by alert type and outcome: sent or failed by the outbox, or failed before being queued.", "type", "outcome")
+
+	SchedulerJobDuration = histogramVec("scheduler_job_duration_seconds",
+		"Duration of scheduler job runs, by job.",
+		[]float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600}, "job")
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format. With a token,
+// scrapes must send it as "Authorization: Bearer <token>".
+func Handler(token string) http.Handler {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return h
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// Since returns the seconds elapsed since start, for observing histograms.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+func counterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	Registry.MustRegister(c)
+	return c
+}
+
+func histogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{Namespace: namespace, Name: name, Help: help, Buckets: buckets}, labels)
+	Registry.MustRegister(h)
+	return h
+}
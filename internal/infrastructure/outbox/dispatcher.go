@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"smart_alert_system/internal/usecase"
+)
+
+// minRetryWait keeps the dispatcher from spinning on throttled messages.
+const minRetryWait = 100 * time.Millisecond
+
+// Dispatcher periodically drains the outbox and hands due messages to Waha.
+type Dispatcher struct {
+	outboxUC  *usecase.OutboxUseCase
+	interval  time.Duration
+	batchSize int
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(outboxUC *usecase.OutboxUseCase, interval time.Duration, batchSize int) *Dispatcher {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	if batchSize <= 0 {
+		batchSize = 20
+	}
+	return &Dispatcher{
+		outboxUC:  outboxUC,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (d *Dispatcher) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		for {
+			timer := time.NewTimer(d.wait(d.drain(ctx)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}()
+
+	slog.Info("Outbox dispatcher started", "interval", d.interval.String(), "batch_size", d.batchSize)
+}
+
+// drain keeps dispatching full batches until the queue has no due messages
+// left. It returns the earliest time a throttled message may go out, zero if
+// none was throttled.
+func (d *Dispatcher) drain(ctx context.Context) time.Time {
+	var retryAt time.Time
+	for ctx.Err() == nil {
+		result, err := d.outboxUC.DispatchDue(ctx, d.batchSize)
+		if !result.RetryAt.IsZero() && (retryAt.IsZero() || result.RetryAt.Before(retryAt)) {
+			retryAt = result.RetryAt
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Failed to dispatch outbox", "error", err)
+			}
+			return retryAt
+		}
+		if result.Claimed < d.batchSize {
+			return retryAt
+		}
+	}
+	return retryAt
+}
+
+// wait returns how long to sleep before the next drain: the poll interval, or
+// less if a throttled message becomes sendable sooner, so the send limits
+// rather than the poll interval set the pace.
+func (d *Dispatcher) wait(retryAt time.Time) time.Duration {
+	if retryAt.IsZero() {
+		return d.interval
+	}
+	return min(max(time.Until(retryAt), minRetryWait), d.interval)
+}
+
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+	slog.Info("Outbox dispatcher stopped")
+}
@@ -20,29 +20,32 @@ func NewActivityRepository(db *database.PostgresDB) *activityRepository {
 
 func (r *activityRepository) Create(ctx context.Context, activity *entity.Activity) error {
 	query := `INSERT INTO activities (id, user_id, category_id, title, description, scheduled_time, 
-	          reminder_time, status, priority, created_at, updated_at, completed_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	          reminder_time, status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid,
+	          assignee_id)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`
 	
 	_, err := r.db.DB.ExecContext(ctx, query,
 		activity.ID, activity.UserID, activity.CategoryID, activity.Title, activity.Description,
 		activity.ScheduledTime, activity.ReminderTime, activity.Status, activity.Priority,
-		activity.CreatedAt, activity.UpdatedAt, activity.CompletedAt)
+		activity.CreatedAt, activity.UpdatedAt, activity.CompletedAt, nullString(activity.RecurrenceRule),
+		nullString(activity.ExternalUID), activity.AssigneeID)
 	return err
 }
 
 func (r *activityRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Activity, error) {
 	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
-	          status, priority, created_at, updated_at, completed_at
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
 	          FROM activities WHERE id = $1`
 	
 	activity := &entity.Activity{}
-	var categoryID sql.NullString
+	var categoryID, recurrenceRule, externalUID, assigneeID sql.NullString
 	var reminderTime, completedAt sql.NullTime
 	
 	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
 		&activity.ID, &activity.UserID, &categoryID, &activity.Title, &activity.Description,
 		&activity.ScheduledTime, &reminderTime, &activity.Status, &activity.Priority,
-		&activity.CreatedAt, &activity.UpdatedAt, &completedAt)
+		&activity.CreatedAt, &activity.UpdatedAt, &completedAt, &recurrenceRule, &externalUID,
+		&activity.PostponeCount, &assigneeID)
 	
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -61,13 +64,19 @@ func (r *activityRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity
 	if completedAt.Valid {
 		activity.CompletedAt = &completedAt.Time
 	}
+	activity.RecurrenceRule = recurrenceRule.String
+	activity.ExternalUID = externalUID.String
+	if assigneeID.Valid {
+		id, _ := uuid.Parse(assigneeID.String)
+		activity.AssigneeID = &id
+	}
 	
 	return activity, nil
 }
 
 func (r *activityRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.Activity, error) {
 	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
-	          status, priority, created_at, updated_at, completed_at
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
 	          FROM activities WHERE user_id = $1 ORDER BY scheduled_time ASC`
 	
 	return r.scanActivities(ctx, query, userID)
@@ -78,7 +87,7 @@ func (r *activityRepository) GetByUserIDAndDate(ctx context.Context, userID uuid
 	endOfDay := startOfDay.Add(24 * time.Hour)
 	
 	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
-	          status, priority, created_at, updated_at, completed_at
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
 	          FROM activities WHERE user_id = $1 AND scheduled_time >= $2 AND scheduled_time < $3
 	          ORDER BY scheduled_time ASC`
 	
@@ -93,7 +102,7 @@ func (r *activityRepository) GetByUserIDAndDate(ctx context.Context, userID uuid
 
 func (r *activityRepository) GetByUserIDAndStatus(ctx context.Context, userID uuid.UUID, status entity.ActivityStatus) ([]*entity.Activity, error) {
 	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
-	          status, priority, created_at, updated_at, completed_at
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
 	          FROM activities WHERE user_id = $1 AND status = $2 ORDER BY scheduled_time ASC`
 	
 	return r.scanActivities(ctx, query, userID, status)
@@ -101,13 +110,14 @@ func (r *activityRepository) GetByUserIDAndStatus(ctx context.Context, userID uu
 
 func (r *activityRepository) Update(ctx context.Context, activity *entity.Activity) error {
 	query := `UPDATE activities SET category_id = $1, title = $2, description = $3, scheduled_time = $4,
-	          reminder_time = $5, status = $6, priority = $7, updated_at = $8, completed_at = $9
-	          WHERE id = $10`
+	          reminder_time = $5, status = $6, priority = $7, updated_at = $8, completed_at = $9,
+	          recurrence_rule = $10, assignee_id = $11
+	          WHERE id = $12`
 	
 	_, err := r.db.DB.ExecContext(ctx, query,
 		activity.CategoryID, activity.Title, activity.Description, activity.ScheduledTime,
 		activity.ReminderTime, activity.Status, activity.Priority, activity.UpdatedAt,
-		activity.CompletedAt, activity.ID)
+		activity.CompletedAt, nullString(activity.RecurrenceRule), activity.AssigneeID, activity.ID)
 	return err
 }
 
@@ -128,7 +138,7 @@ func (r *activityRepository) GetCompletedToday(ctx context.Context, userID uuid.
 	endOfDay := startOfDay.Add(24 * time.Hour)
 	
 	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
-	          status, priority, created_at, updated_at, completed_at
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
 	          FROM activities WHERE user_id = $1 AND status = $2 AND completed_at >= $3 AND completed_at < $4
 	          ORDER BY completed_at ASC`
 	
@@ -141,6 +151,99 @@ func (r *activityRepository) GetCompletedToday(ctx context.Context, userID uuid.
 	return r.scanActivityRows(rows)
 }
 
+func (r *activityRepository) GetByUserIDBetween(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*entity.Activity, error) {
+	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
+	          FROM activities WHERE user_id = $1 AND scheduled_time >= $2 AND scheduled_time < $3
+	          ORDER BY scheduled_time ASC`
+
+	return r.scanActivities(ctx, query, userID, from, to)
+}
+
+func (r *activityRepository) GetUnconfirmedWithCaregivers(ctx context.Context, from, to time.Time) ([]*entity.Activity, error) {
+	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
+	          FROM activities
+	          WHERE status IN ($1, $4)
+	            AND scheduled_time BETWEEN $2 AND $3
+	            AND user_id IN (SELECT id FROM users WHERE is_active = true)
+	            AND user_id IN (SELECT user_id FROM caregivers)
+	          ORDER BY scheduled_time ASC`
+
+	return r.scanActivities(ctx, query, entity.ActivityStatusPending, from, to, entity.ActivityStatusOverdue)
+}
+
+func (r *activityRepository) GetByExternalUID(ctx context.Context, userID uuid.UUID, externalUID string) (*entity.Activity, error) {
+	query := `SELECT id, user_id, category_id, title, description, scheduled_time, reminder_time,
+	          status, priority, created_at, updated_at, completed_at, recurrence_rule, external_uid, postpone_count, assignee_id
+	          FROM activities WHERE user_id = $1 AND external_uid = $2`
+
+	activities, err := r.scanActivities(ctx, query, userID, externalUID)
+	if err != nil || len(activities) == 0 {
+		return nil, err
+	}
+	return activities[0], nil
+}
+
+func (r *activityRepository) Postpone(ctx context.Context, activity *entity.Activity, postponement *entity.ActivityPostponement) error {
+	tx, err := r.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE activities SET scheduled_time = $1, reminder_time = $2, status = $3, updated_at = $4,
+	          postpone_count = postpone_count + 1
+	          WHERE id = $5
+	          RETURNING postpone_count`
+	err = tx.QueryRowContext(ctx, query,
+		activity.ScheduledTime, activity.ReminderTime, activity.Status, activity.UpdatedAt,
+		activity.ID).Scan(&activity.PostponeCount)
+	if err != nil {
+		return err
+	}
+
+	query = `INSERT INTO activity_postponements (id, activity_id, user_id, alert_log_id, from_time, to_time, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.ExecContext(ctx, query,
+		postponement.ID, postponement.ActivityID, postponement.UserID, postponement.AlertLogID,
+		postponement.FromTime, postponement.ToTime, postponement.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *activityRepository) GetPostponementsBetween(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*entity.ActivityPostponement, error) {
+	query := `SELECT id, activity_id, user_id, alert_log_id, from_time, to_time, created_at
+	          FROM activity_postponements WHERE user_id = $1 AND created_at >= $2 AND created_at < $3
+	          ORDER BY created_at ASC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var postponements []*entity.ActivityPostponement
+	for rows.Next() {
+		postponement := &entity.ActivityPostponement{}
+		var alertLogID sql.NullString
+		err := rows.Scan(&postponement.ID, &postponement.ActivityID, &postponement.UserID, &alertLogID,
+			&postponement.FromTime, &postponement.ToTime, &postponement.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if alertLogID.Valid {
+			id, _ := uuid.Parse(alertLogID.String)
+			postponement.AlertLogID = &id
+		}
+		postponements = append(postponements, postponement)
+	}
+	return postponements, rows.Err()
+}
+
 func (r *activityRepository) scanActivities(ctx context.Context, query string, args ...interface{}) ([]*entity.Activity, error) {
 	rows, err := r.db.DB.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -154,13 +257,14 @@ func (r *activityRepository) scanActivityRows(rows *sql.Rows) ([]*entity.Activit
 	var activities []*entity.Activity
 	for rows.Next() {
 		activity := &entity.Activity{}
-		var categoryID sql.NullString
+		var categoryID, recurrenceRule, externalUID, assigneeID sql.NullString
 		var reminderTime, completedAt sql.NullTime
 		
 		err := rows.Scan(
 			&activity.ID, &activity.UserID, &categoryID, &activity.Title, &activity.Description,
 			&activity.ScheduledTime, &reminderTime, &activity.Status, &activity.Priority,
-			&activity.CreatedAt, &activity.UpdatedAt, &completedAt)
+			&activity.CreatedAt, &activity.UpdatedAt, &completedAt, &recurrenceRule, &externalUID,
+		&activity.PostponeCount, &assigneeID)
 		if err != nil {
 			return nil, err
 		}
@@ -175,6 +279,13 @@ func (r *activityRepository) scanActivityRows(rows *sql.Rows) ([]*entity.Activit
 		if completedAt.Valid {
 			activity.CompletedAt = &completedAt.Time
 		}
+		activity.RecurrenceRule = recurrenceRule.String
+		activity.ExternalUID = externalUID.String
+	activity.ExternalUID = externalUID.String
+		if assigneeID.Valid {
+			id, _ := uuid.Parse(assigneeID.String)
+			activity.AssigneeID = &id
+		}
 		
 		activities = append(activities, activity)
 	}
@@ -10,6 +10,14 @@ import (
 	"smart_alert_system/internal/infrastructure/database"
 )
 
+const alertColumns = `id, user_id, alert_type, alert_content, scheduled_time, alert_date, activity_id, sent_at,
+	          is_sent, status, error_message, waha_message_id, delivery_status, delivered_at,
+	          read_at, created_at`
+
+// alertDateLayout formats alert dates for the DATE column, so the date isn't
+// shifted by the session time zone.
+const alertDateLayout = "2006-01-02"
+
 type alertRepository struct {
 	db *database.PostgresDB
 }
@@ -19,146 +27,195 @@ func NewAlertRepository(db *database.PostgresDB) *alertRepository {
 }
 
 func (r *alertRepository) Create(ctx context.Context, alert *entity.AlertLog) error {
-	query := `INSERT INTO alert_logs (id, user_id, alert_type, alert_content, scheduled_time,
-	          sent_at, is_sent, status, error_message, created_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
-	
-	_, err := r.db.DB.ExecContext(ctx, query,
-		alert.ID, alert.UserID, alert.AlertType, alert.AlertContent, alert.ScheduledTime,
-		alert.SentAt, alert.IsSent, alert.Status, alert.ErrorMessage, alert.CreatedAt)
+	query := `INSERT INTO alert_logs (id, user_id, alert_type, alert_content, scheduled_time, alert_date,
+	          activity_id, sent_at, is_sent, status, error_message, waha_message_id, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	_, err := r.db.DB.ExecContext(ctx, query, r.insertArgs(alert)...)
 	return err
 }
 
+func (r *alertRepository) Reserve(ctx context.Context, alert *entity.AlertLog) (bool, error) {
+	query := `INSERT INTO alert_logs (id, user_id, alert_type, alert_content, scheduled_time, alert_date,
+	          activity_id, sent_at, is_sent, status, error_message, waha_message_id, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	          ON CONFLICT DO NOTHING`
+
+	result, err := r.db.DB.ExecContext(ctx, query, r.insertArgs(alert)...)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (r *alertRepository) insertArgs(alert *entity.AlertLog) []interface{} {
+	var alertDate sql.NullString
+	if alert.AlertDate != nil {
+		alertDate = sql.NullString{String: alert.AlertDate.Format(alertDateLayout), Valid: true}
+	}
+	return []interface{}{
+		alert.ID, alert.UserID, alert.AlertType, alert.AlertContent, alert.ScheduledTime, alertDate,
+		alert.ActivityID, alert.SentAt, alert.IsSent, alert.Status, alert.ErrorMessage, nullString(alert.WahaMessageID),
+		alert.CreatedAt,
+	}
+}
+
 func (r *alertRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.AlertLog, error) {
-	query := `SELECT id, user_id, alert_type, alert_content, scheduled_time, sent_at,
-	          is_sent, status, error_message, created_at
+	query := `SELECT ` + alertColumns + `
 	          FROM alert_logs WHERE id = $1`
-	
-	alert := &entity.AlertLog{}
-	var sentAt sql.NullTime
-	
-	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
-		&alert.ID, &alert.UserID, &alert.AlertType, &alert.AlertContent, &alert.ScheduledTime,
-		&sentAt, &alert.IsSent, &alert.Status, &alert.ErrorMessage, &alert.CreatedAt)
-	
+
+	alert, err := r.scanAlert(r.db.DB.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	
-	if sentAt.Valid {
-		alert.SentAt = &sentAt.Time
+
+	return alert, nil
+}
+
+func (r *alertRepository) GetByUserTypeDate(ctx context.Context, userID uuid.UUID, alertType entity.AlertType, alertDate time.Time) (*entity.AlertLog, error) {
+	query := `SELECT ` + alertColumns + `
+	          FROM alert_logs WHERE user_id = $1 AND alert_type = $2 AND alert_date = $3`
+
+	alert, err := r.scanAlert(r.db.DB.QueryRowContext(ctx, query, userID, alertType, alertDate.Format(alertDateLayout)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
-	
+
 	return alert, nil
 }
 
-func (r *alertRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.AlertLog, error) {
-	query := `SELECT id, user_id, alert_type, alert_content, scheduled_time, sent_at,
-	          is_sent, status, error_message, created_at
-	          FROM alert_logs WHERE user_id = $1 ORDER BY scheduled_time DESC`
-	
-	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+func (r *alertRepository) GetLatestReminder(ctx context.Context, userID uuid.UUID, sentAfter time.Time) (*entity.AlertLog, error) {
+	query := `SELECT ` + alertColumns + `
+	          FROM alert_logs
+	          WHERE user_id = $1 AND alert_type = $2 AND activity_id IS NOT NULL
+	            AND status = $3 AND sent_at >= $4
+	          ORDER BY sent_at DESC
+	          LIMIT 1`
+
+	alert, err := r.scanAlert(r.db.DB.QueryRowContext(ctx, query,
+		userID, entity.AlertTypeActivityReminder, entity.AlertStatusSent, sentAfter))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	
-	var alerts []*entity.AlertLog
-	for rows.Next() {
-		alert := &entity.AlertLog{}
-		var sentAt sql.NullTime
-		
-		err := rows.Scan(
-			&alert.ID, &alert.UserID, &alert.AlertType, &alert.AlertContent, &alert.ScheduledTime,
-			&sentAt, &alert.IsSent, &alert.Status, &alert.ErrorMessage, &alert.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		
-		if sentAt.Valid {
-			alert.SentAt = &sentAt.Time
-		}
-		
-		alerts = append(alerts, alert)
+
+	return alert, nil
+}
+
+func (r *alertRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.AlertLog, error) {
+	query := `SELECT ` + alertColumns + `
+	          FROM alert_logs WHERE user_id = $1 ORDER BY scheduled_time DESC`
+
+	return r.scanAlerts(ctx, query, userID)
+}
+
+func (r *alertRepository) ListByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.AlertLog, int, error) {
+	var total int
+	if err := r.db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM alert_logs WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, err
 	}
-	return alerts, rows.Err()
+
+	query := `SELECT ` + alertColumns + `
+	          FROM alert_logs WHERE user_id = $1 ORDER BY scheduled_time DESC, id LIMIT $2 OFFSET $3`
+
+	alerts, err := r.scanAlerts(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return alerts, total, nil
 }
 
 func (r *alertRepository) Update(ctx context.Context, alert *entity.AlertLog) error {
-	query := `UPDATE alert_logs SET alert_content = $1, sent_at = $2, is_sent = $3, status = $4, error_message = $5
-	          WHERE id = $6`
-	
+	query := `UPDATE alert_logs SET alert_content = $1, sent_at = $2, is_sent = $3, status = $4, error_message = $5,
+	          waha_message_id = $6
+	          WHERE id = $7`
+
 	_, err := r.db.DB.ExecContext(ctx, query,
-		alert.AlertContent, alert.SentAt, alert.IsSent, alert.Status, alert.ErrorMessage, alert.ID)
+		alert.AlertContent, alert.SentAt, alert.IsSent, alert.Status, alert.ErrorMessage,
+		nullString(alert.WahaMessageID), alert.ID)
+	return err
+}
+
+func (r *alertRepository) UpdateDeliveryState(ctx context.Context, id uuid.UUID, state entity.DeliveryState) error {
+	query := `UPDATE alert_logs SET delivery_status = $1, delivered_at = $2, read_at = $3 WHERE id = $4`
+	_, err := r.db.DB.ExecContext(ctx, query,
+		nullString(string(state.DeliveryStatus)), state.DeliveredAt, state.ReadAt, id)
 	return err
 }
 
 func (r *alertRepository) GetPendingAlerts(ctx context.Context, alertType entity.AlertType) ([]*entity.AlertLog, error) {
-	query := `SELECT id, user_id, alert_type, alert_content, scheduled_time, sent_at,
-	          is_sent, status, error_message, created_at
+	query := `SELECT ` + alertColumns + `
 	          FROM alert_logs WHERE alert_type = $1 AND is_sent = false AND status = $2
 	          ORDER BY scheduled_time ASC`
-	
-	rows, err := r.db.DB.QueryContext(ctx, query, alertType, entity.AlertStatusPending)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	
-	var alerts []*entity.AlertLog
-	for rows.Next() {
-		alert := &entity.AlertLog{}
-		var sentAt sql.NullTime
-		
-		err := rows.Scan(
-			&alert.ID, &alert.UserID, &alert.AlertType, &alert.AlertContent, &alert.ScheduledTime,
-			&sentAt, &alert.IsSent, &alert.Status, &alert.ErrorMessage, &alert.CreatedAt)
-		if err != nil {
-			return nil, err
-		}
-		
-		if sentAt.Valid {
-			alert.SentAt = &sentAt.Time
-		}
-		
-		alerts = append(alerts, alert)
-	}
-	return alerts, rows.Err()
+
+	return r.scanAlerts(ctx, query, alertType, entity.AlertStatusPending)
 }
 
 func (r *alertRepository) GetByScheduledTime(ctx context.Context, startTime, endTime time.Time) ([]*entity.AlertLog, error) {
-	query := `SELECT id, user_id, alert_type, alert_content, scheduled_time, sent_at,
-	          is_sent, status, error_message, created_at
+	query := `SELECT ` + alertColumns + `
 	          FROM alert_logs WHERE scheduled_time >= $1 AND scheduled_time <= $2
 	          ORDER BY scheduled_time ASC`
-	
-	rows, err := r.db.DB.QueryContext(ctx, query, startTime, endTime)
+
+	return r.scanAlerts(ctx, query, startTime, endTime)
+}
+
+func (r *alertRepository) scanAlerts(ctx context.Context, query string, args ...interface{}) ([]*entity.AlertLog, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
+
 	var alerts []*entity.AlertLog
 	for rows.Next() {
-		alert := &entity.AlertLog{}
-		var sentAt sql.NullTime
-		
-		err := rows.Scan(
-			&alert.ID, &alert.UserID, &alert.AlertType, &alert.AlertContent, &alert.ScheduledTime,
-			&sentAt, &alert.IsSent, &alert.Status, &alert.ErrorMessage, &alert.CreatedAt)
+		alert, err := r.scanAlert(rows)
 		if err != nil {
 			return nil, err
 		}
-		
-		if sentAt.Valid {
-			alert.SentAt = &sentAt.Time
-		}
-		
 		alerts = append(alerts, alert)
 	}
 	return alerts, rows.Err()
 }
 
+func (r *alertRepository) scanAlert(row rowScanner) (*entity.AlertLog, error) {
+	alert := &entity.AlertLog{}
+	var errorMessage, wahaMessageID, deliveryStatus sql.NullString
+	var activityID sql.NullString
+	var alertDate, sentAt, deliveredAt, readAt sql.NullTime
+
+	err := row.Scan(
+		&alert.ID, &alert.UserID, &alert.AlertType, &alert.AlertContent, &alert.ScheduledTime,
+		&alertDate, &activityID, &sentAt, &alert.IsSent, &alert.Status, &errorMessage, &wahaMessageID, &deliveryStatus,
+		&deliveredAt, &readAt, &alert.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if alertDate.Valid {
+		alert.AlertDate = &alertDate.Time
+	}
+	if activityID.Valid {
+		id, _ := uuid.Parse(activityID.String)
+		alert.ActivityID = &id
+	}
+	if sentAt.Valid {
+		alert.SentAt = &sentAt.Time
+	}
+	alert.ErrorMessage = errorMessage.String
+	alert.WahaMessageID = wahaMessageID.String
+	scanDeliveryState(&alert.DeliveryState, deliveryStatus, deliveredAt, readAt)
+
+	return alert, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+const calendarSubscriptionColumns = `id, user_id, url, etag, last_synced_at, last_error, created_at, updated_at`
+
+type calendarSubscriptionRepository struct {
+	db *database.PostgresDB
+}
+
+func NewCalendarSubscriptionRepository(db *database.PostgresDB) *calendarSubscriptionRepository {
+	return &calendarSubscriptionRepository{db: db}
+}
+
+func (r *calendarSubscriptionRepository) Create(ctx context.Context, subscription *entity.CalendarSubscription) (bool, error) {
+	query := `INSERT INTO calendar_subscriptions (` + calendarSubscriptionColumns + `)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	          ON CONFLICT (user_id, url) DO NOTHING`
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		subscription.ID, subscription.UserID, subscription.URL, nullString(subscription.ETag),
+		subscription.LastSyncedAt, nullString(subscription.LastError), subscription.CreatedAt, subscription.UpdatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return created > 0, nil
+}
+
+func (r *calendarSubscriptionRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.CalendarSubscription, error) {
+	query := `SELECT ` + calendarSubscriptionColumns + ` FROM calendar_subscriptions WHERE id = $1`
+
+	subscription, err := scanCalendarSubscription(r.db.DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return subscription, err
+}
+
+func (r *calendarSubscriptionRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.CalendarSubscription, error) {
+	query := `SELECT ` + calendarSubscriptionColumns + ` FROM calendar_subscriptions
+	          WHERE user_id = $1 ORDER BY created_at ASC`
+
+	return r.query(ctx, query, userID)
+}
+
+func (r *calendarSubscriptionRepository) GetDue(ctx context.Context, syncedBefore time.Time, limit int) ([]*entity.CalendarSubscription, error) {
+	query := `SELECT ` + calendarSubscriptionColumns + ` FROM calendar_subscriptions
+	          WHERE (last_synced_at IS NULL OR last_synced_at < $1)
+	            AND user_id IN (SELECT id FROM users WHERE is_active = true)
+	          ORDER BY last_synced_at ASC NULLS FIRST
+	          LIMIT $2`
+
+	return r.query(ctx, query, syncedBefore, limit)
+}
+
+func (r *calendarSubscriptionRepository) UpdateSyncResult(ctx context.Context, subscription *entity.CalendarSubscription) error {
+	query := `UPDATE calendar_subscriptions SET etag = $1, last_synced_at = $2, last_error = $3, updated_at = $4
+	          WHERE id = $5`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		nullString(subscription.ETag), subscription.LastSyncedAt, nullString(subscription.LastError),
+		subscription.UpdatedAt, subscription.ID)
+	return err
+}
+
+func (r *calendarSubscriptionRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM calendar_subscriptions WHERE id = $1`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *calendarSubscriptionRepository) query(ctx context.Context, query string, args ...interface{}) ([]*entity.CalendarSubscription, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscriptions []*entity.CalendarSubscription
+	for rows.Next() {
+		subscription, err := scanCalendarSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func scanCalendarSubscription(row rowScanner) (*entity.CalendarSubscription, error) {
+	subscription := &entity.CalendarSubscription{}
+	var etag, lastError sql.NullString
+	var lastSyncedAt sql.NullTime
+
+	err := row.Scan(
+		&subscription.ID, &subscription.UserID, &subscription.URL, &etag, &lastSyncedAt, &lastError,
+		&subscription.CreatedAt, &subscription.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.ETag = etag.String
+	subscription.LastError = lastError.String
+	if lastSyncedAt.Valid {
+		subscription.LastSyncedAt = &lastSyncedAt.Time
+	}
+	return subscription, nil
+}
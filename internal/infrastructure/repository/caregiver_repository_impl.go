@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+const caregiverColumns = `id, user_id, COALESCE(name, ''), phone_number, daily_digest, created_at`
+
+type caregiverRepository struct {
+	db *database.PostgresDB
+}
+
+func NewCaregiverRepository(db *database.PostgresDB) *caregiverRepository {
+	return &caregiverRepository{db: db}
+}
+
+func (r *caregiverRepository) Create(ctx context.Context, caregiver *entity.Caregiver) error {
+	query := `INSERT INTO caregivers (id, user_id, name, phone_number, daily_digest, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		caregiver.ID, caregiver.UserID, nullString(caregiver.Name), caregiver.PhoneNumber,
+		caregiver.DailyDigest, caregiver.CreatedAt)
+	return err
+}
+
+func (r *caregiverRepository) Update(ctx context.Context, caregiver *entity.Caregiver) error {
+	query := `UPDATE caregivers SET name = $1, daily_digest = $2 WHERE id = $3`
+
+	_, err := r.db.DB.ExecContext(ctx, query, nullString(caregiver.Name), caregiver.DailyDigest, caregiver.ID)
+	return err
+}
+
+func (r *caregiverRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.DB.ExecContext(ctx, `DELETE FROM caregivers WHERE id = $1`, id)
+	return err
+}
+
+func (r *caregiverRepository) GetByPhoneNumber(ctx context.Context, userID uuid.UUID, phoneNumber string) (*entity.Caregiver, error) {
+	query := `SELECT ` + caregiverColumns + ` FROM caregivers WHERE user_id = $1 AND phone_number = $2`
+
+	caregiver, err := scanCaregiver(r.db.DB.QueryRowContext(ctx, query, userID, phoneNumber))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return caregiver, err
+}
+
+func (r *caregiverRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.Caregiver, error) {
+	query := `SELECT ` + caregiverColumns + ` FROM caregivers WHERE user_id = $1 ORDER BY created_at ASC`
+	return r.list(ctx, query, userID)
+}
+
+func (r *caregiverRepository) ListWithDailyDigest(ctx context.Context) ([]*entity.Caregiver, error) {
+	query := `SELECT ` + caregiverColumns + ` FROM caregivers
+	          WHERE daily_digest = true
+	            AND user_id IN (SELECT id FROM users WHERE is_active = true)
+	          ORDER BY user_id, created_at ASC`
+	return r.list(ctx, query)
+}
+
+func (r *caregiverRepository) list(ctx context.Context, query string, args ...interface{}) ([]*entity.Caregiver, error) {
+	rows, err := r.db.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var caregivers []*entity.Caregiver
+	for rows.Next() {
+		caregiver, err := scanCaregiver(rows)
+		if err != nil {
+			return nil, err
+		}
+		caregivers = append(caregivers, caregiver)
+	}
+	return caregivers, rows.Err()
+}
+
+func scanCaregiver(row rowScanner) (*entity.Caregiver, error) {
+	caregiver := &entity.Caregiver{}
+	err := row.Scan(&caregiver.ID, &caregiver.UserID, &caregiver.Name, &caregiver.PhoneNumber,
+		&caregiver.DailyDigest, &caregiver.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return caregiver, nil
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+const groupMemberColumns = `group_id, user_id, COALESCE(display_name, ''), chat_id, joined_at, last_seen_at`
+
+type groupMemberRepository struct {
+	db *database.PostgresDB
+}
+
+func NewGroupMemberRepository(db *database.PostgresDB) *groupMemberRepository {
+	return &groupMemberRepository{db: db}
+}
+
+func (r *groupMemberRepository) Upsert(ctx context.Context, member *entity.GroupMember) error {
+	query := `INSERT INTO group_members (group_id, user_id, display_name, chat_id, joined_at, last_seen_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)
+	          ON CONFLICT (group_id, user_id) DO UPDATE SET
+	              display_name = COALESCE(EXCLUDED.display_name, group_members.display_name),
+	              chat_id = EXCLUDED.chat_id,
+	              last_seen_at = EXCLUDED.last_seen_at`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		member.GroupID, member.UserID, nullString(member.DisplayName), member.ChatID,
+		member.JoinedAt, member.LastSeenAt)
+	return err
+}
+
+func (r *groupMemberRepository) Get(ctx context.Context, groupID, userID uuid.UUID) (*entity.GroupMember, error) {
+	query := `SELECT ` + groupMemberColumns + ` FROM group_members WHERE group_id = $1 AND user_id = $2`
+
+	member, err := scanGroupMember(r.db.DB.QueryRowContext(ctx, query, groupID, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return member, err
+}
+
+func (r *groupMemberRepository) ListByGroupID(ctx context.Context, groupID uuid.UUID) ([]*entity.GroupMember, error) {
+	query := `SELECT ` + groupMemberColumns + ` FROM group_members WHERE group_id = $1
+	          ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*entity.GroupMember
+	for rows.Next() {
+		member, err := scanGroupMember(rows)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func scanGroupMember(row rowScanner) (*entity.GroupMember, error) {
+	member := &entity.GroupMember{}
+	err := row.Scan(&member.GroupID, &member.UserID, &member.DisplayName, &member.ChatID,
+		&member.JoinedAt, &member.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"smart_alert_system/internal/domain/entity"
@@ -136,3 +137,14 @@ func (r *healthRepository) GetRecommendationsByUserID(ctx context.Context, userI
 	return recommendations, rows.Err()
 }
 
+func (r *healthRepository) MarkRecommendationSent(ctx context.Context, id uuid.UUID, sentAt time.Time) error {
+	query := `UPDATE health_recommendations SET sent_at = $1 WHERE id = $2`
+	_, err := r.db.DB.ExecContext(ctx, query, sentAt, id)
+	return err
+}
+
+func (r *healthRepository) MarkRecommendationRead(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE health_recommendations SET is_read = true WHERE id = $1`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
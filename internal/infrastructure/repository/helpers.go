@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"database/sql"
+
+	"smart_alert_system/internal/domain/entity"
+)
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// nullString stores empty strings as NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// scanDeliveryState fills receipt columns scanned as nullable values.
+func scanDeliveryState(state *entity.DeliveryState, status sql.NullString, deliveredAt, readAt sql.NullTime) {
+	state.DeliveryStatus = entity.DeliveryStatus(status.String)
+	if deliveredAt.Valid {
+		state.DeliveredAt = &deliveredAt.Time
+	}
+	if readAt.Valid {
+		state.ReadAt = &readAt.Time
+	}
+}
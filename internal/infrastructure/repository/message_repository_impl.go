@@ -19,90 +19,131 @@ func NewMessageRepository(db *database.PostgresDB) *messageRepository {
 
 func (r *messageRepository) Create(ctx context.Context, message *entity.MessageHistory) error {
 	query := `INSERT INTO message_history (id, user_id, message_content, message_type, intent_detected,
-	          ai_response, received_at, sent_at, is_processed, created_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
-	
+	          ai_response, received_at, sent_at, is_processed, waha_message_id, media_type, transcript, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
 	_, err := r.db.DB.ExecContext(ctx, query,
 		message.ID, message.UserID, message.MessageContent, message.MessageType,
 		message.IntentDetected, message.AIResponse, message.ReceivedAt, message.SentAt,
-		message.IsProcessed, message.CreatedAt)
+		message.IsProcessed, nullString(message.WahaMessageID), nullString(message.MediaType),
+		nullString(message.Transcript), message.CreatedAt)
 	return err
 }
 
 func (r *messageRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.MessageHistory, error) {
 	query := `SELECT id, user_id, message_content, message_type, intent_detected, ai_response,
-	          received_at, sent_at, is_processed, created_at
+	          received_at, sent_at, is_processed, waha_message_id, delivery_status, delivered_at,
+	          read_at, media_type, transcript, created_at
 	          FROM message_history WHERE id = $1`
-	
-	message := &entity.MessageHistory{}
-	var receivedAt, sentAt sql.NullTime
-	
-	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
-		&message.ID, &message.UserID, &message.MessageContent, &message.MessageType,
-		&message.IntentDetected, &message.AIResponse, &receivedAt, &sentAt,
-		&message.IsProcessed, &message.CreatedAt)
-	
+
+	message, err := r.scanMessage(r.db.DB.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	
-	if receivedAt.Valid {
-		message.ReceivedAt = &receivedAt.Time
-	}
-	if sentAt.Valid {
-		message.SentAt = &sentAt.Time
-	}
-	
+
 	return message, nil
 }
 
 func (r *messageRepository) GetByUserID(ctx context.Context, userID uuid.UUID, limit int) ([]*entity.MessageHistory, error) {
 	query := `SELECT id, user_id, message_content, message_type, intent_detected, ai_response,
-	          received_at, sent_at, is_processed, created_at
+	          received_at, sent_at, is_processed, waha_message_id, delivery_status, delivered_at,
+	          read_at, media_type, transcript, created_at
 	          FROM message_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2`
-	
+
 	rows, err := r.db.DB.QueryContext(ctx, query, userID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	
+
 	var messages []*entity.MessageHistory
 	for rows.Next() {
-		message := &entity.MessageHistory{}
-		var receivedAt, sentAt sql.NullTime
-		
-		err := rows.Scan(
-			&message.ID, &message.UserID, &message.MessageContent, &message.MessageType,
-			&message.IntentDetected, &message.AIResponse, &receivedAt, &sentAt,
-			&message.IsProcessed, &message.CreatedAt)
+		message, err := r.scanMessage(rows)
 		if err != nil {
 			return nil, err
 		}
-		
-		if receivedAt.Valid {
-			message.ReceivedAt = &receivedAt.Time
-		}
-		if sentAt.Valid {
-			message.SentAt = &sentAt.Time
-		}
-		
 		messages = append(messages, message)
 	}
 	return messages, rows.Err()
 }
 
+func (r *messageRepository) ListByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*entity.MessageHistory, int, error) {
+	var total int
+	if err := r.db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM message_history WHERE user_id = $1`, userID).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, user_id, message_content, message_type, intent_detected, ai_response,
+	          received_at, sent_at, is_processed, waha_message_id, delivery_status, delivered_at,
+	          read_at, media_type, transcript, created_at
+	          FROM message_history WHERE user_id = $1 ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var messages []*entity.MessageHistory
+	for rows.Next() {
+		message, err := r.scanMessage(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, total, rows.Err()
+}
+
 func (r *messageRepository) Update(ctx context.Context, message *entity.MessageHistory) error {
 	query := `UPDATE message_history SET message_content = $1, message_type = $2, intent_detected = $3,
-	          ai_response = $4, received_at = $5, sent_at = $6, is_processed = $7
-	          WHERE id = $8`
-	
+	          ai_response = $4, received_at = $5, sent_at = $6, is_processed = $7, waha_message_id = $8,
+	          media_type = $9, transcript = $10
+	          WHERE id = $11`
+
 	_, err := r.db.DB.ExecContext(ctx, query,
 		message.MessageContent, message.MessageType, message.IntentDetected,
-		message.AIResponse, message.ReceivedAt, message.SentAt, message.IsProcessed, message.ID)
+		message.AIResponse, message.ReceivedAt, message.SentAt, message.IsProcessed,
+		nullString(message.WahaMessageID), nullString(message.MediaType), nullString(message.Transcript), message.ID)
 	return err
 }
 
+func (r *messageRepository) UpdateDeliveryState(ctx context.Context, id uuid.UUID, state entity.DeliveryState) error {
+	query := `UPDATE message_history SET delivery_status = $1, delivered_at = $2, read_at = $3 WHERE id = $4`
+	_, err := r.db.DB.ExecContext(ctx, query,
+		nullString(string(state.DeliveryStatus)), state.DeliveredAt, state.ReadAt, id)
+	return err
+}
+
+func (r *messageRepository) scanMessage(row rowScanner) (*entity.MessageHistory, error) {
+	message := &entity.MessageHistory{}
+	var intentDetected, aiResponse, wahaMessageID, deliveryStatus, mediaType, transcript sql.NullString
+	var receivedAt, sentAt, deliveredAt, readAt sql.NullTime
+
+	err := row.Scan(
+		&message.ID, &message.UserID, &message.MessageContent, &message.MessageType,
+		&intentDetected, &aiResponse, &receivedAt, &sentAt, &message.IsProcessed,
+		&wahaMessageID, &deliveryStatus, &deliveredAt, &readAt, &mediaType, &transcript,
+		&message.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	message.IntentDetected = intentDetected.String
+	message.AIResponse = aiResponse.String
+	message.WahaMessageID = wahaMessageID.String
+	message.MediaType = mediaType.String
+	message.Transcript = transcript.String
+	if receivedAt.Valid {
+		message.ReceivedAt = &receivedAt.Time
+	}
+	if sentAt.Valid {
+		message.SentAt = &sentAt.Time
+	}
+	scanDeliveryState(&message.DeliveryState, deliveryStatus, deliveredAt, readAt)
+
+	return message, nil
+}
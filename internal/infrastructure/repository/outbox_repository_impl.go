@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+const outboxColumns = `id, idempotency_key, user_id, channel, chat_id, session, body, content_type, payload, status, attempts, max_attempts,
+	          next_attempt_at, last_error, waha_message_id, message_history_id, alert_log_id,
+	          recommendation_id, sent_at, delivery_status, delivered_at, read_at, created_at, updated_at, correlation_id, trace_parent`
+
+type outboxRepository struct {
+	db *database.PostgresDB
+}
+
+func NewOutboxRepository(db *database.PostgresDB) *outboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, message *entity.OutboundMessage) (bool, error) {
+	var payload []byte
+	if message.Payload != nil {
+		var err error
+		if payload, err = json.Marshal(message.Payload); err != nil {
+			return false, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+	}
+
+	query := `INSERT INTO outbound_messages (id, idempotency_key, user_id, channel, chat_id, session, body, content_type,
+	          payload, status, attempts, max_attempts, next_attempt_at, last_error, waha_message_id, message_history_id,
+	          alert_log_id, recommendation_id, sent_at, created_at, updated_at, correlation_id, trace_parent)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+	          ON CONFLICT (idempotency_key) DO NOTHING`
+
+	result, err := r.db.DB.ExecContext(ctx, query,
+		message.ID, message.IdempotencyKey, message.UserID, message.Channel, message.ChatID, nullString(message.Session), message.Body,
+		message.ContentType, payload, message.Status, message.Attempts, message.MaxAttempts, message.NextAttemptAt, message.LastError,
+		nullString(message.WahaMessageID), message.MessageHistoryID, message.AlertLogID,
+		message.RecommendationID, message.SentAt, message.CreatedAt, message.UpdatedAt, nullString(message.CorrelationID),
+		nullString(message.TraceParent))
+	if err != nil {
+		return false, err
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return inserted > 0, nil
+}
+
+func (r *outboxRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.OutboundMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbound_messages WHERE id = $1`
+	return r.scanOne(r.db.DB.QueryRowContext(ctx, query, id))
+}
+
+func (r *outboxRepository) GetByIdempotencyKey(ctx context.Context, key string) (*entity.OutboundMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbound_messages WHERE idempotency_key = $1`
+	return r.scanOne(r.db.DB.QueryRowContext(ctx, query, key))
+}
+
+func (r *outboxRepository) GetByWahaMessageID(ctx context.Context, wahaMessageID string) (*entity.OutboundMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbound_messages WHERE waha_message_id = $1`
+	return r.scanOne(r.db.DB.QueryRowContext(ctx, query, wahaMessageID))
+}
+
+func (r *outboxRepository) GetByWahaMessageIDSuffix(ctx context.Context, id string, sentAfter time.Time) (*entity.OutboundMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbound_messages
+	          WHERE sent_at >= $2 AND right(waha_message_id, length($1) + 1) = '_' || $1
+	          ORDER BY sent_at DESC LIMIT 1`
+	return r.scanOne(r.db.DB.QueryRowContext(ctx, query, id, sentAfter))
+}
+
+func (r *outboxRepository) GetLatestInteractive(ctx context.Context, userID uuid.UUID, sentAfter time.Time) (*entity.OutboundMessage, error) {
+	query := `SELECT ` + outboxColumns + ` FROM outbound_messages
+	          WHERE user_id = $1 AND content_type IN ($2, $3, $4) AND sent_at >= $5
+	          ORDER BY sent_at DESC LIMIT 1`
+	return r.scanOne(r.db.DB.QueryRowContext(ctx, query, userID,
+		entity.ContentTypeButtons, entity.ContentTypeList, entity.ContentTypePoll, sentAfter))
+}
+
+func (r *outboxRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.OutboundMessage, error) {
+	// SKIP LOCKED lets several dispatchers (or replicas) share the queue
+	// without handing the same message to two of them.
+	query := `UPDATE outbound_messages SET status = $1, attempts = attempts + 1, next_attempt_at = $2
+	          WHERE id IN (
+	              SELECT id FROM outbound_messages
+	              WHERE status IN ($3, $1) AND next_attempt_at <= $4 AND attempts < max_attempts
+	              ORDER BY next_attempt_at ASC
+	              LIMIT $5
+	              FOR UPDATE SKIP LOCKED
+	          )
+	          RETURNING ` + outboxColumns
+
+	rows, err := r.db.DB.QueryContext(ctx, query,
+		entity.OutboundStatusSending, now.Add(lease), entity.OutboundStatusPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanAll(rows)
+}
+
+func (r *outboxRepository) FailExhaustedClaims(ctx context.Context, now time.Time, lastError string) ([]*entity.OutboundMessage, error) {
+	query := `UPDATE outbound_messages SET status = $1, last_error = $2, updated_at = $3
+	          WHERE status = $4 AND next_attempt_at <= $3 AND attempts >= max_attempts
+	          RETURNING ` + outboxColumns
+
+	rows, err := r.db.DB.QueryContext(ctx, query,
+		entity.OutboundStatusFailed, lastError, now, entity.OutboundStatusSending)
+	if err != nil {
+		return nil, err
+	}
+	return r.scanAll(rows)
+}
+
+func (r *outboxRepository) scanAll(rows *sql.Rows) ([]*entity.OutboundMessage, error) {
+	defer rows.Close()
+
+	var messages []*entity.OutboundMessage
+	for rows.Next() {
+		message, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+func (r *outboxRepository) Update(ctx context.Context, message *entity.OutboundMessage) error {
+	query := `UPDATE outbound_messages SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4,
+	          waha_message_id = $5, sent_at = $6, delivery_status = $7, delivered_at = $8, read_at = $9,
+	          updated_at = $10
+	          WHERE id = $11`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		message.Status, message.Attempts, message.NextAttemptAt, message.LastError,
+		nullString(message.WahaMessageID), message.SentAt, nullString(string(message.DeliveryStatus)),
+		message.DeliveredAt, message.ReadAt, message.UpdatedAt, message.ID)
+	return err
+}
+
+func (r *outboxRepository) scanOne(row *sql.Row) (*entity.OutboundMessage, error) {
+	message, err := r.scan(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return message, err
+}
+
+func (r *outboxRepository) scan(row rowScanner) (*entity.OutboundMessage, error) {
+	message := &entity.OutboundMessage{}
+	var userID, messageHistoryID, alertLogID, recommendationID sql.NullString
+	var session, contentType, lastError, wahaMessageID, deliveryStatus, correlationID, traceParent sql.NullString
+	var payload []byte
+	var sentAt, deliveredAt, readAt sql.NullTime
+
+	err := row.Scan(
+		&message.ID, &message.IdempotencyKey, &userID, &message.Channel, &message.ChatID, &session, &message.Body, &contentType,
+		&payload, &message.Status,
+		&message.Attempts, &message.MaxAttempts, &message.NextAttemptAt, &lastError, &wahaMessageID,
+		&messageHistoryID, &alertLogID, &recommendationID, &sentAt, &deliveryStatus, &deliveredAt,
+		&readAt, &message.CreatedAt, &message.UpdatedAt, &correlationID, &traceParent)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		id, _ := uuid.Parse(userID.String)
+		message.UserID = &id
+	}
+	if messageHistoryID.Valid {
+		id, _ := uuid.Parse(messageHistoryID.String)
+		message.MessageHistoryID = &id
+	}
+	if alertLogID.Valid {
+		id, _ := uuid.Parse(alertLogID.String)
+		message.AlertLogID = &id
+	}
+	if recommendationID.Valid {
+		id, _ := uuid.Parse(recommendationID.String)
+		message.RecommendationID = &id
+	}
+	message.ContentType = entity.ContentTypeText
+	if contentType.Valid {
+		message.ContentType = entity.ContentType(contentType.String)
+	}
+	if payload != nil {
+		message.Payload = &entity.OutboundPayload{}
+		if err := json.Unmarshal(payload, message.Payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+	}
+	message.Session = session.String
+	message.LastError = lastError.String
+	message.WahaMessageID = wahaMessageID.String
+	message.CorrelationID = correlationID.String
+	message.TraceParent = traceParent.String
+	if sentAt.Valid {
+		message.SentAt = &sentAt.Time
+	}
+	scanDeliveryState(&message.DeliveryState, deliveryStatus, deliveredAt, readAt)
+
+	return message, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+type pendingConfirmationRepository struct {
+	db *database.PostgresDB
+}
+
+func NewPendingConfirmationRepository(db *database.PostgresDB) *pendingConfirmationRepository {
+	return &pendingConfirmationRepository{db: db}
+}
+
+func (r *pendingConfirmationRepository) Save(ctx context.Context, confirmation *entity.PendingConfirmation) error {
+	payload, err := json.Marshal(confirmation.Activities)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	query := `INSERT INTO pending_confirmations (id, user_id, action, summary, payload, expires_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)
+	          ON CONFLICT (user_id) DO UPDATE SET id = EXCLUDED.id, action = EXCLUDED.action,
+	          summary = EXCLUDED.summary, payload = EXCLUDED.payload, expires_at = EXCLUDED.expires_at,
+	          created_at = EXCLUDED.created_at`
+
+	_, err = r.db.DB.ExecContext(ctx, query,
+		confirmation.ID, confirmation.UserID, confirmation.Action, nullString(confirmation.Summary),
+		payload, confirmation.ExpiresAt, confirmation.CreatedAt)
+	return err
+}
+
+func (r *pendingConfirmationRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*entity.PendingConfirmation, error) {
+	query := `SELECT id, user_id, action, summary, payload, expires_at, created_at
+	          FROM pending_confirmations WHERE user_id = $1`
+
+	confirmation := &entity.PendingConfirmation{}
+	var summary sql.NullString
+	var payload []byte
+
+	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(
+		&confirmation.ID, &confirmation.UserID, &confirmation.Action, &summary, &payload,
+		&confirmation.ExpiresAt, &confirmation.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	confirmation.Summary = summary.String
+	if err := json.Unmarshal(payload, &confirmation.Activities); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	return confirmation, nil
+}
+
+func (r *pendingConfirmationRepository) Delete(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM pending_confirmations WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+const identityColumns = `id, user_id, channel, external_id, chat_id, COALESCE(account, ''), created_at, last_seen_at`
+
+type userIdentityRepository struct {
+	db *database.PostgresDB
+}
+
+func NewUserIdentityRepository(db *database.PostgresDB) *userIdentityRepository {
+	return &userIdentityRepository{db: db}
+}
+
+func (r *userIdentityRepository) Create(ctx context.Context, identity *entity.UserIdentity) error {
+	query := `INSERT INTO user_identities (id, user_id, channel, external_id, chat_id, account, created_at, last_seen_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		identity.ID, identity.UserID, identity.Channel, identity.ExternalID, identity.ChatID,
+		nullString(identity.Account), identity.CreatedAt, identity.LastSeenAt)
+	return err
+}
+
+func (r *userIdentityRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE id = $1`
+
+	identity, err := scanIdentity(r.db.DB.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return identity, err
+}
+
+func (r *userIdentityRepository) GetByExternalID(ctx context.Context, channel, externalID string) (*entity.UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE channel = $1 AND external_id = $2`
+
+	identity, err := scanIdentity(r.db.DB.QueryRowContext(ctx, query, channel, externalID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return identity, err
+}
+
+func (r *userIdentityRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]*entity.UserIdentity, error) {
+	query := `SELECT ` + identityColumns + ` FROM user_identities WHERE user_id = $1
+	          ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*entity.UserIdentity
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+func (r *userIdentityRepository) Touch(ctx context.Context, identity *entity.UserIdentity) error {
+	query := `UPDATE user_identities SET chat_id = $1, account = $2, last_seen_at = $3 WHERE id = $4`
+	_, err := r.db.DB.ExecContext(ctx, query,
+		identity.ChatID, nullString(identity.Account), identity.LastSeenAt, identity.ID)
+	return err
+}
+
+func (r *userIdentityRepository) MoveToUser(ctx context.Context, id, userID uuid.UUID) error {
+	query := `UPDATE user_identities SET user_id = $1 WHERE id = $2`
+	_, err := r.db.DB.ExecContext(ctx, query, userID, id)
+	return err
+}
+
+func (r *userIdentityRepository) CreateLinkCode(ctx context.Context, code *entity.IdentityLinkCode) error {
+	query := `INSERT INTO identity_link_codes (id, user_id, code_hash, expires_at, used_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		code.ID, code.UserID, code.CodeHash, code.ExpiresAt, code.UsedAt, code.CreatedAt)
+	return err
+}
+
+func (r *userIdentityRepository) UseLinkCode(ctx context.Context, codeHash string, now time.Time) (*entity.IdentityLinkCode, error) {
+	query := `UPDATE identity_link_codes SET used_at = $2
+	          WHERE id = (
+	              SELECT id FROM identity_link_codes
+	              WHERE code_hash = $1 AND used_at IS NULL AND expires_at > $2
+	              ORDER BY created_at DESC LIMIT 1
+	          ) AND used_at IS NULL
+	          RETURNING id, user_id, code_hash, expires_at, used_at, created_at`
+
+	code := &entity.IdentityLinkCode{}
+	var usedAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, codeHash, now).Scan(
+		&code.ID, &code.UserID, &code.CodeHash, &code.ExpiresAt, &usedAt, &code.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		code.UsedAt = &usedAt.Time
+	}
+	return code, nil
+}
+
+func scanIdentity(row rowScanner) (*entity.UserIdentity, error) {
+	identity := &entity.UserIdentity{}
+	err := row.Scan(&identity.ID, &identity.UserID, &identity.Channel, &identity.ExternalID,
+		&identity.ChatID, &identity.Account, &identity.CreatedAt, &identity.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
 	"smart_alert_system/internal/infrastructure/database"
 )
 
@@ -19,24 +20,25 @@ func NewUserRepository(db *database.PostgresDB) *userRepository {
 }
 
 func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
-	query := `INSERT INTO users (id, whatsapp_number, name, timezone, is_active, is_first_time, created_at, updated_at)
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	query := `INSERT INTO users (id, whatsapp_number, name, timezone, is_active, is_first_time, created_at, updated_at,
+	          waha_session)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 	
 	_, err := r.db.DB.ExecContext(ctx, query,
-		user.ID, user.WhatsAppNumber, user.Name, user.Timezone,
-		user.IsActive, user.IsFirstTime, user.CreatedAt, user.UpdatedAt)
+		user.ID, nullString(user.WhatsAppNumber), user.Name, user.Timezone,
+		user.IsActive, user.IsFirstTime, user.CreatedAt, user.UpdatedAt, nullString(user.WahaSession))
 	return err
 }
 
 func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
-	query := `SELECT id, whatsapp_number, name, timezone, is_active, is_first_time, 
-	          created_at, updated_at, last_interaction_at 
+	query := `SELECT id, COALESCE(whatsapp_number, ''), name, timezone, is_active, is_first_time, 
+	          created_at, updated_at, last_interaction_at, COALESCE(waha_session, '')
 	          FROM users WHERE id = $1`
 	
 	user := &entity.User{}
 	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.WhatsAppNumber, &user.Name, &user.Timezone,
-		&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt)
+		&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt, &user.WahaSession)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -44,14 +46,14 @@ func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.Use
 }
 
 func (r *userRepository) GetByWhatsAppNumber(ctx context.Context, whatsappNumber string) (*entity.User, error) {
-	query := `SELECT id, whatsapp_number, name, timezone, is_active, is_first_time, 
-	          created_at, updated_at, last_interaction_at 
+	query := `SELECT id, COALESCE(whatsapp_number, ''), name, timezone, is_active, is_first_time, 
+	          created_at, updated_at, last_interaction_at, COALESCE(waha_session, '')
 	          FROM users WHERE whatsapp_number = $1`
 	
 	user := &entity.User{}
 	err := r.db.DB.QueryRowContext(ctx, query, whatsappNumber).Scan(
 		&user.ID, &user.WhatsAppNumber, &user.Name, &user.Timezone,
-		&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt)
+		&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt, &user.WahaSession)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -76,8 +78,8 @@ func (r *userRepository) UpdateLastInteraction(ctx context.Context, userID uuid.
 }
 
 func (r *userRepository) GetAllActive(ctx context.Context) ([]*entity.User, error) {
-	query := `SELECT id, whatsapp_number, name, timezone, is_active, is_first_time, 
-	          created_at, updated_at, last_interaction_at 
+	query := `SELECT id, COALESCE(whatsapp_number, ''), name, timezone, is_active, is_first_time, 
+	          created_at, updated_at, last_interaction_at, COALESCE(waha_session, '')
 	          FROM users WHERE is_active = true`
 	
 	rows, err := r.db.DB.QueryContext(ctx, query)
@@ -91,7 +93,33 @@ func (r *userRepository) GetAllActive(ctx context.Context) ([]*entity.User, erro
 		user := &entity.User{}
 		err := rows.Scan(
 			&user.ID, &user.WhatsAppNumber, &user.Name, &user.Timezone,
-			&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt)
+			&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt, &user.WahaSession)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepository) ListActive(ctx context.Context, afterID uuid.UUID, limit int) ([]*entity.User, error) {
+	query := `SELECT id, COALESCE(whatsapp_number, ''), name, timezone, is_active, is_first_time,
+	          created_at, updated_at, last_interaction_at, COALESCE(waha_session, '')
+	          FROM users WHERE is_active = true AND id > $1
+	          ORDER BY id LIMIT $2`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		user := &entity.User{}
+		err := rows.Scan(
+			&user.ID, &user.WhatsAppNumber, &user.Name, &user.Timezone,
+			&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt, &user.WahaSession)
 		if err != nil {
 			return nil, err
 		}
@@ -106,3 +134,85 @@ func (r *userRepository) MarkAsNotFirstTime(ctx context.Context, userID uuid.UUI
 	return err
 }
 
+
+func (r *userRepository) Search(ctx context.Context, filter repository.UserFilter) ([]*entity.User, int, error) {
+	where := `WHERE ($1 = '' OR whatsapp_number ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%')
+	            AND ($2::boolean IS NULL OR is_active = $2)`
+
+	var isActive sql.NullBool
+	if filter.IsActive != nil {
+		isActive = sql.NullBool{Bool: *filter.IsActive, Valid: true}
+	}
+
+	var total int
+	if err := r.db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM users `+where, filter.Query, isActive).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, COALESCE(whatsapp_number, ''), name, timezone, is_active, is_first_time,
+	          created_at, updated_at, last_interaction_at, COALESCE(waha_session, '')
+	          FROM users ` + where + `
+	          ORDER BY created_at DESC, id
+	          LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.DB.QueryContext(ctx, query, filter.Query, isActive, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []*entity.User
+	for rows.Next() {
+		user := &entity.User{}
+		err := rows.Scan(
+			&user.ID, &user.WhatsAppNumber, &user.Name, &user.Timezone,
+			&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt, &user.WahaSession)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+func (r *userRepository) GetByCalendarToken(ctx context.Context, token string) (*entity.User, error) {
+	query := `SELECT id, COALESCE(whatsapp_number, ''), name, timezone, is_active, is_first_time,
+	          created_at, updated_at, last_interaction_at, COALESCE(waha_session, '')
+	          FROM users WHERE calendar_token = $1`
+
+	user := &entity.User{}
+	err := r.db.DB.QueryRowContext(ctx, query, token).Scan(
+		&user.ID, &user.WhatsAppNumber, &user.Name, &user.Timezone,
+		&user.IsActive, &user.IsFirstTime, &user.CreatedAt, &user.UpdatedAt, &user.LastInteractionAt, &user.WahaSession)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return user, err
+}
+
+func (r *userRepository) GetCalendarToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	var token sql.NullString
+	err := r.db.DB.QueryRowContext(ctx, `SELECT calendar_token FROM users WHERE id = $1`, userID).Scan(&token)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return token.String, err
+}
+
+func (r *userRepository) SetCalendarToken(ctx context.Context, userID uuid.UUID, token string) error {
+	query := `UPDATE users SET calendar_token = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.DB.ExecContext(ctx, query, nullString(token), time.Now(), userID)
+	return err
+}
+
+func (r *userRepository) SetWahaSession(ctx context.Context, userID uuid.UUID, session string) error {
+	query := `UPDATE users SET waha_session = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.DB.ExecContext(ctx, query, nullString(session), time.Now(), userID)
+	return err
+}
+
+func (r *userRepository) SetWhatsAppNumber(ctx context.Context, userID uuid.UUID, whatsappNumber string) error {
+	query := `UPDATE users SET whatsapp_number = $1, updated_at = $2 WHERE id = $3`
+	_, err := r.db.DB.ExecContext(ctx, query, nullString(whatsappNumber), time.Now(), userID)
+	return err
+}
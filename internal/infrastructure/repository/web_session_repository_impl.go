@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+type webSessionRepository struct {
+	db *database.PostgresDB
+}
+
+func NewWebSessionRepository(db *database.PostgresDB) *webSessionRepository {
+	return &webSessionRepository{db: db}
+}
+
+func (r *webSessionRepository) CreateLoginCode(ctx context.Context, code *entity.LoginCode) error {
+	query := `INSERT INTO login_codes (id, user_id, code_hash, attempts, expires_at, used_at, created_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		code.ID, code.UserID, code.CodeHash, code.Attempts, code.ExpiresAt, code.UsedAt, code.CreatedAt)
+	return err
+}
+
+func (r *webSessionRepository) GetLatestLoginCode(ctx context.Context, userID uuid.UUID) (*entity.LoginCode, error) {
+	query := `SELECT id, user_id, code_hash, attempts, expires_at, used_at, created_at
+	          FROM login_codes WHERE user_id = $1 AND used_at IS NULL
+	          ORDER BY created_at DESC LIMIT 1`
+
+	code := &entity.LoginCode{}
+	var usedAt sql.NullTime
+
+	err := r.db.DB.QueryRowContext(ctx, query, userID).Scan(
+		&code.ID, &code.UserID, &code.CodeHash, &code.Attempts, &code.ExpiresAt, &usedAt, &code.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if usedAt.Valid {
+		code.UsedAt = &usedAt.Time
+	}
+	return code, nil
+}
+
+func (r *webSessionRepository) IncrementLoginCodeAttempts(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE login_codes SET attempts = attempts + 1 WHERE id = $1`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *webSessionRepository) CreateLoginFailure(ctx context.Context, userID uuid.UUID, at time.Time) (uuid.UUID, error) {
+	id := uuid.New()
+	query := `INSERT INTO login_failures (id, user_id, created_at) VALUES ($1, $2, $3)`
+	_, err := r.db.DB.ExecContext(ctx, query, id, userID, at)
+	return id, err
+}
+
+func (r *webSessionRepository) DeleteLoginFailure(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.DB.ExecContext(ctx, `DELETE FROM login_failures WHERE id = $1`, id)
+	return err
+}
+
+func (r *webSessionRepository) CountLoginFailures(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	query := `SELECT COUNT(*) FROM login_failures WHERE user_id = $1 AND created_at >= $2`
+
+	var count int
+	err := r.db.DB.QueryRowContext(ctx, query, userID, since).Scan(&count)
+	return count, err
+}
+
+func (r *webSessionRepository) UseLoginCode(ctx context.Context, id uuid.UUID, usedAt time.Time) (bool, error) {
+	query := `UPDATE login_codes SET used_at = $1 WHERE id = $2 AND used_at IS NULL`
+
+	result, err := r.db.DB.ExecContext(ctx, query, usedAt, id)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return updated > 0, nil
+}
+
+func (r *webSessionRepository) CreateSession(ctx context.Context, session *entity.WebSession) error {
+	query := `INSERT INTO web_sessions (id, user_id, token_hash, csrf_token, expires_at, created_at, last_seen_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		session.ID, session.UserID, session.TokenHash, session.CSRFToken, session.ExpiresAt,
+		session.CreatedAt, session.LastSeenAt)
+	return err
+}
+
+func (r *webSessionRepository) GetSessionByTokenHash(ctx context.Context, tokenHash string, now time.Time) (*entity.WebSession, error) {
+	query := `SELECT id, user_id, token_hash, csrf_token, expires_at, created_at, last_seen_at
+	          FROM web_sessions WHERE token_hash = $1 AND expires_at > $2`
+
+	session := &entity.WebSession{}
+	err := r.db.DB.QueryRowContext(ctx, query, tokenHash, now).Scan(
+		&session.ID, &session.UserID, &session.TokenHash, &session.CSRFToken, &session.ExpiresAt,
+		&session.CreatedAt, &session.LastSeenAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (r *webSessionRepository) TouchSession(ctx context.Context, id uuid.UUID, lastSeenAt time.Time) error {
+	query := `UPDATE web_sessions SET last_seen_at = $1 WHERE id = $2`
+	_, err := r.db.DB.ExecContext(ctx, query, lastSeenAt, id)
+	return err
+}
+
+func (r *webSessionRepository) DeleteSession(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM web_sessions WHERE id = $1`
+	_, err := r.db.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *webSessionRepository) DeleteExpired(ctx context.Context, now, failuresBefore time.Time) error {
+	if _, err := r.db.DB.ExecContext(ctx, `DELETE FROM web_sessions WHERE expires_at <= $1`, now); err != nil {
+		return err
+	}
+	if _, err := r.db.DB.ExecContext(ctx, `DELETE FROM login_codes WHERE expires_at <= $1`, now); err != nil {
+		return err
+	}
+	_, err := r.db.DB.ExecContext(ctx, `DELETE FROM login_failures WHERE created_at < $1`, failuresBefore)
+	return err
+}
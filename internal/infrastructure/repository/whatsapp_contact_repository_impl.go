@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+const contactColumns = `id, identity_id, COALESCE(lid, ''), COALESCE(phone_number, ''), chat_id, created_at, updated_at`
+
+type whatsAppContactRepository struct {
+	db *database.PostgresDB
+}
+
+func NewWhatsAppContactRepository(db *database.PostgresDB) *whatsAppContactRepository {
+	return &whatsAppContactRepository{db: db}
+}
+
+func (r *whatsAppContactRepository) Create(ctx context.Context, contact *entity.WhatsAppContact) error {
+	query := `INSERT INTO whatsapp_contacts (id, identity_id, lid, phone_number, chat_id, created_at, updated_at)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		contact.ID, contact.IdentityID, nullString(contact.LID), nullString(contact.PhoneNumber),
+		contact.ChatID, contact.CreatedAt, contact.UpdatedAt)
+	return err
+}
+
+func (r *whatsAppContactRepository) Update(ctx context.Context, contact *entity.WhatsAppContact) error {
+	query := `UPDATE whatsapp_contacts SET lid = $1, phone_number = $2, chat_id = $3, updated_at = $4
+	          WHERE id = $5`
+
+	_, err := r.db.DB.ExecContext(ctx, query,
+		nullString(contact.LID), nullString(contact.PhoneNumber), contact.ChatID, contact.UpdatedAt, contact.ID)
+	return err
+}
+
+func (r *whatsAppContactRepository) GetByLID(ctx context.Context, lid string) (*entity.WhatsAppContact, error) {
+	query := `SELECT ` + contactColumns + ` FROM whatsapp_contacts WHERE lid = $1`
+	return r.get(ctx, query, lid)
+}
+
+func (r *whatsAppContactRepository) GetByPhoneNumber(ctx context.Context, phoneNumber string) (*entity.WhatsAppContact, error) {
+	query := `SELECT ` + contactColumns + ` FROM whatsapp_contacts WHERE phone_number = $1`
+	return r.get(ctx, query, phoneNumber)
+}
+
+func (r *whatsAppContactRepository) GetByIdentityID(ctx context.Context, identityID uuid.UUID) (*entity.WhatsAppContact, error) {
+	query := `SELECT ` + contactColumns + ` FROM whatsapp_contacts WHERE identity_id = $1
+	          ORDER BY updated_at DESC LIMIT 1`
+	return r.get(ctx, query, identityID)
+}
+
+func (r *whatsAppContactRepository) get(ctx context.Context, query string, arg interface{}) (*entity.WhatsAppContact, error) {
+	contact, err := scanContact(r.db.DB.QueryRowContext(ctx, query, arg))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return contact, err
+}
+
+func scanContact(row rowScanner) (*entity.WhatsAppContact, error) {
+	contact := &entity.WhatsAppContact{}
+	err := row.Scan(&contact.ID, &contact.IdentityID, &contact.LID, &contact.PhoneNumber,
+		&contact.ChatID, &contact.CreatedAt, &contact.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
@@ -0,0 +1,87 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"smart_alert_system/internal/infrastructure/database"
+)
+
+// schedulerLockKey is the Postgres advisory lock key shared by all instances.
+// The value is arbitrary but must never change between releases.
+const schedulerLockKey int64 = 7203001
+
+// LeaderLock elects one scheduler instance using a session-level Postgres
+// advisory lock. The lock lives on a dedicated connection, so it is released
+// automatically when the holding process dies or loses its connection.
+type LeaderLock struct {
+	db   *sql.DB
+	key  int64
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewLeaderLock(db *database.PostgresDB) *LeaderLock {
+	return &LeaderLock{db: db.DB, key: schedulerLockKey}
+}
+
+// TryAcquire reports whether this instance is the leader, taking the lock if
+// it is free. It never blocks waiting for another instance.
+func (l *LeaderLock) TryAcquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if _, err := l.conn.ExecContext(ctx, "SELECT 1"); err == nil {
+			return true, nil
+		}
+		// The connection is gone, and the lock with it
+		slog.WarnContext(ctx, "Lost scheduler leader connection, re-electing")
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to open leader connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+		conn.Close()
+		return false, fmt.Errorf("failed to acquire leader lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return false, nil
+	}
+
+	slog.InfoContext(ctx, "This instance is now the scheduler leader")
+	l.conn = conn
+	return true, nil
+}
+
+// Held reports whether this instance held the lock the last time it checked.
+func (l *LeaderLock) Held() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conn != nil
+}
+
+// Release gives up leadership so another instance can take over immediately.
+func (l *LeaderLock) Release(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return
+	}
+	if _, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		slog.ErrorContext(ctx, "Failed to release scheduler leader lock", "error", err)
+	}
+	l.conn.Close()
+	l.conn = nil
+}
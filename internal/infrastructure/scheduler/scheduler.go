@@ -3,10 +3,14 @@ package scheduler
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"smart_alert_system/internal/infrastructure/logging"
 	"smart_alert_system/internal/usecase"
 )
 
@@ -16,28 +20,65 @@ type Scheduler struct {
 	morningTime   string
 	eveningTime   string
 	location      *time.Location
+	leader        *LeaderLock
+	catchUpWindow time.Duration
+	morningRun    sync.Mutex
+	eveningRun    sync.Mutex
+	calendarUC    *usecase.CalendarUseCase
+	calendarRun   sync.Mutex
+	caregiverUC   *usecase.CaregiverUseCase
+	escalationRun sync.Mutex
+	running       atomic.Bool
+	// lastTick is when the every-minute heartbeat job last fired, in Unix
+	// nanoseconds; it stops moving if cron is stuck
+	lastTick atomic.Int64
 }
 
-func NewScheduler(schedulerUC *usecase.SchedulerUseCase, morningTime, eveningTime string, location *time.Location) *Scheduler {
+// State is a snapshot of the scheduler for health checks.
+type State struct {
+	Running bool
+	// Leader is whether this instance runs the jobs; always true without a
+	// leader lock
+	Leader      bool
+	LastTick    time.Time
+	NextMorning time.Time
+	NextEvening time.Time
+}
+
+// NewScheduler creates the cron scheduler. When leader is set, jobs only run
+// on the instance holding the leader lock. Runs missed less than catchUpWindow
+// ago (e.g. because the server was down at 05:00) are run on Start.
+func NewScheduler(schedulerUC *usecase.SchedulerUseCase, morningTime, eveningTime string, location *time.Location, leader *LeaderLock, catchUpWindow time.Duration) *Scheduler {
 	c := cron.New(cron.WithLocation(location))
 	return &Scheduler{
-		cron:        c,
-		schedulerUC: schedulerUC,
-		morningTime: morningTime,
-		eveningTime: eveningTime,
-		location:    location,
+		cron:          c,
+		schedulerUC:   schedulerUC,
+		morningTime:   morningTime,
+		eveningTime:   eveningTime,
+		location:      location,
+		leader:        leader,
+		catchUpWindow: catchUpWindow,
 	}
 }
 
+// SetCalendarSync makes the scheduler poll subscribed calendars. Each check
+// syncs the subscriptions whose sync interval has passed.
+func (s *Scheduler) SetCalendarSync(calendarUC *usecase.CalendarUseCase) {
+	s.calendarUC = calendarUC
+}
+
+// SetCaregivers makes the scheduler alert caregivers about missed medication
+// and health activities, checked every minute, and send their daily digest
+// with the evening summaries.
+func (s *Scheduler) SetCaregivers(caregiverUC *usecase.CaregiverUseCase) {
+	s.caregiverUC = caregiverUC
+}
+
 func (s *Scheduler) Start() error {
 	// Schedule morning alert (format: "05:00" -> "0 5 * * *")
 	morningCron := s.parseTimeToCron(s.morningTime)
 	_, err := s.cron.AddFunc(morningCron, func() {
-		log.Println("Running morning alert scheduler...")
-		ctx := context.Background()
-		if err := s.schedulerUC.SendMorningAlerts(ctx); err != nil {
-			log.Printf("Error sending morning alerts: %v", err)
-		}
+		s.runMorning(time.Now().In(s.location))
 	})
 	if err != nil {
 		return fmt.Errorf("failed to schedule morning alert: %w", err)
@@ -46,22 +87,192 @@ func (s *Scheduler) Start() error {
 	// Schedule evening summary (format: "22:00" -> "0 22 * * *")
 	eveningCron := s.parseTimeToCron(s.eveningTime)
 	_, err = s.cron.AddFunc(eveningCron, func() {
-		log.Println("Running evening summary scheduler...")
-		ctx := context.Background()
-		if err := s.schedulerUC.SendEveningSummaries(ctx); err != nil {
-			log.Printf("Error sending evening summaries: %v", err)
-		}
+		s.runEvening(time.Now().In(s.location))
 	})
 	if err != nil {
 		return fmt.Errorf("failed to schedule evening summary: %w", err)
 	}
 
+	// Health checks see cron as stuck once this stops firing
+	_, err = s.cron.AddFunc("@every 1m", func() {
+		s.lastTick.Store(time.Now().UnixNano())
+	})
+	if err != nil {
+		return fmt.Errorf("failed to schedule heartbeat: %w", err)
+	}
+
+	// Subscribed calendars due for a sync are checked every 5 minutes
+	if s.calendarUC != nil {
+		_, err = s.cron.AddFunc("@every 5m", s.runCalendarSync)
+		if err != nil {
+			return fmt.Errorf("failed to schedule calendar sync: %w", err)
+		}
+	}
+
+	// Missed medication and health activities are checked every minute
+	if s.caregiverUC != nil {
+		_, err = s.cron.AddFunc("@every 1m", func() {
+			s.runEscalations(time.Now().In(s.location))
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule caregiver escalations: %w", err)
+		}
+	}
+
 	s.cron.Start()
-	log.Printf("Scheduler started. Morning alert: %s (%s), Evening summary: %s (%s)", 
-		s.morningTime, morningCron, s.eveningTime, eveningCron)
+	s.lastTick.Store(time.Now().UnixNano())
+	s.running.Store(true)
+	slog.Info("Scheduler started", "morning_time", s.morningTime, "morning_cron", morningCron,
+		"evening_time", s.eveningTime, "evening_cron", eveningCron)
+
+	go s.catchUp(time.Now().In(s.location))
 	return nil
 }
 
+func (s *Scheduler) runMorning(runAt time.Time) {
+	// Skip if the previous run (or a catch-up) is still going
+	if !s.morningRun.TryLock() {
+		slog.Warn("Morning alert run already in progress, skipping")
+		return
+	}
+	defer s.morningRun.Unlock()
+
+	ctx := newRunContext()
+	if !s.isLeader(ctx) {
+		return
+	}
+
+	slog.InfoContext(ctx, "Running morning alert scheduler", "run_at", runAt)
+	stats, err := s.schedulerUC.SendMorningAlerts(ctx, runAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to send morning alerts", "error", err)
+	}
+	slog.InfoContext(ctx, "Morning alert run finished", "stats", stats.String())
+}
+
+func (s *Scheduler) runEvening(runAt time.Time) {
+	if !s.eveningRun.TryLock() {
+		slog.Warn("Evening summary run already in progress, skipping")
+		return
+	}
+	defer s.eveningRun.Unlock()
+
+	ctx := newRunContext()
+	if !s.isLeader(ctx) {
+		return
+	}
+
+	slog.InfoContext(ctx, "Running evening summary scheduler", "run_at", runAt)
+	stats, err := s.schedulerUC.SendEveningSummaries(ctx, runAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to send evening summaries", "error", err)
+	}
+	slog.InfoContext(ctx, "Evening summary run finished", "stats", stats.String())
+
+	if s.caregiverUC != nil {
+		stats, err := s.caregiverUC.SendDailyDigests(ctx, runAt)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to send caregiver digests", "error", err)
+		}
+		slog.InfoContext(ctx, "Caregiver digest run finished", "stats", stats.String())
+	}
+}
+
+func (s *Scheduler) runEscalations(now time.Time) {
+	if !s.escalationRun.TryLock() {
+		return
+	}
+	defer s.escalationRun.Unlock()
+
+	ctx := newRunContext()
+	if s.leader != nil {
+		if isLeader, err := s.leader.TryAcquire(ctx); err != nil || !isLeader {
+			return
+		}
+	}
+
+	stats, err := s.caregiverUC.SendEscalations(ctx, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to send caregiver escalations", "error", err)
+	}
+	if stats.Sent > 0 || stats.Failed > 0 {
+		slog.InfoContext(ctx, "Caregiver escalation run finished", "stats", stats.String())
+	}
+}
+
+func (s *Scheduler) runCalendarSync() {
+	if !s.calendarRun.TryLock() {
+		return
+	}
+	defer s.calendarRun.Unlock()
+
+	ctx := newRunContext()
+	if s.leader != nil {
+		if isLeader, err := s.leader.TryAcquire(ctx); err != nil || !isLeader {
+			return
+		}
+	}
+
+	if _, err := s.calendarUC.SyncSubscriptions(ctx); err != nil {
+		slog.ErrorContext(ctx, "Failed to sync calendar subscriptions", "error", err)
+	}
+}
+
+func (s *Scheduler) isLeader(ctx context.Context) bool {
+	if s.leader == nil {
+		return true
+	}
+	isLeader, err := s.leader.TryAcquire(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to check scheduler leadership", "error", err)
+		return false
+	}
+	if !isLeader {
+		slog.InfoContext(ctx, "Another instance is the scheduler leader, skipping run")
+	}
+	return isLeader
+}
+
+// catchUp runs the morning and evening jobs whose last scheduled time was
+// missed by less than the catch-up window. Users who already got that run's
+// alert are skipped, so this is safe even if the run did happen.
+func (s *Scheduler) catchUp(now time.Time) {
+	if s.catchUpWindow <= 0 {
+		return
+	}
+
+	if runAt, ok := s.lastRun(s.morningTime, now); ok && now.Sub(runAt) <= s.catchUpWindow {
+		slog.Info("Catching up morning alert run", "run_at", runAt)
+		s.runMorning(runAt)
+	}
+	if runAt, ok := s.lastRun(s.eveningTime, now); ok && now.Sub(runAt) <= s.catchUpWindow {
+		slog.Info("Catching up evening summary run", "run_at", runAt)
+		s.runEvening(runAt)
+	}
+}
+
+// lastRun returns the most recent time at or before now that a job scheduled
+// daily at timeStr ("HH:MM") should have run.
+func (s *Scheduler) lastRun(timeStr string, now time.Time) (time.Time, bool) {
+	if len(timeStr) < 5 {
+		return time.Time{}, false
+	}
+	hour, err := strconv.Atoi(timeStr[0:2])
+	if err != nil {
+		return time.Time{}, false
+	}
+	minute, err := strconv.Atoi(timeStr[3:5])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	runAt := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if runAt.After(now) {
+		runAt = runAt.AddDate(0, 0, -1)
+	}
+	return runAt, true
+}
+
 func (s *Scheduler) parseTimeToCron(timeStr string) string {
 	// Parse time string like "05:00" or "22:00" to cron format "0 H * * *"
 	// For simplicity, assume format is "HH:MM"
@@ -74,8 +285,34 @@ func (s *Scheduler) parseTimeToCron(timeStr string) string {
 	return "0 5 * * *"
 }
 
+// State returns the current state of the scheduler.
+func (s *Scheduler) State() State {
+	state := State{
+		Running:  s.running.Load(),
+		Leader:   s.leader == nil || s.leader.Held(),
+		LastTick: time.Unix(0, s.lastTick.Load()),
+	}
+	now := time.Now().In(s.location)
+	if runAt, ok := s.lastRun(s.morningTime, now); ok {
+		state.NextMorning = runAt.AddDate(0, 0, 1)
+	}
+	if runAt, ok := s.lastRun(s.eveningTime, now); ok {
+		state.NextEvening = runAt.AddDate(0, 0, 1)
+	}
+	return state
+}
+
 func (s *Scheduler) Stop() {
+	s.running.Store(false)
 	s.cron.Stop()
-	log.Println("Scheduler stopped")
+	if s.leader != nil {
+		s.leader.Release(context.Background())
+	}
+	slog.Info("Scheduler stopped")
 }
 
+// newRunContext returns the context of one job run, carrying a fresh
+// correlation ID so the messages it queues can be traced back to it.
+func newRunContext() context.Context {
+	return logging.WithCorrelationID(context.Background(), logging.NewCorrelationID())
+}
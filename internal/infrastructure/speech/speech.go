@@ -0,0 +1,170 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transcriber turns recorded speech into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio Audio) (string, error)
+}
+
+// Audio is a recording to transcribe. Filename may be empty: WhatsApp voice
+// notes only carry a mimetype.
+type Audio struct {
+	Data     []byte
+	Filename string
+	Mimetype string
+}
+
+// OpenAITranscriber uses an OpenAI-compatible /audio/transcriptions endpoint
+// (OpenAI, Groq, faster-whisper-server, LocalAI, ...).
+type OpenAITranscriber struct {
+	apiKey   string
+	model    string
+	language string
+	baseURL  string
+	client   *http.Client
+}
+
+func NewOpenAITranscriber(apiKey, model, baseURL, language string, timeout time.Duration) *OpenAITranscriber {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAITranscriber{
+		apiKey:   apiKey,
+		model:    model,
+		language: language,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	fields := map[string]string{
+		"model":           t.model,
+		"language":        t.language,
+		"response_format": "json",
+	}
+	return transcribe(ctx, t.client, t.baseURL+"/audio/transcriptions", t.apiKey, fields, audio)
+}
+
+// WhisperTranscriber uses the /inference endpoint of a local whisper.cpp
+// server, so voice notes never leave the machine.
+type WhisperTranscriber struct {
+	language string
+	baseURL  string
+	client   *http.Client
+}
+
+func NewWhisperTranscriber(baseURL, language string, timeout time.Duration) *WhisperTranscriber {
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	return &WhisperTranscriber{
+		language: language,
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+func (t *WhisperTranscriber) Transcribe(ctx context.Context, audio Audio) (string, error) {
+	fields := map[string]string{
+		"language":        t.language,
+		"response_format": "json",
+		"temperature":     "0",
+	}
+	return transcribe(ctx, t.client, t.baseURL+"/inference", "", fields, audio)
+}
+
+type transcriptionResponse struct {
+	Text string `json:"text"`
+}
+
+// transcribe posts the audio as multipart form data and returns the "text"
+// field of the JSON response. Empty fields are left out.
+func transcribe(ctx context.Context, client *http.Client, url, apiKey string, fields map[string]string, audio Audio) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return "", fmt.Errorf("failed to write form field: %w", err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", fileName(audio))
+	if err != nil {
+		return "", fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(audio.Data); err != nil {
+		return "", fmt.Errorf("failed to write audio: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to close form: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result transcriptionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
+
+// audioExtensions maps audio mimetypes to the file extension transcription
+// servers use to detect the format.
+var audioExtensions = map[string]string{
+	"audio/ogg":   ".ogg",
+	"audio/opus":  ".ogg",
+	"audio/mpeg":  ".mp3",
+	"audio/mp3":   ".mp3",
+	"audio/mp4":   ".m4a",
+	"audio/m4a":   ".m4a",
+	"audio/x-m4a": ".m4a",
+	"audio/aac":   ".m4a",
+	"audio/wav":   ".wav",
+	"audio/x-wav": ".wav",
+	"audio/webm":  ".webm",
+	"audio/flac":  ".flac",
+}
+
+func fileName(audio Audio) string {
+	if audio.Filename != "" {
+		return audio.Filename
+	}
+	mimetype, _, _ := strings.Cut(audio.Mimetype, ";")
+	if ext, ok := audioExtensions[strings.ToLower(strings.TrimSpace(mimetype))]; ok {
+		return "voice" + ext
+	}
+	// WhatsApp voice notes are Ogg/Opus
+	return "voice.ogg"
+}
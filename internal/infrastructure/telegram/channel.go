@@ -0,0 +1,184 @@
+package telegram
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/channel"
+)
+
+// Channel connects the app to Telegram through one bot. It has a single
+// account, so addresses leave Account empty.
+type Channel struct {
+	client *Client
+	// secret is the secret_token given to setWebhook; Telegram sends it back
+	// in every webhook request
+	secret string
+}
+
+func NewChannel(client *Client, webhookSecret string) *Channel {
+	return &Channel{client: client, secret: webhookSecret}
+}
+
+func (ch *Channel) Name() string {
+	return channel.Telegram
+}
+
+// ParseWebhook reads a Telegram update: messages from private chats and taps
+// on inline keyboard buttons. Taps are answered right away so the button
+// stops loading.
+func (ch *Channel) ParseWebhook(r *http.Request) ([]channel.Event, error) {
+	if ch.secret != "" {
+		token := r.Header.Get("X-Telegram-Bot-Api-Secret-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(ch.secret)) != 1 {
+			return nil, channel.ErrUnauthorized
+		}
+	}
+
+	var update Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		return nil, fmt.Errorf("failed to decode update: %w", err)
+	}
+
+	switch {
+	case update.Message != nil:
+		message := update.Message
+		if message.From == nil || message.From.IsBot || message.Chat.Type != "private" {
+			slog.DebugContext(r.Context(), "Ignoring Telegram message", "message_id", message.MessageID, "chat_type", message.Chat.Type)
+			return nil, nil
+		}
+		return []channel.Event{{Type: channel.EventMessage, Message: normalizeMessage(message)}}, nil
+	case update.CallbackQuery != nil:
+		query := update.CallbackQuery
+		if err := ch.client.AnswerCallbackQuery(r.Context(), query.ID); err != nil {
+			slog.WarnContext(r.Context(), "Failed to answer Telegram callback query", "error", err)
+		}
+		if query.Message == nil {
+			return nil, nil
+		}
+		return []channel.Event{{Type: channel.EventMessage, Message: &channel.Message{
+			Address:    address(query.Message.Chat),
+			ID:         "callback:" + query.ID,
+			SenderID:   strconv.FormatInt(query.From.ID, 10),
+			SenderName: query.From.FirstName,
+			Kind:       channel.KindText,
+			ChoiceID:   query.Data,
+			Timestamp:  time.Now(),
+		}}}, nil
+	default:
+		slog.DebugContext(r.Context(), "Ignoring Telegram update", "update_id", update.UpdateID)
+		return nil, nil
+	}
+}
+
+func normalizeMessage(message *Message) *channel.Message {
+	normalized := &channel.Message{
+		Address:    address(message.Chat),
+		ID:         messageID(message),
+		SenderID:   strconv.FormatInt(message.From.ID, 10),
+		SenderName: message.From.FirstName,
+		Body:       message.Text,
+		Kind:       channel.KindText,
+		Timestamp:  time.Unix(message.Date, 0),
+	}
+	if message.Caption != "" {
+		normalized.Body = message.Caption
+	}
+	if message.ReplyToMessage != nil {
+		normalized.ReplyToID = messageID(message.ReplyToMessage)
+	}
+
+	switch {
+	case message.Voice != nil:
+		normalized.Kind = channel.KindVoice
+		normalized.Media = media(message.Voice, "voice.ogg")
+	case message.Audio != nil:
+		normalized.Kind = channel.KindVoice
+		normalized.Media = media(message.Audio, "audio")
+	case len(message.Photo) > 0:
+		// Sizes are listed smallest first
+		photo := message.Photo[len(message.Photo)-1]
+		normalized.Kind = channel.KindImage
+		normalized.Media = &channel.Media{Ref: photo.FileID, Mimetype: "image/jpeg", Filename: "photo.jpg"}
+	case message.Document != nil:
+		normalized.Kind = channel.KindDocument
+		normalized.Media = media(message.Document, "document")
+		if strings.HasPrefix(message.Document.MimeType, "image/") {
+			normalized.Kind = channel.KindImage
+		}
+	case message.Sticker != nil:
+		normalized.Kind = channel.KindOther
+	}
+	return normalized
+}
+
+func media(file *File, defaultName string) *channel.Media {
+	filename := file.FileName
+	if filename == "" {
+		filename = defaultName
+	}
+	return &channel.Media{Ref: file.FileID, Mimetype: file.MimeType, Filename: filename}
+}
+
+func address(chat Chat) channel.Address {
+	return channel.Address{Channel: channel.Telegram, ChatID: strconv.FormatInt(chat.ID, 10)}
+}
+
+// messageID makes Telegram's per-chat message IDs unique across chats.
+func messageID(message *Message) string {
+	return fmt.Sprintf("%d:%d", message.Chat.ID, message.MessageID)
+}
+
+// Send delivers an outbox message. Buttons, lists and polls become an inline
+// keyboard with one button per choice; tapping one sends the choice ID back.
+func (ch *Channel) Send(ctx context.Context, message *entity.OutboundMessage) (string, error) {
+	payload := message.Payload
+	if payload == nil {
+		payload = &entity.OutboundPayload{}
+	}
+
+	var sent *Message
+	var err error
+	switch message.ContentType {
+	case entity.ContentTypeButtons, entity.ContentTypeList, entity.ContentTypePoll:
+		keyboard := &InlineKeyboardMarkup{}
+		for _, choice := range payload.Choices {
+			keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []InlineKeyboardButton{
+				{Text: choice.Title, CallbackData: choice.ID},
+			})
+		}
+		text := message.Body
+		if payload.Footer != "" {
+			text += "\n\n_" + payload.Footer + "_"
+		}
+		sent, err = ch.client.SendMessage(ctx, message.ChatID, text, keyboard)
+	case entity.ContentTypeImage:
+		sent, err = ch.client.SendPhoto(ctx, message.ChatID, payload.MediaURL, message.Body)
+	case entity.ContentTypeFile:
+		sent, err = ch.client.SendDocument(ctx, message.ChatID, payload.MediaURL, message.Body)
+	default:
+		sent, err = ch.client.SendMessage(ctx, message.ChatID, message.Body, nil)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && !apiErr.Temporary() {
+		return "", fmt.Errorf("%w: %w", channel.ErrPermanent, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return messageID(sent), nil
+}
+
+// DownloadMedia downloads a file by the file ID in ref.
+func (ch *Channel) DownloadMedia(ctx context.Context, account, ref string, maxBytes int64) ([]byte, error) {
+	return ch.client.DownloadFile(ctx, ref, maxBytes)
+}
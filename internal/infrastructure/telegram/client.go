@@ -0,0 +1,299 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"smart_alert_system/internal/infrastructure/channel"
+)
+
+// DefaultBaseURL is the Telegram Bot API server.
+const DefaultBaseURL = "https://api.telegram.org"
+
+// Client calls the Telegram Bot API for one bot.
+type Client struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// APIError is returned when the Bot API answers a call with ok=false.
+type APIError struct {
+	StatusCode  int
+	Description string
+	// RetryAfter is how many seconds to wait after a flood limit (429)
+	RetryAfter int
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram error %d: %s", e.StatusCode, e.Description)
+}
+
+// Temporary reports whether retrying the same call may succeed. Bad chat IDs,
+// blocked bots and bad tokens are permanent.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Update is a Telegram webhook update. Only the kinds the app handles are
+// decoded.
+type Update struct {
+	UpdateID      int64          `json:"update_id"`
+	Message       *Message       `json:"message"`
+	CallbackQuery *CallbackQuery `json:"callback_query"`
+}
+
+type Message struct {
+	MessageID int64       `json:"message_id"`
+	From      *User       `json:"from"`
+	Chat      Chat        `json:"chat"`
+	Date      int64       `json:"date"`
+	Text      string      `json:"text"`
+	Caption   string      `json:"caption"`
+	Voice     *File       `json:"voice"`
+	Audio     *File       `json:"audio"`
+	Photo     []PhotoSize `json:"photo"`
+	Document  *File       `json:"document"`
+	Sticker   *File       `json:"sticker"`
+	// ReplyToMessage is the message this one quotes
+	ReplyToMessage *Message `json:"reply_to_message"`
+}
+
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+}
+
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"`
+}
+
+// File is a voice note, audio file, document or sticker.
+type File struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	MimeType string `json:"mime_type"`
+	FileSize int64  `json:"file_size"`
+}
+
+type PhotoSize struct {
+	FileID   string `json:"file_id"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	FileSize int64  `json:"file_size"`
+}
+
+// CallbackQuery is sent when the user taps an inline keyboard button.
+type CallbackQuery struct {
+	ID      string   `json:"id"`
+	From    User     `json:"from"`
+	Message *Message `json:"message"`
+	Data    string   `json:"data"`
+}
+
+type InlineKeyboardMarkup struct {
+	InlineKeyboard [][]InlineKeyboardButton `json:"inline_keyboard"`
+}
+
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
+}
+
+type sendMessageRequest struct {
+	ChatID      string                `json:"chat_id"`
+	Text        string                `json:"text"`
+	ParseMode   string                `json:"parse_mode,omitempty"`
+	ReplyMarkup *InlineKeyboardMarkup `json:"reply_markup,omitempty"`
+}
+
+type sendMediaRequest struct {
+	ChatID    string `json:"chat_id"`
+	Photo     string `json:"photo,omitempty"`
+	Document  string `json:"document,omitempty"`
+	Caption   string `json:"caption,omitempty"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
+
+// The legacy Markdown mode reads *bold* and _italic_ like WhatsApp does
+const parseMode = "Markdown"
+
+// SendMessage sends a text message, with buttons if markup is set. Text that
+// isn't valid Markdown (e.g. an unpaired "_" in AI output) is sent as is.
+func (c *Client) SendMessage(ctx context.Context, chatID, text string, markup *InlineKeyboardMarkup) (*Message, error) {
+	req := sendMessageRequest{ChatID: chatID, Text: text, ParseMode: parseMode, ReplyMarkup: markup}
+	var sent Message
+	err := c.call(ctx, "sendMessage", req, &sent)
+	if isParseError(err) {
+		req.ParseMode = ""
+		err = c.call(ctx, "sendMessage", req, &sent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// SendPhoto sends the image at photoURL, which Telegram downloads itself.
+func (c *Client) SendPhoto(ctx context.Context, chatID, photoURL, caption string) (*Message, error) {
+	return c.sendMedia(ctx, "sendPhoto", sendMediaRequest{ChatID: chatID, Photo: photoURL, Caption: caption})
+}
+
+// SendDocument sends the file at documentURL, which Telegram downloads itself.
+func (c *Client) SendDocument(ctx context.Context, chatID, documentURL, caption string) (*Message, error) {
+	return c.sendMedia(ctx, "sendDocument", sendMediaRequest{ChatID: chatID, Document: documentURL, Caption: caption})
+}
+
+func (c *Client) sendMedia(ctx context.Context, method string, req sendMediaRequest) (*Message, error) {
+	if req.Caption != "" {
+		req.ParseMode = parseMode
+	}
+	var sent Message
+	err := c.call(ctx, method, req, &sent)
+	if isParseError(err) {
+		req.ParseMode = ""
+		err = c.call(ctx, method, req, &sent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// AnswerCallbackQuery stops the loading animation on the tapped button.
+func (c *Client) AnswerCallbackQuery(ctx context.Context, callbackQueryID string) error {
+	return c.call(ctx, "answerCallbackQuery", map[string]string{"callback_query_id": callbackQueryID}, nil)
+}
+
+// DownloadFile fetches a file of an incoming message by its file ID. It
+// returns channel.ErrMediaTooLarge for files over maxBytes.
+func (c *Client) DownloadFile(ctx context.Context, fileID string, maxBytes int64) ([]byte, error) {
+	var file struct {
+		FileSize int64  `json:"file_size"`
+		FilePath string `json:"file_path"`
+	}
+	if err := c.call(ctx, "getFile", map[string]string{"file_id": fileID}, &file); err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file.FileSize > maxBytes {
+		return nil, channel.ErrMediaTooLarge
+	}
+
+	fileURL := fmt.Sprintf("%s/file/bot%s/%s", c.baseURL, c.token, file.FilePath)
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &APIError{StatusCode: resp.StatusCode, Description: string(body)}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, channel.ErrMediaTooLarge
+	}
+	return data, nil
+}
+
+// call posts a Bot API method and decodes its result into result, if given.
+func (c *Client) call(ctx context.Context, method string, body, result interface{}) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method)
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The URL holds the bot token; don't let it end up in logs
+		return fmt.Errorf("failed to call %s: %w", method, unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var apiResp struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+		Parameters  struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return &APIError{StatusCode: resp.StatusCode, Description: http.StatusText(resp.StatusCode)}
+		}
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if !apiResp.OK {
+		code := apiResp.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return &APIError{StatusCode: code, Description: apiResp.Description, RetryAfter: apiResp.Parameters.RetryAfter}
+	}
+
+	if result != nil && len(apiResp.Result) > 0 {
+		if err := json.Unmarshal(apiResp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode result: %w", err)
+		}
+	}
+	return nil
+}
+
+// isParseError reports whether Telegram rejected the text's formatting.
+func isParseError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest &&
+		strings.Contains(apiErr.Description, "can't parse entities")
+}
+
+// unwrapURLError drops the request URL from HTTP client errors.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
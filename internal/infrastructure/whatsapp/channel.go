@@ -0,0 +1,108 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/channel"
+)
+
+// Channel connects the app to WhatsApp through the configured Waha sessions.
+// Accounts are session names.
+type Channel struct {
+	sessions *Sessions
+}
+
+func NewChannel(sessions *Sessions) *Channel {
+	return &Channel{sessions: sessions}
+}
+
+func (ch *Channel) Name() string {
+	return channel.WhatsApp
+}
+
+// Welcome returns the welcome text of the session, "" for the built-in one.
+func (ch *Channel) Welcome(account string) string {
+	return ch.sessions.Resolve(account).Welcome
+}
+
+// Send delivers the message with the Waha endpoint of its content type,
+// through the message's session. Messages of sessions that are no longer
+// configured go out through the default session.
+func (ch *Channel) Send(ctx context.Context, message *entity.OutboundMessage) (string, error) {
+	resp, err := ch.send(ctx, ch.sessions.Resolve(message.Session).Client, message)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && !apiErr.Temporary() {
+		return "", fmt.Errorf("%w: %w", channel.ErrPermanent, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+func (ch *Channel) send(ctx context.Context, client *WahaClient, message *entity.OutboundMessage) (*SendMessageResponse, error) {
+	payload := message.Payload
+	if payload == nil {
+		payload = &entity.OutboundPayload{}
+	}
+
+	if len(payload.Mentions) > 0 {
+		// Mentions only work in text messages, so choices are listed to be
+		// answered by number
+		text := message.Body
+		if len(payload.Choices) > 0 {
+			titles := make([]string, len(payload.Choices))
+			for i, choice := range payload.Choices {
+				titles[i] = choice.Title
+			}
+			text = choicesText(text, titles, payload.Footer)
+		}
+		return client.SendMentions(ctx, message.ChatID, text, payload.Mentions)
+	}
+
+	switch message.ContentType {
+	case entity.ContentTypeButtons:
+		buttons := make([]Button, len(payload.Choices))
+		for i, choice := range payload.Choices {
+			buttons[i] = Button{ID: choice.ID, Text: choice.Title}
+		}
+		return client.SendButtons(ctx, message.ChatID, message.Body, payload.Footer, buttons)
+	case entity.ContentTypeList:
+		rows := make([]ListRow, len(payload.Choices))
+		for i, choice := range payload.Choices {
+			rows[i] = ListRow{ID: choice.ID, Title: choice.Title, Description: choice.Description}
+		}
+		button := payload.ListButton
+		if button == "" {
+			button = "Pilih"
+		}
+		return client.SendList(ctx, message.ChatID, ListMessage{
+			Description: message.Body,
+			Footer:      payload.Footer,
+			Button:      button,
+			Sections:    []ListSection{{Rows: rows}},
+		})
+	case entity.ContentTypePoll:
+		options := make([]string, len(payload.Choices))
+		for i, choice := range payload.Choices {
+			options[i] = choice.Title
+		}
+		return client.SendPoll(ctx, message.ChatID, message.Body, options, false)
+	case entity.ContentTypeImage:
+		file := File{URL: payload.MediaURL, Mimetype: payload.Mimetype, Filename: payload.Filename}
+		return client.SendImage(ctx, message.ChatID, file, message.Body)
+	case entity.ContentTypeFile:
+		file := File{URL: payload.MediaURL, Mimetype: payload.Mimetype, Filename: payload.Filename}
+		return client.SendFile(ctx, message.ChatID, file, message.Body)
+	default:
+		return client.SendText(ctx, message.ChatID, message.Body)
+	}
+}
+
+// DownloadMedia downloads a file through the session that received it.
+func (ch *Channel) DownloadMedia(ctx context.Context, account, ref string, maxBytes int64) ([]byte, error) {
+	return ch.sessions.Resolve(account).Client.DownloadMedia(ctx, ref, maxBytes)
+}
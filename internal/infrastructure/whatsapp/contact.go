@@ -0,0 +1,132 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParseChatID splits a Waha chat ID of a person into their LID or phone
+// number: "25675515867262@lid" has a LID, "6281234567890@c.us" (or
+// "@s.whatsapp.net", or a bare number) has a phone number. Device suffixes
+// ("628…:12@c.us") are dropped. Group and broadcast chat IDs have neither.
+func ParseChatID(chatID string) (lid, phoneNumber string) {
+	user, server, found := strings.Cut(strings.TrimSpace(chatID), "@")
+	user, _, _ = strings.Cut(user, ":")
+	user = strings.TrimPrefix(user, "+")
+	if user == "" || strings.Trim(user, "0123456789") != "" {
+		return "", ""
+	}
+
+	switch {
+	case server == "lid":
+		return user, ""
+	case !found, server == "c.us", server == "s.whatsapp.net":
+		return "", user
+	default:
+		return "", ""
+	}
+}
+
+// LIDChatID returns the chat ID of a LID.
+func LIDChatID(lid string) string {
+	return lid + "@lid"
+}
+
+// PhoneChatID returns the chat ID of a phone number.
+func PhoneChatID(phoneNumber string) string {
+	return phoneNumber + "@c.us"
+}
+
+// lidMapping is Waha's answer to a LID lookup. Either side is null when
+// WhatsApp hasn't shared the mapping with the session.
+type lidMapping struct {
+	LID string `json:"lid"`
+	PN  string `json:"pn"`
+}
+
+// PhoneNumberByLID asks Waha for the phone number behind a LID. It returns
+// "" if the number isn't known to the session.
+func (c *WahaClient) PhoneNumberByLID(ctx context.Context, lid string) (string, error) {
+	var mapping lidMapping
+	path := fmt.Sprintf("/api/%s/lids/%s", url.PathEscape(c.session), url.PathEscape(LIDChatID(lid)))
+	if err := c.get(ctx, path, &mapping); err != nil {
+		return "", err
+	}
+	_, phoneNumber := ParseChatID(mapping.PN)
+	return phoneNumber, nil
+}
+
+// LIDByPhoneNumber asks Waha for the LID of a phone number. It returns "" if
+// the LID isn't known to the session.
+func (c *WahaClient) LIDByPhoneNumber(ctx context.Context, phoneNumber string) (string, error) {
+	var mapping lidMapping
+	path := fmt.Sprintf("/api/%s/lids/pn/%s", url.PathEscape(c.session), url.PathEscape(phoneNumber))
+	if err := c.get(ctx, path, &mapping); err != nil {
+		return "", err
+	}
+	lid, _ := ParseChatID(mapping.LID)
+	return lid, nil
+}
+
+// get calls a Waha GET endpoint and decodes its JSON answer into result. A
+// 404 leaves result empty.
+func (c *WahaClient) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(c.baseURL, "/")+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-Api-Key", c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if len(body) == 0 || string(body) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// LookupContact returns the LID and phone number of the person behind a chat
+// ID, asking the session that received their message for the one the chat ID
+// doesn't carry. Engines without LID support just give back what the chat ID
+// has.
+func (ch *Channel) LookupContact(ctx context.Context, account, chatID string) (lid, phoneNumber string, err error) {
+	lid, phoneNumber = ParseChatID(chatID)
+	client := ch.sessions.Resolve(account).Client
+
+	switch {
+	case lid != "":
+		phoneNumber, err = client.PhoneNumberByLID(ctx, lid)
+	case phoneNumber != "":
+		lid, err = client.LIDByPhoneNumber(ctx, phoneNumber)
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Unsupported() {
+		err = nil
+	}
+	return lid, phoneNumber, err
+}
@@ -0,0 +1,154 @@
+package whatsapp
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// maxIdleChatBuckets bounds how many per-chat buckets are kept before idle
+// (fully refilled) ones are dropped.
+const maxIdleChatBuckets = 10000
+
+// RateLimitConfig configures outbound throttling. WhatsApp bans numbers that
+// send in bursts, so sends are limited globally and per chat, and consecutive
+// sends are spaced by a random jitter to avoid a machine-like rhythm.
+// A rate of zero disables that limit.
+//
+// The limits are kept in memory, so they apply per replica: with N replicas
+// dispatching the outbox, a session may send up to N times the global rate.
+type RateLimitConfig struct {
+	GlobalPerMinute int
+	GlobalBurst     int
+	ChatPerMinute   int
+	ChatBurst       int
+	JitterMin       time.Duration
+	JitterMax       time.Duration
+}
+
+type RateLimiter struct {
+	cfg    RateLimitConfig
+	mu     sync.Mutex
+	global *tokenBucket
+	chats  map[string]*tokenBucket
+	// notBefore is the end of the jitter gap after the last send
+	notBefore time.Time
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	limiter := &RateLimiter{
+		cfg:   cfg,
+		chats: make(map[string]*tokenBucket),
+	}
+	if cfg.GlobalPerMinute > 0 {
+		limiter.global = newTokenBucket(cfg.GlobalPerMinute, cfg.GlobalBurst)
+	}
+	return limiter
+}
+
+// Reserve takes a send slot for chatID and returns zero if one is free now.
+// Otherwise it takes nothing and returns how long until one may be free, so
+// the caller can retry later instead of blocking.
+func (l *RateLimiter) Reserve(chatID string) time.Duration {
+	return l.reserve(chatID, time.Now())
+}
+
+func (l *RateLimiter) reserve(chatID string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var wait time.Duration
+	if now.Before(l.notBefore) {
+		wait = l.notBefore.Sub(now)
+	}
+	if l.global != nil {
+		wait = max(wait, l.global.wait(now))
+	}
+
+	var bucket *tokenBucket
+	if l.cfg.ChatPerMinute > 0 {
+		var ok bool
+		bucket, ok = l.chats[chatID]
+		if !ok {
+			if len(l.chats) >= maxIdleChatBuckets {
+				l.evictIdle(now)
+			}
+			bucket = newTokenBucket(l.cfg.ChatPerMinute, l.cfg.ChatBurst)
+			l.chats[chatID] = bucket
+		}
+		wait = max(wait, bucket.wait(now))
+	}
+	if wait > 0 {
+		return wait
+	}
+
+	// Take from both buckets only once both have a token, so a throttled
+	// chat doesn't use up the global rate
+	if l.global != nil {
+		l.global.tokens--
+	}
+	if bucket != nil {
+		bucket.tokens--
+	}
+	l.notBefore = now.Add(l.jitter())
+	return 0
+}
+
+func (l *RateLimiter) evictIdle(now time.Time) {
+	for chatID, bucket := range l.chats {
+		if bucket.full(now) {
+			delete(l.chats, chatID)
+		}
+	}
+}
+
+func (l *RateLimiter) jitter() time.Duration {
+	if l.cfg.JitterMax <= l.cfg.JitterMin {
+		return l.cfg.JitterMin
+	}
+	return l.cfg.JitterMin + time.Duration(rand.Int64N(int64(l.cfg.JitterMax-l.cfg.JitterMin)))
+}
+
+// tokenBucket refills continuously at rate tokens per second up to burst.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(perMinute, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+}
+
+// wait returns how long until the bucket has a whole token, zero if it has
+// one now.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
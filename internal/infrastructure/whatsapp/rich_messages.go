@@ -0,0 +1,189 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Shown under the numbered choices when buttons, lists or polls fall back to
+// plain text
+const choiceFallbackHint = "_Balas dengan nomor pilihan Anda._"
+
+// Button is a quick reply button. ID comes back in the webhook when the user
+// taps it.
+type Button struct {
+	ID   string
+	Text string
+}
+
+// ListMessage is a message with a button that opens a list of rows.
+type ListMessage struct {
+	Title       string
+	Description string
+	Footer      string
+	// Button is the label of the button that opens the list
+	Button   string
+	Sections []ListSection
+}
+
+type ListSection struct {
+	Title string    `json:"title"`
+	Rows  []ListRow `json:"rows"`
+}
+
+type ListRow struct {
+	ID          string `json:"rowId"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// File is an image or document to send, given either by URL or as data.
+type File struct {
+	URL      string `json:"url,omitempty"`
+	Data     []byte `json:"data,omitempty"`
+	Mimetype string `json:"mimetype"`
+	Filename string `json:"filename,omitempty"`
+}
+
+type sendButtonsRequest struct {
+	Session string          `json:"session"`
+	ChatID  string          `json:"chatId"`
+	Body    string          `json:"body"`
+	Footer  string          `json:"footer,omitempty"`
+	Buttons []buttonPayload `json:"buttons"`
+}
+
+type buttonPayload struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	Text string `json:"text"`
+}
+
+type sendListRequest struct {
+	Session string      `json:"session"`
+	ChatID  string      `json:"chatId"`
+	Message listPayload `json:"message"`
+}
+
+type listPayload struct {
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Footer      string        `json:"footer,omitempty"`
+	Button      string        `json:"button"`
+	Sections    []ListSection `json:"sections"`
+}
+
+type sendPollRequest struct {
+	Session string      `json:"session"`
+	ChatID  string      `json:"chatId"`
+	Poll    pollPayload `json:"poll"`
+}
+
+type pollPayload struct {
+	Name            string   `json:"name"`
+	Options         []string `json:"options"`
+	MultipleAnswers bool     `json:"multipleAnswers"`
+}
+
+type sendFileRequest struct {
+	Session string `json:"session"`
+	ChatID  string `json:"chatId"`
+	File    File   `json:"file"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// SendButtons sends a message with quick reply buttons. Engines without
+// button support get the buttons as a numbered list instead.
+func (c *WahaClient) SendButtons(ctx context.Context, chatID, body, footer string, buttons []Button) (*SendMessageResponse, error) {
+	titles := make([]string, len(buttons))
+	payload := make([]buttonPayload, len(buttons))
+	for i, button := range buttons {
+		titles[i] = button.Text
+		payload[i] = buttonPayload{Type: "reply", ID: button.ID, Text: button.Text}
+	}
+
+	return c.send(ctx, "/api/sendButtons", chatID, func(chatID string) interface{} {
+		return sendButtonsRequest{Session: c.session, ChatID: chatID, Body: body, Footer: footer, Buttons: payload}
+	}, choicesText(body, titles, footer))
+}
+
+// SendList sends a message whose rows open from a button.
+func (c *WahaClient) SendList(ctx context.Context, chatID string, list ListMessage) (*SendMessageResponse, error) {
+	var titles []string
+	for _, section := range list.Sections {
+		for _, row := range section.Rows {
+			title := row.Title
+			if row.Description != "" {
+				title += " - " + row.Description
+			}
+			titles = append(titles, title)
+		}
+	}
+	text := list.Description
+	if list.Title != "" {
+		text = "*" + list.Title + "*\n" + text
+	}
+
+	return c.send(ctx, "/api/sendList", chatID, func(chatID string) interface{} {
+		return sendListRequest{Session: c.session, ChatID: chatID, Message: listPayload{
+			Title:       list.Title,
+			Description: list.Description,
+			Footer:      list.Footer,
+			Button:      list.Button,
+			Sections:    list.Sections,
+		}}
+	}, choicesText(text, titles, list.Footer))
+}
+
+// SendPoll sends a poll. Votes arrive as "poll.vote" webhook events.
+func (c *WahaClient) SendPoll(ctx context.Context, chatID, name string, options []string, multipleAnswers bool) (*SendMessageResponse, error) {
+	return c.send(ctx, "/api/sendPoll", chatID, func(chatID string) interface{} {
+		return sendPollRequest{Session: c.session, ChatID: chatID, Poll: pollPayload{
+			Name:            name,
+			Options:         options,
+			MultipleAnswers: multipleAnswers,
+		}}
+	}, choicesText("📊 "+name, options, ""))
+}
+
+// SendImage sends an image with an optional caption.
+func (c *WahaClient) SendImage(ctx context.Context, chatID string, file File, caption string) (*SendMessageResponse, error) {
+	return c.send(ctx, "/api/sendImage", chatID, func(chatID string) interface{} {
+		return sendFileRequest{Session: c.session, ChatID: chatID, File: file, Caption: caption}
+	}, fileText(file, caption))
+}
+
+// SendFile sends a document with an optional caption.
+func (c *WahaClient) SendFile(ctx context.Context, chatID string, file File, caption string) (*SendMessageResponse, error) {
+	return c.send(ctx, "/api/sendFile", chatID, func(chatID string) interface{} {
+		return sendFileRequest{Session: c.session, ChatID: chatID, File: file, Caption: caption}
+	}, fileText(file, caption))
+}
+
+// choicesText renders choices as a numbered list that can be answered by
+// number.
+func choicesText(text string, choices []string, footer string) string {
+	var b strings.Builder
+	b.WriteString(text)
+	b.WriteString("\n")
+	for i, choice := range choices {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, choice)
+	}
+	if footer != "" {
+		b.WriteString("\n\n" + footer)
+	}
+	b.WriteString("\n\n" + choiceFallbackHint)
+	return b.String()
+}
+
+// fileText links the file in the caption; files sent as data are lost.
+func fileText(file File, caption string) string {
+	if file.URL == "" {
+		return caption
+	}
+	if caption == "" {
+		return file.URL
+	}
+	return caption + "\n" + file.URL
+}
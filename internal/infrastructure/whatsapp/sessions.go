@@ -0,0 +1,57 @@
+package whatsapp
+
+// Session is one WhatsApp number connected to Waha, with the settings of the
+// assistant on that number.
+type Session struct {
+	Client *WahaClient
+	// Welcome is sent to users who message this number first; empty for the
+	// built-in text
+	Welcome string
+	// Persona tells the AI who it writes as on this number, e.g. a clinic
+	// nurse or a personal coach
+	Persona string
+}
+
+func (s *Session) Name() string {
+	return s.Client.Session()
+}
+
+// Sessions are the Waha sessions the server sends and receives through. The
+// first one is the default, used for users not bound to a session yet.
+type Sessions struct {
+	byName   map[string]*Session
+	sessions []*Session
+}
+
+func NewSessions(sessions ...*Session) *Sessions {
+	byName := make(map[string]*Session, len(sessions))
+	for _, session := range sessions {
+		byName[session.Name()] = session
+	}
+	return &Sessions{byName: byName, sessions: sessions}
+}
+
+// Get returns the session with this name, or false if it isn't configured.
+func (s *Sessions) Get(name string) (*Session, bool) {
+	session, ok := s.byName[name]
+	return session, ok
+}
+
+// Default returns the first configured session.
+func (s *Sessions) Default() *Session {
+	return s.sessions[0]
+}
+
+// Resolve returns the session with this name, or the default session if it
+// isn't configured (e.g. empty, or removed since the user was bound to it).
+func (s *Sessions) Resolve(name string) *Session {
+	if session, ok := s.byName[name]; ok {
+		return session
+	}
+	return s.Default()
+}
+
+// All returns the configured sessions, the default first.
+func (s *Sessions) All() []*Session {
+	return s.sessions
+}
@@ -2,18 +2,35 @@ package whatsapp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
+
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/logging"
+	"smart_alert_system/internal/infrastructure/metrics"
+	"smart_alert_system/internal/infrastructure/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// WahaClient sends through one Waha session, i.e. one WhatsApp number.
 type WahaClient struct {
 	baseURL string
+	session string
 	apiKey  string
 	client  *http.Client
+	limiter *RateLimiter
+	// Send endpoints the engine answered as not implemented
+	unsupported sync.Map
 }
 
 type WahaMessage struct {
@@ -29,6 +46,8 @@ type SendMessageRequest struct {
 	Session string `json:"session,omitempty"` // Optional session name
 	ChatID  string `json:"chatId"`
 	Text    string `json:"text"`
+	// Mentions are the chat IDs @mentioned in Text
+	Mentions []string `json:"mentions,omitempty"`
 }
 
 type SendMessageResponse struct {
@@ -36,9 +55,83 @@ type SendMessageResponse struct {
 	ID   string `json:"id"`
 }
 
-func NewWahaClient(baseURL, apiKey string) *WahaClient {
+// UnmarshalJSON accepts the different shapes Waha engines return for a sent
+// message: a plain string ID, a WEBJS ID object, or a NOWEB "key" object.
+func (r *SendMessageResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Sent bool            `json:"sent"`
+		ID   json.RawMessage `json:"id"`
+		Key  *struct {
+			ID string `json:"id"`
+		} `json:"key"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	r.Sent = raw.Sent
+	r.ID = ""
+	if len(raw.ID) > 0 {
+		var id string
+		if err := json.Unmarshal(raw.ID, &id); err == nil {
+			r.ID = id
+		} else {
+			var nested struct {
+				ID         string `json:"id"`
+				Serialized string `json:"_serialized"`
+			}
+			if err := json.Unmarshal(raw.ID, &nested); err == nil {
+				r.ID = nested.Serialized
+				if r.ID == "" {
+					r.ID = nested.ID
+				}
+			}
+		}
+	}
+	if r.ID == "" && raw.Key != nil {
+		r.ID = raw.Key.ID
+	}
+	return nil
+}
+
+// APIError is returned when Waha answers with a non-success status code.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether retrying the same request may succeed.
+// Client errors other than timeouts and rate limiting are permanent.
+func (e *APIError) Temporary() bool {
+	if e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return e.StatusCode >= 500
+}
+
+// Unsupported reports whether the endpoint isn't available for the session's
+// engine (e.g. buttons on WEBJS) or in this Waha version.
+func (e *APIError) Unsupported() bool {
+	if e.StatusCode == http.StatusNotImplemented {
+		return true
+	}
+	body := strings.ToLower(e.Body)
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusUnprocessableEntity:
+		return strings.Contains(body, "not implemented") || strings.Contains(body, "not supported") ||
+			strings.Contains(body, "cannot post")
+	}
+	return false
+}
+
+func NewWahaClient(baseURL, session, apiKey string) *WahaClient {
 	return &WahaClient{
 		baseURL: baseURL,
+		session: session,
 		apiKey:  apiKey,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
@@ -46,60 +139,230 @@ func NewWahaClient(baseURL, apiKey string) *WahaClient {
 	}
 }
 
+// Session returns the name of the Waha session the client sends through.
+func (c *WahaClient) Session() string {
+	return c.session
+}
+
+// SetRateLimiter throttles every send made through this client.
+func (c *WahaClient) SetRateLimiter(limiter *RateLimiter) {
+	c.limiter = limiter
+}
+
 func (c *WahaClient) SendMessage(chatID, message string) error {
-	// Remove trailing slash from baseURL
-	baseURL := strings.TrimSuffix(c.baseURL, "/")
+	_, err := c.SendText(context.Background(), chatID, message)
+	return err
+}
+
+// SendText sends a text message and returns Waha's response, including the
+// WhatsApp message ID used later to correlate ack events.
+func (c *WahaClient) SendText(ctx context.Context, chatID, message string) (*SendMessageResponse, error) {
+	return c.send(ctx, sendTextPath, chatID, nil, message)
+}
 
-	// Format chatID - ensure it has proper format
-	formattedChatID := chatID
-	if !strings.Contains(chatID, "@") {
-		// If no @, assume it's a phone number, add @c.us
-		formattedChatID = chatID + "@c.us"
+// SendMentions sends a text message that @mentions the members in mentions;
+// message must contain their "@number" tags.
+func (c *WahaClient) SendMentions(ctx context.Context, chatID, message string, mentions []string) (*SendMessageResponse, error) {
+	return c.send(ctx, sendTextPath, chatID, func(chatID string) interface{} {
+		return SendMessageRequest{Session: c.session, ChatID: chatID, Text: message, Mentions: mentions}
+	}, message)
+}
+
+// formatChatID completes a chat ID without a server part. Those come from
+// identities backfilled from users.whatsapp_number, which held LIDs as well
+// as phone numbers before contacts existed, so the ID is ambiguous: one Waha
+// knows as a LID is sent to the phone number behind it, anything else is
+// taken for a phone number.
+func (c *WahaClient) formatChatID(ctx context.Context, chatID string) string {
+	if strings.Contains(chatID, "@") {
+		return chatID
+	}
+	phoneNumber, err := c.PhoneNumberByLID(ctx, chatID)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to look up bare WhatsApp chat ID as a LID", "chat_id", chatID, "error", err)
+	}
+	if phoneNumber != "" {
+		return PhoneChatID(phoneNumber)
 	}
+	return PhoneChatID(chatID)
+}
 
-	// Use the correct format based on Waha API documentation
-	// Format: POST /api/sendText with {"session":"default","chatId":"...","text":"..."}
-	url := fmt.Sprintf("%s/api/sendText", baseURL)
+const sendTextPath = "/api/sendText"
+
+// send posts a message to a Waha send endpoint. request builds the body for
+// the formatted chat ID; for rich messages (buttons, polls, ...) that the
+// engine doesn't support, fallbackText is sent as plain text instead.
+func (c *WahaClient) send(ctx context.Context, path, chatID string, request func(chatID string) interface{}, fallbackText string) (*SendMessageResponse, error) {
+	formattedChatID := c.formatChatID(ctx, chatID)
+
+	if c.limiter != nil {
+		if wait := c.limiter.Reserve(formattedChatID); wait > 0 {
+			return nil, &channel.ThrottledError{RetryAfter: wait}
+		}
+	}
 
-	reqBody := SendMessageRequest{
-		Session: "default", // Session name is required
+	// Format: POST /api/sendText with {"session":"default","chatId":"...","text":"..."}
+	textRequest := SendMessageRequest{
+		Session: c.session, // Session name is required
 		ChatID:  formattedChatID,
-		Text:    message,
+		Text:    fallbackText,
+	}
+	if request == nil {
+		return c.post(ctx, sendTextPath, textRequest)
 	}
+	if _, unsupported := c.unsupported.Load(path); unsupported {
+		return c.post(ctx, sendTextPath, textRequest)
+	}
+
+	resp, err := c.post(ctx, path, request(formattedChatID))
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Unsupported() {
+		slog.WarnContext(ctx, "Waha endpoint not supported by this engine, sending text instead", "path", path, "error", err)
+		c.unsupported.Store(path, true)
+		return c.post(ctx, sendTextPath, textRequest)
+	}
+	return resp, err
+}
+
+// post sends one request to a Waha send endpoint, traced as a client span.
+func (c *WahaClient) post(ctx context.Context, path string, body interface{}) (result *SendMessageResponse, err error) {
+	ctx, span := tracing.StartClient(ctx, "POST "+path,
+		attribute.String("waha.session", c.session), attribute.String("url.path", path))
+	defer func() { tracing.End(span, err) }()
 
-	jsonData, err := json.Marshal(reqBody)
+	// Remove trailing slash from baseURL
+	url := strings.TrimSuffix(c.baseURL, "/") + path
+
+	jsonData, err := json.Marshal(body)
 	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	if c.apiKey != "" {
 		req.Header.Set("X-Api-Key", c.apiKey)
 	}
+	if id := logging.CorrelationID(ctx); id != "" {
+		req.Header.Set(logging.CorrelationHeader, id)
+	}
+	tracing.InjectHeaders(ctx, req.Header)
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	metrics.WahaSendDuration.WithLabelValues(c.session, path).Observe(metrics.Since(start))
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		metrics.WahaSendFailures.WithLabelValues(c.session, path).Inc()
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	slog.DebugContext(ctx, "Waha request sent", "session", c.session, "path", path,
+		"status", resp.StatusCode, "duration_ms", time.Since(start).Milliseconds())
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
 
 	// Accept both 200 OK and 201 Created as success
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+		metrics.WahaSendFailures.WithLabelValues(c.session, path).Inc()
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
 	}
 
-	// Log success (optional, can be removed in production)
-	if resp.StatusCode == http.StatusCreated {
-		// 201 Created is also valid for POST requests
+	// The message was accepted; a body we can't decode only costs us the ID
+	result = &SendMessageResponse{Sent: true}
+	if len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, result); err == nil {
+			result.Sent = true
+		}
 	}
 
-	return nil
+	return result, nil
+}
+
+// DownloadMedia fetches a file attached to an incoming message. mediaURL is
+// the "media.url" of the webhook payload, absolute or relative to the Waha
+// server. The API key is only sent to the Waha server itself.
+func (c *WahaClient) DownloadMedia(ctx context.Context, mediaURL string, maxBytes int64) ([]byte, error) {
+	baseURL := strings.TrimSuffix(c.baseURL, "/")
+	if strings.HasPrefix(mediaURL, "/") {
+		mediaURL = baseURL + mediaURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", mediaURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" && strings.HasPrefix(mediaURL, baseURL+"/") {
+		req.Header.Set("X-Api-Key", c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read media: %w", err)
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, channel.ErrMediaTooLarge
+	}
+	return data, nil
+}
+
+// SessionWorking is the status of a Waha session that is connected to
+// WhatsApp and can send and receive.
+const SessionWorking = "WORKING"
+
+// SessionInfo is the state of a Waha session, e.g. "WORKING", "STARTING",
+// "SCAN_QR_CODE", "FAILED" or "STOPPED".
+type SessionInfo struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// SessionStatus asks Waha for the state of this client's session.
+func (c *WahaClient) SessionStatus(ctx context.Context) (*SessionInfo, error) {
+	endpoint := strings.TrimSuffix(c.baseURL, "/") + "/api/sessions/" + url.PathEscape(c.session)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-Api-Key", c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var info SessionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return &info, nil
 }
 
 func (c *WahaClient) GetWebhookURL() string {
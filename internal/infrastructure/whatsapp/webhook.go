@@ -0,0 +1,342 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"smart_alert_system/internal/infrastructure/channel"
+)
+
+// Waha webhook payload structure
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Timestamp int64       `json:"timestamp"`
+	Event     string      `json:"event"`
+	Session   string      `json:"session"`
+	Metadata  interface{} `json:"metadata"`
+	Me        struct {
+		ID       string `json:"id"`
+		PushName string `json:"pushName"`
+	} `json:"me"`
+	Payload MessageData `json:"payload"`
+}
+
+// MessageData structure from Waha (actual format)
+type MessageData struct {
+	ID        string        `json:"id"` // String format: "false_25675515867262@lid_AC91F329..."
+	Timestamp int64         `json:"timestamp"`
+	From      string        `json:"from"` // Format: "25675515867262@lid" or "6281234567890@c.us"
+	FromMe    bool          `json:"fromMe"`
+	Source    string        `json:"source"`
+	To        string        `json:"to"` // Format: "62881024952694@c.us"
+	Body      string        `json:"body"`
+	HasMedia  bool          `json:"hasMedia"`
+	Media     *MessageMedia `json:"media"`
+	Ack       int           `json:"ack"`
+	AckName   string        `json:"ackName"`
+	Data      *struct {
+		ID         *MessageID `json:"id"` // Nested ID structure in _data
+		Type       string     `json:"type"`
+		Body       string     `json:"body"`
+		From       string     `json:"from"`
+		To         string     `json:"to"`
+		NotifyName string     `json:"notifyName"`
+		// Members @mentioned in the message, as chat ID strings or as
+		// {"_serialized": "…"} objects depending on the engine
+		MentionedJidList []json.RawMessage `json:"mentionedJidList"`
+		// Answers to buttons and lists. WEBJS sets these; NOWEB and GOWS
+		// nest them in Message. Kept raw as their shapes vary by engine.
+		SelectedButtonID json.RawMessage `json:"selectedButtonId"`
+		ListResponse     json.RawMessage `json:"listResponse"`
+		Message          json.RawMessage `json:"message"`
+		// QuotedStanzaID is the ID of the quoted message (WEBJS)
+		QuotedStanzaID string `json:"quotedStanzaID"`
+	} `json:"_data"`
+	// ReplyTo is the quoted message, on engines that report it
+	ReplyTo *struct {
+		ID string `json:"id"`
+	} `json:"replyTo"`
+
+	// Participant is the member who wrote a message in a group; From is then
+	// the group ("120363…@g.us")
+	Participant string `json:"participant"`
+}
+
+// MessageMedia describes a file attached to a message. Waha leaves URL empty
+// if it couldn't download the file.
+type MessageMedia struct {
+	URL      string `json:"url"`
+	Mimetype string `json:"mimetype"`
+	Filename string `json:"filename"`
+}
+
+// Message ID structure (nested in _data)
+type MessageID struct {
+	FromMe     bool   `json:"fromMe"`
+	Remote     string `json:"remote"`
+	ID         string `json:"id"`
+	Serialized string `json:"_serialized"`
+}
+
+// PollVote is the payload of a Waha "poll.vote" event.
+type PollVote struct {
+	Vote struct {
+		ID              string   `json:"id"`
+		From            string   `json:"from"`
+		SelectedOptions []string `json:"selectedOptions"`
+	} `json:"vote"`
+	Poll struct {
+		ID string `json:"id"`
+	} `json:"poll"`
+}
+
+// ParseWebhook reads a Waha webhook: messages, message.ack and poll.vote
+// events. Events for sessions that aren't configured are dropped, as they
+// can't be answered through the right number.
+func (ch *Channel) ParseWebhook(r *http.Request) ([]channel.Event, error) {
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	ctx := r.Context()
+	slog.DebugContext(ctx, "Waha webhook payload", "bytes", len(bodyBytes), "payload", string(bodyBytes))
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(bodyBytes, &payload); err != nil {
+		slog.DebugContext(ctx, "Failed to decode Waha webhook payload, trying legacy format", "error", err)
+
+		// Try alternative format (legacy format)
+		var altPayload struct {
+			Event string          `json:"event"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if err2 := json.Unmarshal(bodyBytes, &altPayload); err2 == nil && altPayload.Event == "message" {
+			var messageData MessageData
+			if err3 := json.Unmarshal(altPayload.Data, &messageData); err3 != nil {
+				return nil, fmt.Errorf("failed to decode legacy message data: %w", err3)
+			}
+			return ch.messageEvents(ctx, ch.sessions.Default().Name(), messageData), nil
+		}
+		return nil, fmt.Errorf("failed to decode webhook payload: %w", err)
+	}
+
+	slog.DebugContext(ctx, "Waha webhook parsed", "event", payload.Event, "session", payload.Session)
+
+	// Payloads without a session name belong to the default session
+	session := ch.sessions.Default()
+	if payload.Session != "" {
+		var ok bool
+		if session, ok = ch.sessions.Get(payload.Session); !ok {
+			slog.WarnContext(ctx, "Ignoring event of unknown session (add it to WAHA_SESSIONS)", "session", payload.Session)
+			return nil, nil
+		}
+	}
+
+	switch payload.Event {
+	case "message":
+		return ch.messageEvents(ctx, session.Name(), payload.Payload), nil
+	case "message.ack":
+		ackData := payload.Payload
+		if !ackData.FromMe {
+			// Acks for messages the user sent to us carry nothing we track
+			return nil, nil
+		}
+		slog.DebugContext(ctx, "Waha ack", "ack", ackData.AckName, "level", ackData.Ack, "message_id", ackData.ID)
+		return []channel.Event{{Type: channel.EventAck, Ack: &channel.Ack{
+			MessageID: ackData.ID,
+			Recipient: ackData.To,
+			Level:     ackData.Ack,
+		}}}, nil
+	case "poll.vote":
+		var event struct {
+			Payload PollVote `json:"payload"`
+		}
+		if err := json.Unmarshal(bodyBytes, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode poll vote: %w", err)
+		}
+		vote := event.Payload
+		return []channel.Event{{Type: channel.EventVote, Vote: &channel.Vote{
+			Address:  channel.Address{Channel: channel.WhatsApp, Account: session.Name(), ChatID: vote.Vote.From},
+			ID:       vote.Vote.ID,
+			SenderID: vote.Vote.From,
+			PollID:   vote.Poll.ID,
+			Options:  vote.Vote.SelectedOptions,
+		}}}, nil
+	default:
+		slog.DebugContext(ctx, "Ignoring non-message event", "event", payload.Event)
+		return nil, nil
+	}
+}
+
+// messageEvents normalizes a message the user sent to session. Messages we
+// sent ourselves are left out.
+func (ch *Channel) messageEvents(ctx context.Context, session string, messageData MessageData) []channel.Event {
+	slog.DebugContext(ctx, "Waha message", "message_id", messageData.ID, "sender", messageData.From,
+		"recipient", messageData.To, "type", getMessageType(messageData), "timestamp", messageData.Timestamp,
+		"from_me", messageData.FromMe, "body", messageData.Body)
+
+	if messageData.FromMe {
+		slog.DebugContext(ctx, "Ignoring message from self", "message_id", messageData.ID)
+		return nil
+	}
+
+	senderID := messageData.From
+	group := isGroupChatID(messageData.From)
+	if group {
+		if messageData.Participant == "" {
+			slog.WarnContext(ctx, "Ignoring group message without participant", "message_id", messageData.ID)
+			return nil
+		}
+		senderID = messageData.Participant
+	}
+
+	message := &channel.Message{
+		// Replies go to the original 'from' format (with @lid or @c.us), or
+		// to the group
+		Address:   channel.Address{Channel: channel.WhatsApp, Account: session, ChatID: messageData.From},
+		ID:        messageData.ID,
+		SenderID:  senderID,
+		Group:     group,
+		Body:      messageData.Body,
+		Kind:      messageKind(messageData),
+		ChoiceID:  selectedChoiceID(messageData),
+		Timestamp: time.Unix(messageData.Timestamp, 0),
+	}
+	if messageData.Data != nil {
+		message.SenderName = messageData.Data.NotifyName
+		message.Mentions = mentionedChatIDs(messageData.Data.MentionedJidList)
+		message.ReplyToID = messageData.Data.QuotedStanzaID
+	}
+	if messageData.ReplyTo != nil && messageData.ReplyTo.ID != "" {
+		message.ReplyToID = messageData.ReplyTo.ID
+	}
+	if messageData.HasMedia && messageData.Media != nil {
+		message.Media = &channel.Media{
+			Ref:      messageData.Media.URL,
+			Mimetype: messageData.Media.Mimetype,
+			Filename: messageData.Media.Filename,
+		}
+	}
+	return []channel.Event{{Type: channel.EventMessage, Message: message}}
+}
+
+// isGroupChatID reports whether a chat ID is a group's.
+func isGroupChatID(chatID string) bool {
+	return strings.HasSuffix(chatID, "@g.us")
+}
+
+// mentionedChatIDs reads the chat IDs of mentionedJidList.
+func mentionedChatIDs(list []json.RawMessage) []string {
+	var chatIDs []string
+	for _, raw := range list {
+		var chatID string
+		if json.Unmarshal(raw, &chatID) != nil {
+			var wid struct {
+				Serialized string `json:"_serialized"`
+			}
+			if json.Unmarshal(raw, &wid) != nil {
+				continue
+			}
+			chatID = wid.Serialized
+		}
+		if chatID != "" {
+			chatIDs = append(chatIDs, chatID)
+		}
+	}
+	return chatIDs
+}
+
+// Helper function to get message type
+func getMessageType(msg MessageData) string {
+	if msg.Data != nil && msg.Data.Type != "" {
+		return msg.Data.Type
+	}
+	return "chat" // Default type
+}
+
+// messageKind tells voice notes, photos and documents apart. Waha marks
+// recorded voice notes as "ptt" (push to talk); stickers are KindOther.
+func messageKind(messageData MessageData) channel.MessageKind {
+	if !messageData.HasMedia {
+		return channel.KindText
+	}
+
+	var mimetype string
+	if messageData.Media != nil {
+		mimetype = strings.ToLower(messageData.Media.Mimetype)
+	}
+	if messageData.Data != nil && messageData.Data.Type != "" {
+		switch messageData.Data.Type {
+		case "ptt", "audio":
+			return channel.KindVoice
+		case "image":
+			return channel.KindImage
+		case "document":
+			return channel.KindDocument
+		}
+		if !strings.HasPrefix(mimetype, "audio/") {
+			return channel.KindOther
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(mimetype, "audio/"):
+		return channel.KindVoice
+	case strings.HasPrefix(mimetype, "image/"):
+		return channel.KindImage
+	default:
+		return channel.KindDocument
+	}
+}
+
+// selectedChoiceID returns the ID of the button or list row the message
+// answers, or "" if it isn't such an answer.
+func selectedChoiceID(messageData MessageData) string {
+	data := messageData.Data
+	if data == nil {
+		return ""
+	}
+
+	type listReply struct {
+		SingleSelectReply struct {
+			SelectedRowID string `json:"selectedRowId"`
+		} `json:"singleSelectReply"`
+	}
+
+	var buttonID string
+	if json.Unmarshal(data.SelectedButtonID, &buttonID) == nil && buttonID != "" {
+		return buttonID
+	}
+	var list listReply
+	if json.Unmarshal(data.ListResponse, &list) == nil && list.SingleSelectReply.SelectedRowID != "" {
+		return list.SingleSelectReply.SelectedRowID
+	}
+
+	var message struct {
+		ButtonsResponseMessage struct {
+			SelectedButtonID string `json:"selectedButtonId"`
+		} `json:"buttonsResponseMessage"`
+		TemplateButtonReplyMessage struct {
+			SelectedID string `json:"selectedId"`
+		} `json:"templateButtonReplyMessage"`
+		ListResponseMessage listReply `json:"listResponseMessage"`
+	}
+	if json.Unmarshal(data.Message, &message) != nil {
+		return ""
+	}
+	switch {
+	case message.ButtonsResponseMessage.SelectedButtonID != "":
+		return message.ButtonsResponseMessage.SelectedButtonID
+	case message.TemplateButtonReplyMessage.SelectedID != "":
+		return message.TemplateButtonReplyMessage.SelectedID
+	default:
+		return message.ListResponseMessage.SingleSelectReply.SelectedRowID
+	}
+}
@@ -3,28 +3,33 @@ package usecase
 import (
 	"context"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	"smart_alert_system/internal/domain/entity"
 	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/infrastructure/ical"
 )
 
 type ActivityUseCase struct {
 	activityRepo repository.ActivityRepository
 	userRepo     repository.UserRepository
 	categoryRepo repository.CategoryRepository
+	alertRepo    repository.AlertRepository
 }
 
 func NewActivityUseCase(
 	activityRepo repository.ActivityRepository,
 	userRepo repository.UserRepository,
 	categoryRepo repository.CategoryRepository,
+	alertRepo repository.AlertRepository,
 ) *ActivityUseCase {
 	return &ActivityUseCase{
 		activityRepo: activityRepo,
 		userRepo:     userRepo,
 		categoryRepo: categoryRepo,
+		alertRepo:    alertRepo,
 	}
 }
 
@@ -35,7 +40,7 @@ func (uc *ActivityUseCase) CreateActivity(ctx context.Context, userID uuid.UUID,
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", ErrNotFound)
 	}
 
 	// Set default scheduled time if not provided
@@ -54,6 +59,14 @@ func (uc *ActivityUseCase) CreateActivity(ctx context.Context, userID uuid.UUID,
 	if data.CategoryID != nil {
 		activity.CategoryID = data.CategoryID
 	}
+	activity.AssigneeID = data.AssigneeID
+	if data.RecurrenceRule != "" {
+		rule, err := normalizeRecurrenceRule(data.RecurrenceRule)
+		if err != nil {
+			return nil, err
+		}
+		activity.RecurrenceRule = rule
+	}
 
 	if err := uc.activityRepo.Create(ctx, activity); err != nil {
 		return nil, fmt.Errorf("failed to create activity: %w", err)
@@ -62,10 +75,74 @@ func (uc *ActivityUseCase) CreateActivity(ctx context.Context, userID uuid.UUID,
 	return activity, nil
 }
 
+func (uc *ActivityUseCase) GetActivity(ctx context.Context, activityID uuid.UUID) (*entity.Activity, error) {
+	activity, err := uc.activityRepo.GetByID(ctx, activityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	if activity == nil {
+		return nil, fmt.Errorf("activity %w", ErrNotFound)
+	}
+	return activity, nil
+}
+
 func (uc *ActivityUseCase) GetUserActivities(ctx context.Context, userID uuid.UUID) ([]*entity.Activity, error) {
 	return uc.activityRepo.GetByUserID(ctx, userID)
 }
 
+func (uc *ActivityUseCase) GetUserActivitiesOnDate(ctx context.Context, userID uuid.UUID, date time.Time) ([]*entity.Activity, error) {
+	return uc.activityRepo.GetByUserIDAndDate(ctx, userID, date)
+}
+
+// GetUserActivitiesBetween returns the user's activities scheduled in [from, to).
+func (uc *ActivityUseCase) GetUserActivitiesBetween(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]*entity.Activity, error) {
+	return uc.activityRepo.GetByUserIDBetween(ctx, userID, from, to)
+}
+
+// DailyCompletion counts the activities scheduled on one day and how many of
+// them were completed.
+type DailyCompletion struct {
+	Date      time.Time
+	Scheduled int
+	Completed int
+}
+
+// GetCompletionHistory returns one entry per day for the given number of days
+// ending today, in the location's calendar.
+func (uc *ActivityUseCase) GetCompletionHistory(ctx context.Context, userID uuid.UUID, days int, loc *time.Location) ([]DailyCompletion, error) {
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from := today.AddDate(0, 0, -(days - 1))
+
+	activities, err := uc.activityRepo.GetByUserIDBetween(ctx, userID, from, today.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activities: %w", err)
+	}
+
+	history := make([]DailyCompletion, days)
+	for i := range history {
+		history[i].Date = from.AddDate(0, 0, i)
+	}
+	for _, activity := range activities {
+		t := activity.ScheduledTime.In(loc)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		// Day count, not hours, so DST changes don't shift the index
+		i := int(math.Round(day.Sub(from).Hours() / 24))
+		if i < 0 || i >= days {
+			continue
+		}
+		history[i].Scheduled++
+		if activity.Status == entity.ActivityStatusCompleted {
+			history[i].Completed++
+		}
+	}
+	return history, nil
+}
+
+func (uc *ActivityUseCase) GetUserActivitiesByStatus(ctx context.Context, userID uuid.UUID, status entity.ActivityStatus) ([]*entity.Activity, error) {
+	return uc.activityRepo.GetByUserIDAndStatus(ctx, userID, status)
+}
+
 func (uc *ActivityUseCase) GetTodayActivities(ctx context.Context, userID uuid.UUID) ([]*entity.Activity, error) {
 	return uc.activityRepo.GetTodayActivities(ctx, userID)
 }
@@ -80,7 +157,7 @@ func (uc *ActivityUseCase) UpdateActivity(ctx context.Context, activityID uuid.U
 		return fmt.Errorf("failed to get activity: %w", err)
 	}
 	if activity == nil {
-		return fmt.Errorf("activity not found")
+		return fmt.Errorf("activity %w", ErrNotFound)
 	}
 
 	if data.Title != nil {
@@ -93,11 +170,28 @@ func (uc *ActivityUseCase) UpdateActivity(ctx context.Context, activityID uuid.U
 		activity.ScheduledTime = *data.ScheduledTime
 	}
 	if data.Status != nil {
-		activity.Status = entity.ActivityStatus(*data.Status)
+		status := entity.ActivityStatus(*data.Status)
+		switch {
+		case status == entity.ActivityStatusCompleted && activity.Status != status:
+			activity.Complete()
+		case status != entity.ActivityStatusCompleted:
+			activity.Status = status
+			activity.CompletedAt = nil
+		}
 	}
 	if data.Priority != nil {
 		activity.Priority = *data.Priority
 	}
+	if data.RecurrenceRule != nil {
+		activity.RecurrenceRule = ""
+		if *data.RecurrenceRule != "" {
+			rule, err := normalizeRecurrenceRule(*data.RecurrenceRule)
+			if err != nil {
+				return err
+			}
+			activity.RecurrenceRule = rule
+		}
+	}
 
 	activity.UpdatedAt = time.Now()
 
@@ -105,6 +199,14 @@ func (uc *ActivityUseCase) UpdateActivity(ctx context.Context, activityID uuid.U
 }
 
 func (uc *ActivityUseCase) DeleteActivity(ctx context.Context, activityID uuid.UUID) error {
+	activity, err := uc.activityRepo.GetByID(ctx, activityID)
+	if err != nil {
+		return fmt.Errorf("failed to get activity: %w", err)
+	}
+	if activity == nil {
+		return fmt.Errorf("activity %w", ErrNotFound)
+	}
+
 	return uc.activityRepo.Delete(ctx, activityID)
 }
 
@@ -114,10 +216,17 @@ func (uc *ActivityUseCase) CompleteActivity(ctx context.Context, activityID uuid
 		return fmt.Errorf("failed to get activity: %w", err)
 	}
 	if activity == nil {
-		return fmt.Errorf("activity not found")
+		return fmt.Errorf("activity %w", ErrNotFound)
 	}
 
 	activity.Complete()
 	return uc.activityRepo.Update(ctx, activity)
 }
 
+func normalizeRecurrenceRule(rule string) (string, error) {
+	normalized, err := ical.NormalizeRRule(rule)
+	if err != nil {
+		return "", fmt.Errorf("%w: recurrence_rule: %v", ErrInvalidInput, err)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,377 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/ical"
+)
+
+const (
+	// Events without a time of day are scheduled at this hour, so their
+	// reminder doesn't go off the evening before
+	allDayEventHour = 8
+	// Longest title the activities table accepts
+	maxActivityTitle = 255
+)
+
+// CalendarImportResult counts what an import did with the calendar's events.
+type CalendarImportResult struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	// Skipped counts events that couldn't be read, ended long ago, or came
+	// from this system's own feed.
+	Skipped int `json:"skipped"`
+}
+
+// MaxBytes is the size limit of imported calendar files.
+func (uc *CalendarUseCase) MaxBytes() int64 {
+	return uc.config.MaxBytes
+}
+
+// ImportCalendar creates activities from an uploaded iCalendar file. Events
+// imported before, matched by UID, are updated instead of duplicated.
+func (uc *CalendarUseCase) ImportCalendar(ctx context.Context, userID uuid.UUID, r io.Reader) (*CalendarImportResult, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	calendar, err := ical.ParseLimit(r, uc.userLocation(user), uc.config.MaxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a valid iCalendar file: %v", ErrInvalidInput, err)
+	}
+	return uc.importEvents(ctx, user, calendar)
+}
+
+// Subscribe imports the calendar at rawURL and keeps polling it for changes.
+func (uc *CalendarUseCase) Subscribe(ctx context.Context, userID uuid.UUID, rawURL string) (*entity.CalendarSubscription, *CalendarImportResult, error) {
+	calendarURL, err := normalizeCalendarURL(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, nil, fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	// Fetch first so an unreachable URL is reported instead of saved
+	calendar, etag, err := uc.fetcher.Fetch(ctx, calendarURL, "", uc.userLocation(user))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: cannot read calendar: %v", ErrInvalidInput, err)
+	}
+
+	subscription := entity.NewCalendarSubscription(userID, calendarURL)
+	created, err := uc.subscriptionRepo.Create(ctx, subscription)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+	if !created {
+		return nil, nil, fmt.Errorf("%w: already subscribed to this calendar", ErrInvalidInput)
+	}
+
+	result, err := uc.importEvents(ctx, user, calendar)
+	if err != nil {
+		return nil, nil, err
+	}
+	subscription.ETag = etag
+	uc.saveSyncResult(ctx, subscription, nil)
+	return subscription, result, nil
+}
+
+// Unsubscribe stops polling a calendar. Activities already imported from it
+// are kept.
+func (uc *CalendarUseCase) Unsubscribe(ctx context.Context, userID, subscriptionID uuid.UUID) error {
+	subscription, err := uc.subscriptionRepo.GetByID(ctx, subscriptionID)
+	if err != nil {
+		return fmt.Errorf("failed to get subscription: %w", err)
+	}
+	if subscription == nil || subscription.UserID != userID {
+		return fmt.Errorf("subscription %w", ErrNotFound)
+	}
+
+	if err := uc.subscriptionRepo.Delete(ctx, subscriptionID); err != nil {
+		return fmt.Errorf("failed to delete subscription: %w", err)
+	}
+	return nil
+}
+
+func (uc *CalendarUseCase) GetSubscriptions(ctx context.Context, userID uuid.UUID) ([]*entity.CalendarSubscription, error) {
+	subscriptions, err := uc.subscriptionRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// SyncSubscriptions re-imports subscribed calendars that weren't synced
+// within the sync interval, up to one batch per call. It returns the number
+// of calendars checked; failures are recorded on the subscription.
+func (uc *CalendarUseCase) SyncSubscriptions(ctx context.Context) (int, error) {
+	due, err := uc.subscriptionRepo.GetDue(ctx, time.Now().Add(-uc.config.SyncInterval), uc.config.SyncBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due subscriptions: %w", err)
+	}
+
+	for _, subscription := range due {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		result, err := uc.syncSubscription(ctx, subscription)
+		if err != nil {
+			slog.WarnContext(ctx, "Calendar sync failed", "subscription_id", subscription.ID, "error", err)
+			continue
+		}
+		if result.Created > 0 || result.Updated > 0 {
+			slog.InfoContext(ctx, "Calendar synced", "subscription_id", subscription.ID, "created", result.Created, "updated", result.Updated)
+		}
+	}
+	return len(due), nil
+}
+
+func (uc *CalendarUseCase) syncSubscription(ctx context.Context, subscription *entity.CalendarSubscription) (*CalendarImportResult, error) {
+	user, err := uc.userRepo.GetByID(ctx, subscription.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	calendar, etag, err := uc.fetcher.Fetch(ctx, subscription.URL, subscription.ETag, uc.userLocation(user))
+	if errors.Is(err, ical.ErrNotModified) {
+		uc.saveSyncResult(ctx, subscription, nil)
+		return &CalendarImportResult{}, nil
+	}
+	if err != nil {
+		uc.saveSyncResult(ctx, subscription, err)
+		return nil, err
+	}
+
+	result, err := uc.importEvents(ctx, user, calendar)
+	if err != nil {
+		uc.saveSyncResult(ctx, subscription, err)
+		return nil, err
+	}
+	subscription.ETag = etag
+	uc.saveSyncResult(ctx, subscription, nil)
+	return result, nil
+}
+
+func (uc *CalendarUseCase) saveSyncResult(ctx context.Context, subscription *entity.CalendarSubscription, syncErr error) {
+	now := time.Now()
+	subscription.LastSyncedAt = &now
+	subscription.UpdatedAt = now
+	subscription.LastError = ""
+	if syncErr != nil {
+		subscription.LastError = syncErr.Error()
+	}
+	if err := uc.subscriptionRepo.UpdateSyncResult(ctx, subscription); err != nil {
+		slog.ErrorContext(ctx, "Failed to save calendar sync result", "subscription_id", subscription.ID, "error", err)
+	}
+}
+
+func (uc *CalendarUseCase) importEvents(ctx context.Context, user *entity.User, calendar *ical.Calendar) (*CalendarImportResult, error) {
+	categories := map[string]uuid.UUID{}
+	all, err := uc.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	for _, category := range all {
+		categories[strings.ToLower(category.Name)] = category.ID
+	}
+
+	result := &CalendarImportResult{Skipped: calendar.Invalid}
+	loc := uc.userLocation(user)
+	cutoff := time.Now().Add(-calendarFeedHistory)
+	for _, event := range calendar.Events {
+		// Re-importing our own feed would duplicate every activity
+		if strings.HasSuffix(event.UID, calendarUIDSuffix) {
+			result.Skipped++
+			continue
+		}
+		if event.RRule == "" && event.Start.Before(cutoff) {
+			result.Skipped++
+			continue
+		}
+
+		imported := eventActivity(event, loc, categories)
+		existing, err := uc.activityRepo.GetByExternalUID(ctx, user.ID, imported.ExternalUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get activity: %w", err)
+		}
+
+		if existing == nil {
+			activity := entity.NewActivity(user.ID, imported.Title, imported.Description, imported.ScheduledTime, imported.Priority)
+			applyImportedFields(activity, imported)
+			if err := uc.activityRepo.Create(ctx, activity); err != nil {
+				return nil, fmt.Errorf("failed to create activity: %w", err)
+			}
+			result.Created++
+			continue
+		}
+
+		if !applyImportedFields(existing, imported) {
+			result.Unchanged++
+			continue
+		}
+		existing.UpdatedAt = time.Now()
+		if err := uc.activityRepo.Update(ctx, existing); err != nil {
+			return nil, fmt.Errorf("failed to update activity: %w", err)
+		}
+		result.Updated++
+	}
+	return result, nil
+}
+
+// eventActivity maps an event to the activity fields that an import sets.
+func eventActivity(event ical.Event, loc *time.Location, categories map[string]uuid.UUID) *entity.Activity {
+	start := event.Start.In(loc)
+	if event.AllDay {
+		start = time.Date(start.Year(), start.Month(), start.Day(), allDayEventHour, 0, 0, 0, loc)
+	}
+
+	title := strings.TrimSpace(event.Summary)
+	if title == "" {
+		title = "(tanpa judul)"
+	}
+
+	activity := &entity.Activity{
+		Title:         truncateRunes(title, maxActivityTitle),
+		Description:   event.Description,
+		ScheduledTime: start,
+		Status:        entity.ActivityStatusPending,
+		// iCalendar's priority is 1 (highest) to 9, ours 1 to 5
+		Priority:    3,
+		ExternalUID: event.UID,
+	}
+	if event.Priority >= 1 && event.Priority <= 9 {
+		activity.Priority = (event.Priority + 1) / 2
+	}
+	if event.Status == ical.StatusCancelled {
+		activity.Status = entity.ActivityStatusCancelled
+	}
+
+	// UID is required by RFC 5545, but not every exporter sets one. Fall back
+	// to a hash so re-imports still match.
+	if activity.ExternalUID == "" || len(activity.ExternalUID) > 255 {
+		activity.ExternalUID = "sha256:" + hashSecret(event.UID+"\n"+event.Summary+"\n"+event.Start.UTC().String())
+	}
+
+	// Rules we can't store are dropped; the first occurrence is still imported
+	if rule, err := ical.NormalizeRRule(event.RRule); err == nil {
+		activity.RecurrenceRule = rule
+	}
+
+	for _, name := range event.Categories {
+		if id, ok := categories[strings.ToLower(name)]; ok {
+			activity.CategoryID = &id
+			break
+		}
+	}
+
+	for _, alarm := range event.Alarms {
+		if alarm.Before > 0 {
+			reminder := start.Add(-alarm.Before)
+			activity.ReminderTime = &reminder
+			break
+		}
+	}
+	return activity
+}
+
+// applyImportedFields copies the imported fields onto activity and reports
+// whether anything changed. Completed activities stay completed.
+func applyImportedFields(activity, imported *entity.Activity) bool {
+	changed := activity.Title != imported.Title ||
+		activity.Description != imported.Description ||
+		!activity.ScheduledTime.Equal(imported.ScheduledTime) ||
+		!equalTimes(activity.ReminderTime, imported.ReminderTime) ||
+		activity.Priority != imported.Priority ||
+		activity.RecurrenceRule != imported.RecurrenceRule ||
+		!equalUUIDs(activity.CategoryID, imported.CategoryID)
+
+	activity.Title = imported.Title
+	activity.Description = imported.Description
+	activity.ScheduledTime = imported.ScheduledTime
+	activity.ReminderTime = imported.ReminderTime
+	activity.Priority = imported.Priority
+	activity.RecurrenceRule = imported.RecurrenceRule
+	activity.CategoryID = imported.CategoryID
+	activity.ExternalUID = imported.ExternalUID
+
+	if imported.Status == entity.ActivityStatusCancelled &&
+		activity.Status != entity.ActivityStatusCancelled && activity.Status != entity.ActivityStatusCompleted {
+		activity.Cancel()
+		changed = true
+	}
+	return changed
+}
+
+// normalizeCalendarURL accepts http(s) URLs and webcal:// links, which
+// calendar apps use for the same thing.
+func normalizeCalendarURL(rawURL string) (string, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if rest, ok := strings.CutPrefix(rawURL, "webcal://"); ok {
+		rawURL = "https://" + rest
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("%w: calendar URL must be an http(s) or webcal link", ErrInvalidInput)
+	}
+	return parsed.String(), nil
+}
+
+func (uc *CalendarUseCase) userLocation(user *entity.User) *time.Location {
+	return userLocation(user, uc.location)
+}
+
+// userLocation returns the user's time zone, or fallback if it is unset or
+// unknown.
+func userLocation(user *entity.User, fallback *time.Location) *time.Location {
+	if user.Timezone != "" {
+		if loc, err := time.LoadLocation(user.Timezone); err == nil {
+			return loc
+		}
+	}
+	return fallback
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func equalUUIDs(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
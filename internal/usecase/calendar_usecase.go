@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/infrastructure/ical"
+)
+
+const (
+	// Activities have no end time; calendar apps show them with this length
+	calendarEventDuration = 30 * time.Minute
+	// One-off activities older than this are left out of the feed and
+	// skipped on import
+	calendarFeedHistory = 180 * 24 * time.Hour
+	// Appended to activity IDs to form event UIDs
+	calendarUIDSuffix = "@smart-alert-system"
+)
+
+// CalendarConfig tunes calendar import and subscription polling.
+type CalendarConfig struct {
+	// MaxBytes limits the size of imported calendars.
+	MaxBytes int64
+	// SyncInterval is how often subscribed calendars are re-imported.
+	SyncInterval time.Duration
+	// SyncBatchSize is the most subscriptions synced per run.
+	SyncBatchSize int
+}
+
+// CalendarUseCase publishes each user's activities as an iCalendar feed at a
+// secret URL that calendar apps can subscribe to, and imports activities
+// from other calendars.
+type CalendarUseCase struct {
+	userRepo         repository.UserRepository
+	activityRepo     repository.ActivityRepository
+	categoryRepo     repository.CategoryRepository
+	subscriptionRepo repository.CalendarSubscriptionRepository
+	fetcher          *ical.Fetcher
+	location         *time.Location
+	config           CalendarConfig
+}
+
+func NewCalendarUseCase(
+	userRepo repository.UserRepository,
+	activityRepo repository.ActivityRepository,
+	categoryRepo repository.CategoryRepository,
+	subscriptionRepo repository.CalendarSubscriptionRepository,
+	fetcher *ical.Fetcher,
+	location *time.Location,
+	config CalendarConfig,
+) *CalendarUseCase {
+	return &CalendarUseCase{
+		userRepo:         userRepo,
+		activityRepo:     activityRepo,
+		categoryRepo:     categoryRepo,
+		subscriptionRepo: subscriptionRepo,
+		fetcher:          fetcher,
+		location:         location,
+		config:           config,
+	}
+}
+
+// CalendarToken returns the user's feed token, creating one on first use.
+func (uc *CalendarUseCase) CalendarToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	token, err := uc.userRepo.GetCalendarToken(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get calendar token: %w", err)
+	}
+	if token != "" {
+		return token, nil
+	}
+	return uc.ResetCalendarToken(ctx, userID)
+}
+
+// ResetCalendarToken replaces the user's feed token, so the old feed URL
+// stops working.
+func (uc *CalendarUseCase) ResetCalendarToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+	if err := uc.userRepo.SetCalendarToken(ctx, userID, token); err != nil {
+		return "", fmt.Errorf("failed to save calendar token: %w", err)
+	}
+	return token, nil
+}
+
+// Feed builds the iCalendar feed for a feed token.
+func (uc *CalendarUseCase) Feed(ctx context.Context, token string) (*ical.Calendar, error) {
+	user, err := uc.userRepo.GetByCalendarToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.IsActive {
+		return nil, fmt.Errorf("calendar %w", ErrNotFound)
+	}
+
+	activities, err := uc.activityRepo.GetByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activities: %w", err)
+	}
+
+	categories := map[uuid.UUID]string{}
+	all, err := uc.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	for _, category := range all {
+		categories[category.ID] = category.Name
+	}
+
+	name := "Smart Alert"
+	if user.Name != "" {
+		name += " - " + user.Name
+	}
+	calendar := &ical.Calendar{
+		ProdID:          "-//Smart Alert System//Activities//ID",
+		Name:            name,
+		RefreshInterval: time.Hour,
+		Location:        uc.location,
+	}
+
+	cutoff := time.Now().Add(-calendarFeedHistory)
+	for _, activity := range activities {
+		if activity.RecurrenceRule == "" && activity.ScheduledTime.Before(cutoff) {
+			continue
+		}
+		calendar.Events = append(calendar.Events, activityEvent(activity, categories))
+	}
+	return calendar, nil
+}
+
+func activityEvent(activity *entity.Activity, categories map[uuid.UUID]string) ical.Event {
+	event := ical.Event{
+		UID:          activity.ID.String() + calendarUIDSuffix,
+		Summary:      activity.Title,
+		Description:  activity.Description,
+		Start:        activity.ScheduledTime,
+		End:          activity.ScheduledTime.Add(calendarEventDuration),
+		Status:       ical.StatusConfirmed,
+		RRule:        activity.RecurrenceRule,
+		Created:      activity.CreatedAt,
+		LastModified: activity.UpdatedAt,
+	}
+
+	switch activity.Status {
+	case entity.ActivityStatusCancelled:
+		event.Status = ical.StatusCancelled
+	case entity.ActivityStatusCompleted:
+		// VEVENT has no "completed" status
+		event.Summary = "✓ " + event.Summary
+	}
+
+	// Our priority is 1 (highest) to 5, iCalendar's is 1 to 9
+	if activity.Priority >= 1 && activity.Priority <= 5 {
+		event.Priority = activity.Priority*2 - 1
+	}
+
+	if activity.CategoryID != nil {
+		if name, ok := categories[*activity.CategoryID]; ok {
+			event.Categories = []string{name}
+		}
+	}
+
+	if activity.ReminderTime != nil && activity.ReminderTime.Before(activity.ScheduledTime) {
+		event.Alarms = []ical.Alarm{{
+			Before:      activity.ScheduledTime.Sub(*activity.ReminderTime),
+			Description: "Pengingat: " + activity.Title,
+		}}
+	}
+	return event
+}
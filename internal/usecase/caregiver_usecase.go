@@ -0,0 +1,442 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/whatsapp"
+)
+
+// MaxCaregivers is how many caregivers one user can authorize.
+const MaxCaregivers = 5
+
+// CaregiverConfig tunes the alerts sent to caregivers.
+type CaregiverConfig struct {
+	// EscalationDelay is how long after its scheduled time a medication or
+	// health activity that is still not confirmed is reported to the
+	// user's caregivers.
+	EscalationDelay time.Duration
+	// Lookback is how late an escalation may still be sent, e.g. after a
+	// restart.
+	Lookback time.Duration
+	// Categories are the names of the activity categories caregivers are
+	// alerted about. Activities whose title mentions medication or a
+	// check-up are always included.
+	Categories []string
+}
+
+// careTitlePattern matches titles of medication and health activities that
+// were created without a category, e.g. "minum obat darah tinggi".
+var careTitlePattern = regexp.MustCompile(`(?i)\b(obat|vitamin|suplemen|insulin|suntik|kontrol|check[- ]?up|cek (gula|tensi|darah|kesehatan)|dokter|terapi|cuci darah)\b`)
+
+// CaregiverUseCase lets users authorize caregivers, e.g. family members of an
+// elderly user, and alerts them when a dose or check-up is missed. Alerts to
+// caregivers are logged under the user they are about.
+type CaregiverUseCase struct {
+	caregiverRepo repository.CaregiverRepository
+	userRepo      repository.UserRepository
+	activityRepo  repository.ActivityRepository
+	categoryRepo  repository.CategoryRepository
+	alertRepo     repository.AlertRepository
+	outboxUC      *OutboxUseCase
+	config        CaregiverConfig
+}
+
+func NewCaregiverUseCase(
+	caregiverRepo repository.CaregiverRepository,
+	userRepo repository.UserRepository,
+	activityRepo repository.ActivityRepository,
+	categoryRepo repository.CategoryRepository,
+	alertRepo repository.AlertRepository,
+	outboxUC *OutboxUseCase,
+	config CaregiverConfig,
+) *CaregiverUseCase {
+	return &CaregiverUseCase{
+		caregiverRepo: caregiverRepo,
+		userRepo:      userRepo,
+		activityRepo:  activityRepo,
+		categoryRepo:  categoryRepo,
+		alertRepo:     alertRepo,
+		outboxUC:      outboxUC,
+		config:        config,
+	}
+}
+
+// AddCaregiver authorizes the WhatsApp number as a caregiver of user and
+// tells the caregiver about it. Adding a number that already is a caregiver
+// only updates its name; created is false then.
+func (uc *CaregiverUseCase) AddCaregiver(ctx context.Context, user *entity.User, number, name string) (caregiver *entity.Caregiver, created bool, err error) {
+	phoneNumber, err := caregiverNumber(number)
+	if err != nil {
+		return nil, false, err
+	}
+	if phoneNumber == user.WhatsAppNumber {
+		return nil, false, fmt.Errorf("%w: caregiver can't be the user", ErrInvalidInput)
+	}
+
+	existing, err := uc.caregiverRepo.GetByPhoneNumber(ctx, user.ID, phoneNumber)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get caregiver: %w", err)
+	}
+	if existing != nil {
+		if name != "" && name != existing.Name {
+			existing.Name = name
+			if err := uc.caregiverRepo.Update(ctx, existing); err != nil {
+				return nil, false, fmt.Errorf("failed to update caregiver: %w", err)
+			}
+		}
+		return existing, false, nil
+	}
+
+	caregivers, err := uc.caregiverRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list caregivers: %w", err)
+	}
+	if len(caregivers) >= MaxCaregivers {
+		return nil, false, ErrTooManyCaregivers
+	}
+
+	caregiver = entity.NewCaregiver(user.ID, name, phoneNumber)
+	if err := uc.caregiverRepo.Create(ctx, caregiver); err != nil {
+		return nil, false, fmt.Errorf("failed to create caregiver: %w", err)
+	}
+
+	// The caregiver should know why they start getting alerts about the user
+	notice := fmt.Sprintf("👋 Halo %s!\n\n*%s* menambahkan Anda sebagai pendamping di asisten pengingat ini. Anda akan diberi tahu jika %s melewatkan jadwal minum obat atau pemeriksaan kesehatan.",
+		caregiver.DisplayName(), userDisplayName(user), userDisplayName(user))
+	if err := uc.sendToCaregiver(ctx, "caregiver:"+caregiver.ID.String(), caregiver, notice, user.ID, nil); err != nil {
+		slog.ErrorContext(ctx, "Failed to notify caregiver", "caregiver_id", caregiver.ID, "error", err)
+	}
+	return caregiver, true, nil
+}
+
+// RemoveCaregiver withdraws the authorization of a caregiver of the user.
+func (uc *CaregiverUseCase) RemoveCaregiver(ctx context.Context, userID uuid.UUID, number string) (*entity.Caregiver, error) {
+	caregiver, err := uc.getCaregiver(ctx, userID, number)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.caregiverRepo.Delete(ctx, caregiver.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete caregiver: %w", err)
+	}
+	return caregiver, nil
+}
+
+// SetDailyDigest turns the daily adherence digest of a caregiver of the user
+// on or off.
+func (uc *CaregiverUseCase) SetDailyDigest(ctx context.Context, userID uuid.UUID, number string, enabled bool) (*entity.Caregiver, error) {
+	caregiver, err := uc.getCaregiver(ctx, userID, number)
+	if err != nil {
+		return nil, err
+	}
+	caregiver.DailyDigest = enabled
+	if err := uc.caregiverRepo.Update(ctx, caregiver); err != nil {
+		return nil, fmt.Errorf("failed to update caregiver: %w", err)
+	}
+	return caregiver, nil
+}
+
+// ListCaregivers returns the user's caregivers, oldest first.
+func (uc *CaregiverUseCase) ListCaregivers(ctx context.Context, userID uuid.UUID) ([]*entity.Caregiver, error) {
+	return uc.caregiverRepo.ListByUserID(ctx, userID)
+}
+
+func (uc *CaregiverUseCase) getCaregiver(ctx context.Context, userID uuid.UUID, number string) (*entity.Caregiver, error) {
+	phoneNumber, err := caregiverNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	caregiver, err := uc.caregiverRepo.GetByPhoneNumber(ctx, userID, phoneNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caregiver: %w", err)
+	}
+	if caregiver == nil {
+		return nil, fmt.Errorf("caregiver %w", ErrNotFound)
+	}
+	return caregiver, nil
+}
+
+// caregiverNumber normalizes a typed WhatsApp number of a caregiver.
+func caregiverNumber(number string) (string, error) {
+	phoneNumber := NormalizeWhatsAppNumber(number)
+	if len(phoneNumber) < 8 || len(phoneNumber) > 15 {
+		return "", fmt.Errorf("%w: %q is not a phone number", ErrInvalidInput, number)
+	}
+	return phoneNumber, nil
+}
+
+// SendEscalations alerts the caregivers of users who haven't confirmed a
+// medication or health activity EscalationDelay after its scheduled time.
+// The activity is marked overdue. Each scheduled time of an activity is
+// escalated only once, so overlapping runs are harmless.
+func (uc *CaregiverUseCase) SendEscalations(ctx context.Context, now time.Time) (RunStats, error) {
+	start := time.Now()
+	var stats RunStats
+	defer func() { observeRun(entity.AlertTypeCaregiverEscalation, start, stats) }()
+
+	to := now.Add(-uc.config.EscalationDelay)
+	activities, err := uc.activityRepo.GetUnconfirmedWithCaregivers(ctx, to.Add(-uc.config.Lookback), to)
+	if err != nil {
+		return stats, fmt.Errorf("failed to get unconfirmed activities: %w", err)
+	}
+	if len(activities) == 0 {
+		return stats, nil
+	}
+
+	categories := uc.careCategoryIDs(ctx)
+	users := make(map[uuid.UUID]*entity.User)
+	for _, activity := range activities {
+		if !isCareActivity(activity, categories) {
+			continue
+		}
+		user, ok := users[activity.UserID]
+		if !ok {
+			user, err = uc.userRepo.GetByID(ctx, activity.UserID)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to load user for caregiver escalation", "user_id", activity.UserID, "error", err)
+				stats.Failed++
+				continue
+			}
+			users[activity.UserID] = user
+		}
+		if user == nil {
+			stats.Skipped++
+			continue
+		}
+
+		err := uc.escalate(ctx, user, activity, userLocation(user, now.Location()))
+		switch {
+		case errors.Is(err, errSkip):
+			stats.Skipped++
+		case err != nil:
+			slog.ErrorContext(ctx, "Failed to escalate activity to caregivers", "activity_id", activity.ID, "error", err)
+			stats.Failed++
+		default:
+			stats.Sent++
+		}
+	}
+
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+// escalate tells every caregiver of user that activity wasn't confirmed.
+func (uc *CaregiverUseCase) escalate(ctx context.Context, user *entity.User, activity *entity.Activity, location *time.Location) error {
+	caregivers, err := uc.caregiverRepo.ListByUserID(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list caregivers: %w", err)
+	}
+	if len(caregivers) == 0 {
+		return fmt.Errorf("%w: user has no caregivers", errSkip)
+	}
+
+	content := fmt.Sprintf("⚠️ *%s* belum mengonfirmasi kegiatan berikut:\n\n*%s*\n🕐 Dijadwalkan %s",
+		userDisplayName(user), activity.Title, activity.ScheduledTime.In(location).Format("15:04"))
+	if activity.PostponeCount > 0 {
+		content += fmt.Sprintf(" (sudah ditunda %d kali)", activity.PostponeCount)
+	}
+	content += "\n\nMohon bantu ingatkan atau pastikan keadaannya."
+
+	alert := entity.NewAlertLog(user.ID, entity.AlertTypeCaregiverEscalation, content, activity.ScheduledTime)
+	activityID := activity.ID
+	alert.ActivityID = &activityID
+	inserted, err := uc.alertRepo.Reserve(ctx, alert)
+	if err != nil {
+		return fmt.Errorf("failed to create alert log: %w", err)
+	}
+	if !inserted {
+		return fmt.Errorf("%w: activity already escalated", errSkip)
+	}
+
+	activity.MarkOverdue()
+	if err := uc.activityRepo.Update(ctx, activity); err != nil {
+		slog.ErrorContext(ctx, "Failed to mark activity overdue", "activity_id", activity.ID, "error", err)
+	}
+
+	return uc.sendAlert(ctx, alert, caregivers)
+}
+
+// SendDailyDigests sends the caregivers who asked for it a summary of the
+// medication and health activities of the user on runAt's date. Users whose
+// caregivers already got the digest for that date are skipped.
+func (uc *CaregiverUseCase) SendDailyDigests(ctx context.Context, runAt time.Time) (RunStats, error) {
+	start := time.Now()
+	var stats RunStats
+	defer func() { observeRun(entity.AlertTypeCaregiverDigest, start, stats) }()
+
+	caregivers, err := uc.caregiverRepo.ListWithDailyDigest(ctx)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list caregivers: %w", err)
+	}
+
+	// Caregivers are ordered by user
+	for len(caregivers) > 0 {
+		n := 1
+		for n < len(caregivers) && caregivers[n].UserID == caregivers[0].UserID {
+			n++
+		}
+		userID := caregivers[0].UserID
+
+		err := uc.sendDigest(ctx, userID, caregivers[:n], runAt)
+		switch {
+		case errors.Is(err, errSkip):
+			stats.Skipped++
+		case err != nil:
+			slog.ErrorContext(ctx, "Failed to send caregiver digest", "user_id", userID, "error", err)
+			stats.Failed++
+		default:
+			stats.Sent++
+		}
+		caregivers = caregivers[n:]
+	}
+
+	stats.Duration = time.Since(start)
+	return stats, nil
+}
+
+func (uc *CaregiverUseCase) sendDigest(ctx context.Context, userID uuid.UUID, caregivers []*entity.Caregiver, runAt time.Time) error {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("%w: user not found", errSkip)
+	}
+
+	// The user's day, in their time zone
+	runAt = runAt.In(userLocation(user, runAt.Location()))
+	activities, err := uc.activityRepo.GetByUserIDAndDate(ctx, userID, runAt)
+	if err != nil {
+		return fmt.Errorf("failed to get activities: %w", err)
+	}
+
+	alertDate := localAlertDate(user, runAt)
+	alert := entity.NewAlertLog(userID, entity.AlertTypeCaregiverDigest,
+		uc.composeDigest(ctx, user, activities, runAt), runAt)
+	alert.AlertDate = &alertDate
+	inserted, err := uc.alertRepo.Reserve(ctx, alert)
+	if err != nil {
+		return fmt.Errorf("failed to reserve alert log: %w", err)
+	}
+	if !inserted {
+		return fmt.Errorf("%w: digest already sent for this date", errSkip)
+	}
+
+	return uc.sendAlert(ctx, alert, caregivers)
+}
+
+// composeDigest lists the user's medication and health activities of the day
+// with whether each was done.
+func (uc *CaregiverUseCase) composeDigest(ctx context.Context, user *entity.User, activities []*entity.Activity, runAt time.Time) string {
+	categories := uc.careCategoryIDs(ctx)
+	var lines []string
+	var scheduled, completed int
+	for _, activity := range activities {
+		if !isCareActivity(activity, categories) || activity.Status == entity.ActivityStatusCancelled {
+			continue
+		}
+		scheduled++
+		status := "⏳"
+		switch {
+		case activity.Status == entity.ActivityStatusCompleted:
+			completed++
+			status = "✅"
+		case activity.Status == entity.ActivityStatusOverdue, activity.ScheduledTime.Before(runAt):
+			status = "❌"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s - %s", status, activity.ScheduledTime.In(runAt.Location()).Format("15:04"), activity.Title))
+	}
+
+	msg := fmt.Sprintf("📋 Ringkasan harian *%s* (%s)\n\n", userDisplayName(user), runAt.Format("02/01/2006"))
+	if scheduled == 0 {
+		return msg + "Tidak ada jadwal obat atau pemeriksaan kesehatan hari ini."
+	}
+	msg += strings.Join(lines, "\n")
+	msg += fmt.Sprintf("\n\nKepatuhan: %d dari %d kegiatan dikonfirmasi (%d%%).", completed, scheduled, completed*100/scheduled)
+	return msg
+}
+
+// sendAlert queues alert for each of the caregivers. The alert only fails if
+// it couldn't be queued for any of them.
+func (uc *CaregiverUseCase) sendAlert(ctx context.Context, alert *entity.AlertLog, caregivers []*entity.Caregiver) error {
+	alertID := alert.ID
+	var lastErr error
+	queued := 0
+	for _, caregiver := range caregivers {
+		key := "alert:" + alert.ID.String() + ":" + caregiver.ID.String()
+		if err := uc.sendToCaregiver(ctx, key, caregiver, alert.AlertContent, alert.UserID, &alertID); err != nil {
+			slog.ErrorContext(ctx, "Failed to queue caregiver alert", "alert_id", alert.ID, "caregiver_id", caregiver.ID, "error", err)
+			lastErr = err
+			continue
+		}
+		queued++
+	}
+	if queued == 0 && lastErr != nil {
+		alert.MarkFailed(lastErr)
+		uc.alertRepo.Update(ctx, alert)
+		return fmt.Errorf("failed to queue message: %w", lastErr)
+	}
+	return nil
+}
+
+// sendToCaregiver queues a WhatsApp message to the caregiver. It goes through
+// the Waha session of the user it is about.
+func (uc *CaregiverUseCase) sendToCaregiver(ctx context.Context, idempotencyKey string, caregiver *entity.Caregiver, text string, userID uuid.UUID, alertLogID *uuid.UUID) error {
+	_, err := uc.outboxUC.Enqueue(ctx, OutboundRequest{
+		IdempotencyKey: idempotencyKey,
+		Channel:        channel.WhatsApp,
+		ChatID:         whatsapp.PhoneChatID(caregiver.PhoneNumber),
+		Body:           text,
+		UserID:         &userID,
+		AlertLogID:     alertLogID,
+	})
+	return err
+}
+
+// careCategoryIDs looks up the categories caregivers are alerted about.
+// Unknown names are ignored.
+func (uc *CaregiverUseCase) careCategoryIDs(ctx context.Context) map[uuid.UUID]bool {
+	ids := make(map[uuid.UUID]bool)
+	for _, name := range uc.config.Categories {
+		category, err := uc.categoryRepo.GetByName(ctx, name)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load category", "category", name, "error", err)
+			continue
+		}
+		if category != nil {
+			ids[category.ID] = true
+		}
+	}
+	return ids
+}
+
+// isCareActivity reports whether caregivers are alerted about the activity:
+// it is in one of their categories, or its title mentions medication or a
+// check-up.
+func isCareActivity(activity *entity.Activity, categories map[uuid.UUID]bool) bool {
+	if activity.CategoryID != nil && categories[*activity.CategoryID] {
+		return true
+	}
+	return careTitlePattern.MatchString(activity.Title)
+}
+
+// userDisplayName is how caregivers know the user: their name, or their
+// number if they have none.
+func userDisplayName(user *entity.User) string {
+	if user.Name != "" {
+		return user.Name
+	}
+	if user.WhatsAppNumber != "" {
+		return "+" + user.WhatsAppNumber
+	}
+	return "Pengguna"
+}
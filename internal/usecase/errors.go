@@ -0,0 +1,29 @@
+package usecase
+
+import "errors"
+
+// ErrNotFound is wrapped by use case errors for missing users, activities, etc.
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidInput is wrapped by use case errors caused by invalid arguments.
+var ErrInvalidInput = errors.New("invalid input")
+
+// ErrInvalidLoginCode is returned for wrong, expired or already used web
+// dashboard login codes.
+var ErrInvalidLoginCode = errors.New("invalid or expired login code")
+
+// ErrInvalidLinkCode is returned for wrong, expired or already used codes to
+// link a channel identity.
+var ErrInvalidLinkCode = errors.New("invalid or expired link code")
+
+// ErrNoActivitiesFound is returned when a photo shows nothing that can be
+// scheduled.
+var ErrNoActivitiesFound = errors.New("no activities found in image")
+
+// ErrNoRecentReminder is returned when a reply like "tunda 30 menit" can't be
+// linked to a recent reminder.
+var ErrNoRecentReminder = errors.New("no recent reminder")
+
+// ErrTooManyCaregivers is returned when a user who already has
+// MaxCaregivers caregivers adds another one.
+var ErrTooManyCaregivers = errors.New("too many caregivers")
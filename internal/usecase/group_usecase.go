@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+)
+
+// GroupUseCase manages the shared calendars of group chats. A group's
+// calendar is a users row reached through the group's identity, so its
+// activities, reminders and summaries work like a user's and go to the group.
+type GroupUseCase struct {
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	memberRepo   repository.GroupMemberRepository
+}
+
+func NewGroupUseCase(userRepo repository.UserRepository, identityRepo repository.UserIdentityRepository, memberRepo repository.GroupMemberRepository) *GroupUseCase {
+	return &GroupUseCase{userRepo: userRepo, identityRepo: identityRepo, memberRepo: memberRepo}
+}
+
+// GetOrCreateGroup returns the calendar of the group chat chatID on a
+// channel, creating it the first time someone writes in the group.
+func (uc *GroupUseCase) GetOrCreateGroup(ctx context.Context, channelName, chatID, account, name string) (*entity.User, error) {
+	now := time.Now()
+	identity, err := uc.identityRepo.GetByExternalID(ctx, channelName, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group identity: %w", err)
+	}
+
+	if identity == nil {
+		group := entity.NewUser("", name, "Asia/Jakarta")
+		group.WahaSession = account
+		if err := uc.userRepo.Create(ctx, group); err != nil {
+			return nil, fmt.Errorf("failed to create group: %w", err)
+		}
+		identity = entity.NewUserIdentity(group.ID, channelName, chatID, chatID, account)
+		if err := uc.identityRepo.Create(ctx, identity); err != nil {
+			return nil, fmt.Errorf("failed to create group identity: %w", err)
+		}
+		return group, nil
+	}
+
+	identity.Account = account
+	identity.LastSeenAt = now
+	if err := uc.identityRepo.Touch(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to update group identity: %w", err)
+	}
+	group, err := uc.userRepo.GetByID(ctx, identity.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	if group == nil {
+		return nil, fmt.Errorf("group %w", ErrNotFound)
+	}
+	if err := uc.userRepo.UpdateLastInteraction(ctx, group.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to update last interaction: %w", err)
+	}
+	group.LastInteractionAt = &now
+	return group, nil
+}
+
+// AddMember records that user wrote in the group from chatID, under
+// displayName or else their user name.
+func (uc *GroupUseCase) AddMember(ctx context.Context, groupID uuid.UUID, user *entity.User, displayName, chatID string) (*entity.GroupMember, error) {
+	if displayName == "" {
+		displayName = user.Name
+	}
+	member := entity.NewGroupMember(groupID, user.ID, displayName, chatID)
+	if err := uc.memberRepo.Upsert(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to save group member: %w", err)
+	}
+	return member, nil
+}
+
+// Member returns the user's membership of the group, or nil.
+func (uc *GroupUseCase) Member(ctx context.Context, groupID, userID uuid.UUID) (*entity.GroupMember, error) {
+	member, err := uc.memberRepo.Get(ctx, groupID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group member: %w", err)
+	}
+	return member, nil
+}
+
+// assigneePattern finds who an activity is for: "ingatkan Budi minum obat",
+// "tugaskan ke Sari", "untuk @Budi".
+var assigneePattern = regexp.MustCompile(`(?i)\b(?:ingatkan|tugaskan|untuk)\s+(?:ke\s+)?@?([\p{L}\d]+)`)
+
+// selfWords refer to the member who sent the message.
+var selfWords = map[string]bool{"saya": true, "aku": true, "sy": true, "gue": true, "gw": true}
+
+// FindAssignee returns the member an activity added by sender is for: the
+// first member @mentioned in the message, or the member named after
+// "ingatkan", "tugaskan" or "untuk". It returns nil if the message names no
+// member, so the activity is for the whole group.
+func (uc *GroupUseCase) FindAssignee(ctx context.Context, sender *entity.GroupMember, text string, mentions []string) (*entity.GroupMember, error) {
+	members, err := uc.memberRepo.ListByGroupID(ctx, sender.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group members: %w", err)
+	}
+
+	for _, mention := range mentions {
+		for _, member := range members {
+			if member.MentionTag() == "@"+chatIDUser(mention) {
+				return member, nil
+			}
+		}
+	}
+
+	for _, match := range assigneePattern.FindAllStringSubmatch(text, -1) {
+		name := strings.ToLower(match[1])
+		if selfWords[name] {
+			return sender, nil
+		}
+		for _, member := range members {
+			firstName, _, _ := strings.Cut(member.DisplayName, " ")
+			if strings.EqualFold(firstName, name) || strings.EqualFold(member.DisplayName, name) ||
+				member.MentionTag() == "@"+name {
+				return member, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// chatIDUser returns the part of a chat ID before "@".
+func chatIDUser(chatID string) string {
+	user, _, _ := strings.Cut(chatID, "@")
+	return user
+}
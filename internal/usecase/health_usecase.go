@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+)
+
+// HealthProfileInput is an edited health profile. The list fields are stored
+// as JSON arrays in the JSONB columns of user_health_profiles.
+type HealthProfileInput struct {
+	Age                 *int
+	Gender              string
+	MedicalConditions   []string
+	Allergies           []string
+	Medications         []string
+	ActivityPreferences []string
+	HealthGoals         []string
+}
+
+type HealthUseCase struct {
+	healthRepo repository.HealthRepository
+}
+
+func NewHealthUseCase(healthRepo repository.HealthRepository) *HealthUseCase {
+	return &HealthUseCase{healthRepo: healthRepo}
+}
+
+// GetProfile returns the user's health profile as editable input. Users
+// without a profile get an empty one.
+func (uc *HealthUseCase) GetProfile(ctx context.Context, userID uuid.UUID) (HealthProfileInput, error) {
+	profile, err := uc.healthRepo.GetHealthProfileByUserID(ctx, userID)
+	if err != nil {
+		return HealthProfileInput{}, fmt.Errorf("failed to get health profile: %w", err)
+	}
+	if profile == nil {
+		return HealthProfileInput{}, nil
+	}
+
+	return HealthProfileInput{
+		Age:                 profile.Age,
+		Gender:              profile.Gender,
+		MedicalConditions:   decodeProfileList(profile.MedicalConditions),
+		Allergies:           decodeProfileList(profile.Allergies),
+		Medications:         decodeProfileList(profile.Medications),
+		ActivityPreferences: decodeProfileList(profile.ActivityPreferences),
+		HealthGoals:         decodeProfileList(profile.HealthGoals),
+	}, nil
+}
+
+func (uc *HealthUseCase) SaveProfile(ctx context.Context, userID uuid.UUID, input HealthProfileInput) error {
+	if input.Age != nil && (*input.Age < 1 || *input.Age > 130) {
+		return fmt.Errorf("%w: age must be between 1 and 130", ErrInvalidInput)
+	}
+
+	profile, err := uc.healthRepo.GetHealthProfileByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get health profile: %w", err)
+	}
+	now := time.Now()
+	if profile == nil {
+		profile = &entity.UserHealthProfile{ID: uuid.New(), UserID: userID, CreatedAt: now}
+	}
+
+	profile.Age = input.Age
+	profile.Gender = strings.TrimSpace(input.Gender)
+	profile.MedicalConditions = encodeProfileList(input.MedicalConditions)
+	profile.Allergies = encodeProfileList(input.Allergies)
+	profile.Medications = encodeProfileList(input.Medications)
+	profile.ActivityPreferences = encodeProfileList(input.ActivityPreferences)
+	profile.HealthGoals = encodeProfileList(input.HealthGoals)
+	profile.UpdatedAt = now
+
+	if err := uc.healthRepo.CreateOrUpdateHealthProfile(ctx, profile); err != nil {
+		return fmt.Errorf("failed to save health profile: %w", err)
+	}
+	return nil
+}
+
+// decodeProfileList reads a JSONB list column. Values that aren't a JSON
+// array of strings are returned as a single item so nothing is lost on edit.
+func decodeProfileList(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || raw == "null" || raw == "[]" {
+		return nil
+	}
+	var items []string
+	if err := json.Unmarshal([]byte(raw), &items); err == nil {
+		return items
+	}
+	return []string{raw}
+}
+
+func encodeProfileList(items []string) string {
+	cleaned := []string{}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			cleaned = append(cleaned, item)
+		}
+	}
+	data, _ := json.Marshal(cleaned)
+	return string(data)
+}
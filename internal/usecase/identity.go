@@ -0,0 +1,204 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/channel"
+)
+
+const (
+	linkCodeLength = 8
+	// LinkCodeTTL is how long a link code can be used
+	LinkCodeTTL = 15 * time.Minute
+	// Letters and digits that can't be mistaken for each other
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// GetOrCreateByIdentity returns the user behind a channel identity, creating
+// the user and the identity if needed, and records that the identity was just
+// used. identity needs its channel, external ID, chat ID and account; the rest
+// is filled in. WhatsApp users are looked up by their contact, so writing from
+// their LID or their phone number leads to the same user; users from before
+// identities existed are found by their number. WhatsApp users are bound to
+// the Waha session they messaged if they aren't bound to one yet.
+func (uc *UserUseCase) GetOrCreateByIdentity(ctx context.Context, identity *entity.UserIdentity, name, timezone string) (*entity.User, error) {
+	now := time.Now()
+	var contact *entity.WhatsAppContact
+	if identity.Channel == channel.WhatsApp {
+		var err error
+		if contact, err = uc.resolveContact(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+
+	existing, err := uc.identityRepo.GetByExternalID(ctx, identity.Channel, identity.ExternalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	var user *entity.User
+	switch {
+	case existing != nil:
+		existing.ChatID = identity.ChatID
+		existing.Account = identity.Account
+		existing.LastSeenAt = now
+		if err := uc.identityRepo.Touch(ctx, existing); err != nil {
+			return nil, fmt.Errorf("failed to update identity: %w", err)
+		}
+		*identity = *existing
+
+		if user, err = uc.userRepo.GetByID(ctx, identity.UserID); err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return nil, fmt.Errorf("user %w", ErrNotFound)
+		}
+	case contact != nil && contact.PhoneNumber != "":
+		if user, err = uc.userRepo.GetByWhatsAppNumber(ctx, contact.PhoneNumber); err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+	}
+
+	if user == nil {
+		if timezone == "" {
+			timezone = "Asia/Jakarta"
+		}
+		user = entity.NewUser("", name, timezone)
+		if contact != nil {
+			user.WhatsAppNumber = contact.PhoneNumber
+			user.WahaSession = identity.Account
+		}
+		if err := uc.userRepo.Create(ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+	} else {
+		if err := uc.userRepo.UpdateLastInteraction(ctx, user.ID, now); err != nil {
+			return nil, fmt.Errorf("failed to update last interaction: %w", err)
+		}
+		user.LastInteractionAt = &now
+
+		if identity.Channel == channel.WhatsApp && user.WahaSession == "" && identity.Account != "" {
+			if err := uc.userRepo.SetWahaSession(ctx, user.ID, identity.Account); err != nil {
+				return nil, fmt.Errorf("failed to bind session: %w", err)
+			}
+			user.WahaSession = identity.Account
+		}
+
+		// Until contacts existed, LIDs were stored as numbers
+		if contact != nil && contact.PhoneNumber != "" &&
+			(user.WhatsAppNumber == "" || user.WhatsAppNumber == contact.LID) {
+			if err := uc.userRepo.SetWhatsAppNumber(ctx, user.ID, contact.PhoneNumber); err != nil {
+				slog.WarnContext(ctx, "Failed to set WhatsApp number of user", "user_id", user.ID, "error", err)
+			} else {
+				user.WhatsAppNumber = contact.PhoneNumber
+			}
+		}
+	}
+
+	if existing == nil {
+		*identity = *entity.NewUserIdentity(user.ID, identity.Channel, identity.ExternalID, identity.ChatID, identity.Account)
+		if err := uc.identityRepo.Create(ctx, identity); err != nil {
+			return nil, fmt.Errorf("failed to create identity: %w", err)
+		}
+	}
+	if contact != nil {
+		if err := uc.saveContact(ctx, contact, identity); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// CreateLinkCode returns a one-time code the user can send from another
+// channel to link that identity to their account.
+func (uc *UserUseCase) CreateLinkCode(ctx context.Context, userID uuid.UUID) (string, error) {
+	code, err := randomCode(linkCodeLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate link code: %w", err)
+	}
+	if err := uc.identityRepo.CreateLinkCode(ctx, entity.NewIdentityLinkCode(userID, hashSecret(code), LinkCodeTTL)); err != nil {
+		return "", fmt.Errorf("failed to save link code: %w", err)
+	}
+	return code, nil
+}
+
+// LinkIdentity moves identity to the user who created the link code and
+// returns that user. The user the identity belonged to is deactivated if it
+// has no identities left; their activities are not moved. It returns
+// ErrInvalidLinkCode for wrong, expired or used codes.
+func (uc *UserUseCase) LinkIdentity(ctx context.Context, identity *entity.UserIdentity, code string) (*entity.User, error) {
+	linkCode, err := uc.identityRepo.UseLinkCode(ctx, hashSecret(strings.ToUpper(strings.TrimSpace(code))), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to use link code: %w", err)
+	}
+	if linkCode == nil {
+		return nil, ErrInvalidLinkCode
+	}
+
+	user, err := uc.GetUser(ctx, linkCode.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if identity.UserID == user.ID {
+		return user, nil
+	}
+
+	previousUserID := identity.UserID
+	if identity.Channel == channel.WhatsApp {
+		contact, err := uc.contactRepo.GetByIdentityID(ctx, identity.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get contact: %w", err)
+		}
+		if contact != nil && contact.PhoneNumber != "" {
+			// The number is unique, so it is taken from the previous user first
+			if err := uc.userRepo.SetWhatsAppNumber(ctx, previousUserID, ""); err != nil {
+				return nil, fmt.Errorf("failed to remove number from previous user: %w", err)
+			}
+			if err := uc.userRepo.SetWhatsAppNumber(ctx, user.ID, contact.PhoneNumber); err != nil {
+				return nil, fmt.Errorf("failed to set number: %w", err)
+			}
+			user.WhatsAppNumber = contact.PhoneNumber
+		}
+		if user.WahaSession == "" && identity.Account != "" {
+			if err := uc.userRepo.SetWahaSession(ctx, user.ID, identity.Account); err != nil {
+				return nil, fmt.Errorf("failed to bind session: %w", err)
+			}
+			user.WahaSession = identity.Account
+		}
+	}
+	if err := uc.identityRepo.MoveToUser(ctx, identity.ID, user.ID); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+	identity.UserID = user.ID
+
+	remaining, err := uc.identityRepo.ListByUserID(ctx, previousUserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	if len(remaining) == 0 {
+		if _, err := uc.SetUserActive(ctx, previousUserID, false); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+func randomCode(n int) (string, error) {
+	var code strings.Builder
+	for i := 0; i < n; i++ {
+		index, err := rand.Int(rand.Reader, big.NewInt(int64(len(linkCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(linkCodeAlphabet[index.Int64()])
+	}
+	return code.String(), nil
+}
@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/infrastructure/ai"
+)
+
+// Most activities proposed from one photo
+const maxImageActivities = 30
+
+// ImageConfig tunes reading activities from photos.
+type ImageConfig struct {
+	// MaxBytes limits the size of photos sent to the vision model.
+	MaxBytes int64
+	// ConfirmationTTL is how long proposed activities wait for the user's
+	// answer.
+	ConfirmationTTL time.Duration
+}
+
+// ImageUseCase reads activities from photos of prescription labels, class
+// schedules and appointment cards. They are only created once the user
+// confirms them.
+type ImageUseCase struct {
+	aiService        ai.AIService
+	activityUseCase  *ActivityUseCase
+	userRepo         repository.UserRepository
+	categoryRepo     repository.CategoryRepository
+	confirmationRepo repository.PendingConfirmationRepository
+	location         *time.Location
+	config           ImageConfig
+}
+
+func NewImageUseCase(
+	aiService ai.AIService,
+	activityUseCase *ActivityUseCase,
+	userRepo repository.UserRepository,
+	categoryRepo repository.CategoryRepository,
+	confirmationRepo repository.PendingConfirmationRepository,
+	location *time.Location,
+	config ImageConfig,
+) *ImageUseCase {
+	return &ImageUseCase{
+		aiService:        aiService,
+		activityUseCase:  activityUseCase,
+		userRepo:         userRepo,
+		categoryRepo:     categoryRepo,
+		confirmationRepo: confirmationRepo,
+		location:         location,
+		config:           config,
+	}
+}
+
+func (uc *ImageUseCase) MaxBytes() int64 {
+	return uc.config.MaxBytes
+}
+
+// ProposeFromImage reads the activities in a photo and saves them as the
+// user's pending confirmation, replacing any earlier one. It returns
+// ErrNoActivitiesFound if there is nothing to schedule.
+func (uc *ImageUseCase) ProposeFromImage(ctx context.Context, userID uuid.UUID, image []byte, mimetype, caption string) (*entity.PendingConfirmation, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %w", ErrNotFound)
+	}
+
+	now := time.Now().In(userLocation(user, uc.location))
+	extraction, err := uc.aiService.ExtractFromImage(ctx, image, mimetype, caption, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	slog.InfoContext(ctx, "Image read", "kind", extraction.Kind, "activities", len(extraction.Activities))
+
+	categories := map[string]uuid.UUID{}
+	all, err := uc.categoryRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	for _, category := range all {
+		categories[strings.ToLower(category.Name)] = category.ID
+	}
+
+	confirmation := entity.NewPendingConfirmation(userID, entity.ConfirmationCreateActivities, uc.config.ConfirmationTTL)
+	confirmation.Summary = extraction.Summary
+	for _, extracted := range extraction.Activities {
+		if len(confirmation.Activities) == maxImageActivities {
+			break
+		}
+		confirmation.Activities = append(confirmation.Activities, extractedActivityData(extracted, categories))
+	}
+	if len(confirmation.Activities) == 0 {
+		return nil, ErrNoActivitiesFound
+	}
+
+	if err := uc.confirmationRepo.Save(ctx, confirmation); err != nil {
+		return nil, fmt.Errorf("failed to save confirmation: %w", err)
+	}
+	return confirmation, nil
+}
+
+// GetPendingConfirmation returns the user's unanswered confirmation, or nil
+// if there is none or it expired.
+func (uc *ImageUseCase) GetPendingConfirmation(ctx context.Context, userID uuid.UUID) (*entity.PendingConfirmation, error) {
+	confirmation, err := uc.confirmationRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get confirmation: %w", err)
+	}
+	if confirmation == nil {
+		return nil, nil
+	}
+	if confirmation.IsExpired(time.Now()) {
+		if _, err := uc.confirmationRepo.Delete(ctx, confirmation.ID); err != nil {
+			slog.ErrorContext(ctx, "Failed to delete expired confirmation", "confirmation_id", confirmation.ID, "error", err)
+		}
+		return nil, nil
+	}
+	return confirmation, nil
+}
+
+// Confirm creates the proposed activities. It returns ErrNotFound if the
+// confirmation was already answered.
+func (uc *ImageUseCase) Confirm(ctx context.Context, confirmation *entity.PendingConfirmation) ([]*entity.Activity, error) {
+	deleted, err := uc.confirmationRepo.Delete(ctx, confirmation.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete confirmation: %w", err)
+	}
+	if !deleted {
+		return nil, fmt.Errorf("confirmation %w", ErrNotFound)
+	}
+
+	var created []*entity.Activity
+	for _, data := range confirmation.Activities {
+		activity, err := uc.activityUseCase.CreateActivity(ctx, confirmation.UserID, data)
+		if err != nil {
+			return created, err
+		}
+		created = append(created, activity)
+	}
+	return created, nil
+}
+
+// Cancel drops the proposed activities.
+func (uc *ImageUseCase) Cancel(ctx context.Context, confirmation *entity.PendingConfirmation) error {
+	if _, err := uc.confirmationRepo.Delete(ctx, confirmation.ID); err != nil {
+		return fmt.Errorf("failed to delete confirmation: %w", err)
+	}
+	return nil
+}
+
+// extractedActivityData converts an activity read from a photo. Invalid
+// recurrence rules are dropped rather than failing the whole photo.
+func extractedActivityData(extracted entity.ExtractedActivity, categories map[string]uuid.UUID) entity.ActivityIntentData {
+	scheduledTime := extracted.ScheduledTime
+	data := entity.ActivityIntentData{
+		Title:         truncateRunes(extracted.Title, 255),
+		Description:   extracted.Description,
+		ScheduledTime: &scheduledTime,
+		Priority:      extracted.Priority,
+	}
+	if data.Priority < 1 || data.Priority > 5 {
+		data.Priority = 3
+	}
+	if id, ok := categories[strings.ToLower(extracted.Category)]; ok {
+		data.CategoryID = &id
+	}
+	if extracted.RecurrenceRule != "" {
+		if rule, err := normalizeRecurrenceRule(extracted.RecurrenceRule); err == nil {
+			data.RecurrenceRule = rule
+		}
+	}
+	return data
+}
@@ -0,0 +1,494 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/logging"
+	"smart_alert_system/internal/infrastructure/metrics"
+	"smart_alert_system/internal/infrastructure/tracing"
+)
+
+// RetryPolicy controls how often and how fast failed sends are retried.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Lease is how long a claimed message stays reserved for one dispatcher
+	// before another one may pick it up again.
+	Lease time.Duration
+}
+
+// Backoff returns the delay before the given attempt number is retried:
+// base * 2^(attempt-1), capped at MaxBackoff, with up to 20% jitter.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := p.BaseBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if delay > 0 {
+		delay += time.Duration(rand.Int64N(int64(delay)/5 + 1))
+	}
+	return delay
+}
+
+// OutboundRequest describes a message to put in the outbox.
+type OutboundRequest struct {
+	// IdempotencyKey makes enqueueing the same logical message twice a no-op,
+	// e.g. "reply:<incoming message id>" or "alert:<alert log id>".
+	IdempotencyKey string
+	// Channel and ChatID say where the message goes. If ChatID is empty, it
+	// goes to UserID on the channel they used last.
+	Channel string
+	ChatID  string
+	// Session is the account to send through, e.g. the Waha session. If
+	// empty, WhatsApp messages go through the session UserID is bound to.
+	Session string
+	Body    string
+	// ContentType defaults to text; other types take their choices or media
+	// from Payload
+	ContentType      entity.ContentType
+	Payload          *entity.OutboundPayload
+	UserID           *uuid.UUID
+	MessageHistoryID *uuid.UUID
+	AlertLogID       *uuid.UUID
+	// RecommendationID marks the health recommendation as read once the
+	// recipient reads this message.
+	RecommendationID *uuid.UUID
+	// NotBefore delays the first delivery attempt; zero means as soon as possible.
+	NotBefore time.Time
+}
+
+type OutboxUseCase struct {
+	outboxRepo   repository.OutboxRepository
+	messageRepo  repository.MessageRepository
+	alertRepo    repository.AlertRepository
+	healthRepo   repository.HealthRepository
+	userRepo     repository.UserRepository
+	identityRepo repository.UserIdentityRepository
+	channels     *channel.Channels
+	policy       RetryPolicy
+}
+
+func NewOutboxUseCase(
+	outboxRepo repository.OutboxRepository,
+	messageRepo repository.MessageRepository,
+	alertRepo repository.AlertRepository,
+	healthRepo repository.HealthRepository,
+	userRepo repository.UserRepository,
+	identityRepo repository.UserIdentityRepository,
+	channels *channel.Channels,
+	policy RetryPolicy,
+) *OutboxUseCase {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.Lease <= 0 {
+		policy.Lease = 5 * time.Minute
+	}
+	return &OutboxUseCase{
+		outboxRepo:   outboxRepo,
+		messageRepo:  messageRepo,
+		alertRepo:    alertRepo,
+		healthRepo:   healthRepo,
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		channels:     channels,
+		policy:       policy,
+	}
+}
+
+// Enqueue stores an outbound message for delivery by the dispatcher. If a
+// message with the same idempotency key already exists, that message is
+// returned instead and nothing new is queued.
+func (uc *OutboxUseCase) Enqueue(ctx context.Context, req OutboundRequest) (*entity.OutboundMessage, error) {
+	if req.IdempotencyKey == "" {
+		return nil, fmt.Errorf("idempotency key is required")
+	}
+
+	message := entity.NewOutboundMessage(req.IdempotencyKey, req.ChatID, req.Body, uc.policy.MaxAttempts)
+	message.Channel = req.Channel
+	message.Session = req.Session
+	if message.ChatID == "" {
+		if err := uc.addressToUser(ctx, message, req.UserID); err != nil {
+			return nil, err
+		}
+	}
+	if message.Channel == "" {
+		message.Channel = channel.WhatsApp
+	}
+	if message.Channel == channel.WhatsApp && message.Session == "" && req.UserID != nil {
+		user, err := uc.userRepo.GetByID(ctx, *req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user != nil {
+			message.Session = user.WahaSession
+		}
+	}
+	if req.ContentType != "" {
+		message.ContentType = req.ContentType
+	}
+	message.Payload = req.Payload
+	message.UserID = req.UserID
+	message.MessageHistoryID = req.MessageHistoryID
+	message.AlertLogID = req.AlertLogID
+	message.RecommendationID = req.RecommendationID
+	message.CorrelationID = logging.CorrelationID(ctx)
+	message.TraceParent = tracing.TraceParent(ctx)
+	if !req.NotBefore.IsZero() {
+		message.NextAttemptAt = req.NotBefore
+	}
+
+	inserted, err := uc.outboxRepo.Enqueue(ctx, message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue message: %w", err)
+	}
+	if inserted {
+		return message, nil
+	}
+
+	existing, err := uc.outboxRepo.GetByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing message: %w", err)
+	}
+	return existing, nil
+}
+
+// addressToUser sends the message to the user on the channel they used last.
+func (uc *OutboxUseCase) addressToUser(ctx context.Context, message *entity.OutboundMessage, userID *uuid.UUID) error {
+	if userID == nil {
+		return fmt.Errorf("%w: chat ID or user is required", ErrInvalidInput)
+	}
+	identities, err := uc.identityRepo.ListByUserID(ctx, *userID)
+	if err != nil {
+		return fmt.Errorf("failed to get identities: %w", err)
+	}
+	if len(identities) == 0 {
+		return fmt.Errorf("user %s has no channel to send to: %w", *userID, ErrNotFound)
+	}
+	identity := identities[0]
+	message.Channel = identity.Channel
+	message.ChatID = identity.ChatID
+	if message.Session == "" {
+		message.Session = identity.Account
+	}
+	return nil
+}
+
+// DispatchResult is the outcome of one DispatchDue batch.
+type DispatchResult struct {
+	Claimed int
+	// RetryAt is when the earliest message held back by the channel's send
+	// limits may go out, zero if none was.
+	RetryAt time.Time
+}
+
+// DispatchDue claims up to limit due messages and tries to deliver them.
+// Messages the channel throttles are put back with their retry time instead
+// of waiting, so one busy chat doesn't hold up the rest of the batch.
+func (uc *OutboxUseCase) DispatchDue(ctx context.Context, limit int) (DispatchResult, error) {
+	uc.failExhaustedClaims(ctx)
+
+	messages, err := uc.outboxRepo.ClaimDue(ctx, time.Now(), uc.policy.Lease, limit)
+	if err != nil {
+		return DispatchResult{}, fmt.Errorf("failed to claim outbound messages: %w", err)
+	}
+
+	result := DispatchResult{Claimed: len(messages)}
+	for _, message := range messages {
+		if ctx.Err() != nil {
+			// Unsent claims become due again once their lease expires
+			return result, ctx.Err()
+		}
+		if retryAt := uc.deliver(ctx, message); !retryAt.IsZero() && (result.RetryAt.IsZero() || retryAt.Before(result.RetryAt)) {
+			result.RetryAt = retryAt
+		}
+	}
+
+	return result, nil
+}
+
+// errLeaseExpired is recorded on messages whose dispatcher stopped during
+// their last attempt. They may or may not have been sent.
+var errLeaseExpired = errors.New("lease expired on the last attempt")
+
+// failExhaustedClaims gives up on messages whose last attempt never finished,
+// which ClaimDue no longer picks up.
+func (uc *OutboxUseCase) failExhaustedClaims(ctx context.Context) {
+	messages, err := uc.outboxRepo.FailExhaustedClaims(ctx, time.Now(), errLeaseExpired.Error())
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fail exhausted outbound messages", "error", err)
+		return
+	}
+	for _, message := range messages {
+		slog.ErrorContext(ctx, "Giving up on outbound message", "message_id", message.ID, "attempts", message.Attempts, "error", errLeaseExpired)
+		uc.markLinkedFailed(ctx, message, errLeaseExpired)
+	}
+}
+
+// deliver sends one claimed message and records the outcome. It returns the
+// retry time if the channel throttled the message, zero otherwise.
+func (uc *OutboxUseCase) deliver(ctx context.Context, message *entity.OutboundMessage) time.Time {
+	// Log and trace the send under the request or job that queued it
+	if message.CorrelationID != "" {
+		ctx = logging.WithCorrelationID(ctx, message.CorrelationID)
+	}
+	ctx, span := tracing.Start(tracing.WithTraceParent(ctx, message.TraceParent), "OutboxUseCase.deliver",
+		attribute.String("outbox.message_id", message.ID.String()),
+		attribute.String("messaging.channel", message.Channel),
+		attribute.Int("outbox.attempt", message.Attempts))
+
+	messageID, err := uc.send(ctx, message)
+
+	var throttled *channel.ThrottledError
+	if errors.As(err, &throttled) {
+		// Nothing was sent, so the claim doesn't count as an attempt
+		retryAt := time.Now().Add(throttled.RetryAfter)
+		span.SetAttributes(attribute.Bool("outbox.throttled", true))
+		tracing.End(span, nil)
+		slog.DebugContext(ctx, "Outbound message throttled", "message_id", message.ID, "retry_at", retryAt)
+		message.Attempts--
+		message.ScheduleRetry(nil, retryAt)
+		if err := uc.outboxRepo.Update(ctx, message); err != nil {
+			slog.ErrorContext(ctx, "Failed to reschedule outbound message", "message_id", message.ID, "error", err)
+		}
+		return retryAt
+	}
+
+	defer tracing.End(span, err)
+	if err == nil {
+		message.MarkSent(messageID)
+		if err := uc.outboxRepo.Update(ctx, message); err != nil {
+			slog.ErrorContext(ctx, "Failed to mark outbound message as sent", "message_id", message.ID, "error", err)
+		}
+		uc.markLinkedSent(ctx, message)
+		return time.Time{}
+	}
+
+	if message.AttemptsExhausted() || !isRetryable(err) {
+		slog.ErrorContext(ctx, "Giving up on outbound message", "message_id", message.ID, "attempts", message.Attempts, "error", err)
+		message.MarkFailed(err)
+		if err := uc.outboxRepo.Update(ctx, message); err != nil {
+			slog.ErrorContext(ctx, "Failed to mark outbound message as failed", "message_id", message.ID, "error", err)
+		}
+		uc.markLinkedFailed(ctx, message, err)
+		return time.Time{}
+	}
+
+	nextAttempt := time.Now().Add(uc.policy.Backoff(message.Attempts))
+	slog.WarnContext(ctx, "Outbound message send failed, retrying", "message_id", message.ID, "attempt", message.Attempts,
+		"max_attempts", message.MaxAttempts, "next_attempt", nextAttempt, "error", err)
+	message.ScheduleRetry(err, nextAttempt)
+	if err := uc.outboxRepo.Update(ctx, message); err != nil {
+		slog.ErrorContext(ctx, "Failed to reschedule outbound message", "message_id", message.ID, "error", err)
+	}
+	return time.Time{}
+}
+
+// errUnknownChannel is returned for messages of channels that are no longer
+// connected; they are not retried.
+var errUnknownChannel = errors.New("channel not connected")
+
+// send delivers the message on its channel and returns the channel's
+// message ID.
+func (uc *OutboxUseCase) send(ctx context.Context, message *entity.OutboundMessage) (string, error) {
+	ch, ok := uc.channels.Get(message.Channel)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", errUnknownChannel, message.Channel)
+	}
+	return ch.Send(ctx, message)
+}
+
+// ResolveChoice matches a typed answer ("2" or "Tunda 15 menit") to the
+// choices of the last buttons, list or poll message the user got after
+// sentAfter. This also covers engines that sent those as numbered text. It
+// returns an empty ID if the answer matches nothing.
+func (uc *OutboxUseCase) ResolveChoice(ctx context.Context, userID uuid.UUID, answer string, sentAfter time.Time) (string, error) {
+	message, err := uc.outboxRepo.GetLatestInteractive(ctx, userID, sentAfter)
+	if err != nil {
+		return "", fmt.Errorf("failed to get last interactive message: %w", err)
+	}
+	if message == nil || message.Payload == nil {
+		return "", nil
+	}
+	choice, ok := message.Payload.MatchChoice(answer)
+	if !ok {
+		return "", nil
+	}
+	return choice.ID, nil
+}
+
+// ResolvePollVote returns the poll message a vote refers to and the ID of the
+// chosen option. The message is nil for polls not sent through the outbox.
+func (uc *OutboxUseCase) ResolvePollVote(ctx context.Context, pollMessageID, option string) (*entity.OutboundMessage, string, error) {
+	message, err := uc.findByWahaMessageID(ctx, pollMessageID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find poll message: %w", err)
+	}
+	if message == nil || message.Payload == nil {
+		return nil, "", nil
+	}
+	for _, choice := range message.Payload.Choices {
+		if choice.Title == option {
+			return message, choice.ID, nil
+		}
+	}
+	return message, "", nil
+}
+
+func (uc *OutboxUseCase) markLinkedSent(ctx context.Context, message *entity.OutboundMessage) {
+	if message.MessageHistoryID != nil {
+		history, err := uc.messageRepo.GetByID(ctx, *message.MessageHistoryID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load message history", "message_history_id", *message.MessageHistoryID, "error", err)
+		} else if history != nil {
+			history.SentAt = message.SentAt
+			history.WahaMessageID = message.WahaMessageID
+			if err := uc.messageRepo.Update(ctx, history); err != nil {
+				slog.ErrorContext(ctx, "Failed to update message history", "message_history_id", history.ID, "error", err)
+			}
+		}
+	}
+
+	if message.AlertLogID != nil {
+		alert, err := uc.alertRepo.GetByID(ctx, *message.AlertLogID)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load alert log", "alert_id", *message.AlertLogID, "error", err)
+		} else if alert != nil {
+			alert.MarkSent()
+			alert.WahaMessageID = message.WahaMessageID
+			metrics.Alerts.WithLabelValues(string(alert.AlertType), metrics.AlertSent).Inc()
+			if err := uc.alertRepo.Update(ctx, alert); err != nil {
+				slog.ErrorContext(ctx, "Failed to update alert log", "alert_id", alert.ID, "error", err)
+			}
+		}
+	}
+
+	if message.RecommendationID != nil && message.SentAt != nil {
+		if err := uc.healthRepo.MarkRecommendationSent(ctx, *message.RecommendationID, *message.SentAt); err != nil {
+			slog.ErrorContext(ctx, "Failed to update recommendation", "recommendation_id", *message.RecommendationID, "error", err)
+		}
+	}
+}
+
+func (uc *OutboxUseCase) markLinkedFailed(ctx context.Context, message *entity.OutboundMessage, sendErr error) {
+	if message.AlertLogID == nil {
+		return
+	}
+
+	alert, err := uc.alertRepo.GetByID(ctx, *message.AlertLogID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load alert log", "alert_id", *message.AlertLogID, "error", err)
+		return
+	}
+	if alert == nil {
+		return
+	}
+
+	alert.MarkFailed(sendErr)
+	metrics.Alerts.WithLabelValues(string(alert.AlertType), metrics.AlertFailed).Inc()
+	if err := uc.alertRepo.Update(ctx, alert); err != nil {
+		slog.ErrorContext(ctx, "Failed to update alert log", "alert_id", alert.ID, "error", err)
+	}
+}
+
+// RecordAck applies a Waha message.ack event to the outbound message it refers
+// to and to the message_history, alert_logs and health_recommendations rows
+// linked to it. Acks for messages that didn't go through the outbox are ignored.
+func (uc *OutboxUseCase) RecordAck(ctx context.Context, wahaMessageID string, ack int, at time.Time) error {
+	status := entity.DeliveryStatusFromAck(ack)
+	if status == "" || wahaMessageID == "" {
+		return nil
+	}
+
+	message, err := uc.findByWahaMessageID(ctx, wahaMessageID)
+	if err != nil {
+		return fmt.Errorf("failed to find outbound message: %w", err)
+	}
+	if message == nil {
+		return nil
+	}
+
+	if !message.Advance(status, at) {
+		// Duplicate or out-of-order ack, nothing new to record
+		return nil
+	}
+	message.UpdatedAt = time.Now()
+	if err := uc.outboxRepo.Update(ctx, message); err != nil {
+		return fmt.Errorf("failed to update outbound message: %w", err)
+	}
+
+	if message.MessageHistoryID != nil {
+		if err := uc.messageRepo.UpdateDeliveryState(ctx, *message.MessageHistoryID, message.DeliveryState); err != nil {
+			slog.ErrorContext(ctx, "Failed to update delivery state of message", "message_history_id", *message.MessageHistoryID, "error", err)
+		}
+	}
+	if message.AlertLogID != nil {
+		if err := uc.alertRepo.UpdateDeliveryState(ctx, *message.AlertLogID, message.DeliveryState); err != nil {
+			slog.ErrorContext(ctx, "Failed to update delivery state of alert", "alert_id", *message.AlertLogID, "error", err)
+		}
+	}
+	if message.RecommendationID != nil && status.IsRead() {
+		if err := uc.healthRepo.MarkRecommendationRead(ctx, *message.RecommendationID); err != nil {
+			slog.ErrorContext(ctx, "Failed to mark recommendation as read", "recommendation_id", *message.RecommendationID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// findByWahaMessageID matches the serialized ID from ack events
+// ("true_628xx@c.us_3EB0...") as well as the bare ID some engines return
+// from sendText ("3EB0...").
+// QuotedAlertID returns the alert log of the message a reply quotes, nil if
+// it quotes none of ours sent after sentAfter, or one that isn't an alert.
+func (uc *OutboxUseCase) QuotedAlertID(ctx context.Context, quotedID string, sentAfter time.Time) (*uuid.UUID, error) {
+	message, err := uc.findByWahaMessageID(ctx, quotedID)
+	if err == nil && message == nil && !strings.Contains(quotedID, "_") {
+		// A bare ID quoting a message stored with its serialized ID
+		message, err = uc.outboxRepo.GetByWahaMessageIDSuffix(ctx, quotedID, sentAfter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find quoted message: %w", err)
+	}
+	if message == nil || message.SentAt == nil || message.SentAt.Before(sentAfter) {
+		return nil, nil
+	}
+	return message.AlertLogID, nil
+}
+
+func (uc *OutboxUseCase) findByWahaMessageID(ctx context.Context, wahaMessageID string) (*entity.OutboundMessage, error) {
+	message, err := uc.outboxRepo.GetByWahaMessageID(ctx, wahaMessageID)
+	if err != nil || message != nil {
+		return message, err
+	}
+
+	if idx := strings.LastIndex(wahaMessageID, "_"); idx >= 0 && idx < len(wahaMessageID)-1 {
+		return uc.outboxRepo.GetByWahaMessageID(ctx, wahaMessageID[idx+1:])
+	}
+	return nil, nil
+}
+
+// isRetryable treats errors the channel marks as permanent (bad chat ID,
+// auth) as final and everything else (network errors, 5xx, 429) as worth
+// another attempt.
+func isRetryable(err error) bool {
+	return !errors.Is(err, errUnknownChannel) && !errors.Is(err, channel.ErrPermanent)
+}
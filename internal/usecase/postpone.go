@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/utils"
+)
+
+// PostponeReplyWindow is how long after a reminder "tunda 30 menit" or
+// "pindah ke besok" still postpones its activity.
+const PostponeReplyWindow = 3 * time.Hour
+
+// PostponeLatestReminder moves the activity of the user's most recent
+// reminder, sent within PostponeReplyWindow. It returns ErrNoRecentReminder if
+// there is none and ErrInvalidInput if the new time has already passed.
+// changed is false if the activity was already completed or cancelled; it is
+// then left as it is.
+func (uc *ActivityUseCase) PostponeLatestReminder(ctx context.Context, userID uuid.UUID, req utils.PostponeRequest) (activity *entity.Activity, changed bool, err error) {
+	now := time.Now()
+	alert, err := uc.alertRepo.GetLatestReminder(ctx, userID, now.Add(-PostponeReplyWindow))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get latest reminder: %w", err)
+	}
+	if alert == nil {
+		return nil, false, ErrNoRecentReminder
+	}
+	return uc.postponeReminder(ctx, userID, alert, req, now)
+}
+
+// PostponeReminder moves the activity of the reminder alertID, which the user
+// quoted in their reply. It returns ErrNoRecentReminder if alertID isn't one
+// of the user's reminders, otherwise it behaves like PostponeLatestReminder.
+func (uc *ActivityUseCase) PostponeReminder(ctx context.Context, userID, alertID uuid.UUID, req utils.PostponeRequest) (activity *entity.Activity, changed bool, err error) {
+	alert, err := uc.alertRepo.GetByID(ctx, alertID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get reminder: %w", err)
+	}
+	if alert == nil || alert.UserID != userID || alert.AlertType != entity.AlertTypeActivityReminder || alert.ActivityID == nil {
+		return nil, false, ErrNoRecentReminder
+	}
+	return uc.postponeReminder(ctx, userID, alert, req, time.Now())
+}
+
+func (uc *ActivityUseCase) postponeReminder(ctx context.Context, userID uuid.UUID, alert *entity.AlertLog, req utils.PostponeRequest, now time.Time) (activity *entity.Activity, changed bool, err error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, false, fmt.Errorf("user %w", ErrNotFound)
+	}
+	activity, err = uc.activityRepo.GetByID(ctx, *alert.ActivityID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get activity: %w", err)
+	}
+	if activity == nil || activity.UserID != userID {
+		return nil, false, fmt.Errorf("activity %w", ErrNotFound)
+	}
+	if activity.Status == entity.ActivityStatusCompleted || activity.Status == entity.ActivityStatusCancelled {
+		return activity, false, nil
+	}
+
+	localNow := now.In(userLocation(user, time.Local))
+	if err := uc.postpone(ctx, activity, alert, req.Target(activity.ScheduledTime, localNow), now); err != nil {
+		return nil, false, err
+	}
+	return activity, true, nil
+}
+
+// postpone moves the activity to scheduledTime and logs the postponement.
+// The reminder keeps its lead on the scheduled time; alert, if given, is the
+// reminder the user answered and tells that lead for activities without a
+// reminder_time. Reminders that would fall in the past go out at the new
+// scheduled time.
+func (uc *ActivityUseCase) postpone(ctx context.Context, activity *entity.Activity, alert *entity.AlertLog, scheduledTime, now time.Time) error {
+	if !scheduledTime.After(now) {
+		return fmt.Errorf("%w: %s has already passed", ErrInvalidInput, scheduledTime.Format("2006-01-02 15:04"))
+	}
+
+	var lead time.Duration
+	switch {
+	case activity.ReminderTime != nil:
+		lead = activity.ScheduledTime.Sub(*activity.ReminderTime)
+	case alert != nil:
+		lead = activity.ScheduledTime.Sub(alert.ScheduledTime)
+	}
+	reminderTime := scheduledTime.Add(-max(lead, 0))
+	if reminderTime.Before(now) {
+		reminderTime = scheduledTime
+	}
+
+	postponement := entity.NewActivityPostponement(activity, activity.ScheduledTime, scheduledTime)
+	if alert != nil {
+		alertID := alert.ID
+		postponement.AlertLogID = &alertID
+	}
+
+	activity.ScheduledTime = scheduledTime
+	activity.ReminderTime = &reminderTime
+	// Overdue activities are pending again so the reminder goes out
+	activity.Status = entity.ActivityStatusPending
+	activity.UpdatedAt = now
+
+	if err := uc.activityRepo.Postpone(ctx, activity, postponement); err != nil {
+		return fmt.Errorf("failed to postpone activity: %w", err)
+	}
+	return nil
+}
+
+// latestReminderOf returns the user's most recent reminder if it is about the
+// activity, nil otherwise.
+func (uc *ActivityUseCase) latestReminderOf(ctx context.Context, activity *entity.Activity, now time.Time) (*entity.AlertLog, error) {
+	alert, err := uc.alertRepo.GetLatestReminder(ctx, activity.UserID, now.Add(-PostponeReplyWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest reminder: %w", err)
+	}
+	if alert == nil || *alert.ActivityID != activity.ID {
+		return nil, nil
+	}
+	return alert, nil
+}
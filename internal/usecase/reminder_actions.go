@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/utils"
+)
+
+// Answers offered with each activity reminder
+const (
+	ReminderActionDone   = "done"
+	ReminderActionSnooze = "snooze"
+	ReminderActionCancel = "cancel"
+)
+
+// ReminderSnooze is how long "Tunda 15 menit" pushes the activity back.
+const ReminderSnooze = 15 * time.Minute
+
+const reminderChoicePrefix = "reminder:"
+
+// reminderChoices are the buttons sent with a reminder. Their IDs carry the
+// action and the activity, e.g. "reminder:snooze:<activity id>".
+func reminderChoices(activityID uuid.UUID) []entity.Choice {
+	id := activityID.String()
+	return []entity.Choice{
+		{ID: reminderChoicePrefix + ReminderActionDone + ":" + id, Title: "Selesai"},
+		{ID: reminderChoicePrefix + ReminderActionSnooze + ":" + id, Title: "Tunda 15 menit"},
+		{ID: reminderChoicePrefix + ReminderActionCancel + ":" + id, Title: "Batal"},
+	}
+}
+
+// ParseReminderChoice splits the ID of a reminder button. ok is false for IDs
+// that don't belong to a reminder.
+func ParseReminderChoice(choiceID string) (action string, activityID uuid.UUID, ok bool) {
+	rest, found := strings.CutPrefix(choiceID, reminderChoicePrefix)
+	if !found {
+		return "", uuid.Nil, false
+	}
+	action, rawID, _ := strings.Cut(rest, ":")
+	activityID, err := uuid.Parse(rawID)
+	if err != nil {
+		return "", uuid.Nil, false
+	}
+	switch action {
+	case ReminderActionDone, ReminderActionSnooze, ReminderActionCancel:
+		return action, activityID, true
+	}
+	return "", uuid.Nil, false
+}
+
+// AnswerReminder applies a reminder button to the user's activity: it is
+// completed, postponed by ReminderSnooze, or cancelled. changed is
+// false if the activity was already completed or cancelled; it is then left
+// as it is.
+func (uc *ActivityUseCase) AnswerReminder(ctx context.Context, userID uuid.UUID, action string, activityID uuid.UUID) (activity *entity.Activity, changed bool, err error) {
+	activity, err = uc.activityRepo.GetByID(ctx, activityID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get activity: %w", err)
+	}
+	if activity == nil || activity.UserID != userID {
+		return nil, false, fmt.Errorf("activity %w", ErrNotFound)
+	}
+	if activity.Status == entity.ActivityStatusCompleted || activity.Status == entity.ActivityStatusCancelled {
+		return activity, false, nil
+	}
+
+	now := time.Now()
+	switch action {
+	case ReminderActionDone:
+		activity.Complete()
+	case ReminderActionSnooze:
+		alert, err := uc.latestReminderOf(ctx, activity, now)
+		if err != nil {
+			return nil, false, err
+		}
+		target := utils.PostponeRequest{Delay: ReminderSnooze}.Target(activity.ScheduledTime, now)
+		if err := uc.postpone(ctx, activity, alert, target, now); err != nil {
+			return nil, false, err
+		}
+		return activity, true, nil
+	case ReminderActionCancel:
+		activity.Status = entity.ActivityStatusCancelled
+	default:
+		return nil, false, fmt.Errorf("%w: unknown reminder action %q", ErrInvalidInput, action)
+	}
+	activity.UpdatedAt = now
+
+	if err := uc.activityRepo.Update(ctx, activity); err != nil {
+		return nil, false, fmt.Errorf("failed to update activity: %w", err)
+	}
+	return activity, true, nil
+}
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+)
+
+// TriggerRequest runs an alert outside the cron schedule, for testing or to
+// resend a missed alert.
+type TriggerRequest struct {
+	AlertType entity.AlertType
+	// UserID limits the run to one user; nil runs it for all active users.
+	UserID *uuid.UUID
+	// ActivityID picks the activity to remind about. By default a reminder is
+	// about the user's next pending activity.
+	ActivityID *uuid.UUID
+	// DryRun only generates the text: nothing is sent or written to alert_logs.
+	DryRun bool
+	// Force sends a morning alert or evening summary even if the user already
+	// got one today.
+	Force bool
+}
+
+type TriggerStatus string
+
+const (
+	TriggerStatusPreview TriggerStatus = "preview"
+	TriggerStatusQueued  TriggerStatus = "queued"
+	TriggerStatusSkipped TriggerStatus = "skipped"
+	TriggerStatusFailed  TriggerStatus = "failed"
+)
+
+// TriggerResult is the outcome of a triggered alert for one user.
+type TriggerResult struct {
+	UserID     uuid.UUID     `json:"user_id"`
+	ActivityID *uuid.UUID    `json:"activity_id,omitempty"`
+	Status     TriggerStatus `json:"status"`
+	AlertID    *uuid.UUID    `json:"alert_id,omitempty"`
+	Message    string        `json:"message,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+type TriggerReport struct {
+	AlertType  entity.AlertType `json:"alert_type"`
+	DryRun     bool             `json:"dry_run"`
+	Sent       int              `json:"sent"`
+	Previewed  int              `json:"previewed"`
+	Failed     int              `json:"failed"`
+	Skipped    int              `json:"skipped"`
+	DurationMS int64            `json:"duration_ms"`
+	Results    []TriggerResult  `json:"results"`
+}
+
+// Trigger runs a morning alert, evening summary or activity reminder now.
+func (uc *SchedulerUseCase) Trigger(ctx context.Context, req TriggerRequest) (*TriggerReport, error) {
+	switch req.AlertType {
+	case entity.AlertTypeMorning, entity.AlertTypeEvening:
+		if req.ActivityID != nil {
+			return nil, fmt.Errorf("%w: activity_id is only allowed for %s", ErrInvalidInput, entity.AlertTypeActivityReminder)
+		}
+	case entity.AlertTypeActivityReminder:
+	default:
+		return nil, fmt.Errorf("%w: unsupported alert type %q", ErrInvalidInput, req.AlertType)
+	}
+
+	start := time.Now()
+	report := &TriggerReport{AlertType: req.AlertType, DryRun: req.DryRun, Results: []TriggerResult{}}
+
+	// A reminder for a given activity implies its owner
+	if req.UserID == nil && req.ActivityID != nil {
+		activity, err := uc.activityRepo.GetByID(ctx, *req.ActivityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get activity: %w", err)
+		}
+		if activity == nil {
+			return nil, fmt.Errorf("activity %w", ErrNotFound)
+		}
+		req.UserID = &activity.UserID
+	}
+
+	if req.UserID != nil {
+		user, err := uc.userRepo.GetByID(ctx, *req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return nil, fmt.Errorf("user %w", ErrNotFound)
+		}
+
+		result, err := uc.triggerForUser(ctx, user, req, start)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrInvalidInput) {
+			return nil, err
+		}
+		report.add(result)
+		report.DurationMS = time.Since(start).Milliseconds()
+		return report, nil
+	}
+
+	var mu sync.Mutex
+	stats, err := uc.fanOut(ctx, "triggered "+string(req.AlertType), func(ctx context.Context, user *entity.User) error {
+		result, err := uc.triggerForUser(ctx, user, req, start)
+		mu.Lock()
+		report.add(result)
+		mu.Unlock()
+		return err
+	})
+	// Users left over when the run timed out never got a result
+	report.Skipped += stats.Skipped - countStatus(report.Results, TriggerStatusSkipped)
+	report.DurationMS = stats.Duration.Milliseconds()
+	return report, err
+}
+
+func (r *TriggerReport) add(result TriggerResult) {
+	switch result.Status {
+	case TriggerStatusFailed:
+		r.Failed++
+	case TriggerStatusSkipped:
+		r.Skipped++
+	case TriggerStatusPreview:
+		r.Previewed++
+	default:
+		r.Sent++
+	}
+	r.Results = append(r.Results, result)
+}
+
+// countStatus returns how many of results have status.
+func countStatus(results []TriggerResult, status TriggerStatus) int {
+	n := 0
+	for _, result := range results {
+		if result.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+func (uc *SchedulerUseCase) triggerForUser(ctx context.Context, user *entity.User, req TriggerRequest, now time.Time) (TriggerResult, error) {
+	result := TriggerResult{UserID: user.ID}
+
+	var alert *entity.AlertLog
+	var err error
+	switch req.AlertType {
+	case entity.AlertTypeActivityReminder:
+		var activity *entity.Activity
+		activity, err = uc.reminderActivity(ctx, user.ID, req.ActivityID, now)
+		if err != nil {
+			break
+		}
+		result.ActivityID = &activity.ID
+		if req.DryRun {
+			result.Message = uc.composeReminder(activity)
+		} else {
+			alert, err = uc.sendReminder(ctx, user, activity, now)
+		}
+
+	default:
+		if req.DryRun {
+			result.Message, _, err = uc.composeDailyAlert(ctx, user, req.AlertType)
+		} else {
+			// Alerts for everyone are spread out like scheduled runs
+			opts := alertOptions{runAt: now, force: req.Force, spread: req.UserID == nil}
+			alert, err = uc.sendDailyAlert(ctx, user, req.AlertType, opts)
+		}
+	}
+
+	switch {
+	case errors.Is(err, errSkip):
+		result.Status = TriggerStatusSkipped
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = TriggerStatusFailed
+		result.Error = err.Error()
+	case req.DryRun:
+		result.Status = TriggerStatusPreview
+	default:
+		result.Status = TriggerStatusQueued
+		result.AlertID = &alert.ID
+		result.Message = alert.AlertContent
+	}
+	return result, err
+}
+
+// reminderActivity returns the activity to remind the user about: the given
+// one, or else the user's next pending activity.
+func (uc *SchedulerUseCase) reminderActivity(ctx context.Context, userID uuid.UUID, activityID *uuid.UUID, now time.Time) (*entity.Activity, error) {
+	if activityID != nil {
+		activity, err := uc.activityRepo.GetByID(ctx, *activityID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get activity: %w", err)
+		}
+		if activity == nil || activity.UserID != userID {
+			return nil, fmt.Errorf("activity %w", ErrNotFound)
+		}
+		return activity, nil
+	}
+
+	activities, err := uc.activityRepo.GetByUserIDAndStatus(ctx, userID, entity.ActivityStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get activities: %w", err)
+	}
+	// Sorted by scheduled time
+	for _, activity := range activities {
+		if !activity.ScheduledTime.Before(now) {
+			return activity, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no upcoming pending activity", errSkip)
+}
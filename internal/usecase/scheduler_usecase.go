@@ -2,24 +2,65 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"smart_alert_system/internal/domain/entity"
 	"smart_alert_system/internal/domain/repository"
 	"smart_alert_system/internal/infrastructure/ai"
+	"smart_alert_system/internal/infrastructure/metrics"
 	"smart_alert_system/internal/infrastructure/whatsapp"
 )
 
+// SchedulerConfig tunes how scheduled alerts are delivered.
+type SchedulerConfig struct {
+	// SpreadWindow spreads a batch of alerts over this duration instead of
+	// queueing them all for the same moment. Zero sends them right away.
+	SpreadWindow time.Duration
+	// Concurrency is how many users are processed at the same time.
+	Concurrency int
+	// PageSize is how many users are loaded from the database per query.
+	PageSize int
+	// GenerationTimeout bounds the AI call for a single user; on timeout the
+	// default message is sent instead.
+	GenerationTimeout time.Duration
+	// RunTimeout bounds a whole run. Users not reached in time are skipped.
+	RunTimeout time.Duration
+}
+
+// errSkip marks a user that was deliberately not sent anything, e.g. because
+// they already got this run's alert.
+var errSkip = errors.New("skipped")
+
+// RunStats summarizes one scheduled run.
+type RunStats struct {
+	Sent     int
+	Failed   int
+	Skipped  int
+	Duration time.Duration
+}
+
+func (s RunStats) String() string {
+	return fmt.Sprintf("sent=%d failed=%d skipped=%d duration=%s",
+		s.Sent, s.Failed, s.Skipped, s.Duration.Round(time.Millisecond))
+}
+
 type SchedulerUseCase struct {
 	userRepo       repository.UserRepository
 	activityRepo   repository.ActivityRepository
 	healthRepo     repository.HealthRepository
 	alertRepo      repository.AlertRepository
 	aiService      ai.AIService
-	wahaClient     *whatsapp.WahaClient
+	outboxUC       *OutboxUseCase
+	sessions       *whatsapp.Sessions
+	groups         *GroupUseCase
+	config         SchedulerConfig
 }
 
 func NewSchedulerUseCase(
@@ -28,118 +69,384 @@ func NewSchedulerUseCase(
 	healthRepo repository.HealthRepository,
 	alertRepo repository.AlertRepository,
 	aiService ai.AIService,
-	wahaClient *whatsapp.WahaClient,
+	outboxUC *OutboxUseCase,
+	config SchedulerConfig,
 ) *SchedulerUseCase {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	if config.PageSize < 1 {
+		config.PageSize = 100
+	}
 	return &SchedulerUseCase{
 		userRepo:     userRepo,
 		activityRepo: activityRepo,
 		healthRepo:   healthRepo,
 		alertRepo:    alertRepo,
 		aiService:    aiService,
-		wahaClient:   wahaClient,
+		outboxUC:     outboxUC,
+		config:       config,
 	}
 }
 
-func (uc *SchedulerUseCase) SendMorningAlerts(ctx context.Context) error {
-	users, err := uc.userRepo.GetAllActive(ctx)
+// SetSessions writes each user's AI-generated alerts in the persona of the
+// Waha session they are bound to.
+func (uc *SchedulerUseCase) SetSessions(sessions *whatsapp.Sessions) {
+	uc.sessions = sessions
+}
+
+// SetGroups @mentions the assigned member in reminders of group activities.
+func (uc *SchedulerUseCase) SetGroups(groups *GroupUseCase) {
+	uc.groups = groups
+}
+
+// SendMorningAlerts sends the morning alert of the run scheduled at runAt.
+// Users that already got the alert for runAt's date are skipped, so a run can
+// safely be repeated after a restart.
+func (uc *SchedulerUseCase) SendMorningAlerts(ctx context.Context, runAt time.Time) (RunStats, error) {
+	start := time.Now()
+	stats, err := uc.fanOut(ctx, "morning alert", func(ctx context.Context, user *entity.User) error {
+		_, err := uc.sendDailyAlert(ctx, user, entity.AlertTypeMorning, alertOptions{runAt: runAt, spread: true})
+		return err
+	})
+	observeRun(entity.AlertTypeMorning, start, stats)
+	return stats, err
+}
+
+// SendEveningSummaries sends the evening summary of the run scheduled at runAt,
+// skipping users that already got it.
+func (uc *SchedulerUseCase) SendEveningSummaries(ctx context.Context, runAt time.Time) (RunStats, error) {
+	start := time.Now()
+	stats, err := uc.fanOut(ctx, "evening summary", func(ctx context.Context, user *entity.User) error {
+		_, err := uc.sendDailyAlert(ctx, user, entity.AlertTypeEvening, alertOptions{runAt: runAt, spread: true})
+		return err
+	})
+	observeRun(entity.AlertTypeEvening, start, stats)
+	return stats, err
+}
+
+// observeRun records the duration of a scheduled run and how many of its
+// alerts failed before reaching the outbox. The job is labeled with the alert
+// type. Queued alerts are counted by the outbox once they are delivered.
+func observeRun(alertType entity.AlertType, start time.Time, stats RunStats) {
+	metrics.SchedulerJobDuration.WithLabelValues(string(alertType)).Observe(metrics.Since(start))
+	metrics.Alerts.WithLabelValues(string(alertType), metrics.AlertFailed).Add(float64(stats.Failed))
+}
+
+// alertOptions controls how a single alert is produced.
+type alertOptions struct {
+	runAt time.Time
+	// force sends the alert even if the user already got one for runAt's date
+	force bool
+	// spread delays delivery by the user's offset in the spread window
+	spread bool
+}
+
+// sendDailyAlert generates the morning alert or evening summary for one user
+// and queues it in the outbox.
+func (uc *SchedulerUseCase) sendDailyAlert(ctx context.Context, user *entity.User, alertType entity.AlertType, opts alertOptions) (*entity.AlertLog, error) {
+	var alert *entity.AlertLog
+	if opts.force {
+		// Without an alert date the alert doesn't take the user's daily slot
+		alert = entity.NewAlertLog(user.ID, alertType, "", opts.runAt)
+		if err := uc.alertRepo.Create(ctx, alert); err != nil {
+			return nil, fmt.Errorf("failed to create alert log: %w", err)
+		}
+	} else {
+		var err error
+		alert, err = uc.reserveAlert(ctx, user, alertType, opts.runAt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	message, aiGenerated, err := uc.composeDailyAlert(ctx, user, alertType)
 	if err != nil {
-		return fmt.Errorf("failed to get active users: %w", err)
+		return nil, err
 	}
 
-	for _, user := range users {
-		if err := uc.sendMorningAlertForUser(ctx, user.ID, user.WhatsAppNumber); err != nil {
-			log.Printf("Error sending morning alert to user %s: %v", user.ID, err)
-			continue
-		}
+	// AI messages carry personalized health tips; track them as a recommendation
+	var recommendationID *uuid.UUID
+	if aiGenerated {
+		recommendationID = uc.recordRecommendation(ctx, user.ID, message)
 	}
 
-	return nil
-}
+	// Fill in the reserved alert log
+	alert.AlertContent = message
+	if err := uc.alertRepo.Update(ctx, alert); err != nil {
+		return nil, fmt.Errorf("failed to update alert log: %w", err)
+	}
 
-func (uc *SchedulerUseCase) sendMorningAlertForUser(ctx context.Context, userID uuid.UUID, whatsappNumber string) error {
-	// Get today's activities
-	activities, err := uc.activityRepo.GetTodayActivities(ctx, userID)
-	if err != nil {
-		return fmt.Errorf("failed to get activities: %w", err)
+	var notBefore time.Time
+	if opts.spread {
+		notBefore = opts.runAt.Add(uc.spreadOffset(user.ID))
 	}
 
+	// Queue message; the outbox dispatcher marks the alert sent or failed
+	return alert, uc.enqueueAlert(ctx, alert, recommendationID, notBefore)
+}
+
+// composeDailyAlert generates the text of a morning alert or evening summary,
+// falling back to a plain list when the AI call fails. It has no side effects.
+func (uc *SchedulerUseCase) composeDailyAlert(ctx context.Context, user *entity.User, alertType entity.AlertType) (string, bool, error) {
+	userID := user.ID
 	// Get health profile
 	healthProfile, _ := uc.healthRepo.GetHealthProfileByUserID(ctx, userID)
 
-	// Generate alert message
-	message, err := uc.aiService.GenerateMorningAlert(ctx, activities, healthProfile)
-	if err != nil {
-		message = uc.generateDefaultMorningAlert(activities)
+	genCtx, cancel := uc.generationContext(ctx)
+	defer cancel()
+	if uc.sessions != nil {
+		genCtx = ai.WithPersona(genCtx, uc.sessions.Resolve(user.WahaSession).Persona)
 	}
 
-	// Create alert log
-	alert := entity.NewAlertLog(userID, entity.AlertTypeMorning, message, time.Now())
-	if err := uc.alertRepo.Create(ctx, alert); err != nil {
-		return fmt.Errorf("failed to create alert log: %w", err)
+	switch alertType {
+	case entity.AlertTypeMorning:
+		// Get today's activities
+		activities, err := uc.activityRepo.GetTodayActivities(ctx, userID)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to get activities: %w", err)
+		}
+		message, err := uc.aiService.GenerateMorningAlert(genCtx, activities, healthProfile)
+		if err != nil {
+			return uc.generateDefaultMorningAlert(activities), false, nil
+		}
+		return message, true, nil
+
+	case entity.AlertTypeEvening:
+		// Get completed activities today
+		activities, err := uc.activityRepo.GetCompletedToday(ctx, userID)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to get completed activities: %w", err)
+		}
+		// Postponements are counted here rather than by the AI so the numbers
+		// are exact
+		note := uc.postponementNote(ctx, userID)
+		message, err := uc.aiService.GenerateEveningSummary(genCtx, activities, healthProfile)
+		if err != nil {
+			return uc.generateDefaultEveningSummary(activities) + note, false, nil
+		}
+		return message + note, true, nil
 	}
 
-	// Send message
-	if err := uc.wahaClient.SendMessage(whatsappNumber, message); err != nil {
-		alert.MarkFailed(err)
-		uc.alertRepo.Update(ctx, alert)
-		return fmt.Errorf("failed to send message: %w", err)
+	return "", false, fmt.Errorf("%w: unsupported alert type %q", ErrInvalidInput, alertType)
+}
+
+// sendReminder queues the reminder of one activity. remindAt identifies the
+// reminder, so the same reminder time is never sent twice.
+func (uc *SchedulerUseCase) sendReminder(ctx context.Context, user *entity.User, activity *entity.Activity, remindAt time.Time) (*entity.AlertLog, error) {
+	payload := &entity.OutboundPayload{Choices: reminderChoices(activity.ID)}
+	content := uc.composeReminder(activity)
+	if assignee := uc.assignee(ctx, activity); assignee != nil {
+		content += "\n👤 " + assignee.MentionTag()
+		payload.Mentions = []string{assignee.ChatID}
 	}
 
-	alert.MarkSent()
-	uc.alertRepo.Update(ctx, alert)
+	alert := entity.NewAlertLog(user.ID, entity.AlertTypeActivityReminder, content, remindAt)
+	activityID := activity.ID
+	alert.ActivityID = &activityID
 
-	return nil
+	inserted, err := uc.alertRepo.Reserve(ctx, alert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert log: %w", err)
+	}
+	if !inserted {
+		return nil, fmt.Errorf("%w: reminder already sent", errSkip)
+	}
+
+	return alert, uc.enqueueAlertMessage(ctx, alert, OutboundRequest{
+		ContentType: entity.ContentTypeButtons,
+		Payload:     payload,
+	})
 }
 
-func (uc *SchedulerUseCase) SendEveningSummaries(ctx context.Context) error {
-	users, err := uc.userRepo.GetAllActive(ctx)
+// assignee returns the group member a group activity is assigned to, or nil.
+func (uc *SchedulerUseCase) assignee(ctx context.Context, activity *entity.Activity) *entity.GroupMember {
+	if activity.AssigneeID == nil || uc.groups == nil {
+		return nil
+	}
+	member, err := uc.groups.Member(ctx, activity.UserID, *activity.AssigneeID)
 	if err != nil {
-		return fmt.Errorf("failed to get active users: %w", err)
+		slog.ErrorContext(ctx, "Failed to load assignee of activity", "activity_id", activity.ID, "error", err)
+		return nil
+	}
+	return member
+}
+
+func (uc *SchedulerUseCase) composeReminder(activity *entity.Activity) string {
+	msg := fmt.Sprintf("⏰ Pengingat kegiatan\n\n*%s*\n🕐 %s", activity.Title, activity.ScheduledTime.Format("15:04"))
+	if activity.Description != "" {
+		msg += "\n📝 " + activity.Description
+	}
+	return msg
+}
+
+// fanOut pages through active users and runs send for each of them on a
+// bounded pool of workers. Once ctx is done, users that were already loaded
+// are counted as skipped and no further pages are read.
+func (uc *SchedulerUseCase) fanOut(ctx context.Context, name string, send func(context.Context, *entity.User) error) (RunStats, error) {
+	start := time.Now()
+	if uc.config.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, uc.config.RunTimeout)
+		defer cancel()
+	}
+
+	var sent, failed, skipped atomic.Int64
+	users := make(chan *entity.User)
+
+	var wg sync.WaitGroup
+	for i := 0; i < uc.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for user := range users {
+				if ctx.Err() != nil {
+					skipped.Add(1)
+					continue
+				}
+				err := send(ctx, user)
+				if errors.Is(err, errSkip) {
+					skipped.Add(1)
+					continue
+				}
+				if err != nil {
+					slog.ErrorContext(ctx, "Failed to send scheduled alert", "alert", name, "user_id", user.ID, "error", err)
+					failed.Add(1)
+					continue
+				}
+				sent.Add(1)
+			}
+		}()
 	}
 
-	for _, user := range users {
-		if err := uc.sendEveningSummaryForUser(ctx, user.ID, user.WhatsAppNumber); err != nil {
-			log.Printf("Error sending evening summary to user %s: %v", user.ID, err)
-			continue
+	var runErr error
+	afterID := uuid.Nil
+	for {
+		if err := ctx.Err(); err != nil {
+			runErr = err
+			break
+		}
+		page, err := uc.userRepo.ListActive(ctx, afterID, uc.config.PageSize)
+		if err != nil {
+			runErr = fmt.Errorf("failed to get active users: %w", err)
+			break
 		}
+		for _, user := range page {
+			users <- user
+		}
+		if len(page) < uc.config.PageSize {
+			break
+		}
+		afterID = page[len(page)-1].ID
 	}
 
-	return nil
+	close(users)
+	wg.Wait()
+
+	return RunStats{
+		Sent:     int(sent.Load()),
+		Failed:   int(failed.Load()),
+		Skipped:  int(skipped.Load()),
+		Duration: time.Since(start),
+	}, runErr
 }
 
-func (uc *SchedulerUseCase) sendEveningSummaryForUser(ctx context.Context, userID uuid.UUID, whatsappNumber string) error {
-	// Get completed activities today
-	activities, err := uc.activityRepo.GetCompletedToday(ctx, userID)
+// reserveAlert claims the (user, alert type, date) slot of a run before any
+// work is done. An empty pending alert is one whose run was interrupted before
+// it was queued; it is handed back so the run can finish it.
+func (uc *SchedulerUseCase) reserveAlert(ctx context.Context, user *entity.User, alertType entity.AlertType, runAt time.Time) (*entity.AlertLog, error) {
+	alertDate := localAlertDate(user, runAt)
+
+	alert := entity.NewAlertLog(user.ID, alertType, "", runAt)
+	alert.AlertDate = &alertDate
+	inserted, err := uc.alertRepo.Reserve(ctx, alert)
 	if err != nil {
-		return fmt.Errorf("failed to get completed activities: %w", err)
+		return nil, fmt.Errorf("failed to reserve alert log: %w", err)
+	}
+	if inserted {
+		return alert, nil
 	}
 
-	// Get health profile
-	healthProfile, _ := uc.healthRepo.GetHealthProfileByUserID(ctx, userID)
-
-	// Generate summary message
-	message, err := uc.aiService.GenerateEveningSummary(ctx, activities, healthProfile)
+	existing, err := uc.alertRepo.GetByUserTypeDate(ctx, user.ID, alertType, alertDate)
 	if err != nil {
-		message = uc.generateDefaultEveningSummary(activities)
+		return nil, fmt.Errorf("failed to load existing alert log: %w", err)
 	}
+	if existing != nil && existing.Status == entity.AlertStatusPending && existing.AlertContent == "" {
+		return existing, nil
+	}
+	return nil, fmt.Errorf("%w: alert already exists for this date", errSkip)
+}
 
-	// Create alert log
-	alert := entity.NewAlertLog(userID, entity.AlertTypeEvening, message, time.Now())
-	if err := uc.alertRepo.Create(ctx, alert); err != nil {
-		return fmt.Errorf("failed to create alert log: %w", err)
+// localAlertDate returns the date of runAt in the user's time zone, the date
+// alerts are deduplicated by (see migration 015). Users without a time zone
+// get the scheduler's.
+func localAlertDate(user *entity.User, runAt time.Time) time.Time {
+	local := runAt.In(userLocation(user, runAt.Location()))
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// generationContext bounds a single AI call so one slow response can't hold
+// a worker for the whole run.
+func (uc *SchedulerUseCase) generationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if uc.config.GenerationTimeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, uc.config.GenerationTimeout)
+}
 
-	// Send message
-	if err := uc.wahaClient.SendMessage(whatsappNumber, message); err != nil {
+// enqueueAlert queues the alert for delivery, not before notBefore if set.
+// It goes to the user on the channel they used last.
+func (uc *SchedulerUseCase) enqueueAlert(ctx context.Context, alert *entity.AlertLog, recommendationID *uuid.UUID, notBefore time.Time) error {
+	return uc.enqueueAlertMessage(ctx, alert, OutboundRequest{
+		RecommendationID: recommendationID,
+		NotBefore:        notBefore,
+	})
+}
+
+// enqueueAlertMessage queues req with the alert's content as its body.
+func (uc *SchedulerUseCase) enqueueAlertMessage(ctx context.Context, alert *entity.AlertLog, req OutboundRequest) error {
+	userID := alert.UserID
+	alertID := alert.ID
+	req.IdempotencyKey = "alert:" + alert.ID.String()
+	req.Body = alert.AlertContent
+	req.UserID = &userID
+	req.AlertLogID = &alertID
+	_, err := uc.outboxUC.Enqueue(ctx, req)
+	if err != nil {
 		alert.MarkFailed(err)
 		uc.alertRepo.Update(ctx, alert)
-		return fmt.Errorf("failed to send message: %w", err)
+		return fmt.Errorf("failed to queue message: %w", err)
 	}
+	return nil
+}
 
-	alert.MarkSent()
-	uc.alertRepo.Update(ctx, alert)
+// spreadOffset places each user at a fixed point inside the spread window, so a
+// batch of thousands of alerts trickles out instead of hitting Waha at once.
+// The offset is derived from the user ID to keep it stable between runs.
+func (uc *SchedulerUseCase) spreadOffset(userID uuid.UUID) time.Duration {
+	if uc.config.SpreadWindow <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write(userID[:])
+	return time.Duration(h.Sum64() % uint64(uc.config.SpreadWindow))
+}
 
-	return nil
+// recordRecommendation stores the generated text as a health recommendation so
+// its read state can be tracked. Failures only cost us the tracking.
+func (uc *SchedulerUseCase) recordRecommendation(ctx context.Context, userID uuid.UUID, text string) *uuid.UUID {
+	recommendation := &entity.HealthRecommendation{
+		ID:                 uuid.New(),
+		UserID:             userID,
+		RecommendationText: text,
+		GeneratedAt:        time.Now(),
+		Priority:           3,
+	}
+	if err := uc.healthRepo.CreateRecommendation(ctx, recommendation); err != nil {
+		slog.ErrorContext(ctx, "Failed to save health recommendation", "user_id", userID, "error", err)
+		return nil
+	}
+	return &recommendation.ID
 }
 
 func (uc *SchedulerUseCase) generateDefaultMorningAlert(activities []*entity.Activity) string {
@@ -170,3 +477,39 @@ func (uc *SchedulerUseCase) generateDefaultEveningSummary(activities []*entity.A
 	return msg
 }
 
+// postponementNote reports today's postponements for the evening summary, and
+// the activity the user keeps pushing back. It is empty if nothing was
+// postponed today.
+func (uc *SchedulerUseCase) postponementNote(ctx context.Context, userID uuid.UUID) string {
+	now := time.Now()
+	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	postponements, err := uc.activityRepo.GetPostponementsBetween(ctx, userID, startOfDay, startOfDay.AddDate(0, 0, 1))
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load postponements", "user_id", userID, "error", err)
+		return ""
+	}
+	if len(postponements) == 0 {
+		return ""
+	}
+
+	counts := make(map[uuid.UUID]int)
+	var mostPostponed uuid.UUID
+	for _, postponement := range postponements {
+		counts[postponement.ActivityID]++
+		if counts[postponement.ActivityID] > counts[mostPostponed] {
+			mostPostponed = postponement.ActivityID
+		}
+	}
+
+	note := fmt.Sprintf("\n\n⏰ Hari ini Anda menunda %d kegiatan (%d kali).", len(counts), len(postponements))
+	if counts[mostPostponed] < 2 {
+		return note
+	}
+	// Total count, including earlier days
+	activity, err := uc.activityRepo.GetByID(ctx, mostPostponed)
+	if err != nil || activity == nil {
+		return note
+	}
+	return note + fmt.Sprintf(" Paling sering: '%s', sudah ditunda %d kali. Coba jadwalkan di waktu yang lebih pas!",
+		activity.Title, activity.PostponeCount)
+}
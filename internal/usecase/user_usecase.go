@@ -11,45 +11,64 @@ import (
 )
 
 type UserUseCase struct {
-	userRepo repository.UserRepository
+	userRepo      repository.UserRepository
+	identityRepo  repository.UserIdentityRepository
+	contactRepo   repository.WhatsAppContactRepository
+	contactLookup ContactLookup
 }
 
-func NewUserUseCase(userRepo repository.UserRepository) *UserUseCase {
-	return &UserUseCase{userRepo: userRepo}
+func NewUserUseCase(userRepo repository.UserRepository, identityRepo repository.UserIdentityRepository, contactRepo repository.WhatsAppContactRepository) *UserUseCase {
+	return &UserUseCase{userRepo: userRepo, identityRepo: identityRepo, contactRepo: contactRepo}
 }
 
-func (uc *UserUseCase) GetOrCreateUser(ctx context.Context, whatsappNumber, name, timezone string) (*entity.User, error) {
-	user, err := uc.userRepo.GetByWhatsAppNumber(ctx, whatsappNumber)
+// SetContactLookup lets the use case ask WhatsApp for the phone number behind
+// a LID and the other way round. Without it, messages from a user's LID and
+// from their phone number are taken for two users.
+func (uc *UserUseCase) SetContactLookup(lookup ContactLookup) {
+	uc.contactLookup = lookup
+}
+
+func (uc *UserUseCase) MarkAsNotFirstTime(ctx context.Context, userID uuid.UUID) error {
+	return uc.userRepo.MarkAsNotFirstTime(ctx, userID)
+}
+
+func (uc *UserUseCase) GetAllActiveUsers(ctx context.Context) ([]*entity.User, error) {
+	return uc.userRepo.GetAllActive(ctx)
+}
+
+
+func (uc *UserUseCase) GetUser(ctx context.Context, userID uuid.UUID) (*entity.User, error) {
+	user, err := uc.userRepo.GetByID(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
-
 	if user == nil {
-		// Create new user
-		if timezone == "" {
-			timezone = "Asia/Jakarta"
-		}
-		user = entity.NewUser(whatsappNumber, name, timezone)
-		if err := uc.userRepo.Create(ctx, user); err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
-		}
-	} else {
-		// Update last interaction
-		now := time.Now()
-		if err := uc.userRepo.UpdateLastInteraction(ctx, user.ID, now); err != nil {
-			return nil, fmt.Errorf("failed to update last interaction: %w", err)
-		}
-		user.LastInteractionAt = &now
+		return nil, fmt.Errorf("user %w", ErrNotFound)
 	}
-
 	return user, nil
 }
 
-func (uc *UserUseCase) MarkAsNotFirstTime(ctx context.Context, userID uuid.UUID) error {
-	return uc.userRepo.MarkAsNotFirstTime(ctx, userID)
+// SearchUsers returns one page of users matching the filter and the total
+// number of matches.
+func (uc *UserUseCase) SearchUsers(ctx context.Context, filter repository.UserFilter) ([]*entity.User, int, error) {
+	users, total, err := uc.userRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, total, nil
 }
 
-func (uc *UserUseCase) GetAllActiveUsers(ctx context.Context) ([]*entity.User, error) {
-	return uc.userRepo.GetAllActive(ctx)
-}
+// SetUserActive enables or disables a user. Inactive users get no scheduled alerts.
+func (uc *UserUseCase) SetUserActive(ctx context.Context, userID uuid.UUID, active bool) (*entity.User, error) {
+	user, err := uc.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 
+	user.IsActive = active
+	user.UpdatedAt = time.Now()
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	return user, nil
+}
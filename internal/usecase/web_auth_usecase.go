@@ -0,0 +1,282 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+	"smart_alert_system/internal/infrastructure/channel"
+)
+
+const loginCodeDigits = 6
+
+// WebAuthConfig controls web dashboard login.
+type WebAuthConfig struct {
+	CodeTTL    time.Duration
+	SessionTTL time.Duration
+	// MaxCodeAttempts is how many wrong guesses a login code survives.
+	MaxCodeAttempts int
+	// ResendInterval is the minimum time between two codes for the same user.
+	ResendInterval time.Duration
+	// MaxFailedLogins is how many wrong guesses a user may make across all
+	// codes within LockoutWindow. Beyond that, no codes are sent and every
+	// guess fails until older failures fall out of the window.
+	MaxFailedLogins int
+	LockoutWindow   time.Duration
+}
+
+// WebLogin is a new session and the token to hand to the browser. The token
+// itself is never stored.
+type WebLogin struct {
+	Session *entity.WebSession
+	Token   string
+}
+
+type WebAuthUseCase struct {
+	userRepo      repository.UserRepository
+	sessionRepo   repository.WebSessionRepository
+	outboxUseCase *OutboxUseCase
+	config        WebAuthConfig
+}
+
+func NewWebAuthUseCase(
+	userRepo repository.UserRepository,
+	sessionRepo repository.WebSessionRepository,
+	outboxUseCase *OutboxUseCase,
+	config WebAuthConfig,
+) *WebAuthUseCase {
+	if config.CodeTTL <= 0 {
+		config.CodeTTL = 10 * time.Minute
+	}
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = 30 * 24 * time.Hour
+	}
+	if config.MaxCodeAttempts < 1 {
+		config.MaxCodeAttempts = 5
+	}
+	if config.MaxFailedLogins < 1 {
+		config.MaxFailedLogins = 10
+	}
+	if config.LockoutWindow <= 0 {
+		config.LockoutWindow = time.Hour
+	}
+	return &WebAuthUseCase{
+		userRepo:      userRepo,
+		sessionRepo:   sessionRepo,
+		outboxUseCase: outboxUseCase,
+		config:        config,
+	}
+}
+
+// RequestLoginCode sends a one-time login code to the user over WhatsApp.
+// Unknown, inactive and locked out numbers are ignored without an error, so
+// the login form can't be used to find out who is registered.
+func (uc *WebAuthUseCase) RequestLoginCode(ctx context.Context, whatsappNumber string) error {
+	now := time.Now()
+	// Opportunistic cleanup; login is rare enough that this is cheap
+	if err := uc.sessionRepo.DeleteExpired(ctx, now, now.Add(-uc.config.LockoutWindow)); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired web sessions", "error", err)
+	}
+
+	user, err := uc.userRepo.GetByWhatsAppNumber(ctx, NormalizeWhatsAppNumber(whatsappNumber))
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.IsActive {
+		return nil
+	}
+
+	// A locked out user isn't sent codes, so guessing doesn't flood their chat
+	failures, err := uc.sessionRepo.CountLoginFailures(ctx, user.ID, now.Add(-uc.config.LockoutWindow))
+	if err != nil {
+		return fmt.Errorf("failed to count login failures: %w", err)
+	}
+	if failures >= uc.config.MaxFailedLogins {
+		return nil
+	}
+
+	latest, err := uc.sessionRepo.GetLatestLoginCode(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get login code: %w", err)
+	}
+	if latest != nil && time.Since(latest.CreatedAt) < uc.config.ResendInterval {
+		return nil
+	}
+
+	code, err := randomDigits(loginCodeDigits)
+	if err != nil {
+		return fmt.Errorf("failed to generate login code: %w", err)
+	}
+	loginCode := entity.NewLoginCode(user.ID, hashSecret(code), uc.config.CodeTTL)
+	if err := uc.sessionRepo.CreateLoginCode(ctx, loginCode); err != nil {
+		return fmt.Errorf("failed to save login code: %w", err)
+	}
+
+	message := fmt.Sprintf("🔐 Kode login dashboard Smart Alert: *%s*\n\nBerlaku %d menit. Jangan berikan kode ini kepada siapa pun.",
+		code, int(uc.config.CodeTTL.Minutes()))
+	_, err = uc.outboxUseCase.Enqueue(ctx, OutboundRequest{
+		IdempotencyKey: "login:" + loginCode.ID.String(),
+		Channel:        channel.WhatsApp,
+		ChatID:         user.WhatsAppNumber,
+		Body:           message,
+		UserID:         &user.ID,
+	})
+	return err
+}
+
+// VerifyLoginCode checks a login code and starts a session. Any failure,
+// including a lockout, is reported as ErrInvalidLoginCode.
+func (uc *WebAuthUseCase) VerifyLoginCode(ctx context.Context, whatsappNumber, code string) (*WebLogin, error) {
+	user, err := uc.userRepo.GetByWhatsAppNumber(ctx, NormalizeWhatsAppNumber(whatsappNumber))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.IsActive {
+		return nil, ErrInvalidLoginCode
+	}
+
+	// Every guess counts as a failure until it succeeds, so concurrent
+	// guesses can't all get in under the limit. Guesses made while locked
+	// out count too: the lockout lasts as long as the guessing does.
+	now := time.Now()
+	failureID, err := uc.sessionRepo.CreateLoginFailure(ctx, user.ID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	failures, err := uc.sessionRepo.CountLoginFailures(ctx, user.ID, now.Add(-uc.config.LockoutWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count login failures: %w", err)
+	}
+	if failures > uc.config.MaxFailedLogins {
+		return nil, ErrInvalidLoginCode
+	}
+
+	loginCode, err := uc.sessionRepo.GetLatestLoginCode(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login code: %w", err)
+	}
+	if loginCode == nil || now.After(loginCode.ExpiresAt) || loginCode.Attempts >= uc.config.MaxCodeAttempts {
+		return nil, ErrInvalidLoginCode
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(strings.TrimSpace(code))), []byte(loginCode.CodeHash)) != 1 {
+		if err := uc.sessionRepo.IncrementLoginCodeAttempts(ctx, loginCode.ID); err != nil {
+			return nil, fmt.Errorf("failed to record login attempt: %w", err)
+		}
+		return nil, ErrInvalidLoginCode
+	}
+
+	used, err := uc.sessionRepo.UseLoginCode(ctx, loginCode.ID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use login code: %w", err)
+	}
+	if !used {
+		return nil, ErrInvalidLoginCode
+	}
+	if err := uc.sessionRepo.DeleteLoginFailure(ctx, failureID); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete login failure", "error", err)
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	csrfToken, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CSRF token: %w", err)
+	}
+
+	session := entity.NewWebSession(user.ID, hashSecret(token), csrfToken, uc.config.SessionTTL)
+	if err := uc.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return &WebLogin{Session: session, Token: token}, nil
+}
+
+// Authenticate returns the session and user for a session token, or nils if
+// the token is unknown, expired or belongs to an inactive user.
+func (uc *WebAuthUseCase) Authenticate(ctx context.Context, token string) (*entity.WebSession, *entity.User, error) {
+	if token == "" {
+		return nil, nil, nil
+	}
+
+	now := time.Now()
+	session, err := uc.sessionRepo.GetSessionByTokenHash(ctx, hashSecret(token), now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session == nil {
+		return nil, nil, nil
+	}
+
+	user, err := uc.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.IsActive {
+		return nil, nil, nil
+	}
+
+	// Only write last_seen_at occasionally, not on every page view
+	if now.Sub(session.LastSeenAt) > 5*time.Minute {
+		if err := uc.sessionRepo.TouchSession(ctx, session.ID, now); err != nil {
+			slog.ErrorContext(ctx, "Failed to update web session", "session_id", session.ID, "error", err)
+		}
+	}
+	return session, user, nil
+}
+
+func (uc *WebAuthUseCase) Logout(ctx context.Context, session *entity.WebSession) error {
+	return uc.sessionRepo.DeleteSession(ctx, session.ID)
+}
+
+// NormalizeWhatsAppNumber turns a typed phone number ("0812-3456-7890",
+// "+62 812 3456 7890") into the stored format ("6281234567890").
+func NormalizeWhatsAppNumber(number string) string {
+	var digits strings.Builder
+	for _, r := range number {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	normalized := digits.String()
+	if strings.HasPrefix(normalized, "0") {
+		normalized = "62" + normalized[1:]
+	}
+	return normalized
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomDigits(n int) (string, error) {
+	var code strings.Builder
+	for i := 0; i < n; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteString(digit.String())
+	}
+	return code.String(), nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/domain/repository"
+)
+
+const testNumber = "6281234567890"
+
+type fakeUserRepository struct {
+	repository.UserRepository
+	user *entity.User
+}
+
+func (r *fakeUserRepository) GetByWhatsAppNumber(ctx context.Context, whatsappNumber string) (*entity.User, error) {
+	if whatsappNumber == r.user.WhatsAppNumber {
+		return r.user, nil
+	}
+	return nil, nil
+}
+
+// fakeWebSessionRepository keeps login codes and failures in memory.
+type fakeWebSessionRepository struct {
+	repository.WebSessionRepository
+	codes    []*entity.LoginCode
+	failures map[uuid.UUID]time.Time
+	sessions int
+}
+
+func (r *fakeWebSessionRepository) CreateLoginCode(ctx context.Context, code *entity.LoginCode) error {
+	r.codes = append(r.codes, code)
+	return nil
+}
+
+func (r *fakeWebSessionRepository) GetLatestLoginCode(ctx context.Context, userID uuid.UUID) (*entity.LoginCode, error) {
+	for i := len(r.codes) - 1; i >= 0; i-- {
+		if code := r.codes[i]; code.UserID == userID && code.UsedAt == nil {
+			return code, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeWebSessionRepository) IncrementLoginCodeAttempts(ctx context.Context, id uuid.UUID) error {
+	for _, code := range r.codes {
+		if code.ID == id {
+			code.Attempts++
+		}
+	}
+	return nil
+}
+
+func (r *fakeWebSessionRepository) UseLoginCode(ctx context.Context, id uuid.UUID, usedAt time.Time) (bool, error) {
+	for _, code := range r.codes {
+		if code.ID == id && code.UsedAt == nil {
+			code.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeWebSessionRepository) CreateLoginFailure(ctx context.Context, userID uuid.UUID, at time.Time) (uuid.UUID, error) {
+	id := uuid.New()
+	r.failures[id] = at
+	return id, nil
+}
+
+func (r *fakeWebSessionRepository) DeleteLoginFailure(ctx context.Context, id uuid.UUID) error {
+	delete(r.failures, id)
+	return nil
+}
+
+func (r *fakeWebSessionRepository) CountLoginFailures(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	count := 0
+	for _, at := range r.failures {
+		if !at.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeWebSessionRepository) CreateSession(ctx context.Context, session *entity.WebSession) error {
+	r.sessions++
+	return nil
+}
+
+func (r *fakeWebSessionRepository) DeleteExpired(ctx context.Context, now, failuresBefore time.Time) error {
+	return nil
+}
+
+// age moves every recorded failure d into the past.
+func (r *fakeWebSessionRepository) age(d time.Duration) {
+	for id, at := range r.failures {
+		r.failures[id] = at.Add(-d)
+	}
+}
+
+// Wrong guesses add up across codes: a new code doesn't buy new guesses.
+func TestVerifyLoginCodeLocksOutAcrossCodes(t *testing.T) {
+	ctx := context.Background()
+	user := &entity.User{ID: uuid.New(), WhatsAppNumber: testNumber, IsActive: true}
+	sessions := &fakeWebSessionRepository{failures: make(map[uuid.UUID]time.Time)}
+	// A locked out user is sent nothing, so there is no outbox to send with
+	uc := NewWebAuthUseCase(&fakeUserRepository{user: user}, sessions, nil, WebAuthConfig{
+		MaxCodeAttempts: 5,
+		MaxFailedLogins: 8,
+		LockoutWindow:   time.Hour,
+	})
+
+	newCode := func(code string) {
+		sessions.codes = append(sessions.codes, entity.NewLoginCode(user.ID, hashSecret(code), 10*time.Minute))
+	}
+	guess := func(code string) error {
+		_, err := uc.VerifyLoginCode(ctx, "0812-3456-7890", code)
+		return err
+	}
+
+	// Five wrong guesses use up the first code, three more go to the second
+	newCode("111111")
+	for i := 0; i < 5; i++ {
+		if err := guess("000000"); !errors.Is(err, ErrInvalidLoginCode) {
+			t.Fatalf("wrong guess %d: err = %v, want ErrInvalidLoginCode", i+1, err)
+		}
+	}
+	newCode("222222")
+	for i := 0; i < 3; i++ {
+		if err := guess("000000"); !errors.Is(err, ErrInvalidLoginCode) {
+			t.Fatalf("wrong guess %d on the second code: err = %v, want ErrInvalidLoginCode", i+1, err)
+		}
+	}
+
+	if err := guess("222222"); !errors.Is(err, ErrInvalidLoginCode) {
+		t.Fatalf("right code while locked out: err = %v, want ErrInvalidLoginCode", err)
+	}
+	if err := uc.RequestLoginCode(ctx, testNumber); err != nil {
+		t.Fatalf("RequestLoginCode while locked out: %v", err)
+	}
+	if len(sessions.codes) != 2 {
+		t.Errorf("%d codes after requesting one while locked out, want 2", len(sessions.codes))
+	}
+
+	// The window rolls: once the failures are older than it, the code works
+	sessions.age(time.Hour + time.Second)
+	login, err := uc.VerifyLoginCode(ctx, testNumber, "222222")
+	if err != nil {
+		t.Fatalf("right code after the lockout window: %v", err)
+	}
+	if login.Token == "" || sessions.sessions != 1 {
+		t.Errorf("got token %q and %d sessions, want a token and 1 session", login.Token, sessions.sessions)
+	}
+	if count, _ := sessions.CountLoginFailures(ctx, user.ID, time.Now().Add(-time.Minute)); count != 0 {
+		t.Errorf("%d recent failures after a successful login, want 0", count)
+	}
+}
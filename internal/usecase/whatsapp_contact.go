@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"smart_alert_system/internal/domain/entity"
+	"smart_alert_system/internal/infrastructure/channel"
+	"smart_alert_system/internal/infrastructure/whatsapp"
+)
+
+// ContactLookup finds the LID and phone number of the WhatsApp user behind a
+// chat ID, through the account that received their message. Either is "" if
+// WhatsApp doesn't tell.
+type ContactLookup interface {
+	LookupContact(ctx context.Context, account, chatID string) (lid, phoneNumber string, err error)
+}
+
+// resolveContact finds the WhatsApp contact behind identity.ChatID and points
+// identity at the contact's identity: ExternalID is set to the stored one and
+// ChatID to the contact's canonical chat ID. The contact is returned with its
+// LID and phone number filled in, unsaved; a new contact has no IdentityID.
+func (uc *UserUseCase) resolveContact(ctx context.Context, identity *entity.UserIdentity) (*entity.WhatsAppContact, error) {
+	lid, phoneNumber := whatsapp.ParseChatID(identity.ChatID)
+	if lid == "" && phoneNumber == "" {
+		return nil, fmt.Errorf("%w: not a WhatsApp user chat ID: %q", ErrInvalidInput, identity.ChatID)
+	}
+
+	contact, err := uc.findContact(ctx, &lid, &phoneNumber)
+	if err != nil {
+		return nil, err
+	}
+	if (contact == nil || contact.LID == "" || contact.PhoneNumber == "") && uc.contactLookup != nil {
+		foundLID, foundPhoneNumber, err := uc.contactLookup.LookupContact(ctx, identity.Account, identity.ChatID)
+		if err != nil {
+			slog.WarnContext(ctx, "Failed to look up WhatsApp contact", "chat_id", identity.ChatID, "error", err)
+		}
+		if lid == "" {
+			lid = foundLID
+		}
+		if phoneNumber == "" {
+			phoneNumber = foundPhoneNumber
+		}
+		if contact, err = uc.findContact(ctx, &lid, &phoneNumber); err != nil {
+			return nil, err
+		}
+	}
+
+	chatID := whatsapp.LIDChatID(lid)
+	if phoneNumber != "" {
+		chatID = whatsapp.PhoneChatID(phoneNumber)
+	}
+	if contact == nil {
+		contact = entity.NewWhatsAppContact(uuid.Nil, lid, phoneNumber, chatID)
+		externalID, err := uc.contactExternalID(ctx, lid, phoneNumber)
+		if err != nil {
+			return nil, err
+		}
+		identity.ExternalID = externalID
+		identity.ChatID = chatID
+		return contact, nil
+	}
+
+	if contact.LID == "" {
+		contact.LID = lid
+	}
+	if contact.PhoneNumber == "" {
+		contact.PhoneNumber = phoneNumber
+	}
+	if contact.PhoneNumber != "" {
+		contact.ChatID = whatsapp.PhoneChatID(contact.PhoneNumber)
+	}
+
+	stored, err := uc.identityRepo.GetByID(ctx, contact.IdentityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("identity of contact %s %w", contact.ID, ErrNotFound)
+	}
+	identity.ExternalID = stored.ExternalID
+	identity.ChatID = contact.ChatID
+	return contact, nil
+}
+
+// findContact returns the contact with the phone number or the LID. If they
+// belong to two contacts, which happens when both wrote to us before WhatsApp
+// shared the mapping, the phone number wins and lid is cleared so it isn't
+// stored twice.
+func (uc *UserUseCase) findContact(ctx context.Context, lid, phoneNumber *string) (*entity.WhatsAppContact, error) {
+	var byPhoneNumber, byLID *entity.WhatsAppContact
+	var err error
+	if *phoneNumber != "" {
+		if byPhoneNumber, err = uc.contactRepo.GetByPhoneNumber(ctx, *phoneNumber); err != nil {
+			return nil, fmt.Errorf("failed to get contact: %w", err)
+		}
+	}
+	if *lid != "" {
+		if byLID, err = uc.contactRepo.GetByLID(ctx, *lid); err != nil {
+			return nil, fmt.Errorf("failed to get contact: %w", err)
+		}
+	}
+
+	switch {
+	case byPhoneNumber == nil:
+		return byLID, nil
+	case byLID != nil && byLID.ID != byPhoneNumber.ID:
+		slog.WarnContext(ctx, "WhatsApp LID and number belong to different contacts, using the number", "chat_id", *lid, "phone", *phoneNumber)
+		*lid = byPhoneNumber.LID
+	}
+	return byPhoneNumber, nil
+}
+
+// contactExternalID returns the external ID a new contact's identity is
+// stored under. Identities from before contacts existed used the part of the
+// chat ID before "@", phone number or LID alike, so those are reused;
+// otherwise the phone number, or the LID chat ID if the number is unknown.
+func (uc *UserUseCase) contactExternalID(ctx context.Context, lid, phoneNumber string) (string, error) {
+	for _, externalID := range []string{phoneNumber, lid} {
+		if externalID == "" {
+			continue
+		}
+		identity, err := uc.identityRepo.GetByExternalID(ctx, channel.WhatsApp, externalID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get identity: %w", err)
+		}
+		if identity != nil {
+			return externalID, nil
+		}
+	}
+	if phoneNumber != "" {
+		return phoneNumber, nil
+	}
+	return whatsapp.LIDChatID(lid), nil
+}
+
+// saveContact stores the contact resolved for identity.
+func (uc *UserUseCase) saveContact(ctx context.Context, contact *entity.WhatsAppContact, identity *entity.UserIdentity) error {
+	if contact.IdentityID == uuid.Nil {
+		contact.IdentityID = identity.ID
+		if err := uc.contactRepo.Create(ctx, contact); err != nil {
+			return fmt.Errorf("failed to create contact: %w", err)
+		}
+		return nil
+	}
+	contact.UpdatedAt = time.Now()
+	if err := uc.contactRepo.Update(ctx, contact); err != nil {
+		return fmt.Errorf("failed to update contact: %w", err)
+	}
+	return nil
+}
@@ -82,7 +82,7 @@ func detectAddActivity(message string, baseTime time.Time) *entity.ParsedIntent
 		"jam", "pagi", "siang", "sore", "malam",
 		"besok", "lusa", "hari ini", "hari ini",
 		"mau", "akan", "ingin", "rencana", "agenda",
-		"tambah", "add", "buat", "jadwalkan",
+		"tambah", "add", "buat", "jadwalkan", "ingatkan",
 	}
 	
 	hasTimeKeyword := false
@@ -159,7 +159,7 @@ func extractActivityTitle(message string) string {
 	timeWords := []string{
 		"jam", "pagi", "siang", "sore", "malam",
 		"besok", "lusa", "hari ini",
-		"mau", "akan", "ingin", "tambah", "add",
+		"mau", "akan", "ingin", "tambah", "add", "ingatkan",
 	}
 	
 	words := strings.Fields(message)
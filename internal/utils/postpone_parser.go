@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PostponeRequest is a reply asking to move an activity, e.g. "tunda 30
+// menit", "pindah ke besok" or "nanti jam 5".
+type PostponeRequest struct {
+	// Delay pushes the activity back by this long. If set, the other fields
+	// are unused.
+	Delay time.Duration
+	// Days is how many days after today the activity moves to
+	Days int
+	// Hour and Minute are the new time of day; Hour is -1 to keep the
+	// activity's own time
+	Hour   int
+	Minute int
+	// Ambiguous is true for hours like "jam 5" that can be morning or
+	// afternoon
+	Ambiguous bool
+	// Standalone is true if the message reads as a postpone command without
+	// quoting a reminder: it starts with the keyword and, for "nanti", says
+	// nothing but the new time. "nanti jam 5 saya rapat" is not standalone.
+	Standalone bool
+}
+
+var (
+	postponeKeywordPattern = regexp.MustCompile(`\b(tunda\w*|undur\w*|mundur\w*|pindah\w*|geser\w*|nanti|snooze)\b`)
+	postponeDelayPattern   = regexp.MustCompile(`\b(\d{1,3})\s*(menit|mnt|jam)\b`)
+	postponeClockPattern   = regexp.MustCompile(`\b(?:jam|pukul|pkl\.?)\s*(\d{1,2})(?:[.:](\d{2}))?\b|\b(\d{1,2})[.:](\d{2})\b`)
+	postponeDayPartPattern = regexp.MustCompile(`\b(pagi|siang|sore|malam)\b`)
+)
+
+// Words allowed around the new time in a standalone "nanti" request, e.g.
+// "nanti sore aja ya"
+var postponeFillerWords = map[string]bool{
+	"ke": true, "aja": true, "saja": true, "ya": true, "yah": true, "dong": true, "deh": true,
+	"dulu": true, "lagi": true, "hari": true, "ini": true, "besok": true, "lusa": true,
+	"minggu": true, "depan": true, "setengah": true, "sejam": true, "ok": true, "oke": true,
+}
+
+// Default times of day for "besok pagi", "nanti sore", ...
+var dayPartHours = map[string]int{
+	"pagi":  7,
+	"siang": 12,
+	"sore":  16,
+	"malam": 19,
+}
+
+// ParsePostpone reads a request to postpone an activity. ok is false if the
+// message doesn't ask for one. A bare "tunda" or "snooze" delays by
+// defaultDelay.
+func ParsePostpone(message string, defaultDelay time.Duration) (req PostponeRequest, ok bool) {
+	text := strings.ToLower(strings.TrimSpace(message))
+	loc := postponeKeywordPattern.FindStringIndex(text)
+	if loc == nil {
+		return PostponeRequest{}, false
+	}
+	keyword := text[loc[0]:loc[1]]
+	defer func() {
+		req.Standalone = ok && loc[0] == 0 && (keyword != "nanti" || onlyPostpone(text))
+	}()
+
+	// "tunda 30 menit", "undur 1 jam 30 menit", "tunda setengah jam"
+	for _, match := range postponeDelayPattern.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.Atoi(match[1])
+		if match[2] == "jam" {
+			req.Delay += time.Duration(n) * time.Hour
+		} else {
+			req.Delay += time.Duration(n) * time.Minute
+		}
+	}
+	if strings.Contains(text, "setengah jam") {
+		req.Delay += 30 * time.Minute
+	} else if strings.Contains(text, "sejam") {
+		req.Delay += time.Hour
+	}
+	if req.Delay > 0 {
+		return req, true
+	}
+
+	req.Hour = -1
+	found := false
+	switch {
+	case strings.Contains(text, "lusa"):
+		req.Days, found = 2, true
+	case strings.Contains(text, "besok"):
+		req.Days, found = 1, true
+	case strings.Contains(text, "minggu depan"):
+		req.Days, found = 7, true
+	}
+
+	dayPart := postponeDayPartPattern.FindString(text)
+	if match := postponeClockPattern.FindStringSubmatch(text); match != nil {
+		hour, minute := match[1], match[2]
+		if hour == "" {
+			hour, minute = match[3], match[4]
+		}
+		req.Hour, _ = strconv.Atoi(hour)
+		req.Minute, _ = strconv.Atoi(minute)
+		if req.Hour > 23 || req.Minute > 59 {
+			return PostponeRequest{}, false
+		}
+		req.Hour, req.Ambiguous = applyDayPart(req.Hour, dayPart)
+		found = true
+	} else if dayPart != "" {
+		req.Hour = dayPartHours[dayPart]
+		found = true
+	}
+
+	if !found {
+		if keyword == "snooze" || strings.HasPrefix(keyword, "tunda") {
+			return PostponeRequest{Delay: defaultDelay}, true
+		}
+		return PostponeRequest{}, false
+	}
+	return req, true
+}
+
+// onlyPostpone reports whether text has no words besides the postpone
+// keyword, the new time and filler words.
+func onlyPostpone(text string) bool {
+	for _, pattern := range []*regexp.Regexp{postponeKeywordPattern, postponeDelayPattern, postponeClockPattern, postponeDayPartPattern} {
+		text = pattern.ReplaceAllString(text, " ")
+	}
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if !postponeFillerWords[word] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyDayPart turns a 12-hour "jam 5 sore" into 17. Hours from 1 to 12
+// without a part of the day are ambiguous.
+func applyDayPart(hour int, dayPart string) (int, bool) {
+	if hour == 0 || hour > 12 {
+		return hour, false
+	}
+	switch dayPart {
+	case "pagi":
+		if hour == 12 {
+			return 0, false
+		}
+	case "siang":
+		if hour < 11 {
+			return hour + 12, false
+		}
+	case "sore":
+		if hour < 12 {
+			return hour + 12, false
+		}
+	case "malam":
+		if hour == 12 {
+			return 0, false
+		}
+		if hour >= 6 {
+			return hour + 12, false
+		}
+	default:
+		return hour, hour != 12
+	}
+	return hour, false
+}
+
+// Target returns the new scheduled time of an activity scheduled at
+// scheduled, in now's location. Ambiguous hours pick the first one after now,
+// or the afternoon for hours before 7 on later days.
+func (r PostponeRequest) Target(scheduled, now time.Time) time.Time {
+	if r.Delay > 0 {
+		if scheduled.Before(now) {
+			scheduled = now
+		}
+		return scheduled.Add(r.Delay).Truncate(time.Minute)
+	}
+
+	loc := now.Location()
+	scheduled = scheduled.In(loc)
+	hour, minute := scheduled.Hour(), scheduled.Minute()
+	if r.Hour >= 0 {
+		hour, minute = r.Hour, r.Minute
+	}
+	day := now.AddDate(0, 0, r.Days)
+	target := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc)
+
+	if r.Hour >= 0 && r.Ambiguous {
+		if r.Days == 0 && !target.After(now) {
+			target = target.Add(12 * time.Hour)
+		} else if r.Days > 0 && hour < 7 {
+			target = target.Add(12 * time.Hour)
+		}
+	}
+	return target
+}
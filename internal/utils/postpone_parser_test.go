@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePostpone(t *testing.T) {
+	const defaultDelay = 15 * time.Minute
+
+	tests := []struct {
+		message string
+		ok      bool
+		want    PostponeRequest
+	}{
+		{"tunda 30 menit", true, PostponeRequest{Delay: 30 * time.Minute, Standalone: true}},
+		{"undur 1 jam 30 menit", true, PostponeRequest{Delay: 90 * time.Minute, Standalone: true}},
+		{"tunda setengah jam", true, PostponeRequest{Delay: 30 * time.Minute, Standalone: true}},
+		{"tunda sejam", true, PostponeRequest{Delay: time.Hour, Standalone: true}},
+		{"Tunda", true, PostponeRequest{Delay: defaultDelay, Standalone: true}},
+		{"snooze", true, PostponeRequest{Delay: defaultDelay, Standalone: true}},
+		{"pindah ke besok", true, PostponeRequest{Days: 1, Hour: -1, Standalone: true}},
+		{"geser ke lusa", true, PostponeRequest{Days: 2, Hour: -1, Standalone: true}},
+		{"pindah minggu depan", true, PostponeRequest{Days: 7, Hour: -1, Standalone: true}},
+		{"pindah besok jam 5", true, PostponeRequest{Days: 1, Hour: 5, Ambiguous: true, Standalone: true}},
+		{"pindah besok jam 5 sore", true, PostponeRequest{Days: 1, Hour: 17, Standalone: true}},
+		{"tunda ke besok pagi", true, PostponeRequest{Days: 1, Hour: 7, Standalone: true}},
+		{"pindah jam 19.30", true, PostponeRequest{Hour: 19, Minute: 30, Standalone: true}},
+		{"geser ke 07:15", true, PostponeRequest{Hour: 7, Minute: 15, Ambiguous: true, Standalone: true}},
+		{"pindah jam 12 malam", true, PostponeRequest{Hour: 0, Standalone: true}},
+		{"pindah jam 12", true, PostponeRequest{Hour: 12, Standalone: true}},
+		{"pindah jam 8 malam", true, PostponeRequest{Hour: 20, Standalone: true}},
+		{"nanti jam 5", true, PostponeRequest{Hour: 5, Ambiguous: true, Standalone: true}},
+		{"nanti sore aja ya", true, PostponeRequest{Hour: 16, Standalone: true}},
+		// Postpones only when quoting a reminder
+		{"nanti jam 5 saya rapat", true, PostponeRequest{Hour: 5, Ambiguous: true}},
+		{"oke, tunda 10 menit", true, PostponeRequest{Delay: 10 * time.Minute}},
+		// Not postpone requests
+		{"nanti", false, PostponeRequest{}},
+		{"pindah", false, PostponeRequest{}},
+		{"saya mau olahraga besok jam 6", false, PostponeRequest{}},
+		{"pindah jam 25", false, PostponeRequest{}},
+		{"pindah jam 10.75", false, PostponeRequest{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			got, ok := ParsePostpone(tt.message, defaultDelay)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostponeRequestTarget(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, jakarta)
+	}
+
+	tests := []struct {
+		name      string
+		req       PostponeRequest
+		scheduled time.Time
+		now       time.Time
+		want      time.Time
+	}{
+		{
+			name:      "delay from the scheduled time",
+			req:       PostponeRequest{Delay: 30 * time.Minute},
+			scheduled: at(10, 9, 0),
+			now:       at(10, 8, 50),
+			want:      at(10, 9, 30),
+		},
+		{
+			name:      "delay from now once the activity is due",
+			req:       PostponeRequest{Delay: 30 * time.Minute},
+			scheduled: at(10, 9, 0),
+			now:       at(10, 9, 20),
+			want:      at(10, 9, 50),
+		},
+		{
+			name:      "setengah jam",
+			req:       PostponeRequest{Delay: 30 * time.Minute},
+			scheduled: at(10, 14, 0),
+			now:       at(10, 14, 5),
+			want:      at(10, 14, 35),
+		},
+		{
+			name:      "tomorrow keeps the time of day",
+			req:       PostponeRequest{Days: 1, Hour: -1},
+			scheduled: at(10, 9, 15),
+			now:       at(10, 9, 0),
+			want:      at(11, 9, 15),
+		},
+		{
+			name:      "besok jam 5 is the afternoon",
+			req:       PostponeRequest{Days: 1, Hour: 5, Ambiguous: true},
+			scheduled: at(10, 9, 0),
+			now:       at(10, 9, 0),
+			want:      at(11, 17, 0),
+		},
+		{
+			name:      "besok jam 8 is the morning",
+			req:       PostponeRequest{Days: 1, Hour: 8, Ambiguous: true},
+			scheduled: at(10, 9, 0),
+			now:       at(10, 9, 0),
+			want:      at(11, 8, 0),
+		},
+		{
+			name:      "jam 5 today still ahead in the morning",
+			req:       PostponeRequest{Hour: 5, Ambiguous: true},
+			scheduled: at(10, 4, 0),
+			now:       at(10, 4, 30),
+			want:      at(10, 5, 0),
+		},
+		{
+			name:      "jam 5 today already passed means the afternoon",
+			req:       PostponeRequest{Hour: 5, Ambiguous: true},
+			scheduled: at(10, 9, 0),
+			now:       at(10, 9, 0),
+			want:      at(10, 17, 0),
+		},
+		{
+			name:      "unambiguous hour is kept even if passed",
+			req:       PostponeRequest{Hour: 8},
+			scheduled: at(10, 9, 0),
+			now:       at(10, 9, 0),
+			want:      at(10, 8, 0),
+		},
+		{
+			name:      "scheduled time in another zone",
+			req:       PostponeRequest{Days: 1, Hour: -1},
+			scheduled: time.Date(2024, time.March, 10, 2, 0, 0, 0, time.UTC),
+			now:       at(10, 8, 0),
+			want:      at(11, 9, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.Target(tt.scheduled, tt.now); !got.Equal(tt.want) {
+				t.Errorf("Target = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
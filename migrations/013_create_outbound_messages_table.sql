@@ -0,0 +1,30 @@
+-- Create OUTBOUND_MESSAGES table (transactional outbox for WhatsApp sends)
+CREATE TABLE IF NOT EXISTS outbound_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    idempotency_key VARCHAR(255) NOT NULL UNIQUE,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    chat_id VARCHAR(100) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'sent', 'failed')),
+    attempts INTEGER DEFAULT 0,
+    max_attempts INTEGER DEFAULT 5 CHECK (max_attempts >= 1),
+    next_attempt_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    waha_message_id VARCHAR(255),
+    message_history_id UUID REFERENCES message_history(id) ON DELETE SET NULL,
+    alert_log_id UUID REFERENCES alert_logs(id) ON DELETE SET NULL,
+    sent_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_status_next_attempt ON outbound_messages(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_user_id ON outbound_messages(user_id);
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_waha_message_id ON outbound_messages(waha_message_id);
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_alert_log_id ON outbound_messages(alert_log_id);
+
+-- Create trigger to auto-update updated_at
+DROP TRIGGER IF EXISTS update_outbound_messages_updated_at ON outbound_messages;
+CREATE TRIGGER update_outbound_messages_updated_at BEFORE UPDATE ON outbound_messages
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
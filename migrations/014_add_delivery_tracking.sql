@@ -0,0 +1,24 @@
+-- Track WhatsApp delivery/read receipts (Waha message.ack events)
+
+-- Outbox: ack state and link to the health recommendation being delivered
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS delivery_status VARCHAR(20);
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS recommendation_id UUID REFERENCES health_recommendations(id) ON DELETE SET NULL;
+
+-- Outgoing message history
+ALTER TABLE message_history ADD COLUMN IF NOT EXISTS waha_message_id VARCHAR(255);
+ALTER TABLE message_history ADD COLUMN IF NOT EXISTS delivery_status VARCHAR(20);
+ALTER TABLE message_history ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE message_history ADD COLUMN IF NOT EXISTS read_at TIMESTAMP WITH TIME ZONE;
+
+-- Alert logs
+ALTER TABLE alert_logs ADD COLUMN IF NOT EXISTS waha_message_id VARCHAR(255);
+ALTER TABLE alert_logs ADD COLUMN IF NOT EXISTS delivery_status VARCHAR(20);
+ALTER TABLE alert_logs ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE alert_logs ADD COLUMN IF NOT EXISTS read_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_message_history_waha_message_id ON message_history(waha_message_id);
+CREATE INDEX IF NOT EXISTS idx_alert_logs_waha_message_id ON alert_logs(waha_message_id);
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_recommendation_id ON outbound_messages(recommendation_id);
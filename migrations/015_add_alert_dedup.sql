@@ -0,0 +1,34 @@
+-- Exactly-once scheduled alerts: one morning alert and one evening summary per
+-- user per local date. The row is reserved before the alert is generated, so a
+-- restarted or second scheduler instance skips users that were already handled.
+
+ALTER TABLE alert_logs ADD COLUMN IF NOT EXISTS alert_date DATE;
+
+-- Backfill existing alerts. Only the first alert per (user, type, local date) gets
+-- a date so duplicates sent before this migration don't break the unique index.
+UPDATE alert_logs a
+SET alert_date = first_alert.alert_date
+FROM (
+    SELECT DISTINCT ON (al.user_id, al.alert_type, local_date.alert_date)
+        al.id, local_date.alert_date
+    FROM alert_logs al
+    JOIN users u ON u.id = al.user_id
+    CROSS JOIN LATERAL (
+        SELECT (al.scheduled_time AT TIME ZONE COALESCE(u.timezone, 'Asia/Jakarta'))::date AS alert_date
+    ) local_date
+    WHERE al.alert_date IS NULL
+      AND al.alert_type IN ('morning_alert', 'evening_summary')
+      AND NOT EXISTS (
+          SELECT 1 FROM alert_logs existing
+          WHERE existing.user_id = al.user_id
+            AND existing.alert_type = al.alert_type
+            AND existing.alert_date = local_date.alert_date
+      )
+    ORDER BY al.user_id, al.alert_type, local_date.alert_date, al.created_at
+) first_alert
+WHERE a.id = first_alert.id;
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alert_logs_user_type_date
+    ON alert_logs(user_id, alert_type, alert_date)
+    WHERE alert_date IS NOT NULL;
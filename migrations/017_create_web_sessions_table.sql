@@ -0,0 +1,34 @@
+-- Web dashboard login: one-time codes sent over WhatsApp and the sessions
+-- created from them. Only SHA-256 hashes of codes and session tokens are stored.
+CREATE TABLE IF NOT EXISTS login_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Failed login guesses across all of a user's codes, for the rolling lockout
+CREATE TABLE IF NOT EXISTS login_failures (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS web_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    csrf_token VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_login_codes_user_id_created_at ON login_codes(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_login_failures_user_id_created_at ON login_failures(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_web_sessions_user_id ON web_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_web_sessions_expires_at ON web_sessions(expires_at);
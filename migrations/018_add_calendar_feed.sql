@@ -0,0 +1,8 @@
+-- iCalendar feed: a secret token per user for the feed URL, and an optional
+-- RFC 5545 recurrence rule (e.g. FREQ=WEEKLY;BYDAY=MO) on activities
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_token VARCHAR(64);
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS recurrence_rule VARCHAR(255);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_calendar_token ON users(calendar_token) WHERE calendar_token IS NOT NULL;
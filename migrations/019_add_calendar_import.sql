@@ -0,0 +1,25 @@
+-- iCalendar import: the UID of the source event on imported activities, so a
+-- re-import updates them instead of creating duplicates, and calendar URLs
+-- that are polled for changes
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS external_uid VARCHAR(255);
+
+CREATE TABLE IF NOT EXISTS calendar_subscriptions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    etag VARCHAR(255),
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, url)
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_activities_user_id_external_uid ON activities(user_id, external_uid) WHERE external_uid IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_calendar_subscriptions_last_synced_at ON calendar_subscriptions(last_synced_at);
+
+-- Create trigger for updated_at
+DROP TRIGGER IF EXISTS update_calendar_subscriptions_updated_at ON calendar_subscriptions;
+CREATE TRIGGER update_calendar_subscriptions_updated_at BEFORE UPDATE ON calendar_subscriptions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,3 @@
+-- Media attached to incoming messages (voice notes are transcribed)
+ALTER TABLE message_history ADD COLUMN IF NOT EXISTS media_type VARCHAR(20);
+ALTER TABLE message_history ADD COLUMN IF NOT EXISTS transcript TEXT;
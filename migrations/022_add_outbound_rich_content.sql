@@ -0,0 +1,7 @@
+-- Rich outbound messages: buttons, lists, polls, images and files. The
+-- payload holds the choices or media; body stays the text fallback's main text.
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS content_type VARCHAR(20) DEFAULT 'text';
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS payload JSONB;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_user_id_sent_at ON outbound_messages(user_id, sent_at DESC);
@@ -0,0 +1,27 @@
+-- Postponed activities: how often each activity was pushed back, and a log of
+-- every postponement so summaries can show procrastination patterns
+
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS postpone_count INTEGER NOT NULL DEFAULT 0;
+-- The activity a reminder is about, to find what a reply postpones
+ALTER TABLE alert_logs ADD COLUMN IF NOT EXISTS activity_id UUID REFERENCES activities(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS activity_postponements (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    activity_id UUID NOT NULL REFERENCES activities(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- The reminder the user answered, if any
+    alert_log_id UUID REFERENCES alert_logs(id) ON DELETE SET NULL,
+    from_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    to_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_alert_logs_activity_id ON alert_logs(activity_id);
+-- A reminder is sent at most once per activity and reminder time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alert_logs_activity_reminder
+    ON alert_logs(activity_id, scheduled_time)
+    WHERE alert_type = 'activity_reminder' AND activity_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_activity_postponements_activity_id ON activity_postponements(activity_id);
+CREATE INDEX IF NOT EXISTS idx_activity_postponements_user_created_at ON activity_postponements(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_alert_logs_user_type_sent_at ON alert_logs(user_id, alert_type, sent_at DESC);
@@ -0,0 +1,9 @@
+-- Multiple WhatsApp numbers (Waha sessions): users are bound to the session
+-- they first messaged, and every outbound message records the session it is
+-- sent through. NULL means the default session.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS waha_session VARCHAR(100);
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS session VARCHAR(100);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_waha_session ON users(waha_session);
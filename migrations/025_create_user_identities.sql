@@ -0,0 +1,43 @@
+-- Messaging channels: a user can talk to the assistant on WhatsApp and
+-- Telegram, with one identity per channel. Users who only use Telegram have no
+-- WhatsApp number.
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    -- WhatsApp number or Telegram user ID
+    external_id VARCHAR(100) NOT NULL,
+    -- Where messages to the user are sent
+    chat_id VARCHAR(100) NOT NULL,
+    -- Waha session or bot the user talks to; NULL for the default one
+    account VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (channel, external_id)
+);
+
+-- Codes to link an identity on another channel to an existing user
+CREATE TABLE IF NOT EXISTS identity_link_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ALTER COLUMN whatsapp_number DROP NOT NULL;
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS channel VARCHAR(20) NOT NULL DEFAULT 'whatsapp';
+
+-- Existing users talk to us on WhatsApp
+INSERT INTO user_identities (user_id, channel, external_id, chat_id, account, created_at, last_seen_at)
+SELECT id, 'whatsapp', whatsapp_number, whatsapp_number, waha_session, created_at,
+       COALESCE(last_interaction_at, created_at)
+FROM users
+WHERE whatsapp_number IS NOT NULL
+ON CONFLICT (channel, external_id) DO NOTHING;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_last_seen ON user_identities(user_id, last_seen_at DESC);
+CREATE INDEX IF NOT EXISTS idx_identity_link_codes_code_hash ON identity_link_codes(code_hash);
@@ -0,0 +1,21 @@
+-- WhatsApp addresses a user either by phone number ("628…@c.us") or by LID
+-- ("…@lid"). Each contact maps both to the user's WhatsApp identity, so the
+-- same person is one user whichever ID a message arrives with. Contacts are
+-- filled in as users send messages: a LID alone can't be told apart from a
+-- phone number, so existing identities are not backfilled.
+
+CREATE TABLE IF NOT EXISTS whatsapp_contacts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    identity_id UUID NOT NULL REFERENCES user_identities(id) ON DELETE CASCADE,
+    lid VARCHAR(64) UNIQUE,
+    -- E.164 without the plus
+    phone_number VARCHAR(20) UNIQUE,
+    -- Canonical chat ID messages to the contact are sent to
+    chat_id VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (lid IS NOT NULL OR phone_number IS NOT NULL)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_whatsapp_contacts_identity_id ON whatsapp_contacts(identity_id);
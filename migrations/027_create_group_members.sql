@@ -0,0 +1,19 @@
+-- Group chats: a WhatsApp group has its own shared calendar, kept as a users
+-- row that is reached through the group's identity (external_id and chat_id
+-- are the "…@g.us" chat ID). Activities, reminders and summaries of that row
+-- go to the group. Members are recorded as they write in the group, and
+-- activities can be assigned to one of them.
+
+CREATE TABLE IF NOT EXISTS group_members (
+    group_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Name the member goes by in the group
+    display_name VARCHAR(255),
+    -- Member's chat ID, used to @mention them in the group
+    chat_id VARCHAR(100) NOT NULL,
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id)
+);
+
+ALTER TABLE activities ADD COLUMN IF NOT EXISTS assignee_id UUID REFERENCES users(id) ON DELETE SET NULL;
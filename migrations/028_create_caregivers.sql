@@ -0,0 +1,27 @@
+-- Caregivers: numbers a user authorized to be alerted when they miss a
+-- medication or health activity (e.g. a family member of an elderly user).
+-- Escalations and the optional daily adherence digest are logged in
+-- alert_logs under the user they are about.
+
+CREATE TABLE IF NOT EXISTS caregivers (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255),
+    -- Caregiver's WhatsApp number, e.g. "6281234567890"
+    phone_number VARCHAR(20) NOT NULL,
+    daily_digest BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, phone_number)
+);
+
+ALTER TABLE alert_logs DROP CONSTRAINT IF EXISTS alert_logs_alert_type_check;
+ALTER TABLE alert_logs ADD CONSTRAINT alert_logs_alert_type_check
+    CHECK (alert_type IN ('morning_alert', 'evening_summary', 'activity_reminder', 'caregiver_escalation', 'caregiver_digest'));
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_caregivers_daily_digest ON caregivers(user_id) WHERE daily_digest = true;
+-- A missed activity is escalated once per scheduled time; postponing it
+-- and missing it again escalates again
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alert_logs_caregiver_escalation
+    ON alert_logs(activity_id, scheduled_time)
+    WHERE alert_type = 'caregiver_escalation' AND activity_id IS NOT NULL;
@@ -0,0 +1,5 @@
+-- Correlation ID of the request that queued an outbound message (e.g. the
+-- webhook of the message it answers), so the delivery is logged under the
+-- same ID as the rest of that request's work
+
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(64);
@@ -0,0 +1,4 @@
+-- W3C traceparent of the span that queued an outbound message, so its
+-- delivery by the outbox dispatcher shows up in the same trace
+
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS trace_parent VARCHAR(55);
@@ -0,0 +1,12 @@
+-- Cached LLM responses (parsed intents, generated alerts), shared by all
+-- instances. The key is a SHA-256 of the operation, model and prompt inputs;
+-- value holds the response with the tokens it cost, to count tokens saved.
+CREATE TABLE IF NOT EXISTS llm_cache (
+    key VARCHAR(64) PRIMARY KEY,
+    value JSONB NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_llm_cache_expires_at ON llm_cache(expires_at);
@@ -0,0 +1,22 @@
+-- 025 backfilled WhatsApp identities with users.whatsapp_number as the chat
+-- ID, without the "@c.us" or "@lid" it came from, and the Waha client took
+-- every bare ID for a phone number. Before contacts existed that column held
+-- LIDs as well, so those users were sent to "<lid>@c.us".
+
+-- Identities whose contact is known get the contact's chat ID
+UPDATE user_identities i
+SET chat_id = c.chat_id
+FROM whatsapp_contacts c
+WHERE c.identity_id = i.id
+  AND i.channel = 'whatsapp'
+  AND position('@' IN i.chat_id) = 0;
+
+-- Longer than any E.164 number, so a LID
+UPDATE user_identities
+SET chat_id = chat_id || '@lid'
+WHERE channel = 'whatsapp'
+  AND chat_id ~ '^[0-9]{16,}$';
+
+-- The remaining bare chat IDs are ambiguous and stay bare: the Waha client
+-- asks the session whether one is a LID before sending, and the next message
+-- from the user replaces it with their canonical chat ID.
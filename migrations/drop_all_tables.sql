@@ -2,6 +2,19 @@
 -- Jangan jalankan di production!
 
 -- Drop tables in reverse order of dependencies
+DROP TABLE IF EXISTS llm_cache CASCADE;
+DROP TABLE IF EXISTS caregivers CASCADE;
+DROP TABLE IF EXISTS group_members CASCADE;
+DROP TABLE IF EXISTS whatsapp_contacts CASCADE;
+DROP TABLE IF EXISTS identity_link_codes CASCADE;
+DROP TABLE IF EXISTS user_identities CASCADE;
+DROP TABLE IF EXISTS activity_postponements CASCADE;
+DROP TABLE IF EXISTS pending_confirmations CASCADE;
+DROP TABLE IF EXISTS calendar_subscriptions CASCADE;
+DROP TABLE IF EXISTS web_sessions CASCADE;
+DROP TABLE IF EXISTS login_failures CASCADE;
+DROP TABLE IF EXISTS login_codes CASCADE;
+DROP TABLE IF EXISTS outbound_messages CASCADE;
 DROP TABLE IF EXISTS scheduled_alerts CASCADE;
 DROP TABLE IF EXISTS alert_logs CASCADE;
 DROP TABLE IF EXISTS message_history CASCADE;
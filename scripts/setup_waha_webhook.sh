@@ -76,7 +76,7 @@ fi
 
 CURL_CMD="$CURL_CMD -d '{
   \"url\": \"${WEBHOOK_URL}\",
-  \"events\": [\"message\"]
+  \"events\": [\"message\", \"message.ack\", \"poll.vote\"]
 }'"
 
 echo "Executing: $CURL_CMD"
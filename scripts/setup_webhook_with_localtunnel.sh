@@ -60,7 +60,7 @@ fi
 
 CURL_CMD="$CURL_CMD -d '{
   \"url\": \"${WEBHOOK_URL}\",
-  \"events\": [\"message\"]
+  \"events\": [\"message\", \"message.ack\"]
 }'"
 
 echo "Executing: $CURL_CMD"